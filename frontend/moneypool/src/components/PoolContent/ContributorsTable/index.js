@@ -0,0 +1,36 @@
+import {Container, Table, Tag, Tbody, Td, Text, Tr} from "@chakra-ui/react";
+
+const statusColors = {"open": "red", "underpaid": "orange", "paid": "teal", "overpaid": "purple"};
+
+function ContributorsTable(props) {
+
+    const contributors = props.data["contributors"];
+    const amountsHidden = props.data["amountsHidden"] === true;
+    const expected = props.data["expected"]["amount"];
+
+    function formatAmount(amount) {
+        return amount["base"] + "," + (amount["fraction"] === 0 ? "-" : String(amount["fraction"]).padStart(2, "0"));
+    }
+
+    return (
+        <Container w={'90%'}>
+            <Text fontSize='md'>{formatAmount(expected)} per person</Text>
+            <Table variant='simple' size={'sm'}>
+                <Tbody>
+                    {contributors.map((contributor, idx) =>
+                        <Tr key={"contributor-" + idx}>
+                            <Td><Text>{contributor["name"]}</Text></Td>
+                            <Td><Tag size={'sm'} colorScheme={statusColors[contributor["status"]]}>{contributor["status"]}</Tag></Td>
+                            <Td isNumeric={true}>
+                                {!amountsHidden && contributor["status"] !== "paid" && contributor["status"] !== "overpaid" &&
+                                    <Text>{formatAmount(contributor["open"])} open</Text>}
+                            </Td>
+                        </Tr>
+                    )}
+                </Tbody>
+            </Table>
+        </Container>
+    )
+}
+
+export default ContributorsTable;
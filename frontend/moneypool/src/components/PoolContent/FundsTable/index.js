@@ -3,6 +3,8 @@ import {Container, Table, Tbody, Td, Text, Tr} from "@chakra-ui/react";
 function FundsTable(props) {
 
     const transactions = props.data["transactions"];
+    const amountsHidden = props.data["amountsHidden"] === true;
+    const baseCurrency = props.data["baseCurrency"] ? props.data["baseCurrency"] : "EUR";
     transactions.reverse();
     const infos = [];
     let allSameYear = true;
@@ -11,12 +13,22 @@ function FundsTable(props) {
         let base = tr["base"];
         let fraction = tr["fraction"];
         let amount = base + ",";
-        amount += (fraction === 0) ? "-" : fraction;
+        // amounts of currencies with three decimals have their fraction in thousandths
+        amount += (fraction === 0) ? "-" : (tr["decimals"] ? String(fraction).padStart(tr["decimals"], "0") : fraction);
+        if (tr["refundOf"]) {
+            amount = "-" + amount;
+        }
+        if (tr["currency"] && tr["currency"] !== baseCurrency) {
+            amount += " " + tr["currency"];
+        }
+        if (amountsHidden) {
+            amount = "";
+        }
         let date = stringToDate(tr["date"])
         if (date["year"] !== baseYear) {
             allSameYear = false;
         }
-        infos.push({"name": tr["name"], "amount": amount, "date": tr["date"]})
+        infos.push({"name": tr["name"], "amount": amount, "date": tr["date"], "voided": tr["voided"] === true})
     });
 
     if(allSameYear) {
@@ -39,7 +51,7 @@ function FundsTable(props) {
             <Table variant='striped'>
                 <Tbody>
                     {infos.map((item, idx) =>
-                        <Tr key={"tr-" + idx}>
+                        <Tr key={"tr-" + idx} textDecoration={item.voided ? "line-through" : "none"}>
                             <Td key={"td1-" + idx}><Text key={"txt1-" + idx}>{item.date ? item.date : "20.02."}</Text></Td>
                             <Td key={"td2-" + idx}><Text key={"txt2-" + idx}>{item.name}</Text></Td>
                             <Td key={"td3-" + idx} isNumeric={true}><Text key={"txt3-" + idx}>{item.amount}</Text></Td>
@@ -9,16 +9,44 @@ function InfoSection(props) {
     let name = (props.data === null) ? "" : props.data["name"];
     let title = (props.data === null) ? "" : props.data["title"];
     let open = (props.data === null) ? "" : props.data["open"];
-    let paypalLink = props.paypalLink;
+    // pools with a payee of their own link to it instead of the deployment's paypal link
+    let paypalLink = (props.data !== null && props.data["payUrl"]) ? props.data["payUrl"] : props.paypalLink;
+    const image = (props.data === null) ? "" : props.data["imageUrl"];
+    const recipient = (props.data === null) ? "" : props.data["recipient"];
+    const description = (props.data === null) ? "" : props.data["description"];
+    const instructions = (props.data === null) ? "" : props.data["instructions"];
+
+    // totals below zero are negative, their base and fraction are not
+    function totalValue(total) {
+        let value = total["base"] + total["fraction"] / Math.pow(10, total["decimals"] || 2);
+        return total["negative"] ? -value : value;
+    }
 
     let sum = 0;
     transactions.forEach(tr => {
+        // voided transactions don't count, refunds are paid back
+        if (tr["voided"]) {
+            return;
+        }
         let base = tr["base"];
         let fraction = tr["fraction"];
-        sum += base + (fraction/100);
+        let amount = base + fraction / Math.pow(10, tr["decimals"] || 2);
+        sum += tr["refundOf"] ? -amount : amount;
     });
+    // pools that hide individual amounts only report their total, pools with several currencies their converted total
+    if (props.data !== null && props.data["total"]) {
+        sum = totalValue(props.data["total"]);
+    }
+    if (props.data !== null && props.data["convertedTotal"]) {
+        sum = totalValue(props.data["convertedTotal"]);
+    }
+    const currency = (props.data === null || !props.data["baseCurrency"]) ? "EUR" : props.data["baseCurrency"];
+    const missingRates = (props.data === null || !props.data["missingRates"]) ? [] : props.data["missingRates"];
 
-    const sumText = parseFloat(sum).toFixed(2) + "€";
+    let sumText = parseFloat(sum).toFixed(2) + (currency === "EUR" ? "€" : " " + currency);
+    if (missingRates.length > 0) {
+        sumText += " + " + missingRates.join(", ");
+    }
 
     return (
         <Container>
@@ -27,6 +55,7 @@ function InfoSection(props) {
             {!dataLoaded && <Center><Skeleton id={'sum-text-skel'} w={"100px"} h={"40px"}/></Center>}
             <Text id={'contrib-text'} fontSize='xs'>contributed for</Text>
             {dataLoaded && <Text id={'title-text'} fontSize='3xl'>{title}</Text>}
+            {dataLoaded && recipient && <Text fontSize='xs'>for {recipient}</Text>}
             {!dataLoaded && <Center><Skeleton id={'title-text-skel'} w={"300px"} h={"30px"}/></Center>}
             {dataLoaded && <Center id={'tags-stack'}>
                 <Tag className={'tag'} variant='solid' size={'sm'} colorScheme={open ? 'teal':'red'}>{open ? 'Open' : 'Closed'}</Tag>
@@ -34,11 +63,15 @@ function InfoSection(props) {
                 <Tag className={'tag'} variant='solid' size={'sm'} colorScheme='teal'>No Limit</Tag>
             </Center>}
             <hr id={'hr-top'}/>
+            {dataLoaded && image && <Image className={'centered'} src={image} maxH={'200px'}/>}
+            {/* descriptions are markdown, shown as plain text until the frontend renders markdown safely */}
+            {dataLoaded && description && <Text fontSize='md' whiteSpace={'pre-wrap'}>{description}</Text>}
             {dataLoaded && !open && <Text fontSize={'md'}>Thank you for your contributions!</Text>}
             {dataLoaded && open && <Text fontSize='md'>Want to contribute?</Text>}
             {dataLoaded && open && <Text fontSize='md'>Send funds via <Link color='teal' href={paypalLink} isExternal={true}>
                 PayPal
             </Link> and start your message with '{name}'.</Text>}
+            {dataLoaded && open && instructions && <Text fontSize='md' whiteSpace={'pre-wrap'}>{instructions}</Text>}
             <hr id={'hr-bottom'}/>
         </Container>
     )
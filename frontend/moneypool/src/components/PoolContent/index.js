@@ -1,6 +1,7 @@
 import {Center, Container, Skeleton, Stack} from "@chakra-ui/react";
 import InfoSection from "./InfoSection";
 import FundsTable from "./FundsTable";
+import ContributorsTable from "./ContributorsTable";
 import {useEffect, useState} from "react";
 
 
@@ -9,6 +10,8 @@ function PoolContent() {
     const endpoint = "";
     const apiKey = "";
     const paypalLink = "";
+    // websocket url from the stack output LiveConnectionsUrl, leave empty to disable live updates
+    const liveEndpoint = "";
 
     const [mpData, setMpData] = useState(null);
 
@@ -17,18 +20,53 @@ function PoolContent() {
        if(mpName === null) {
            return;
        }
-        fetch(endpoint + mpName, {
-            "headers": {
-                "x-api-key": apiKey
-            }
+        const requestHeaders = {"x-api-key": apiKey};
+        const token = getUrlParam("token");
+        if(token !== null) {
+            requestHeaders["x-pool-token"] = token;
+        }
+        const loadPool = () => fetch(endpoint + mpName, {
+            "headers": requestHeaders
         }).then(resp => resp.json()).then(resp => {
             setMpData(resp)
         })
+        loadPool();
+
+        if(liveEndpoint === "") {
+            return;
+        }
+        let socket = null;
+        let closed = false;
+        const connect = () => {
+            let url = liveEndpoint + "?moneyPool=" + encodeURIComponent(mpName);
+            if(token !== null) {
+                url += "&token=" + encodeURIComponent(token);
+            }
+            socket = new WebSocket(url);
+            socket.onmessage = msg => {
+                const event = JSON.parse(msg.data);
+                if (event["type"] === "contribution.added") {
+                    loadPool();
+                }
+            };
+            // api gateway closes idle connections, so we reconnect to keep the page live
+            socket.onclose = () => {
+                if (!closed) {
+                    setTimeout(connect, 5000);
+                }
+            };
+        };
+        connect();
+        return () => {
+            closed = true;
+            socket.close();
+        };
     }, [])
 
     return(
         <Container id={'content'}>
             <InfoSection data={mpData} paypalLink={paypalLink}/>
+            {mpData != null && mpData["contributors"] && <ContributorsTable data={mpData}/>}
             {mpData != null && <FundsTable data={mpData}/>}
             {mpData === null &&
                 <Center>
@@ -43,6 +81,10 @@ function PoolContent() {
     )
 
     function getMpName() {
+        return getUrlParam("mp");
+    }
+
+    function getUrlParam(name) {
         let split = window.location.href.split("?");
         if(split === null || split.length < 2) {
             return null;
@@ -51,8 +93,8 @@ function PoolContent() {
         for(let i = 0; i < params.length; i++) {
             let param = params[i];
             let kv = param.split("=");
-            if (kv[0].toLowerCase() === "mp") {
-                return kv[1];
+            if (kv[0].toLowerCase() === name) {
+                return decodeURIComponent(kv[1]);
             }
         }
         return null;
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minRefreshInterval limits how often unknown key ids trigger a reload of the key set.
+const minRefreshInterval = time.Minute
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// KeySet loads the public keys of an issuer from a JWKS document and caches them.
+// The location is either an http(s) url or a file path, optionally prefixed with file://, for local tests.
+type KeySet struct {
+	location    string
+	issuer      string
+	httpClient  *http.Client
+	mutex       sync.Mutex
+	keys        map[string]crypto.PublicKey
+	lastRefresh time.Time
+}
+
+func NewKeySet(location string) *KeySet {
+	return &KeySet{
+		location:   location,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// NewDiscoveredKeySet finds the key set location in the issuer's openid configuration on first use.
+func NewDiscoveredKeySet(issuer string) *KeySet {
+	keySet := NewKeySet("")
+	keySet.issuer = issuer
+	return keySet
+}
+
+// Key returns the key with the given id. The key set is reloaded if the id is unknown, e.g. after a key rotation.
+func (s *KeySet) Key(kid string) (crypto.PublicKey, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if key, exists := s.keys[kid]; exists {
+		return key, nil
+	}
+	if s.keys != nil && time.Since(s.lastRefresh) < minRefreshInterval {
+		return nil, fmt.Errorf("unknown key id %s", kid)
+	}
+	keys, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	s.keys = keys
+	s.lastRefresh = time.Now()
+	if key, exists := s.keys[kid]; exists {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %s", kid)
+}
+
+func (s *KeySet) load() (map[string]crypto.PublicKey, error) {
+	if s.location == "" {
+		location, err := s.discover()
+		if err != nil {
+			return nil, err
+		}
+		s.location = location
+	}
+	raw, err := s.read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read key set from %s: %v", s.location, err)
+	}
+	var set jwkSet
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("could not parse key set from %s: %v", s.location, err)
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		publicKey, err := key.publicKey()
+		if err != nil {
+			// an issuer might publish key types we don't support, that must not break the supported ones
+			continue
+		}
+		keys[key.Kid] = publicKey
+	}
+	return keys, nil
+}
+
+func (s *KeySet) discover() (string, error) {
+	configurationUrl := strings.TrimRight(s.issuer, "/") + "/.well-known/openid-configuration"
+	raw, err := s.get(configurationUrl)
+	if err != nil {
+		return "", fmt.Errorf("could not read openid configuration from %s: %v", configurationUrl, err)
+	}
+	var configuration struct {
+		JwksUri string `json:"jwks_uri"`
+	}
+	if err := json.Unmarshal(raw, &configuration); err != nil || configuration.JwksUri == "" {
+		return "", fmt.Errorf("openid configuration of %s contains no jwks_uri", s.issuer)
+	}
+	return configuration.JwksUri, nil
+}
+
+func (s *KeySet) read() ([]byte, error) {
+	if !strings.HasPrefix(s.location, "http://") && !strings.HasPrefix(s.location, "https://") {
+		return ioutil.ReadFile(strings.TrimPrefix(s.location, "file://"))
+	}
+	return s.get(s.location)
+}
+
+func (s *KeySet) get(url string) ([]byte, error) {
+	resp, err := s.httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
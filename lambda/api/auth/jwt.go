@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"api/headers"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// leeway tolerates small clock differences between the issuer and the lambda.
+const leeway = time.Minute
+
+// Claims are the verified claims of a bearer token that the api relies on.
+type Claims struct {
+	Subject string
+	Email   string
+}
+
+type KeyProvider interface {
+	Key(kid string) (crypto.PublicKey, error)
+}
+
+// Verifier validates JWT bearer tokens issued by a single OIDC issuer for a single audience.
+type Verifier struct {
+	issuer   string
+	audience string
+	keys     KeyProvider
+	now      func() time.Time
+}
+
+func NewVerifier(issuer, audience string, keys KeyProvider) *Verifier {
+	return &Verifier{
+		issuer:   issuer,
+		audience: audience,
+		keys:     keys,
+		now:      time.Now,
+	}
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type payload struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	ClientId  string   `json:"client_id"`
+	Email     string   `json:"email"`
+	ExpiresAt *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+}
+
+// audience is either a single string or a list of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(raw []byte) error {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		*a = []string{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// Verify checks the token's signature, issuer, audience and lifetime and returns its claims.
+func (v *Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, fmt.Errorf("token is not a jwt")
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return Claims{}, fmt.Errorf("invalid token header: %v", err)
+	}
+	key, err := v.keys.Key(h.Kid)
+	if err != nil {
+		return Claims{}, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, fmt.Errorf("invalid token signature encoding: %v", err)
+	}
+	if err := verifySignature(h.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return Claims{}, err
+	}
+
+	var p payload
+	if err := decodeSegment(parts[1], &p); err != nil {
+		return Claims{}, fmt.Errorf("invalid token payload: %v", err)
+	}
+	if err := v.validate(p); err != nil {
+		return Claims{}, err
+	}
+	return Claims{Subject: p.Subject, Email: p.Email}, nil
+}
+
+func (v *Verifier) validate(p payload) error {
+	if p.Issuer != v.issuer {
+		return fmt.Errorf("token issued by unexpected issuer %s", p.Issuer)
+	}
+	// cognito access tokens carry the audience as client_id instead of aud
+	if !contains(p.Audience, v.audience) && p.ClientId != v.audience {
+		return fmt.Errorf("token not issued for audience %s", v.audience)
+	}
+	if p.Subject == "" {
+		return fmt.Errorf("token has no subject")
+	}
+	now := v.now()
+	if p.ExpiresAt == nil || now.After(time.Unix(*p.ExpiresAt, 0).Add(leeway)) {
+		return fmt.Errorf("token is expired")
+	}
+	if p.NotBefore != nil && now.Before(time.Unix(*p.NotBefore, 0).Add(-leeway)) {
+		return fmt.Errorf("token is not valid yet")
+	}
+	return nil
+}
+
+// verifySignature supports RS256 and ES256, the algorithms OIDC providers use by default.
+// The algorithm has to match the key type, so a token can't choose a weaker verification.
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key does not match algorithm %s", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("invalid token signature")
+		}
+		return nil
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key does not match algorithm %s", alg)
+		}
+		if len(signature) != 64 {
+			return fmt.Errorf("invalid token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			return fmt.Errorf("invalid token signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported token algorithm %s", alg)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// BearerToken extracts the token of an "Authorization: Bearer <token>" header, if there is one.
+func BearerToken(request events.APIGatewayProxyRequest) string {
+	value := headers.Get(request, "Authorization")
+	if len(value) < 7 || !strings.EqualFold(value[:7], "bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(value[7:])
+}
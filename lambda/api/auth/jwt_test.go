@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	er "errors"
+	"github.com/aws/aws-lambda-go/events"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "moneypool"
+)
+
+var (
+	testNow   = time.Date(2022, 2, 18, 10, 0, 0, 0, time.UTC)
+	rsaKey    *rsa.PrivateKey
+	ecKey     *ecdsa.PrivateKey
+	otherKey  *rsa.PrivateKey
+	jwksFile  string
+	validTime = testNow.Add(time.Hour).Unix()
+)
+
+type verifyTest struct {
+	name           string
+	token          string
+	expectedClaims Claims
+	expectedError  error
+}
+
+func TestVerify(t *testing.T) {
+	setupKeys(t)
+	testTable := []verifyTest{
+		{
+			"valid_rs256",
+			sign(t, "RS256", "rsa", rsaKey, claims(nil)),
+			Claims{Subject: "owner-1", Email: "owner@example.com"},
+			nil,
+		},
+		{
+			"valid_es256",
+			sign(t, "ES256", "ec", ecKey, claims(nil)),
+			Claims{Subject: "owner-1", Email: "owner@example.com"},
+			nil,
+		},
+		{
+			"audience_list",
+			sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"aud": []string{"other", testAudience}})),
+			Claims{Subject: "owner-1", Email: "owner@example.com"},
+			nil,
+		},
+		{
+			"cognito_client_id",
+			sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"aud": nil, "client_id": testAudience})),
+			Claims{Subject: "owner-1", Email: "owner@example.com"},
+			nil,
+		},
+		{
+			"expired",
+			sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"exp": testNow.Add(-2 * time.Minute).Unix()})),
+			Claims{},
+			er.New("token is expired"),
+		},
+		{
+			"expired_within_leeway",
+			sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"exp": testNow.Add(-30 * time.Second).Unix()})),
+			Claims{Subject: "owner-1", Email: "owner@example.com"},
+			nil,
+		},
+		{
+			"no_expiry",
+			sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"exp": nil})),
+			Claims{},
+			er.New("token is expired"),
+		},
+		{
+			"not_yet_valid",
+			sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"nbf": testNow.Add(time.Hour).Unix()})),
+			Claims{},
+			er.New("token is not valid yet"),
+		},
+		{
+			"wrong_issuer",
+			sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"iss": "https://evil.com"})),
+			Claims{},
+			er.New("token issued by unexpected issuer https://evil.com"),
+		},
+		{
+			"wrong_audience",
+			sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"aud": "other"})),
+			Claims{},
+			er.New("token not issued for audience moneypool"),
+		},
+		{
+			"no_subject",
+			sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"sub": ""})),
+			Claims{},
+			er.New("token has no subject"),
+		},
+		{
+			"wrong_key",
+			sign(t, "RS256", "rsa", otherKey, claims(nil)),
+			Claims{},
+			er.New("invalid token signature"),
+		},
+		{
+			"unknown_kid",
+			sign(t, "RS256", "unknown", rsaKey, claims(nil)),
+			Claims{},
+			er.New("unknown key id unknown"),
+		},
+		{
+			"algorithm_mismatch",
+			sign(t, "ES256", "rsa", ecKey, claims(nil)),
+			Claims{},
+			er.New("key does not match algorithm ES256"),
+		},
+		{
+			"none_algorithm",
+			unsigned(t, claims(nil)),
+			Claims{},
+			er.New("unsupported token algorithm none"),
+		},
+		{
+			"not_a_jwt",
+			"abc.def",
+			Claims{},
+			er.New("token is not a jwt"),
+		},
+	}
+	for _, test := range testTable {
+		verifier := NewVerifier(testIssuer, testAudience, NewKeySet("file://"+jwksFile))
+		verifier.now = func() time.Time { return testNow }
+		claims, err := verifier.Verify(test.token)
+		if !compareErrors(err, test.expectedError) || !reflect.DeepEqual(claims, test.expectedClaims) {
+			t.Fatalf("Verify(%s) = %+v, %v but expected %+v, %v", test.name, claims, err, test.expectedClaims, test.expectedError)
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	testTable := map[string]string{
+		"Bearer abc.def.ghi": "abc.def.ghi",
+		"bearer abc":         "abc",
+		"Basic abc":          "",
+		"":                   "",
+	}
+	for header, expected := range testTable {
+		request := events.APIGatewayProxyRequest{Headers: map[string]string{"authorization": header}}
+		if token := BearerToken(request); token != expected {
+			t.Fatalf("BearerToken(%s) = %s, but expected %s", header, token, expected)
+		}
+	}
+}
+
+func setupKeys(t *testing.T) {
+	var err error
+	rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := jwkSet{Keys: []jwk{
+		{Kty: "RSA", Kid: "rsa", Use: "sig", N: encodeBigInt(rsaKey.N), E: encodeBigInt(big.NewInt(int64(rsaKey.E)))},
+		{Kty: "EC", Kid: "ec", Crv: "P-256", X: encodeBigInt(ecKey.X), Y: encodeBigInt(ecKey.Y)},
+		{Kty: "OKP", Kid: "unsupported"},
+	}}
+	raw, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksFile = filepath.Join(t.TempDir(), "jwks.json")
+	if err := ioutil.WriteFile(jwksFile, raw, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func claims(overrides map[string]interface{}) map[string]interface{} {
+	c := map[string]interface{}{
+		"iss":   testIssuer,
+		"sub":   "owner-1",
+		"aud":   testAudience,
+		"email": "owner@example.com",
+		"exp":   validTime,
+	}
+	for key, value := range overrides {
+		if value == nil {
+			delete(c, key)
+			continue
+		}
+		c[key] = value
+	}
+	return c
+}
+
+func sign(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	signed := encodeSegment(t, map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		s, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = s
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func unsigned(t *testing.T, claims map[string]interface{}) string {
+	return encodeSegment(t, map[string]string{"alg": "none", "kid": "rsa"}) + "." + encodeSegment(t, claims) + "."
+}
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func compareErrors(err1, err2 error) bool {
+	if err1 != nil && err2 != nil {
+		return err1.Error() == err2.Error()
+	}
+	return err1 == err2
+}
@@ -0,0 +1,111 @@
+package cors
+
+import (
+	"api/headers"
+	"github.com/aws/aws-lambda-go/events"
+	"strconv"
+	"strings"
+)
+
+// Policy decides which cross-origin requests browsers are allowed to make against the api.
+type Policy struct {
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	MaxAge         int
+}
+
+// NewPolicy creates a policy from a comma separated list of origins, e.g. "https://example.com,http://localhost:8080".
+// Origins are compared exactly, except for the single origin "*" which allows every origin.
+// Bare domains without a scheme are treated as https origins.
+func NewPolicy(allowedOrigins string, allowedMethods, allowedHeaders []string) *Policy {
+	var origins []string
+	for _, origin := range strings.Split(allowedOrigins, ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin == "" {
+			continue
+		}
+		if origin != "*" && !strings.Contains(origin, "://") {
+			origin = "https://" + origin
+		}
+		origins = append(origins, origin)
+	}
+	return &Policy{
+		AllowedOrigins: origins,
+		AllowedMethods: allowedMethods,
+		AllowedHeaders: allowedHeaders,
+		MaxAge:         600,
+	}
+}
+
+// IsPreflight reports whether the request is a cors preflight request that needs to be answered by Preflight.
+func IsPreflight(request events.APIGatewayProxyRequest) bool {
+	return request.HTTPMethod == "OPTIONS" && headers.Get(request, "Origin") != "" && headers.Get(request, "Access-Control-Request-Method") != ""
+}
+
+// Preflight answers a cors preflight request. Requests from unknown origins or for unsupported methods are rejected.
+func (p *Policy) Preflight(request events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	origin := headers.Get(request, "Origin")
+	method := headers.Get(request, "Access-Control-Request-Method")
+	if !p.originAllowed(origin) || !p.methodAllowed(method) {
+		return events.APIGatewayProxyResponse{
+			Headers:    map[string]string{"Vary": "Origin"},
+			StatusCode: 403,
+		}
+	}
+	response := p.Apply(request, events.APIGatewayProxyResponse{StatusCode: 204})
+	response.Headers["Access-Control-Allow-Methods"] = strings.Join(p.AllowedMethods, ",")
+	response.Headers["Access-Control-Allow-Headers"] = strings.Join(p.AllowedHeaders, ",")
+	response.Headers["Access-Control-Max-Age"] = strconv.Itoa(p.MaxAge)
+	return response
+}
+
+// Apply adds the cors headers for the request's origin to the response.
+// The origin is only reflected if it is allowed, but Vary is always set so caches keep responses apart.
+func (p *Policy) Apply(request events.APIGatewayProxyRequest, response events.APIGatewayProxyResponse) events.APIGatewayProxyResponse {
+	if response.Headers == nil {
+		response.Headers = map[string]string{}
+	}
+	response.Headers["Vary"] = "Origin"
+
+	origin := headers.Get(request, "Origin")
+	if !p.originAllowed(origin) {
+		return response
+	}
+	if p.allowsAll() {
+		response.Headers["Access-Control-Allow-Origin"] = "*"
+	} else {
+		response.Headers["Access-Control-Allow-Origin"] = origin
+	}
+	return response
+}
+
+func (p *Policy) originAllowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range p.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) allowsAll() bool {
+	for _, allowed := range p.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) methodAllowed(method string) bool {
+	for _, allowed := range p.AllowedMethods {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package cors
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"reflect"
+	"testing"
+)
+
+type corsTest struct {
+	name            string
+	policy          *Policy
+	request         events.APIGatewayProxyRequest
+	expectedStatus  int
+	expectedHeaders map[string]string
+}
+
+var (
+	methods     = []string{"OPTIONS", "GET"}
+	headerNames = []string{"Content-Type", "X-Api-Key"}
+)
+
+func TestNewPolicy(t *testing.T) {
+	policy := NewPolicy(" https://example.com/, example.org,,http://localhost:8080", methods, headerNames)
+	expected := []string{"https://example.com", "https://example.org", "http://localhost:8080"}
+	if !reflect.DeepEqual(policy.AllowedOrigins, expected) {
+		t.Fatalf("NewPolicy() returned origins %v, but should return %v", policy.AllowedOrigins, expected)
+	}
+}
+
+func TestApply(t *testing.T) {
+	testTable := []corsTest{
+		{
+			"allowed_origin",
+			NewPolicy("https://example.com,http://localhost:8080", methods, headerNames),
+			request("GET", map[string]string{"origin": "http://localhost:8080"}),
+			200,
+			map[string]string{"Vary": "Origin", "Access-Control-Allow-Origin": "http://localhost:8080"},
+		},
+		{
+			"unknown_origin",
+			NewPolicy("https://example.com", methods, headerNames),
+			request("GET", map[string]string{"Origin": "https://evil.com"}),
+			200,
+			map[string]string{"Vary": "Origin"},
+		},
+		{
+			"no_origin",
+			NewPolicy("https://example.com", methods, headerNames),
+			request("GET", nil),
+			200,
+			map[string]string{"Vary": "Origin"},
+		},
+		{
+			"wildcard",
+			NewPolicy("*", methods, headerNames),
+			request("GET", map[string]string{"Origin": "https://any.com"}),
+			200,
+			map[string]string{"Vary": "Origin", "Access-Control-Allow-Origin": "*"},
+		},
+	}
+	for _, test := range testTable {
+		response := test.policy.Apply(test.request, events.APIGatewayProxyResponse{StatusCode: 200})
+		if response.StatusCode != test.expectedStatus || !reflect.DeepEqual(response.Headers, test.expectedHeaders) {
+			t.Fatalf("Apply(%s) = %d, %v but expected %d, %v", test.name, response.StatusCode, response.Headers, test.expectedStatus, test.expectedHeaders)
+		}
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	testTable := []corsTest{
+		{
+			"allowed",
+			NewPolicy("https://example.com", methods, headerNames),
+			request("OPTIONS", map[string]string{"Origin": "https://example.com", "Access-Control-Request-Method": "GET"}),
+			204,
+			map[string]string{
+				"Vary":                         "Origin",
+				"Access-Control-Allow-Origin":  "https://example.com",
+				"Access-Control-Allow-Methods": "OPTIONS,GET",
+				"Access-Control-Allow-Headers": "Content-Type,X-Api-Key",
+				"Access-Control-Max-Age":       "600",
+			},
+		},
+		{
+			"unknown_origin",
+			NewPolicy("https://example.com", methods, headerNames),
+			request("OPTIONS", map[string]string{"Origin": "https://evil.com", "Access-Control-Request-Method": "GET"}),
+			403,
+			map[string]string{"Vary": "Origin"},
+		},
+		{
+			"method_not_allowed",
+			NewPolicy("https://example.com", methods, headerNames),
+			request("OPTIONS", map[string]string{"Origin": "https://example.com", "Access-Control-Request-Method": "DELETE"}),
+			403,
+			map[string]string{"Vary": "Origin"},
+		},
+	}
+	for _, test := range testTable {
+		if !IsPreflight(test.request) {
+			t.Fatalf("IsPreflight(%s) returned false, but should return true", test.name)
+		}
+		response := test.policy.Preflight(test.request)
+		if response.StatusCode != test.expectedStatus || !reflect.DeepEqual(response.Headers, test.expectedHeaders) {
+			t.Fatalf("Preflight(%s) = %d, %v but expected %d, %v", test.name, response.StatusCode, response.Headers, test.expectedStatus, test.expectedHeaders)
+		}
+	}
+}
+
+func TestIsPreflight(t *testing.T) {
+	if IsPreflight(request("OPTIONS", map[string]string{"Origin": "https://example.com"})) {
+		t.Fatalf("IsPreflight(no_request_method) returned true, but should return false")
+	}
+	if IsPreflight(request("GET", map[string]string{"Origin": "https://example.com", "Access-Control-Request-Method": "GET"})) {
+		t.Fatalf("IsPreflight(get) returned true, but should return false")
+	}
+}
+
+func request(method string, headers map[string]string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{HTTPMethod: method, Headers: headers}
+}
@@ -0,0 +1,15 @@
+package errors
+
+// ConflictError signals that a request collides with the current state of a resource, e.g. creating an existing pool.
+type ConflictError struct {
+	Err error
+}
+
+func NewConflictError(err error) *ConflictError {
+	return &ConflictError{Err: err}
+}
+
+func (e *ConflictError) Error() string { return e.Err.Error() }
+func (e *ConflictError) Unwrap() error { return e.Err }
+func (e *ConflictError) Code() string  { return CodeConflict }
+func (e *ConflictError) Status() int   { return 409 }
@@ -0,0 +1,15 @@
+package errors
+
+// ForbiddenError signals that a caller is not allowed to access a resource.
+type ForbiddenError struct {
+	Err error
+}
+
+func NewForbiddenError(err error) *ForbiddenError {
+	return &ForbiddenError{Err: err}
+}
+
+func (e *ForbiddenError) Error() string { return e.Err.Error() }
+func (e *ForbiddenError) Unwrap() error { return e.Err }
+func (e *ForbiddenError) Code() string  { return CodeForbidden }
+func (e *ForbiddenError) Status() int   { return 403 }
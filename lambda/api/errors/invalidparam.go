@@ -10,3 +10,5 @@ func NewInvalidParametersError(err error) *InvalidParametersError {
 
 func (e *InvalidParametersError) Error() string { return e.Err.Error() }
 func (e *InvalidParametersError) Unwrap() error { return e.Err }
+func (e *InvalidParametersError) Code() string  { return CodeInvalidParam }
+func (e *InvalidParametersError) Status() int   { return 400 }
@@ -0,0 +1,16 @@
+package errors
+
+// NotConfiguredError signals that a feature is switched off in this deployment, e.g. because a table or function it needs
+// is not configured.
+type NotConfiguredError struct {
+	Err error
+}
+
+func NewNotConfiguredError(err error) *NotConfiguredError {
+	return &NotConfiguredError{Err: err}
+}
+
+func (e *NotConfiguredError) Error() string { return e.Err.Error() }
+func (e *NotConfiguredError) Unwrap() error { return e.Err }
+func (e *NotConfiguredError) Code() string  { return CodeNotConfigured }
+func (e *NotConfiguredError) Status() int   { return 501 }
@@ -1,12 +1,32 @@
 package errors
 
+// NotFoundError signals that a resource doesn't exist. Its code tells which kind of resource is missing.
 type NotFoundError struct {
-	Err error
+	Err  error
+	code string
 }
 
+// NewNotFoundError signals a missing moneypool.
 func NewNotFoundError(err error) *NotFoundError {
-	return &NotFoundError{Err: err}
+	return &NotFoundError{Err: err, code: CodePoolNotFound}
+}
+
+// NewTransactionNotFoundError signals a missing transaction in an existing moneypool.
+func NewTransactionNotFoundError(err error) *NotFoundError {
+	return &NotFoundError{Err: err, code: CodeTransactionNotFound}
+}
+
+// NewWebhookNotFoundError signals a missing webhook subscription of an existing moneypool.
+func NewWebhookNotFoundError(err error) *NotFoundError {
+	return &NotFoundError{Err: err, code: CodeWebhookNotFound}
+}
+
+// NewRosterNotFoundError signals that an existing moneypool expects no participants.
+func NewRosterNotFoundError(err error) *NotFoundError {
+	return &NotFoundError{Err: err, code: CodeRosterNotFound}
 }
 
 func (e *NotFoundError) Error() string { return e.Err.Error() }
 func (e *NotFoundError) Unwrap() error { return e.Err }
+func (e *NotFoundError) Code() string  { return e.code }
+func (e *NotFoundError) Status() int   { return 404 }
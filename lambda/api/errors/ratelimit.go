@@ -0,0 +1,15 @@
+package errors
+
+// RateLimitError signals that a caller sent too many requests and should retry later.
+type RateLimitError struct {
+	Err error
+}
+
+func NewRateLimitError(err error) *RateLimitError {
+	return &RateLimitError{Err: err}
+}
+
+func (e *RateLimitError) Error() string { return e.Err.Error() }
+func (e *RateLimitError) Unwrap() error { return e.Err }
+func (e *RateLimitError) Code() string  { return CodeRateLimited }
+func (e *RateLimitError) Status() int   { return 429 }
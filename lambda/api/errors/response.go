@@ -1,47 +1,95 @@
 package errors
 
 import (
+	"encoding/json"
 	er "errors"
 	"github.com/aws/aws-lambda-go/events"
 	log "github.com/sirupsen/logrus"
 )
 
-var (
-	invalidParamsError *InvalidParametersError
-	notFoundError      *NotFoundError
+// Error codes are part of the api contract, clients may rely on them. Never change existing ones.
+const (
+	CodeInvalidParam     = "INVALID_PARAM"
+	CodePoolNotFound     = "POOL_NOT_FOUND"
+	CodeConflict         = "CONFLICT"
+	CodeRateLimited      = "RATE_LIMITED"
+	CodeForbidden        = "FORBIDDEN"
+	CodeUnauthorized     = "UNAUTHORIZED"
+	CodeStoreUnavailable = "STORE_UNAVAILABLE"
+	CodeInternal         = "INTERNAL_ERROR"
+
+	CodeTransactionNotFound = "TRANSACTION_NOT_FOUND"
+	CodeWebhookNotFound     = "WEBHOOK_NOT_FOUND"
+	CodeNotConfigured       = "NOT_CONFIGURED"
+	CodeRosterNotFound      = "ROSTER_NOT_FOUND"
 )
 
-func ToResponse(err error) events.APIGatewayProxyResponse {
-	if er.As(err, &invalidParamsError) {
-		log.Errorf("Invalid Parameter Error: %v", err)
-		return badRequestResponse()
-	}
+var messages = map[string]string{
+	CodeInvalidParam:     "invalid request parameters",
+	CodePoolNotFound:     "moneypool not found",
+	CodeConflict:         "request conflicts with the current state of the resource",
+	CodeRateLimited:      "too many requests",
+	CodeForbidden:        "access to the resource is forbidden",
+	CodeUnauthorized:     "authentication required",
+	CodeStoreUnavailable: "moneypool store is currently unavailable",
+	CodeInternal:         "internal error",
 
-	if er.As(err, &notFoundError) {
-		log.Errorf("Invalid Parameter Error: %v", err)
-		return notFoundResponse()
-	}
-	log.Errorf("internal Error: %v", err)
-	return internalErrorResponse()
+	CodeTransactionNotFound: "transaction not found",
+	CodeWebhookNotFound:     "webhook not found",
+	CodeNotConfigured:       "feature is not configured in this deployment",
+	CodeRosterNotFound:      "moneypool has no roster",
 }
 
-func badRequestResponse() events.APIGatewayProxyResponse {
-	return events.APIGatewayProxyResponse{
-		Body:       "bad request",
-		StatusCode: 400,
-	}
+// apiError is implemented by all error types in this package that map to a specific http response.
+type apiError interface {
+	error
+	Code() string
+	Status() int
 }
 
-func notFoundResponse() events.APIGatewayProxyResponse {
-	return events.APIGatewayProxyResponse{
-		Body:       "not found",
-		StatusCode: 404,
-	}
+// ErrorBody is the json envelope of every error response of the api.
+type ErrorBody struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestId string `json:"requestId,omitempty"`
+	Details   string `json:"details,omitempty"`
 }
 
-func internalErrorResponse() events.APIGatewayProxyResponse {
+// ToResponse converts an error into an api response with a json ErrorBody.
+// Details are only exposed for client errors, internal errors might contain information we don't want to leak.
+func ToResponse(err error, requestId string) events.APIGatewayProxyResponse {
+	code, status, details := CodeInternal, 500, ""
+	var typedErr apiError
+	if er.As(err, &typedErr) {
+		code, status = typedErr.Code(), typedErr.Status()
+		if status < 500 {
+			details = typedErr.Error()
+		}
+	}
+
+	logger := log.WithFields(log.Fields{"requestId": requestId, "code": code})
+	if status < 500 {
+		logger.Warnf("client error: %v", err)
+	} else {
+		logger.Errorf("server error: %v", err)
+	}
+
+	body, marshalErr := json.Marshal(ErrorBody{
+		Code:      code,
+		Message:   messages[code],
+		RequestId: requestId,
+		Details:   details,
+	})
+	if marshalErr != nil {
+		logger.Errorf("error while marshalling error body: %v", marshalErr)
+		return events.APIGatewayProxyResponse{
+			Body:       "internal error",
+			StatusCode: 500,
+		}
+	}
 	return events.APIGatewayProxyResponse{
-		Body:       "internal error",
-		StatusCode: 500,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(body),
+		StatusCode: status,
 	}
 }
@@ -0,0 +1,106 @@
+package errors
+
+import (
+	"encoding/json"
+	er "errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type toResponseTest struct {
+	name           string
+	input          error
+	expectedStatus int
+	expectedBody   ErrorBody
+}
+
+func TestToResponse(t *testing.T) {
+	testTable := []toResponseTest{
+		{
+			"invalid_param",
+			NewInvalidParametersError(er.New("no moneypool name given")),
+			400,
+			ErrorBody{Code: CodeInvalidParam, Message: "invalid request parameters", RequestId: "req-1", Details: "no moneypool name given"},
+		},
+		{
+			"not_found",
+			NewNotFoundError(er.New("no moneypool found for given name paul")),
+			404,
+			ErrorBody{Code: CodePoolNotFound, Message: "moneypool not found", RequestId: "req-1", Details: "no moneypool found for given name paul"},
+		},
+		{
+			"wrapped_not_found",
+			fmt.Errorf("handler failed: %w", NewNotFoundError(er.New("no moneypool found for given name paul"))),
+			404,
+			ErrorBody{Code: CodePoolNotFound, Message: "moneypool not found", RequestId: "req-1", Details: "no moneypool found for given name paul"},
+		},
+		{
+			"transaction_not_found",
+			NewTransactionNotFoundError(er.New("no transaction found for given id t1 in moneypool paul")),
+			404,
+			ErrorBody{Code: CodeTransactionNotFound, Message: "transaction not found", RequestId: "req-1", Details: "no transaction found for given id t1 in moneypool paul"},
+		},
+		{
+			"webhook_not_found",
+			NewWebhookNotFoundError(er.New("webhook w1 not found")),
+			404,
+			ErrorBody{Code: CodeWebhookNotFound, Message: "webhook not found", RequestId: "req-1", Details: "webhook w1 not found"},
+		},
+		{
+			"not_configured",
+			NewNotConfiguredError(er.New("webhooks are not configured")),
+			501,
+			ErrorBody{Code: CodeNotConfigured, Message: "feature is not configured in this deployment", RequestId: "req-1"},
+		},
+		{
+			"conflict",
+			NewConflictError(er.New("moneypool paul already exists")),
+			409,
+			ErrorBody{Code: CodeConflict, Message: "request conflicts with the current state of the resource", RequestId: "req-1", Details: "moneypool paul already exists"},
+		},
+		{
+			"rate_limited",
+			NewRateLimitError(er.New("slow down")),
+			429,
+			ErrorBody{Code: CodeRateLimited, Message: "too many requests", RequestId: "req-1", Details: "slow down"},
+		},
+		{
+			"forbidden",
+			NewForbiddenError(er.New("invalid token")),
+			403,
+			ErrorBody{Code: CodeForbidden, Message: "access to the resource is forbidden", RequestId: "req-1", Details: "invalid token"},
+		},
+		{
+			"unauthorized",
+			NewUnauthorizedError(er.New("token is expired")),
+			401,
+			ErrorBody{Code: CodeUnauthorized, Message: "authentication required", RequestId: "req-1", Details: "token is expired"},
+		},
+		{
+			"store_unavailable",
+			NewStoreUnavailableError(er.New("connection refused to 10.0.0.1")),
+			503,
+			ErrorBody{Code: CodeStoreUnavailable, Message: "moneypool store is currently unavailable", RequestId: "req-1"},
+		},
+		{
+			"internal",
+			er.New("something broke"),
+			500,
+			ErrorBody{Code: CodeInternal, Message: "internal error", RequestId: "req-1"},
+		},
+	}
+	for _, test := range testTable {
+		response := ToResponse(test.input, "req-1")
+		var body ErrorBody
+		if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+			t.Fatalf("ToResponse(%s) returned body %s that is not valid json: %v", test.name, response.Body, err)
+		}
+		if response.StatusCode != test.expectedStatus || !reflect.DeepEqual(body, test.expectedBody) {
+			t.Fatalf("ToResponse(%s) = %d, %+v but expected %d, %+v", test.name, response.StatusCode, body, test.expectedStatus, test.expectedBody)
+		}
+		if response.Headers["Content-Type"] != "application/json" {
+			t.Fatalf("ToResponse(%s) returned content type %s, but should return application/json", test.name, response.Headers["Content-Type"])
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package errors
+
+// StoreUnavailableError signals that the database could not be reached or did not answer properly.
+type StoreUnavailableError struct {
+	Err error
+}
+
+func NewStoreUnavailableError(err error) *StoreUnavailableError {
+	return &StoreUnavailableError{Err: err}
+}
+
+func (e *StoreUnavailableError) Error() string { return e.Err.Error() }
+func (e *StoreUnavailableError) Unwrap() error { return e.Err }
+func (e *StoreUnavailableError) Code() string  { return CodeStoreUnavailable }
+func (e *StoreUnavailableError) Status() int   { return 503 }
@@ -0,0 +1,15 @@
+package errors
+
+// UnauthorizedError signals that a caller could not be authenticated, e.g. because of a missing or invalid bearer token.
+type UnauthorizedError struct {
+	Err error
+}
+
+func NewUnauthorizedError(err error) *UnauthorizedError {
+	return &UnauthorizedError{Err: err}
+}
+
+func (e *UnauthorizedError) Error() string { return e.Err.Error() }
+func (e *UnauthorizedError) Unwrap() error { return e.Err }
+func (e *UnauthorizedError) Code() string  { return CodeUnauthorized }
+func (e *UnauthorizedError) Status() int   { return 401 }
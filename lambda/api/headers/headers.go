@@ -0,0 +1,34 @@
+package headers
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"strings"
+)
+
+// Get returns the value of a request header. API Gateway passes headers as sent by the client, so names are matched case-insensitively.
+func Get(request events.APIGatewayProxyRequest, name string) string {
+	for key, value := range request.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// ETagMatches reports whether an If-None-Match header value matches the given entity tag.
+// As defined for If-None-Match, tags are compared weakly, so W/"x" matches "x".
+func ETagMatches(ifNoneMatch, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package headers
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"testing"
+)
+
+type eTagMatchesTest struct {
+	name        string
+	ifNoneMatch string
+	etag        string
+	expected    bool
+}
+
+func TestGet(t *testing.T) {
+	request := events.APIGatewayProxyRequest{Headers: map[string]string{"if-none-match": `"abc"`}}
+	if value := Get(request, "If-None-Match"); value != `"abc"` {
+		t.Fatalf("Get(If-None-Match) = %s, but expected %s", value, `"abc"`)
+	}
+	if value := Get(request, "Origin"); value != "" {
+		t.Fatalf("Get(Origin) = %s, but expected empty value", value)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	testTable := []eTagMatchesTest{
+		{"equal", `"abc"`, `"abc"`, true},
+		{"different", `"abc"`, `"abd"`, false},
+		{"list", `"xyz", "abc"`, `"abc"`, true},
+		{"weak", `W/"abc"`, `"abc"`, true},
+		{"wildcard", `*`, `"abc"`, true},
+		{"empty_header", ``, `"abc"`, false},
+		{"empty_etag", `"abc"`, ``, false},
+	}
+	for _, test := range testTable {
+		if matches := ETagMatches(test.ifNoneMatch, test.etag); matches != test.expected {
+			t.Fatalf("ETagMatches(%s) = %v, but expected %v", test.name, matches, test.expected)
+		}
+	}
+}
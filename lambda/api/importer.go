@@ -0,0 +1,47 @@
+package main
+
+import (
+	"api/moneypool"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	awslambda "github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+)
+
+// lambdaImporter sends imports to the transaction lambda, which adds the contributions the same way as those received
+// by mail.
+type lambdaImporter struct {
+	functionName string
+	client       lambdaiface.LambdaAPI
+}
+
+// newImporter returns nil, which disables imports, if no function is configured.
+func newImporter(functionName string) moneypool.ContributionImporter {
+	if functionName == "" {
+		return nil
+	}
+	return &lambdaImporter{functionName: functionName, client: awslambda.New(awsSession)}
+}
+
+func (i *lambdaImporter) Import(request moneypool.ImportRequest) (moneypool.ImportResponse, error) {
+	payload, err := json.Marshal(map[string]moneypool.ImportRequest{"import": request})
+	if err != nil {
+		return moneypool.ImportResponse{}, err
+	}
+	output, err := i.client.Invoke(&awslambda.InvokeInput{
+		FunctionName: aws.String(i.functionName),
+		Payload:      payload,
+	})
+	if err != nil {
+		return moneypool.ImportResponse{}, err
+	}
+	if output.FunctionError != nil {
+		return moneypool.ImportResponse{}, fmt.Errorf("%s: %s", *output.FunctionError, output.Payload)
+	}
+	var response moneypool.ImportResponse
+	if err := json.Unmarshal(output.Payload, &response); err != nil {
+		return moneypool.ImportResponse{}, fmt.Errorf("invalid import response %s: %v", output.Payload, err)
+	}
+	return response, nil
+}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"api/auth"
+	"api/cors"
 	"api/errors"
+	"api/headers"
 	"api/moneypool"
+	"api/rates"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"github.com/aws/aws-lambda-go/events"
@@ -12,6 +17,8 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	log "github.com/sirupsen/logrus"
 	"os"
+	"strconv"
+	"strings"
 )
 
 func init() {
@@ -20,40 +27,321 @@ func init() {
 	log.SetLevel(log.InfoLevel)
 }
 
+const defaultCacheMaxAge = 30
+
 var (
 	moneyPoolsTableName = os.Getenv("MoneyPoolsTableName")
-	corsDomain          = os.Getenv("CorsDomain")
+	tenantsTableName    = os.Getenv("TenantsTableName")
+	auditLogTableName   = os.Getenv("AuditLogTableName")
+	webhookTableName    = os.Getenv("WebhookDeliveriesTableName")
+	allowedOrigins      = os.Getenv("AllowedOrigins")
+	cacheMaxAge         = os.Getenv("CacheMaxAge")
+	oidcIssuer          = os.Getenv("OidcIssuer")
+	oidcAudience        = os.Getenv("OidcAudience")
+	oidcJwksUrl         = os.Getenv("OidcJwksUrl")
+	importFunctionName  = os.Getenv("ImportFunctionName")
+	webhookFunctionName = os.Getenv("WebhookFunctionName")
+	exchangeRates       = os.Getenv("ExchangeRates")
+	tenantMailDomain    = os.Getenv("TenantMailDomain")
+	defaultMailAddress  = os.Getenv("DefaultMailAddress")
 	awsSession          = session.Must(session.NewSession())
 	dynamoClient        = dynamodb.New(awsSession, aws.NewConfig())
+	corsPolicy          = cors.NewPolicy(allowedOrigins,
+		[]string{"OPTIONS", "GET", "POST", "PUT", "PATCH", "DELETE"},
+		[]string{"Content-Type", "X-Api-Key", "If-None-Match", "Authorization", moneypool.PoolTokenHeader},
+	)
+	tokenVerifier = newTokenVerifier()
+	importer      = newImporter(importFunctionName)
+	rateSource    = newRateSource(exchangeRates)
+	webhookSender = newWebhookSender(webhookFunctionName)
 )
 
+// newTokenVerifier verifies owner tokens against the configured OIDC issuer. The issuer's keys are read from OidcJwksUrl,
+// which can also be a local file, or discovered from the issuer. Without issuer, no bearer tokens are accepted.
+func newTokenVerifier() moneypool.TokenVerifier {
+	if oidcIssuer == "" {
+		return nil
+	}
+	keys := auth.NewDiscoveredKeySet(oidcIssuer)
+	if oidcJwksUrl != "" {
+		keys = auth.NewKeySet(oidcJwksUrl)
+	}
+	return auth.NewVerifier(oidcIssuer, oidcAudience, keys)
+}
+
+// newRateSource reads the exchange rates pool totals are converted with, given inline as json or as a path to a json
+// file. Without valid rates, only totals in a pool's base currency are converted.
+func newRateSource(config string) moneypool.RateSource {
+	if config == "" {
+		return nil
+	}
+	var static *rates.Static
+	var err error
+	if strings.HasPrefix(strings.TrimSpace(config), "{") {
+		static, err = rates.Parse([]byte(config))
+	} else {
+		static, err = rates.Load(config)
+	}
+	if err != nil {
+		log.WithError(err).Error("ignoring exchange rates")
+		return nil
+	}
+	return static
+}
+
+type route func(request events.APIGatewayProxyRequest, poolsHandler *moneypool.MoneyPoolsHandler) events.APIGatewayProxyResponse
+
+// routes maps the http method and resource path, as defined in the template, to the route handling it.
+var routes = map[string]route{
+	"GET /getDetails/{moneyPool}":         getDetails,
+	"GET /pools":                          listPools,
+	"POST /pools":                         createPool,
+	"PATCH /pools/{moneyPool}":            updatePool,
+	"POST /pools/{moneyPool}/admin-token": rotateAdminToken,
+	"PATCH /pools/{moneyPool}/transactions/{transactionId}":  correctTransaction,
+	"DELETE /pools/{moneyPool}/transactions/{transactionId}": deleteTransaction,
+	"GET /pools/{moneyPool}/audit":                           getAuditLog,
+	"POST /pools/{moneyPool}/webhooks":                       createWebhook,
+	"GET /pools/{moneyPool}/webhooks":                        getWebhooks,
+	"DELETE /pools/{moneyPool}/webhooks/{webhookId}":         deleteWebhook,
+	"GET /pools/{moneyPool}/webhooks/deliveries":             getWebhookDeliveries,
+	"GET /pools/{moneyPool}/roster":                          getRoster,
+	"GET /pools/{moneyPool}/export":                          exportPool,
+	"POST /pools/{moneyPool}/import":                         importContributions,
+	"PUT /tenants/{tenant}":                                  registerTenant,
+}
+
 func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	poolsHandler := moneypool.NewHandler(moneyPoolsTableName, corsDomain, *dynamoClient)
+	if cors.IsPreflight(request) {
+		return corsPolicy.Preflight(request), nil
+	}
+
+	handle, exists := routes[request.HTTPMethod+" "+request.Resource]
+	if !exists {
+		err := errors.NewInvalidParametersError(fmt.Errorf("unsupported route %s %s", request.HTTPMethod, request.Resource))
+		return corsPolicy.Apply(request, errors.ToResponse(err, request.RequestContext.RequestID)), nil
+	}
+	poolsHandler := moneypool.NewHandler(moneypool.Tables{
+		MoneyPools:        moneyPoolsTableName,
+		Tenants:           tenantsTableName,
+		Audit:             auditLogTableName,
+		WebhookDeliveries: webhookTableName,
+	}, dynamoClient, tokenVerifier).WithTenantMail(tenantMailDomain, defaultMailAddress)
+	if importer != nil {
+		poolsHandler.WithImporter(importer)
+	}
+	if rateSource != nil {
+		poolsHandler.WithRates(rateSource)
+	}
+	if webhookSender != nil {
+		poolsHandler.WithWebhookEvents(webhookSender)
+	}
+	return corsPolicy.Apply(request, handle(request, poolsHandler)), nil
+}
+
+func getDetails(request events.APIGatewayProxyRequest, poolsHandler *moneypool.MoneyPoolsHandler) events.APIGatewayProxyResponse {
 	moneyPool, err := poolsHandler.GetMoneyPool(request)
+	if err != nil {
+		return errors.ToResponse(err, request.RequestContext.RequestID)
+	}
+
+	if headers.ETagMatches(headers.Get(request, "If-None-Match"), moneyPool.ETag) {
+		return events.APIGatewayProxyResponse{
+			Headers:    cacheHeaders(moneyPool),
+			StatusCode: 304,
+		}
+	}
+	return poolResponse(request, moneyPool)
+}
 
+func listPools(request events.APIGatewayProxyRequest, poolsHandler *moneypool.MoneyPoolsHandler) events.APIGatewayProxyResponse {
+	list, err := poolsHandler.ListMoneyPools(request)
 	if err != nil {
-		return addHeaderToResponse(errors.ToResponse(err)), nil
+		return errors.ToResponse(err, request.RequestContext.RequestID)
 	}
+	return jsonResponse(request, list)
+}
+
+func createPool(request events.APIGatewayProxyRequest, poolsHandler *moneypool.MoneyPoolsHandler) events.APIGatewayProxyResponse {
+	moneyPool, err := poolsHandler.CreateMoneyPool(request)
+	if err != nil {
+		return errors.ToResponse(err, request.RequestContext.RequestID)
+	}
+	response := poolResponse(request, moneyPool)
+	if response.StatusCode == 200 {
+		response.StatusCode = 201
+	}
+	return response
+}
+
+func updatePool(request events.APIGatewayProxyRequest, poolsHandler *moneypool.MoneyPoolsHandler) events.APIGatewayProxyResponse {
+	moneyPool, err := poolsHandler.UpdateMoneyPool(request)
+	if err != nil {
+		return errors.ToResponse(err, request.RequestContext.RequestID)
+	}
+	return poolResponse(request, moneyPool)
+}
+
+func correctTransaction(request events.APIGatewayProxyRequest, poolsHandler *moneypool.MoneyPoolsHandler) events.APIGatewayProxyResponse {
+	moneyPool, err := poolsHandler.CorrectTransaction(request)
+	if err != nil {
+		return errors.ToResponse(err, request.RequestContext.RequestID)
+	}
+	return poolResponse(request, moneyPool)
+}
 
+func deleteTransaction(request events.APIGatewayProxyRequest, poolsHandler *moneypool.MoneyPoolsHandler) events.APIGatewayProxyResponse {
+	moneyPool, err := poolsHandler.DeleteTransaction(request)
+	if err != nil {
+		return errors.ToResponse(err, request.RequestContext.RequestID)
+	}
+	return poolResponse(request, moneyPool)
+}
+
+func rotateAdminToken(request events.APIGatewayProxyRequest, poolsHandler *moneypool.MoneyPoolsHandler) events.APIGatewayProxyResponse {
+	token, err := poolsHandler.RotateAdminToken(request)
+	if err != nil {
+		return errors.ToResponse(err, request.RequestContext.RequestID)
+	}
+	response := jsonResponse(request, token)
+	if response.StatusCode == 200 {
+		response.Headers["Cache-Control"] = "no-store"
+	}
+	return response
+}
+
+func getAuditLog(request events.APIGatewayProxyRequest, poolsHandler *moneypool.MoneyPoolsHandler) events.APIGatewayProxyResponse {
+	entries, err := poolsHandler.GetAuditLog(request)
+	if err != nil {
+		return errors.ToResponse(err, request.RequestContext.RequestID)
+	}
+	return jsonResponse(request, entries)
+}
+
+func createWebhook(request events.APIGatewayProxyRequest, poolsHandler *moneypool.MoneyPoolsHandler) events.APIGatewayProxyResponse {
+	subscription, err := poolsHandler.CreateWebhook(request)
+	if err != nil {
+		return errors.ToResponse(err, request.RequestContext.RequestID)
+	}
+	response := jsonResponse(request, subscription)
+	if response.StatusCode == 200 {
+		response.StatusCode = 201
+	}
+	return response
+}
+
+func getWebhooks(request events.APIGatewayProxyRequest, poolsHandler *moneypool.MoneyPoolsHandler) events.APIGatewayProxyResponse {
+	subscriptions, err := poolsHandler.GetWebhooks(request)
+	if err != nil {
+		return errors.ToResponse(err, request.RequestContext.RequestID)
+	}
+	return jsonResponse(request, subscriptions)
+}
+
+func deleteWebhook(request events.APIGatewayProxyRequest, poolsHandler *moneypool.MoneyPoolsHandler) events.APIGatewayProxyResponse {
+	if err := poolsHandler.DeleteWebhook(request); err != nil {
+		return errors.ToResponse(err, request.RequestContext.RequestID)
+	}
+	return events.APIGatewayProxyResponse{StatusCode: 204}
+}
+
+func getWebhookDeliveries(request events.APIGatewayProxyRequest, poolsHandler *moneypool.MoneyPoolsHandler) events.APIGatewayProxyResponse {
+	deliveries, err := poolsHandler.GetWebhookDeliveries(request)
+	if err != nil {
+		return errors.ToResponse(err, request.RequestContext.RequestID)
+	}
+	return jsonResponse(request, deliveries)
+}
+
+func getRoster(request events.APIGatewayProxyRequest, poolsHandler *moneypool.MoneyPoolsHandler) events.APIGatewayProxyResponse {
+	roster, err := poolsHandler.GetRoster(request)
+	if err != nil {
+		return errors.ToResponse(err, request.RequestContext.RequestID)
+	}
+	return jsonResponse(request, roster)
+}
+
+func exportPool(request events.APIGatewayProxyRequest, poolsHandler *moneypool.MoneyPoolsHandler) events.APIGatewayProxyResponse {
+	export, err := poolsHandler.ExportMoneyPool(request)
+	if err != nil {
+		return errors.ToResponse(err, request.RequestContext.RequestID)
+	}
+	response := events.APIGatewayProxyResponse{
+		Headers: map[string]string{
+			"Content-Type":        export.ContentType,
+			"Content-Disposition": fmt.Sprintf("attachment; filename=%q", export.Filename),
+			"Cache-Control":       "no-store",
+		},
+		Body:       string(export.Body),
+		StatusCode: 200,
+	}
+	if export.Binary {
+		response.Body = base64.StdEncoding.EncodeToString(export.Body)
+		response.IsBase64Encoded = true
+	}
+	return response
+}
+
+func importContributions(request events.APIGatewayProxyRequest, poolsHandler *moneypool.MoneyPoolsHandler) events.APIGatewayProxyResponse {
+	result, err := poolsHandler.ImportContributions(request)
+	if err != nil {
+		return errors.ToResponse(err, request.RequestContext.RequestID)
+	}
+	return jsonResponse(request, result)
+}
+
+func registerTenant(request events.APIGatewayProxyRequest, poolsHandler *moneypool.MoneyPoolsHandler) events.APIGatewayProxyResponse {
+	tenant, err := poolsHandler.RegisterTenant(request)
+	if err != nil {
+		return errors.ToResponse(err, request.RequestContext.RequestID)
+	}
+	return jsonResponse(request, tenant)
+}
+
+func jsonResponse(request events.APIGatewayProxyRequest, value interface{}) events.APIGatewayProxyResponse {
+	jsonResp, err := json.Marshal(value)
+	if err != nil {
+		err = fmt.Errorf("error while marshalling response %v: %v", value, err)
+		return errors.ToResponse(err, request.RequestContext.RequestID)
+	}
+	return events.APIGatewayProxyResponse{
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(jsonResp),
+		StatusCode: 200,
+	}
+}
+
+func poolResponse(request events.APIGatewayProxyRequest, moneyPool moneypool.MoneyPool) events.APIGatewayProxyResponse {
 	jsonResp, err := json.Marshal(moneyPool)
 	if err != nil {
 		err = fmt.Errorf("error while marshalling response %v: %v", moneyPool, err)
-		return addHeaderToResponse(errors.ToResponse(err)), nil
+		return errors.ToResponse(err, request.RequestContext.RequestID)
 	}
 
-	return addHeaderToResponse(events.APIGatewayProxyResponse{
+	responseHeaders := cacheHeaders(moneyPool)
+	responseHeaders["Content-Type"] = "application/json"
+	return events.APIGatewayProxyResponse{
+		Headers:    responseHeaders,
 		Body:       string(jsonResp),
 		StatusCode: 200,
-	}), nil
+	}
 }
 
-func addHeaderToResponse(response events.APIGatewayProxyResponse) events.APIGatewayProxyResponse {
-	response.Headers = map[string]string{
-		"Access-Control-Allow-Headers": "*",
-		"Access-Control-Allow-Origin":  "*",
-		"Access-Control-Allow-Methods": "OPTIONS,GET",
+func cacheHeaders(moneyPool moneypool.MoneyPool) map[string]string {
+	return map[string]string{
+		"ETag":          moneyPool.ETag,
+		"Cache-Control": cacheControl(),
 	}
-	return response
+}
+
+// cacheControl allows browsers to reuse pool details for CacheMaxAge seconds before revalidating them with the ETag.
+// Responses are private, since shared caches must not serve pools to clients without an api key.
+func cacheControl() string {
+	maxAge, err := strconv.Atoi(cacheMaxAge)
+	if err != nil || maxAge < 0 {
+		maxAge = defaultCacheMaxAge
+	}
+	return fmt.Sprintf("private, max-age=%d, must-revalidate", maxAge)
 }
 
 func main() {
@@ -0,0 +1,123 @@
+package moneypool
+
+import (
+	"api/auth"
+	"api/errors"
+	"api/headers"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// PoolTokenHeader carries a pool's read or admin token. Read tokens can also be passed as ?token= query parameter,
+// so pools can be shared by link. Admin tokens are only accepted from the header, so they don't end up in links,
+// browser histories or access logs.
+const PoolTokenHeader = "X-Pool-Token"
+
+type Access int
+
+const (
+	AccessNone Access = iota
+	AccessRead
+	AccessAdmin
+)
+
+// accessItem holds the pool's owner and the sha256 hashes of its tokens. Only hashes are stored, so the tokens can't be
+// read from the table.
+type accessItem struct {
+	Owner          string `dynamodbav:"owner"`
+	ReadTokenHash  string `dynamodbav:"readTokenHash"`
+	AdminTokenHash string `dynamodbav:"adminTokenHash"`
+}
+
+// HashToken returns the hex encoded sha256 hash of a token, as stored in the pool item.
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// requestToken returns the request's pool token and whether it was passed in the PoolTokenHeader.
+func requestToken(request events.APIGatewayProxyRequest) (string, bool) {
+	if token := headers.Get(request, PoolTokenHeader); token != "" {
+		return token, true
+	}
+	return request.QueryStringParameters["token"], false
+}
+
+// accessLevel determines what the owner's subject or the token allow on the pool item. The owner can always administrate
+// the pool. Pools without read token are public, pools without admin token can't be administrated with tokens.
+// Admin tokens only grant admin access if they were passed in the header.
+func accessLevel(item map[string]*dynamodb.AttributeValue, subject, token string, fromHeader bool) (Access, error) {
+	var ai accessItem
+	if err := dynamodbattribute.UnmarshalMap(item, &ai); err != nil {
+		return AccessNone, fmt.Errorf("could not decode moneypool tokens: %v", err)
+	}
+	if subject != "" && ai.Owner == subject {
+		return AccessAdmin, nil
+	}
+	if token != "" && fromHeader && tokenMatches(ai.AdminTokenHash, token) {
+		return AccessAdmin, nil
+	}
+	if ai.ReadTokenHash == "" || tokenMatches(ai.ReadTokenHash, token) {
+		return AccessRead, nil
+	}
+	return AccessNone, nil
+}
+
+func tokenMatches(hash, token string) bool {
+	if hash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(HashToken(token))) == 1
+}
+
+// authenticate verifies the request's bearer token and returns the caller's claims. Requests without bearer token
+// return empty claims, invalid tokens are rejected.
+func (h *MoneyPoolsHandler) authenticate(request events.APIGatewayProxyRequest) (auth.Claims, error) {
+	token := auth.BearerToken(request)
+	if token == "" {
+		return auth.Claims{}, nil
+	}
+	if h.verifier == nil {
+		return auth.Claims{}, errors.NewUnauthorizedError(fmt.Errorf("bearer tokens are not accepted, no issuer configured"))
+	}
+	claims, err := h.verifier.Verify(token)
+	if err != nil {
+		return auth.Claims{}, errors.NewUnauthorizedError(err)
+	}
+	return claims, nil
+}
+
+// authorize fails if neither the request's bearer token nor its pool token grant the required access to the pool item.
+// Callers without read access get the same error as for non-existing pools, so private pools can't be discovered.
+// It returns the claims of the caller's bearer token, which are empty for callers with pool tokens.
+func (h *MoneyPoolsHandler) authorize(request events.APIGatewayProxyRequest, item map[string]*dynamodb.AttributeValue, name string, required Access) (auth.Claims, error) {
+	claims, err := h.authenticate(request)
+	if err != nil {
+		return auth.Claims{}, err
+	}
+	token, fromHeader := requestToken(request)
+	access, err := accessLevel(item, claims.Subject, token, fromHeader)
+	if err != nil {
+		return auth.Claims{}, err
+	}
+	if access < AccessRead {
+		return auth.Claims{}, errors.NewNotFoundError(fmt.Errorf("no moneypool found for given name %s", name))
+	}
+	if access < required {
+		return auth.Claims{}, errors.NewForbiddenError(fmt.Errorf("token does not allow to administrate moneypool %s", name))
+	}
+	return claims, nil
+}
+
+// actor names the caller of an admin request in audit records: the owner's subject, or the pool's admin token.
+func actor(claims auth.Claims) string {
+	if claims.Subject != "" {
+		return "owner:" + claims.Subject
+	}
+	return "adminToken"
+}
@@ -0,0 +1,227 @@
+package moneypool
+
+import (
+	"api/errors"
+	er "errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+)
+
+const (
+	readToken  = "read-token-123"
+	adminToken = "admin-token-456"
+)
+
+type accessTest struct {
+	name          string
+	item          map[string]*dynamodb.AttributeValue
+	request       events.APIGatewayProxyRequest
+	expectedError error
+}
+
+type updateTest struct {
+	name          string
+	request       events.APIGatewayProxyRequest
+	expectedPool  MoneyPool
+	expectedError error
+}
+
+func TestGetMoneyPoolAccess(t *testing.T) {
+	testTable := []accessTest{
+		{
+			"public_pool",
+			testPoolItem("paul", "Gift for Paul", true),
+			poolRequest("paul"),
+			nil,
+		},
+		{
+			"private_pool_header_token",
+			privatePoolItem(),
+			withHeader(poolRequest("paul"), "x-pool-token", readToken),
+			nil,
+		},
+		{
+			"private_pool_query_token",
+			privatePoolItem(),
+			withQuery(poolRequest("paul"), "token", readToken),
+			nil,
+		},
+		{
+			"private_pool_admin_token",
+			privatePoolItem(),
+			withHeader(poolRequest("paul"), PoolTokenHeader, adminToken),
+			nil,
+		},
+		{
+			"private_pool_admin_query_token",
+			privatePoolItem(),
+			withQuery(poolRequest("paul"), "token", adminToken),
+			errors.NewNotFoundError(er.New("no moneypool found for given name paul")),
+		},
+		{
+			"private_pool_without_token",
+			privatePoolItem(),
+			poolRequest("paul"),
+			errors.NewNotFoundError(er.New("no moneypool found for given name paul")),
+		},
+		{
+			"private_pool_wrong_token",
+			privatePoolItem(),
+			withQuery(poolRequest("paul"), "token", "guessed-token"),
+			errors.NewNotFoundError(er.New("no moneypool found for given name paul")),
+		},
+		{
+			"private_pool_owner",
+			ownedPoolItem(),
+			withHeader(poolRequest("paul"), "Authorization", "Bearer "+ownerJwt),
+			nil,
+		},
+		{
+			"private_pool_other_user",
+			ownedPoolItem(),
+			withHeader(poolRequest("paul"), "Authorization", "Bearer "+otherJwt),
+			errors.NewNotFoundError(er.New("no moneypool found for given name paul")),
+		},
+		{
+			"private_pool_invalid_bearer_token",
+			ownedPoolItem(),
+			withHeader(withQuery(poolRequest("paul"), "token", readToken), "Authorization", "Bearer forged-jwt"),
+			errors.NewUnauthorizedError(er.New("invalid token signature")),
+		},
+	}
+	for _, test := range testTable {
+		_, err := NewHandler(testTables, NewFakeDynamoClient(test.item), fakeVerifier).GetMoneyPool(test.request)
+		if !compareErrors(err, test.expectedError) {
+			t.Fatalf("GetMoneyPool(%s) returned error %v, but should return %v", test.name, err, test.expectedError)
+		}
+	}
+}
+
+func TestUpdateMoneyPool(t *testing.T) {
+	testTable := []updateTest{
+		{
+			"update_settings",
+			updateRequest(adminToken, `{"title": " Birthday gift ", "open": false, "privacy": "initials"}`),
+			MoneyPool{Name: "paul", Title: "Birthday gift", Open: false},
+			nil,
+		},
+		{
+			"make_public",
+			updateRequest(adminToken, `{"readToken": ""}`),
+			MoneyPool{Name: "paul", Title: "Gift for Paul", Open: true},
+			nil,
+		},
+		{
+			"read_token_only",
+			updateRequest(readToken, `{"open": false}`),
+			MoneyPool{},
+			errors.NewForbiddenError(er.New("token does not allow to administrate moneypool paul")),
+		},
+		{
+			"admin_token_in_query",
+			withQuery(updateRequest("", `{"open": false}`), "token", adminToken),
+			MoneyPool{},
+			errors.NewNotFoundError(er.New("no moneypool found for given name paul")),
+		},
+		{
+			"no_token",
+			updateRequest("", `{"open": false}`),
+			MoneyPool{},
+			errors.NewNotFoundError(er.New("no moneypool found for given name paul")),
+		},
+		{
+			"invalid_json",
+			updateRequest(adminToken, `{"open": "no"`),
+			MoneyPool{},
+			errors.NewInvalidParametersError(er.New("invalid update body: unexpected end of JSON input")),
+		},
+		{
+			"no_changes",
+			updateRequest(adminToken, `{}`),
+			MoneyPool{},
+			errors.NewInvalidParametersError(er.New("update contains no changes")),
+		},
+		{
+			"empty_title",
+			updateRequest(adminToken, `{"title": "  "}`),
+			MoneyPool{},
+			errors.NewInvalidParametersError(er.New("title must have between 1 and 200 characters")),
+		},
+		{
+			"unknown_privacy",
+			updateRequest(adminToken, `{"privacy": "secret"}`),
+			MoneyPool{},
+			errors.NewInvalidParametersError(er.New("unknown privacy mode secret")),
+		},
+		{
+			"short_read_token",
+			updateRequest(adminToken, `{"readToken": "abc"}`),
+			MoneyPool{},
+			errors.NewInvalidParametersError(er.New("read token must have at least 8 characters")),
+		},
+	}
+	for _, test := range testTable {
+		client := NewFakeDynamoClient(privatePoolItem())
+		pool, err := NewHandler(testTables, client, nil).UpdateMoneyPool(test.request)
+		pool.ETag = ""
+		pool = withoutCurrencies(pool)
+		if !compareErrors(err, test.expectedError) || !reflect.DeepEqual(pool, test.expectedPool) {
+			t.Fatalf("UpdateMoneyPool(%s) = %+v, %v but expected %+v, %v", test.name, pool, err, test.expectedPool, test.expectedError)
+		}
+		if err != nil && client.updates > 0 {
+			t.Fatalf("UpdateMoneyPool(%s) failed, but still updated the moneypool", test.name)
+		}
+	}
+}
+
+func TestUpdateMoneyPoolReadToken(t *testing.T) {
+	client := NewFakeDynamoClient(testPoolItem("paul", "Gift for Paul", true))
+	client.items["paul"]["adminTokenHash"] = &dynamodb.AttributeValue{S: aws.String(HashToken(adminToken))}
+	handler := NewHandler(testTables, client, nil)
+
+	if _, err := handler.UpdateMoneyPool(updateRequest(adminToken, `{"readToken": "new-read-token"}`)); err != nil {
+		t.Fatalf("UpdateMoneyPool(set_read_token) returned error %v", err)
+	}
+	if _, err := handler.GetMoneyPool(poolRequest("paul")); err == nil {
+		t.Fatalf("GetMoneyPool(without_token) succeeded, but the pool should be private")
+	}
+	if _, err := handler.GetMoneyPool(withQuery(poolRequest("paul"), "token", "new-read-token")); err != nil {
+		t.Fatalf("GetMoneyPool(new_token) returned error %v", err)
+	}
+}
+
+func privatePoolItem() map[string]*dynamodb.AttributeValue {
+	item := testPoolItem("paul", "Gift for Paul", true)
+	item["readTokenHash"] = &dynamodb.AttributeValue{S: aws.String(HashToken(readToken))}
+	item["adminTokenHash"] = &dynamodb.AttributeValue{S: aws.String(HashToken(adminToken))}
+	return item
+}
+
+func updateRequest(token, body string) events.APIGatewayProxyRequest {
+	request := poolRequest("paul")
+	request.HTTPMethod = "PATCH"
+	request.Body = body
+	if token != "" {
+		request = withHeader(request, PoolTokenHeader, token)
+	}
+	return request
+}
+
+func withHeader(request events.APIGatewayProxyRequest, name, value string) events.APIGatewayProxyRequest {
+	if request.Headers == nil {
+		request.Headers = map[string]string{}
+	}
+	request.Headers[name] = value
+	return request
+}
+
+func withQuery(request events.APIGatewayProxyRequest, name, value string) events.APIGatewayProxyRequest {
+	if request.QueryStringParameters == nil {
+		request.QueryStringParameters = map[string]string{}
+	}
+	request.QueryStringParameters[name] = value
+	return request
+}
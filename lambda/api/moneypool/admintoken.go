@@ -0,0 +1,79 @@
+package moneypool
+
+import (
+	"api/errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// adminTokenBytes is the number of random bytes of generated admin tokens.
+const adminTokenBytes = 32
+
+// AdminToken is a newly generated admin token of a pool. Only its hash is stored, so it is returned exactly once.
+type AdminToken struct {
+	AdminToken string `json:"adminToken"`
+}
+
+// RotateAdminToken generates a new admin token for a pool and replaces the stored hash, which revokes the previous
+// token. Only the pool's owner can rotate the token, holders of the admin token can't hand out new ones.
+func (h *MoneyPoolsHandler) RotateAdminToken(request events.APIGatewayProxyRequest) (AdminToken, error) {
+	mpName, mpParamExists := request.PathParameters["moneyPool"]
+	if !mpParamExists {
+		return AdminToken{}, errors.NewInvalidParametersError(fmt.Errorf("no moneyppol name given"))
+	}
+	h.logger = log.WithFields(log.Fields{"requestedMP": mpName})
+
+	item, err := h.getPoolItem(mpName)
+	if err != nil {
+		return AdminToken{}, err
+	}
+	claims, err := h.authorize(request, item, mpName, AccessAdmin)
+	if err != nil {
+		return AdminToken{}, err
+	}
+	if claims.Subject == "" {
+		return AdminToken{}, errors.NewForbiddenError(fmt.Errorf("only the owner can rotate the admin token of moneypool %s", mpName))
+	}
+
+	token, err := randomHex(adminTokenBytes)
+	if err != nil {
+		return AdminToken{}, err
+	}
+	names := map[string]*string{
+		"#name": aws.String("name"),
+	}
+	values := map[string]*dynamodb.AttributeValue{
+		":adminTokenHashNew": {S: aws.String(HashToken(token))},
+	}
+	conditions := accessUnchanged(item, names, values)
+	_, err = h.dynamoClient.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(h.tables.MoneyPools),
+		Key: map[string]*dynamodb.AttributeValue{
+			"name": {S: aws.String(mpName)},
+		},
+		UpdateExpression:          aws.String("SET #adminTokenHash = :adminTokenHashNew"),
+		ConditionExpression:       aws.String(strings.Join(conditions, " AND ")),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return AdminToken{}, errors.NewConflictError(fmt.Errorf("moneypool %s was changed concurrently", mpName))
+		}
+		return AdminToken{}, errors.NewStoreUnavailableError(fmt.Errorf("error updating moneypool in db: %v", err))
+	}
+	h.logger.Infof("rotated admin token")
+
+	var old map[string]interface{}
+	if item["adminTokenHash"] != nil {
+		old = map[string]interface{}{"adminTokenHash": redacted}
+	}
+	h.audit(request, AuditEntry{Pool: mpName, Action: AuditAdminTokenRotate, Actor: actor(claims), Old: old,
+		New: map[string]interface{}{"adminTokenHash": redacted}})
+	return AdminToken{AdminToken: token}, nil
+}
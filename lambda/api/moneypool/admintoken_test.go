@@ -0,0 +1,74 @@
+package moneypool
+
+import (
+	"api/errors"
+	er "errors"
+	"github.com/aws/aws-lambda-go/events"
+	"testing"
+)
+
+func TestRotateAdminToken(t *testing.T) {
+	client := NewFakeDynamoClient(ownedPoolItem())
+	handler := NewHandler(testTables, client, fakeVerifier)
+
+	rotated, err := handler.RotateAdminToken(rotateRequest("Authorization", "Bearer "+ownerJwt))
+	if err != nil {
+		t.Fatalf("RotateAdminToken returned error %v", err)
+	}
+	if len(rotated.AdminToken) != 2*adminTokenBytes || rotated.AdminToken == adminToken {
+		t.Fatalf("RotateAdminToken returned token %q", rotated.AdminToken)
+	}
+	if hash := *client.items["paul"]["adminTokenHash"].S; hash != HashToken(rotated.AdminToken) {
+		t.Fatalf("stored admin token hash %s, but expected the hash of %s", hash, rotated.AdminToken)
+	}
+	if _, err := handler.UpdateMoneyPool(updateRequest(adminToken, `{"open": false}`)); err == nil {
+		t.Fatalf("UpdateMoneyPool accepted the revoked admin token")
+	}
+	if _, err := handler.UpdateMoneyPool(updateRequest(rotated.AdminToken, `{"open": false}`)); err != nil {
+		t.Fatalf("UpdateMoneyPool(new admin token) returned error %v", err)
+	}
+	if len(client.audit) != 2 || *client.audit[0]["action"].S != AuditAdminTokenRotate ||
+		*client.audit[0]["new"].M["adminTokenHash"].S != redacted {
+		t.Fatalf("unexpected audit log %v", client.audit)
+	}
+}
+
+func TestRotateAdminTokenAccess(t *testing.T) {
+	testTable := []struct {
+		name          string
+		request       events.APIGatewayProxyRequest
+		expectedError error
+	}{
+		{
+			"admin_token",
+			rotateRequest(PoolTokenHeader, adminToken),
+			errors.NewForbiddenError(er.New("only the owner can rotate the admin token of moneypool paul")),
+		},
+		{
+			"read_token",
+			rotateRequest(PoolTokenHeader, readToken),
+			errors.NewForbiddenError(er.New("token does not allow to administrate moneypool paul")),
+		},
+		{
+			"other_user",
+			rotateRequest("Authorization", "Bearer "+otherJwt),
+			errors.NewNotFoundError(er.New("no moneypool found for given name paul")),
+		},
+	}
+	for _, test := range testTable {
+		client := NewFakeDynamoClient(ownedPoolItem())
+		_, err := NewHandler(testTables, client, fakeVerifier).RotateAdminToken(test.request)
+		if !compareErrors(err, test.expectedError) {
+			t.Fatalf("RotateAdminToken(%s) returned error %v, but should return %v", test.name, err, test.expectedError)
+		}
+		if hash := *client.items["paul"]["adminTokenHash"].S; hash != HashToken(adminToken) {
+			t.Fatalf("RotateAdminToken(%s) changed the admin token hash", test.name)
+		}
+	}
+}
+
+func rotateRequest(header, value string) events.APIGatewayProxyRequest {
+	request := poolRequest("paul")
+	request.HTTPMethod = "POST"
+	return withHeader(request, header, value)
+}
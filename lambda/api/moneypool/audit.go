@@ -0,0 +1,174 @@
+package moneypool
+
+import (
+	"api/errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	log "github.com/sirupsen/logrus"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Audit actions, as written to the audit log by both lambdas.
+const (
+	AuditPoolCreate        = "pool.create"
+	AuditPoolUpdate        = "pool.update"
+	AuditAdminTokenRotate  = "pool.adminToken.rotate"
+	AuditTransactionAdd    = "transaction.add"
+	AuditTransactionRefund = "transaction.refund"
+	AuditTransactionEdit   = "transaction.edit"
+	AuditTransactionVoid   = "transaction.void"
+	AuditTransactionUnvoid = "transaction.unvoid"
+	AuditTransactionDelete = "transaction.delete"
+	AuditWebhookAdd        = "webhook.add"
+	AuditWebhookRemove     = "webhook.remove"
+)
+
+// auditTimeFormat has a fixed width, so entry ids sort by time.
+const auditTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// redacted replaces secrets in audited values.
+const redacted = "<redacted>"
+
+// AuditEntry is one change of a pool. The log is append-only: entries are never changed or deleted.
+type AuditEntry struct {
+	Pool    string `json:"-" dynamodbav:"pool"`
+	EntryId string `json:"-" dynamodbav:"entryId"`
+	At      string `json:"at" dynamodbav:"at"`
+	Action  string `json:"action" dynamodbav:"action"`
+	// Actor is "owner:<subject>" or "adminToken" for api requests, and "mail" for payment notifications.
+	Actor string `json:"actor" dynamodbav:"actor"`
+	// Source is the api request id or the SES message id of the change.
+	Source        string                 `json:"source" dynamodbav:"source"`
+	TransactionId string                 `json:"transactionId,omitempty" dynamodbav:"transactionId,omitempty"`
+	Old           map[string]interface{} `json:"old,omitempty" dynamodbav:"old,omitempty"`
+	New           map[string]interface{} `json:"new,omitempty" dynamodbav:"new,omitempty"`
+}
+
+// correctionAuditActions maps the actions of transaction corrections to audit actions.
+var correctionAuditActions = map[string]string{
+	CorrectionEdit:   AuditTransactionEdit,
+	CorrectionVoid:   AuditTransactionVoid,
+	CorrectionUnvoid: AuditTransactionUnvoid,
+	CorrectionDelete: AuditTransactionDelete,
+}
+
+// GetAuditLog returns the audit trail of a pool, oldest entry first. It requires admin access to the pool.
+func (h *MoneyPoolsHandler) GetAuditLog(request events.APIGatewayProxyRequest) ([]AuditEntry, error) {
+	mpName, mpParamExists := request.PathParameters["moneyPool"]
+	if !mpParamExists {
+		return nil, errors.NewInvalidParametersError(fmt.Errorf("no moneyppol name given"))
+	}
+	h.logger = log.WithFields(log.Fields{"requestedMP": mpName})
+
+	item, err := h.getPoolItem(mpName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := h.authorize(request, item, mpName, AccessAdmin); err != nil {
+		return nil, err
+	}
+	if h.tables.Audit == "" {
+		return nil, errors.NewNotConfiguredError(fmt.Errorf("no audit log configured"))
+	}
+
+	entries := make([]AuditEntry, 0)
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(h.tables.Audit),
+		KeyConditionExpression: aws.String("#pool = :pool"),
+		ExpressionAttributeNames: map[string]*string{
+			"#pool": aws.String("pool"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":pool": {S: aws.String(mpName)},
+		},
+		ScanIndexForward: aws.Bool(true),
+	}
+	for {
+		out, err := h.dynamoClient.Query(input)
+		if err != nil {
+			return nil, errors.NewStoreUnavailableError(fmt.Errorf("error reading audit log from db: %v", err))
+		}
+		for _, item := range out.Items {
+			var entry AuditEntry
+			if err := dynamodbattribute.UnmarshalMap(item, &entry); err != nil {
+				return nil, fmt.Errorf("could not decode audit entry: %v", err)
+			}
+			entries = append(entries, entry)
+		}
+		if len(out.LastEvaluatedKey) == 0 {
+			return entries, nil
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+// audit appends an entry for a change made by the request. The change is already stored, so failing to write the entry
+// does not fail the request; the entry is logged instead.
+func (h *MoneyPoolsHandler) audit(request events.APIGatewayProxyRequest, entry AuditEntry) {
+	if h.tables.Audit == "" {
+		return
+	}
+	now := time.Now().UTC()
+	entry.At = now.Format(time.RFC3339)
+	entry.Source = request.RequestContext.RequestID
+	entry.EntryId = now.Format(auditTimeFormat) + "#" + entry.Source
+
+	item, err := dynamodbattribute.MarshalMap(entry)
+	if err == nil {
+		_, err = h.dynamoClient.PutItem(&dynamodb.PutItemInput{
+			TableName:           aws.String(h.tables.Audit),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(#entryId)"),
+			ExpressionAttributeNames: map[string]*string{
+				"#entryId": aws.String("entryId"),
+			},
+		})
+	}
+	if err != nil {
+		h.logger.WithField("auditEntry", entry).Errorf("error writing audit entry: %v", err)
+	}
+}
+
+// auditValues converts stored attributes to audited values. Token hashes, notification and webhook secrets are redacted
+// and the transactions list, which is audited per transaction, is left out.
+func auditValues(item map[string]*dynamodb.AttributeValue) map[string]interface{} {
+	if item == nil {
+		return nil
+	}
+	values := map[string]interface{}{}
+	if err := dynamodbattribute.UnmarshalMap(item, &values); err != nil {
+		return map[string]interface{}{"error": err.Error()}
+	}
+	delete(values, "transactions")
+	delete(values, "corrections")
+	for attribute := range values {
+		if strings.HasSuffix(attribute, "TokenHash") {
+			values[attribute] = redacted
+		}
+	}
+	redactNotifications(values["notifications"])
+	redactWebhooks(values["webhooks"])
+	return values
+}
+
+// changedValues returns the audited values of the attributes that differ between old and updated item.
+func changedValues(old, updated map[string]*dynamodb.AttributeValue) (map[string]interface{}, map[string]interface{}) {
+	before, after := auditValues(old), auditValues(updated)
+	oldValues, newValues := map[string]interface{}{}, map[string]interface{}{}
+	for attribute, value := range before {
+		if !reflect.DeepEqual(old[attribute], updated[attribute]) {
+			oldValues[attribute] = value
+		}
+	}
+	for attribute, value := range after {
+		if !reflect.DeepEqual(old[attribute], updated[attribute]) {
+			newValues[attribute] = value
+		}
+	}
+	return oldValues, newValues
+}
@@ -0,0 +1,85 @@
+package moneypool
+
+import (
+	"api/errors"
+	er "errors"
+	"github.com/aws/aws-lambda-go/events"
+	"reflect"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	client := NewFakeDynamoClient(correctablePoolItem())
+	handler := NewHandler(testTables, client, fakeVerifier)
+
+	if _, err := handler.UpdateMoneyPool(withRequestId(updateRequest(adminToken, `{"open": false, "readToken": "new-read-token"}`), "request-1")); err != nil {
+		t.Fatalf("UpdateMoneyPool returned error %v", err)
+	}
+	voidRequest := withHeader(correctionRequest("", "id-Anna", `{"voided": true, "reason": "duplicate"}`), "Authorization", "Bearer "+ownerJwt)
+	if _, err := handler.CorrectTransaction(withRequestId(voidRequest, "request-2")); err != nil {
+		t.Fatalf("CorrectTransaction returned error %v", err)
+	}
+
+	entries, err := handler.GetAuditLog(auditRequest(adminToken))
+	if err != nil {
+		t.Fatalf("GetAuditLog returned error %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 audit entries, but got %+v", entries)
+	}
+	update, void := entries[0], entries[1]
+	if update.Action != AuditPoolUpdate || update.Actor != "adminToken" || update.Source != "request-1" || update.At == "" {
+		t.Fatalf("unexpected update entry %+v", update)
+	}
+	expectedOld := map[string]interface{}{"open": true, "readTokenHash": redacted}
+	expectedNew := map[string]interface{}{"open": false, "readTokenHash": redacted}
+	if !reflect.DeepEqual(update.Old, expectedOld) || !reflect.DeepEqual(update.New, expectedNew) {
+		t.Fatalf("update entry has values %v -> %v, but expected %v -> %v", update.Old, update.New, expectedOld, expectedNew)
+	}
+	if void.Action != AuditTransactionVoid || void.Actor != "owner:owner-sub" || void.Source != "request-2" || void.TransactionId != "id-Anna" ||
+		void.Old["voided"] != nil || void.New["voided"] != true {
+		t.Fatalf("unexpected void entry %+v", void)
+	}
+}
+
+func TestAuditLogCreate(t *testing.T) {
+	client := NewFakeDynamoClient()
+	handler := NewHandler(testTables, client, fakeVerifier)
+	if _, err := handler.CreateMoneyPool(withRequestId(createRequest(ownerJwt, `{"name": "anna", "title": "Gift for Anna"}`), "request-1")); err != nil {
+		t.Fatalf("CreateMoneyPool returned error %v", err)
+	}
+	if len(client.audit) != 1 || *client.audit[0]["pool"].S != "anna" || *client.audit[0]["action"].S != AuditPoolCreate ||
+		*client.audit[0]["new"].M["title"].S != "Gift for Anna" {
+		t.Fatalf("unexpected audit log %v", client.audit)
+	}
+}
+
+func TestAuditLogAccess(t *testing.T) {
+	handler := NewHandler(testTables, NewFakeDynamoClient(correctablePoolItem()), nil)
+	_, err := handler.GetAuditLog(auditRequest(readToken))
+	expected := errors.NewForbiddenError(er.New("token does not allow to administrate moneypool paul"))
+	if !compareErrors(err, expected) {
+		t.Fatalf("GetAuditLog(read_token) = %v, but expected %v", err, expected)
+	}
+}
+
+func TestAuditLogNotConfigured(t *testing.T) {
+	tables := testTables
+	tables.Audit = ""
+	handler := NewHandler(tables, NewFakeDynamoClient(correctablePoolItem()), nil)
+	_, err := handler.GetAuditLog(auditRequest(adminToken))
+	if !hasCode(err, errors.CodeNotConfigured) {
+		t.Fatalf("GetAuditLog without audit table = %v, but expected %s", err, errors.CodeNotConfigured)
+	}
+}
+
+func auditRequest(token string) events.APIGatewayProxyRequest {
+	request := updateRequest(token, "")
+	request.HTTPMethod = "GET"
+	return request
+}
+
+func withRequestId(request events.APIGatewayProxyRequest, requestId string) events.APIGatewayProxyRequest {
+	request.RequestContext.RequestID = requestId
+	return request
+}
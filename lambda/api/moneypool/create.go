@@ -0,0 +1,197 @@
+package moneypool
+
+import (
+	"api/errors"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	log "github.com/sirupsen/logrus"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	minNameLength = 2
+	maxNameLength = 40
+)
+
+// namePattern allows names that can be written at the start of a payment note.
+var namePattern = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N}_-]*$`)
+
+// PoolCreation holds the settings of a new pool.
+type PoolCreation struct {
+	Name    string `json:"name"`
+	Title   string `json:"title"`
+	Privacy string `json:"privacy"`
+	// Tenant is the caller's tenant that receives the pool's payment notifications. Pools without tenant receive the
+	// notifications sent to the deployment's own address.
+	Tenant string `json:"tenant"`
+	// BaseCurrency is the currency the pool's totals are converted to, EUR if it is not set.
+	BaseCurrency string `json:"baseCurrency"`
+	// TotalsBasis is "gross" or "net", gross if it is not set.
+	TotalsBasis string `json:"totalsBasis"`
+	Metadata
+}
+
+// CreateMoneyPool creates an open pool owned by the caller of the request. It requires a valid bearer token.
+// Pools of a tenant are named "tenant.name".
+func (h *MoneyPoolsHandler) CreateMoneyPool(request events.APIGatewayProxyRequest) (MoneyPool, error) {
+	claims, err := h.authenticate(request)
+	if err != nil {
+		return MoneyPool{}, err
+	}
+	if claims.Subject == "" {
+		return MoneyPool{}, errors.NewUnauthorizedError(fmt.Errorf("creating a moneypool requires a bearer token"))
+	}
+
+	var creation PoolCreation
+	if err := json.Unmarshal([]byte(request.Body), &creation); err != nil {
+		return MoneyPool{}, errors.NewInvalidParametersError(fmt.Errorf("invalid creation body: %v", err))
+	}
+	creation.Name = strings.ToLower(strings.TrimSpace(creation.Name))
+	creation.Title = strings.TrimSpace(creation.Title)
+	creation.Tenant = strings.ToLower(strings.TrimSpace(creation.Tenant))
+	creation.Metadata = creation.Metadata.trimmed()
+	if err := creation.validate(); err != nil {
+		return MoneyPool{}, errors.NewInvalidParametersError(err)
+	}
+	h.logger = log.WithFields(log.Fields{"requestedMP": creation.Name, "tenant": creation.Tenant, "owner": claims.Subject})
+
+	if creation.Tenant != "" {
+		if _, err := h.getTenant(creation.Tenant, claims.Subject); err != nil {
+			return MoneyPool{}, err
+		}
+	}
+	if err := h.checkNameCollisions(creation.Tenant, creation.Name); err != nil {
+		return MoneyPool{}, err
+	}
+	item, err := h.putPoolItem(creation, claims.Subject)
+	if err != nil {
+		return MoneyPool{}, err
+	}
+	h.logger.Infof("created moneypool")
+	h.audit(request, AuditEntry{Pool: *item["name"].S, Action: AuditPoolCreate, Actor: actor(claims), New: auditValues(item)})
+
+	pool, err := decodePool(item)
+	if err != nil {
+		return MoneyPool{}, err
+	}
+	h.convertTotals(&pool)
+	pool.ETag, err = itemETag(item, h.ratesVersion())
+	if err != nil {
+		return MoneyPool{}, err
+	}
+	return pool, nil
+}
+
+func (c PoolCreation) validate() error {
+	length := utf8.RuneCountInString(c.Name)
+	if length < minNameLength || length > maxNameLength || !namePattern.MatchString(c.Name) {
+		return fmt.Errorf("name must have between %d and %d letters, digits, '-' or '_'", minNameLength, maxNameLength)
+	}
+	if c.Title == "" || len(c.Title) > maxTitleLength {
+		return fmt.Errorf("title must have between 1 and %d characters", maxTitleLength)
+	}
+	if c.Privacy != "" && !validPrivacy(c.Privacy) {
+		return fmt.Errorf("unknown privacy mode %s", c.Privacy)
+	}
+	if c.Tenant != "" && !namePattern.MatchString(c.Tenant) {
+		return fmt.Errorf("unknown tenant %s", c.Tenant)
+	}
+	if c.BaseCurrency != "" && !validCurrency(c.BaseCurrency) {
+		return fmt.Errorf("invalid base currency %q, expected a code like EUR", c.BaseCurrency)
+	}
+	if c.TotalsBasis != "" && !validTotalsBasis(c.TotalsBasis) {
+		return fmt.Errorf("unknown totals basis %s", c.TotalsBasis)
+	}
+	return c.Metadata.validate()
+}
+
+// checkNameCollisions rejects names that start with the name of an existing pool of the same tenant, or are the start
+// of one. Payment notes are matched to the tenant's pools by prefix, so such pools could not be told apart.
+func (h *MoneyPoolsHandler) checkNameCollisions(tenant, name string) error {
+	var collision string
+	err := h.dynamoClient.ScanPages(&dynamodb.ScanInput{
+		TableName:            aws.String(h.tables.MoneyPools),
+		ProjectionExpression: aws.String("#name, #tenant"),
+		ExpressionAttributeNames: map[string]*string{
+			"#name":   aws.String("name"),
+			"#tenant": aws.String("tenant"),
+		},
+	}, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			if item["name"] == nil || item["name"].S == nil {
+				continue
+			}
+			itemTenant := ""
+			if item["tenant"] != nil && item["tenant"].S != nil {
+				itemTenant = *item["tenant"].S
+			}
+			if itemTenant != tenant {
+				continue
+			}
+			existing := strings.TrimPrefix(strings.ToLower(*item["name"].S), poolKey(tenant, ""))
+			if strings.HasPrefix(name, existing) || strings.HasPrefix(existing, name) {
+				collision = existing
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return errors.NewStoreUnavailableError(fmt.Errorf("error reading moneypools from db: %v", err))
+	}
+	if collision != "" {
+		return errors.NewConflictError(fmt.Errorf("name %s collides with existing moneypool %s", name, collision))
+	}
+	return nil
+}
+
+func (h *MoneyPoolsHandler) putPoolItem(creation PoolCreation, owner string) (map[string]*dynamodb.AttributeValue, error) {
+	key := poolKey(creation.Tenant, creation.Name)
+	item := map[string]*dynamodb.AttributeValue{
+		"name":         {S: aws.String(key)},
+		"title":        {S: aws.String(creation.Title)},
+		"open":         {BOOL: aws.Bool(true)},
+		"owner":        {S: aws.String(owner)},
+		"createdAt":    {S: aws.String(time.Now().UTC().Format(time.RFC3339))},
+		"transactions": {L: []*dynamodb.AttributeValue{}},
+	}
+	if creation.Privacy != "" {
+		item["privacy"] = &dynamodb.AttributeValue{S: aws.String(creation.Privacy)}
+	}
+	if creation.Tenant != "" {
+		item["tenant"] = &dynamodb.AttributeValue{S: aws.String(creation.Tenant)}
+	}
+	if creation.BaseCurrency != "" {
+		item["baseCurrency"] = &dynamodb.AttributeValue{S: aws.String(creation.BaseCurrency)}
+	}
+	if creation.TotalsBasis != "" {
+		item["totalsBasis"] = &dynamodb.AttributeValue{S: aws.String(creation.TotalsBasis)}
+	}
+	for attribute, value := range creation.Metadata.attributes() {
+		if value != "" {
+			item[attribute] = &dynamodb.AttributeValue{S: aws.String(value)}
+		}
+	}
+	_, err := h.dynamoClient.PutItem(&dynamodb.PutItemInput{
+		TableName:           aws.String(h.tables.MoneyPools),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(#name)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#name": aws.String("name"),
+		},
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return nil, errors.NewConflictError(fmt.Errorf("moneypool %s already exists", key))
+		}
+		return nil, errors.NewStoreUnavailableError(fmt.Errorf("error creating moneypool in db: %v", err))
+	}
+	return item, nil
+}
@@ -0,0 +1,150 @@
+package moneypool
+
+import (
+	"api/auth"
+	"api/errors"
+	er "errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+)
+
+const (
+	ownerJwt = "owner-jwt"
+	otherJwt = "other-jwt"
+)
+
+// FakeVerifier accepts a fixed set of tokens, mapped to their subjects.
+type FakeVerifier struct {
+	subjects map[string]string
+}
+
+func (v FakeVerifier) Verify(token string) (auth.Claims, error) {
+	subject, exists := v.subjects[token]
+	if !exists {
+		return auth.Claims{}, er.New("invalid token signature")
+	}
+	return auth.Claims{Subject: subject}, nil
+}
+
+var fakeVerifier = FakeVerifier{subjects: map[string]string{ownerJwt: "owner-sub", otherJwt: "other-sub"}}
+
+type createTest struct {
+	name          string
+	request       events.APIGatewayProxyRequest
+	expectedPool  MoneyPool
+	expectedError error
+}
+
+func TestCreateMoneyPool(t *testing.T) {
+	testTable := []createTest{
+		{
+			"create",
+			createRequest(ownerJwt, `{"name": " Anna ", "title": "Gift for Anna", "privacy": "initials"}`),
+			MoneyPool{Name: "anna", Title: "Gift for Anna", Open: true},
+			nil,
+		},
+		{
+			"no_bearer_token",
+			createRequest("", `{"name": "anna", "title": "Gift for Anna"}`),
+			MoneyPool{},
+			errors.NewUnauthorizedError(er.New("creating a moneypool requires a bearer token")),
+		},
+		{
+			"invalid_bearer_token",
+			createRequest("forged-jwt", `{"name": "anna", "title": "Gift for Anna"}`),
+			MoneyPool{},
+			errors.NewUnauthorizedError(er.New("invalid token signature")),
+		},
+		{
+			"invalid_name",
+			createRequest(ownerJwt, `{"name": "anna paul", "title": "Gift for Anna"}`),
+			MoneyPool{},
+			errors.NewInvalidParametersError(er.New("name must have between 2 and 40 letters, digits, '-' or '_'")),
+		},
+		{
+			"missing_title",
+			createRequest(ownerJwt, `{"name": "anna"}`),
+			MoneyPool{},
+			errors.NewInvalidParametersError(er.New("title must have between 1 and 200 characters")),
+		},
+		{
+			"unknown_privacy",
+			createRequest(ownerJwt, `{"name": "anna", "title": "Gift for Anna", "privacy": "secret"}`),
+			MoneyPool{},
+			errors.NewInvalidParametersError(er.New("unknown privacy mode secret")),
+		},
+		{
+			"existing_name",
+			createRequest(ownerJwt, `{"name": "Paul", "title": "Another gift"}`),
+			MoneyPool{},
+			errors.NewConflictError(er.New("name paul collides with existing moneypool paul")),
+		},
+		{
+			"prefix_of_existing_name",
+			createRequest(ownerJwt, `{"name": "pa", "title": "Another gift"}`),
+			MoneyPool{},
+			errors.NewConflictError(er.New("name pa collides with existing moneypool paul")),
+		},
+		{
+			"existing_name_is_prefix",
+			createRequest(ownerJwt, `{"name": "paula", "title": "Gift for Paula"}`),
+			MoneyPool{},
+			errors.NewConflictError(er.New("name paula collides with existing moneypool paul")),
+		},
+	}
+	for _, test := range testTable {
+		client := NewFakeDynamoClient(testPoolItem("paul", "Gift for Paul", true), testPoolItem("zoe", "Gift for Zoe", true))
+		pool, err := NewHandler(testTables, client, fakeVerifier).CreateMoneyPool(test.request)
+		pool.ETag = ""
+		pool = withoutCurrencies(pool)
+		if !compareErrors(err, test.expectedError) || !reflect.DeepEqual(pool, test.expectedPool) {
+			t.Fatalf("CreateMoneyPool(%s) = %+v, %v but expected %+v, %v", test.name, pool, err, test.expectedPool, test.expectedError)
+		}
+		if err != nil && client.updates > 0 {
+			t.Fatalf("CreateMoneyPool(%s) failed, but still created a moneypool", test.name)
+		}
+	}
+}
+
+func TestCreateMoneyPoolStoresOwner(t *testing.T) {
+	client := NewFakeDynamoClient()
+	handler := NewHandler(testTables, client, fakeVerifier)
+	if _, err := handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "anna", "title": "Gift for Anna"}`)); err != nil {
+		t.Fatalf("CreateMoneyPool(anna) returned error %v", err)
+	}
+	if owner := client.items["anna"]["owner"]; owner == nil || *owner.S != "owner-sub" {
+		t.Fatalf("CreateMoneyPool(anna) stored owner %v, but should store owner-sub", owner)
+	}
+
+	request := withHeader(updateRequest("", `{"open": false}`), "Authorization", "Bearer "+ownerJwt)
+	request.PathParameters["moneyPool"] = "anna"
+	pool, err := handler.UpdateMoneyPool(request)
+	if err != nil || pool.Open {
+		t.Fatalf("UpdateMoneyPool(close) = %+v, %v but the owner should be able to close the pool", pool, err)
+	}
+}
+
+func TestCreateMoneyPoolWithoutIssuer(t *testing.T) {
+	_, err := NewHandler(testTables, NewFakeDynamoClient(), nil).CreateMoneyPool(createRequest(ownerJwt, `{"name": "anna", "title": "Gift for Anna"}`))
+	expected := errors.NewUnauthorizedError(er.New("bearer tokens are not accepted, no issuer configured"))
+	if !compareErrors(err, expected) {
+		t.Fatalf("CreateMoneyPool(without_issuer) returned error %v, but should return %v", err, expected)
+	}
+}
+
+func createRequest(jwt, body string) events.APIGatewayProxyRequest {
+	request := events.APIGatewayProxyRequest{HTTPMethod: "POST", Resource: "/pools", Body: body}
+	if jwt != "" {
+		request = withHeader(request, "Authorization", "Bearer "+jwt)
+	}
+	return request
+}
+
+func ownedPoolItem() map[string]*dynamodb.AttributeValue {
+	item := privatePoolItem()
+	item["owner"] = &dynamodb.AttributeValue{S: aws.String("owner-sub")}
+	return item
+}
@@ -0,0 +1,146 @@
+package moneypool
+
+import (
+	"math"
+	"regexp"
+	"sort"
+)
+
+// defaultCurrency is the base currency of pools that don't declare one, and the currency of transactions stored before
+// currencies were.
+const defaultCurrency = "EUR"
+
+// currencyPattern matches ISO currency codes.
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// currencyDecimals are the digits of the minor unit of the currencies that don't have two, as in the transaction
+// lambda's money package. Amounts of currencies with three decimals are stored with them, all others in cents.
+var currencyDecimals = map[string]int{
+	"JPY": 0, "KRW": 0, "VND": 0, "CLP": 0, "ISK": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+func validCurrency(currency string) bool {
+	return currencyPattern.MatchString(currency)
+}
+
+// decimals returns the number of decimals amounts of the currency are written with, 2 for most currencies.
+func decimals(currency string) int {
+	if digits, known := currencyDecimals[currency]; known {
+		return digits
+	}
+	return 2
+}
+
+// precision returns the digits of the fraction amounts of the currency are stored with: its decimals, but at least two.
+func precision(currency string) int {
+	if digits := decimals(currency); digits > 2 {
+		return digits
+	}
+	return 2
+}
+
+// scaledAmount returns the amount of the given units of 10^-digits, e.g. cents for two digits.
+func scaledAmount(scaled, digits int) Amount {
+	var amount Amount
+	if scaled < 0 {
+		scaled, amount.Negative = -scaled, true
+	}
+	unit := pow10(digits)
+	amount.Base, amount.Fraction = scaled/unit, scaled%unit
+	if digits > 2 {
+		amount.Decimals = digits
+	}
+	return amount
+}
+
+// digits returns the number of digits of the amount's fraction.
+func (a Amount) digits() int {
+	if a.Decimals > 2 {
+		return a.Decimals
+	}
+	return 2
+}
+
+// scaled returns the amount in units of 10^-digits. Digits beyond the amount's own are rounded half away from zero.
+func (a Amount) scaled(digits int) int {
+	amount := a.Base*pow10(a.digits()) + a.Fraction
+	if a.Negative {
+		amount = -amount
+	}
+	if digits >= a.digits() {
+		return amount * pow10(digits-a.digits())
+	}
+	divisor := pow10(a.digits() - digits)
+	if amount < 0 {
+		return -((-amount + divisor/2) / divisor)
+	}
+	return (amount + divisor/2) / divisor
+}
+
+// cents returns the amount in cents, e.g. to convert or compare amounts of different currencies.
+func (a Amount) cents() int {
+	return a.scaled(2)
+}
+
+func pow10(exponent int) int {
+	result := 1
+	for i := 0; i < exponent; i++ {
+		result *= 10
+	}
+	return result
+}
+
+// WithRates enables the conversion of pool totals to their base currency. Without rates, only totals already in the
+// base currency are converted.
+func (h *MoneyPoolsHandler) WithRates(rates RateSource) *MoneyPoolsHandler {
+	h.rates = rates
+	return h
+}
+
+// ratesVersion returns the version of the handler's exchange rates, or an empty version without rates.
+func (h *MoneyPoolsHandler) ratesVersion() string {
+	if h.rates == nil {
+		return ""
+	}
+	return h.rates.Version()
+}
+
+// totalsByCurrency sums up the transactions of each currency, like sumTransactions.
+func totalsByCurrency(transactions []Transaction, basis string) map[string]Amount {
+	totals := map[string]Amount{}
+	for _, transaction := range transactions {
+		if _, exists := totals[transaction.Currency]; !exists {
+			totals[transaction.Currency] = sumTransactions(transactions, transaction.Currency, basis)
+		}
+	}
+	return totals
+}
+
+// convertTotals adds the pool's totals, converted to its base currency. Currencies without known rate are left out and
+// listed in the pool's missing rates.
+func (h *MoneyPoolsHandler) convertTotals(pool *MoneyPool) {
+	var cents float64
+	pool.MissingRates = nil
+	for currency, total := range pool.Totals {
+		rate, known := 1.0, true
+		if currency != pool.BaseCurrency {
+			rate, known = 0, false
+			if h.rates != nil {
+				rate, known = h.rates.Rate(currency, pool.BaseCurrency)
+			}
+		}
+		if !known {
+			pool.MissingRates = append(pool.MissingRates, currency)
+			continue
+		}
+		cents += float64(total.scaled(total.digits())) / float64(pow10(total.digits()-2)) * rate
+	}
+	sort.Strings(pool.MissingRates)
+	converted := int(math.Round(cents))
+	convertedTotal := scaledAmount(converted, 2)
+	pool.ConvertedTotal = &convertedTotal
+	if len(pool.MissingRates) > 0 && h.logger != nil {
+		h.logger.Warnf("no exchange rates to %s for %v", pool.BaseCurrency, pool.MissingRates)
+	}
+}
@@ -0,0 +1,222 @@
+package moneypool
+
+import (
+	"encoding/json"
+	er "errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+)
+
+// FakeRates knows the rates to EUR.
+type FakeRates map[string]float64
+
+func (r FakeRates) Rate(from, to string) (float64, bool) {
+	rate, exists := r[from]
+	return rate, exists && to == "EUR"
+}
+
+func (r FakeRates) Version() string {
+	raw, _ := json.Marshal(r)
+	return string(raw)
+}
+
+type currencyTest struct {
+	name             string
+	rates            RateSource
+	baseCurrency     string
+	expectedTotals   map[string]Amount
+	expectedTotal    *Amount
+	expectedMissing  []string
+	expectedCurrency string
+	expectedError    error
+}
+
+func TestGetMoneyPoolCurrencies(t *testing.T) {
+	testTable := []currencyTest{
+		{
+			"converted",
+			FakeRates{"USD": 0.9, "GBP": 1.2},
+			"",
+			map[string]Amount{"EUR": {Base: 12, Fraction: 34}, "USD": {Base: 10, Fraction: 0}, "GBP": {Base: 1, Fraction: 5}},
+			&Amount{Base: 22, Fraction: 60},
+			nil,
+			"EUR",
+			nil,
+		},
+		{
+			"missing_rate",
+			FakeRates{"USD": 0.9},
+			"",
+			map[string]Amount{"EUR": {Base: 12, Fraction: 34}, "USD": {Base: 10, Fraction: 0}, "GBP": {Base: 1, Fraction: 5}},
+			&Amount{Base: 21, Fraction: 34},
+			[]string{"GBP"},
+			"EUR",
+			nil,
+		},
+		{
+			"no_rates",
+			nil,
+			"USD",
+			map[string]Amount{"EUR": {Base: 12, Fraction: 34}, "USD": {Base: 10, Fraction: 0}, "GBP": {Base: 1, Fraction: 5}},
+			&Amount{Base: 10, Fraction: 0},
+			[]string{"EUR", "GBP"},
+			"USD",
+			nil,
+		},
+		{
+			"invalid_base_currency",
+			nil,
+			"euro",
+			nil,
+			nil,
+			nil,
+			"",
+			er.New("moneypool item has invalid base currency euro"),
+		},
+	}
+	for _, test := range testTable {
+		item := testPoolItem("paul", "Gift for Paul", true,
+			testTransactionItem("Sender Person", "01.02.22", "12", "34"),
+			withTransactionAttribute(testTransactionItem("Cher", "02.02.22", "10", "0"), "currency", &dynamodb.AttributeValue{S: aws.String("USD")}),
+			withTransactionAttribute(testTransactionItem("Bono", "03.02.22", "1", "5"), "currency", &dynamodb.AttributeValue{S: aws.String("GBP")}),
+		)
+		if test.baseCurrency != "" {
+			item["baseCurrency"] = &dynamodb.AttributeValue{S: aws.String(test.baseCurrency)}
+		}
+		handler := NewHandler(testTables, NewFakeDynamoClient(item), nil)
+		if test.rates != nil {
+			handler.WithRates(test.rates)
+		}
+		pool, err := handler.GetMoneyPool(poolRequest("paul"))
+		if !compareErrors(err, test.expectedError) {
+			t.Fatalf("GetMoneyPool(%s) returned error %v, but expected %v", test.name, err, test.expectedError)
+		}
+		if test.expectedError != nil {
+			continue
+		}
+		if pool.BaseCurrency != test.expectedCurrency || !reflect.DeepEqual(pool.Totals, test.expectedTotals) ||
+			!reflect.DeepEqual(pool.ConvertedTotal, test.expectedTotal) || !reflect.DeepEqual(pool.MissingRates, test.expectedMissing) {
+			t.Fatalf("GetMoneyPool(%s) returned %s totals %v, converted %v, missing %v, but expected %s totals %v, converted %v, missing %v",
+				test.name, pool.BaseCurrency, pool.Totals, pool.ConvertedTotal, pool.MissingRates,
+				test.expectedCurrency, test.expectedTotals, test.expectedTotal, test.expectedMissing)
+		}
+		if pool.Transactions[0].Currency != "EUR" || pool.Transactions[1].Currency != "USD" {
+			t.Fatalf("GetMoneyPool(%s) returned transactions %+v, but expected currencies EUR and USD", test.name, pool.Transactions)
+		}
+	}
+}
+
+func TestGetMoneyPoolThreeDecimals(t *testing.T) {
+	kwd := &dynamodb.AttributeValue{S: aws.String("KWD")}
+	threeDecimals := &dynamodb.AttributeValue{N: aws.String("3")}
+	item := testPoolItem("paul", "Gift for Paul", true,
+		withTransactionAttribute(withTransactionAttribute(testTransactionItem("Cher", "01.02.22", "1", "255"), "currency", kwd), "decimals", threeDecimals),
+		// stored in cents, before three decimals were
+		withTransactionAttribute(testTransactionItem("Bono", "02.02.22", "2", "50"), "currency", kwd),
+		withTransactionAttribute(testTransactionItem("Sting", "03.02.22", "1", "250"), "currency", kwd),
+		withTransactionAttribute(testTransactionItem("Adele", "04.02.22", "1", "250"), "decimals", threeDecimals),
+	)
+	pool, err := NewHandler(testTables, NewFakeDynamoClient(item), nil).WithRates(FakeRates{"KWD": 3}).GetMoneyPool(poolRequest("paul"))
+	if err != nil {
+		t.Fatalf("GetMoneyPool returned error %v", err)
+	}
+	if len(pool.Transactions) != 2 || pool.Transactions[0].amount() != (Amount{Base: 1, Fraction: 255, Decimals: 3}) {
+		t.Fatalf("GetMoneyPool returned transactions %+v, but expected 1.255 KWD and 2.50 KWD", pool.Transactions)
+	}
+	expectedInvalid := []InvalidTransaction{
+		{Index: 2, Id: "id-Sting", Error: "transaction has fraction 250 out of range 0-99"},
+		{Index: 3, Id: "id-Adele", Error: "transaction has 3 decimals, but EUR has 2"},
+	}
+	if !reflect.DeepEqual(pool.InvalidTransactions, expectedInvalid) {
+		t.Fatalf("GetMoneyPool returned invalid transactions %+v, but expected %+v", pool.InvalidTransactions, expectedInvalid)
+	}
+	if total := pool.Totals["KWD"]; total != (Amount{Base: 3, Fraction: 755, Decimals: 3}) {
+		t.Fatalf("GetMoneyPool returned KWD total %+v, but expected 3.755", total)
+	}
+	if *pool.ConvertedTotal != (Amount{Base: 11, Fraction: 27}) {
+		t.Fatalf("GetMoneyPool returned converted total %+v, but expected 11.27 EUR", pool.ConvertedTotal)
+	}
+}
+
+func TestGetMoneyPoolNegativeTotal(t *testing.T) {
+	testTable := []struct {
+		contribution string
+		total        Amount
+		formatted    string
+	}{
+		{"1", Amount{Base: 1, Fraction: 50, Negative: true}, "-1.50"},
+		{"2", Amount{Base: 0, Fraction: 50, Negative: true}, "-0.50"},
+	}
+	for _, test := range testTable {
+		for _, privacy := range []string{PrivacyFull, PrivacyHideAmounts} {
+			// refunded more than contributed
+			item := testPoolItem("paul", "Gift for Paul", true,
+				testTransactionItem("Paul", "01.02.22", test.contribution, "0"),
+				withTransactionAttribute(testTransactionItem("Paul", "02.02.22", "2", "50"), "refundOf", &dynamodb.AttributeValue{S: aws.String("id-Paul")}),
+			)
+			item["privacy"] = &dynamodb.AttributeValue{S: aws.String(privacy)}
+			pool, err := NewHandler(testTables, NewFakeDynamoClient(item), nil).GetMoneyPool(poolRequest("paul"))
+			if err != nil {
+				t.Fatalf("GetMoneyPool(%s, %s) returned error %v", test.formatted, privacy, err)
+			}
+			total := pool.Totals["EUR"]
+			if privacy == PrivacyHideAmounts {
+				total = *pool.Total
+			}
+			if total != test.total || *pool.ConvertedTotal != test.total {
+				t.Fatalf("GetMoneyPool(%s, %s) returned totals %+v and %+v, but expected %+v", test.formatted, privacy, total, *pool.ConvertedTotal, test.total)
+			}
+		}
+		if formatted := formatAmount(test.total, "EUR"); formatted != test.formatted {
+			t.Fatalf("formatAmount(%+v) = %s, but expected %s", test.total, formatted, test.formatted)
+		}
+	}
+}
+
+func TestBaseCurrencySettings(t *testing.T) {
+	client := NewFakeDynamoClient()
+	handler := NewHandler(testTables, client, fakeVerifier)
+	pool, err := handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "paul", "title": "Gift for Paul", "baseCurrency": "USD"}`))
+	if err != nil || pool.BaseCurrency != "USD" {
+		t.Fatalf("CreateMoneyPool(base_currency) returned %+v, %v but expected base currency USD", pool, err)
+	}
+	if _, err := handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "peter", "title": "Gift for Peter", "baseCurrency": "usd"}`)); !compareErrors(err,
+		er.New(`invalid base currency "usd", expected a code like EUR`)) {
+		t.Fatalf("CreateMoneyPool(invalid_base_currency) returned error %v", err)
+	}
+
+	pool, err = handler.UpdateMoneyPool(ownerUpdate(`{"baseCurrency": "GBP"}`))
+	if err != nil || pool.BaseCurrency != "GBP" {
+		t.Fatalf("UpdateMoneyPool(base_currency) returned %+v, %v but expected base currency GBP", pool, err)
+	}
+	if _, err := handler.UpdateMoneyPool(ownerUpdate(`{"baseCurrency": ""}`)); !compareErrors(err,
+		er.New(`invalid base currency "", expected a code like EUR`)) {
+		t.Fatalf("UpdateMoneyPool(empty_base_currency) returned error %v", err)
+	}
+}
+
+func ownerUpdate(body string) events.APIGatewayProxyRequest {
+	return withHeader(updateRequest("", body), "Authorization", "Bearer "+ownerJwt)
+}
+
+// withoutCurrencies clears the pool's currencies, totals and their settings, so tests that are not about them can
+// compare pools with literals.
+func withoutCurrencies(pool MoneyPool) MoneyPool {
+	pool.BaseCurrency = ""
+	pool.TotalsBasis = ""
+	pool.Totals = nil
+	pool.ConvertedTotal = nil
+	pool.MissingRates = nil
+	if pool.Transactions != nil {
+		transactions := make([]Transaction, len(pool.Transactions))
+		for i, transaction := range pool.Transactions {
+			transaction.Currency = ""
+			transactions[i] = transaction
+		}
+		pool.Transactions = transactions
+	}
+	return pool
+}
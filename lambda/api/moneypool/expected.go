@@ -0,0 +1,235 @@
+package moneypool
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Modes of pools that expect an amount per person. Statuses are the same in both modes, but reminders of pools with a
+// suggested amount only go to participants who haven't paid at all.
+const (
+	ModeSuggested = "suggested"
+	ModeFixed     = "fixed"
+)
+
+// Statuses of contributors of pools that expect an amount per person. Participants of pools without amount are either
+// open or paid.
+const (
+	StatusOpen      = "open" // expected participants who haven't paid yet
+	StatusUnderpaid = "underpaid"
+	StatusPaid      = "paid"
+	StatusOverpaid  = "overpaid"
+)
+
+const (
+	maxParticipants          = 200
+	maxParticipantNameLength = 200
+	maxAliases               = 10
+)
+
+// Expected are the settings of pools that expect an amount per person or a roster of participants, which the transaction
+// lambda reads from the pool's expected attribute to link payments to participants and to remind them. Participants'
+// mail addresses and aliases are only returned by GetRoster.
+type Expected struct {
+	Mode string `json:"mode" dynamodbav:"mode"`
+	// Amount is what each person is expected to pay, in the pool's base currency. Pools with a roster of participants
+	// may leave it out, their participants only pay or don't.
+	Amount       *Amount       `json:"amount,omitempty" dynamodbav:"amount,omitempty"`
+	Participants []Participant `json:"participants,omitempty" dynamodbav:"participants,omitempty"`
+	// Reminders enables weekly reminder mails to participants with an open balance and a mail address.
+	Reminders bool `json:"reminders,omitempty" dynamodbav:"reminders,omitempty"`
+}
+
+// Participant is a person expected to pay. The transaction lambda links payments to participants by the sender's mail
+// address, or by the sender's PayPal name matching the participant's name or one of their aliases, e.g. the name of the
+// parent who pays for a child.
+type Participant struct {
+	// Id is derived from the participant's mail address or name, so it stays the same when the list is replaced.
+	Id      string   `json:"id" dynamodbav:"id"`
+	Name    string   `json:"name" dynamodbav:"name"`
+	Email   string   `json:"email,omitempty" dynamodbav:"email,omitempty"`
+	Aliases []string `json:"aliases,omitempty" dynamodbav:"aliases,omitempty"`
+}
+
+// ExpectedAmount is the public part of a pool's expected settings.
+type ExpectedAmount struct {
+	Mode   string `json:"mode"`
+	Amount Amount `json:"amount"`
+}
+
+// ContributorStatus tells how much of the expected amount a participant or contributor has paid. Amounts are in the
+// pool's base currency; contributions in other currencies don't count.
+type ContributorStatus struct {
+	Name        string `json:"name"`
+	Participant bool   `json:"participant,omitempty"`
+	Status      string `json:"status"`
+	Paid        Amount `json:"paid"`
+	// Open is the balance still to be paid, zero for contributors who paid in full.
+	Open Amount `json:"open"`
+	// anonymous is set if one of the contributor's payments asked to not be named publicly.
+	anonymous bool
+	// transactions are the ids of the payments and refunds that count for the contributor.
+	transactions []string
+}
+
+func (e Expected) validate() error {
+	if e.Mode != ModeSuggested && e.Mode != ModeFixed {
+		return fmt.Errorf("unknown mode %s, expected %s or %s", e.Mode, ModeSuggested, ModeFixed)
+	}
+	if e.Amount == nil && len(e.Participants) == 0 {
+		return fmt.Errorf("expected settings need an amount or participants")
+	}
+	if e.Amount != nil && (e.Amount.Negative || e.Amount.Base < 0 || e.Amount.Fraction < 0 || e.Amount.Fraction > 99 || e.Amount.Base+e.Amount.Fraction == 0) {
+		return fmt.Errorf("expected amount must be a positive amount")
+	}
+	if len(e.Participants) > maxParticipants {
+		return fmt.Errorf("a pool can have at most %d participants", maxParticipants)
+	}
+	ids := map[string]bool{}
+	for i, participant := range e.Participants {
+		if participant.Name == "" || utf8.RuneCountInString(participant.Name) > maxParticipantNameLength {
+			return fmt.Errorf("participant %d: name must have between 1 and %d characters", i+1, maxParticipantNameLength)
+		}
+		if participant.Email != "" && !mailAddressPattern.MatchString(participant.Email) {
+			return fmt.Errorf("participant %d: invalid mail address %q", i+1, participant.Email)
+		}
+		if len(participant.Aliases) > maxAliases {
+			return fmt.Errorf("participant %d: at most %d aliases are allowed", i+1, maxAliases)
+		}
+		for _, alias := range participant.Aliases {
+			if alias == "" || utf8.RuneCountInString(alias) > maxParticipantNameLength {
+				return fmt.Errorf("participant %d: aliases must have between 1 and %d characters", i+1, maxParticipantNameLength)
+			}
+		}
+		if ids[participant.Id] {
+			return fmt.Errorf("participant %d: %s is listed twice", i+1, participant.Name)
+		}
+		ids[participant.Id] = true
+	}
+	if e.Reminders && !e.hasAddresses() {
+		return fmt.Errorf("reminders need participants with mail addresses")
+	}
+	return nil
+}
+
+// normalized trims the participants and derives their ids. Pools without mode get a suggested amount.
+func (e Expected) normalized() Expected {
+	if e.Mode == "" {
+		e.Mode = ModeSuggested
+	}
+	participants := make([]Participant, 0, len(e.Participants))
+	for _, participant := range e.Participants {
+		participant.Name = strings.TrimSpace(participant.Name)
+		participant.Email = strings.ToLower(strings.TrimSpace(participant.Email))
+		aliases := make([]string, 0, len(participant.Aliases))
+		for _, alias := range participant.Aliases {
+			aliases = append(aliases, strings.TrimSpace(alias))
+		}
+		participant.Aliases = aliases
+		participant.Id = participantId(participant)
+		participants = append(participants, participant)
+	}
+	e.Participants = participants
+	return e
+}
+
+// empty tells whether the settings neither expect an amount nor participants, which removes them.
+func (e Expected) empty() bool {
+	return e.Amount == nil && len(e.Participants) == 0
+}
+
+func (e Expected) hasAddresses() bool {
+	for _, participant := range e.Participants {
+		if participant.Email != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// participantId hashes the participant's mail address, or their name if they have none.
+func participantId(participant Participant) string {
+	key := participant.Email
+	if key == "" {
+		key = normalizeName(participant.Name)
+	}
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:8])
+}
+
+// normalizeName makes names that only differ in case or spacing equal.
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// contributorStatuses returns the statuses of the expected participants, in their order, followed by the other
+// contributors in the order of their first payment. Payments count for the participant they were linked to, or else
+// for the participant their sender's name matches. Other senders are told apart by their name, ignoring case and
+// spacing. Voided payments don't count, refunds are subtracted.
+func contributorStatuses(expected Expected, transactions []Transaction, currency string) []ContributorStatus {
+	statuses := make([]ContributorStatus, 0, len(expected.Participants))
+	byId, byName := map[string]int{}, map[string]int{}
+	for _, participant := range expected.Participants {
+		byId[participant.Id] = len(statuses)
+		statuses = append(statuses, ContributorStatus{Name: participant.Name, Participant: true})
+	}
+	paid := make([]int, len(statuses))
+	for _, transaction := range transactions {
+		if transaction.Voided || transaction.Currency != currency {
+			continue
+		}
+		index, linked := byId[transaction.Participant]
+		if !linked {
+			index = matchParticipant(expected.Participants, transaction.Name)
+			linked = index >= 0
+		}
+		if !linked {
+			name := normalizeName(transaction.Name)
+			if index, linked = byName[name]; !linked {
+				index = len(statuses)
+				byName[name] = index
+				statuses = append(statuses, ContributorStatus{Name: transaction.Name})
+				paid = append(paid, 0)
+			}
+		}
+		cents := transaction.amount().cents()
+		if transaction.RefundOf != "" {
+			cents = -cents
+		}
+		paid[index] += cents
+		statuses[index].anonymous = statuses[index].anonymous || transaction.Anonymous
+		if transaction.Id != "" {
+			statuses[index].transactions = append(statuses[index].transactions, transaction.Id)
+		}
+	}
+
+	expectedCents := 0
+	if expected.Amount != nil {
+		expectedCents = expected.Amount.cents()
+	}
+	for i := range statuses {
+		status := &statuses[i]
+		if paid[i] > 0 {
+			status.Paid = scaledAmount(paid[i], 2)
+		}
+		if open := expectedCents - paid[i]; open > 0 {
+			status.Open = scaledAmount(open, 2)
+		}
+		switch {
+		case paid[i] <= 0:
+			status.Status = StatusOpen
+		case expected.Amount == nil:
+			status.Status = StatusPaid
+		case paid[i] < expectedCents:
+			status.Status = StatusUnderpaid
+		case paid[i] == expectedCents:
+			status.Status = StatusPaid
+		default:
+			status.Status = StatusOverpaid
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,108 @@
+package moneypool
+
+import (
+	er "errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+)
+
+func TestContributorStatuses(t *testing.T) {
+	client := NewFakeDynamoClient()
+	handler := NewHandler(testTables, client, fakeVerifier)
+	if _, err := handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "paul", "title": "Concert tickets"}`)); err != nil {
+		t.Fatalf("CreateMoneyPool returned error %v", err)
+	}
+	settings := `{"expected": {"mode": "fixed", "amount": {"base": 25, "fraction": 0}, "reminders": true, "participants": [
+		{"name": "Anna Schmidt", "email": " Anna@Example.com "}, {"name": "Ben Meyer"}, {"name": "Carla Vogel"}]}}`
+	if _, err := handler.UpdateMoneyPool(ownerUpdate(settings)); err != nil {
+		t.Fatalf("UpdateMoneyPool(expected) returned error %v", err)
+	}
+	anna := participantId(Participant{Email: "anna@example.com"})
+	if stored := client.items["paul"]["expected"].M["participants"].L[0].M; *stored["id"].S != anna || *stored["email"].S != "anna@example.com" {
+		t.Fatalf("unexpected stored participant %v", stored)
+	}
+
+	linked := testTransactionItem("A. Schmidt", "01.03.22", "25", "0")
+	linked.M["participant"] = &dynamodb.AttributeValue{S: aws.String(anna)}
+	refunded := testTransactionItem("ben  meyer", "02.03.22", "5", "0")
+	refunded.M["refundOf"] = &dynamodb.AttributeValue{S: aws.String("id-ben meyer")}
+	client.items["paul"]["transactions"] = &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{
+		linked,
+		testTransactionItem("Ben Meyer", "01.03.22", "20", "0"),
+		refunded,
+		testTransactionItem("Dora Klein", "02.03.22", "30", "50"),
+	}}
+
+	pool, err := handler.GetMoneyPool(poolRequest("paul"))
+	if err != nil {
+		t.Fatalf("GetMoneyPool returned error %v", err)
+	}
+	expected := []ContributorStatus{
+		{Name: "Anna Schmidt", Participant: true, Status: StatusPaid, Paid: Amount{Base: 25}, transactions: []string{"id-A. Schmidt"}},
+		{Name: "Ben Meyer", Participant: true, Status: StatusUnderpaid, Paid: Amount{Base: 15}, Open: Amount{Base: 10},
+			transactions: []string{"id-Ben Meyer", "id-ben  meyer"}},
+		{Name: "Carla Vogel", Participant: true, Status: StatusOpen, Open: Amount{Base: 25}},
+		{Name: "Dora Klein", Status: StatusOverpaid, Paid: Amount{Base: 30, Fraction: 50}, transactions: []string{"id-Dora Klein"}},
+	}
+	if !reflect.DeepEqual(pool.Contributors, expected) {
+		t.Fatalf("GetMoneyPool returned contributors %+v, but expected %+v", pool.Contributors, expected)
+	}
+	if pool.Expected == nil || *pool.Expected != (ExpectedAmount{Mode: ModeFixed, Amount: Amount{Base: 25}}) {
+		t.Fatalf("GetMoneyPool returned expected amount %+v", pool.Expected)
+	}
+
+	if _, err := handler.UpdateMoneyPool(ownerUpdate(`{"expected": {}}`)); err != nil {
+		t.Fatalf("UpdateMoneyPool(no_expected) returned error %v", err)
+	}
+	if _, exists := client.items["paul"]["expected"]; exists {
+		t.Fatalf("expected amount was not removed")
+	}
+}
+
+func TestContributorStatusPrivacy(t *testing.T) {
+	expected := Expected{Mode: ModeSuggested, Amount: &Amount{Base: 10}, Participants: []Participant{{Id: "1", Name: "Anna Schmidt"}}}
+	transactions := []Transaction{
+		{Name: "Anna Schmidt", Base: 10, Currency: defaultCurrency},
+		{Name: "Ben Meyer", Base: 5, Currency: defaultCurrency, Anonymous: true},
+	}
+	pool := MoneyPool{Transactions: transactions, BaseCurrency: defaultCurrency,
+		Contributors: contributorStatuses(expected, transactions, defaultCurrency)}
+	if err := applyPrivacy(&pool, PrivacyInitials); err != nil {
+		t.Fatalf("applyPrivacy returned error %v", err)
+	}
+	if pool.Contributors[0].Name != "Anna S." || pool.Contributors[1].Name != "Contributor #2" {
+		t.Fatalf("applyPrivacy returned contributors %+v", pool.Contributors)
+	}
+
+	pool.Contributors = contributorStatuses(expected, transactions, defaultCurrency)
+	if err := applyPrivacy(&pool, PrivacyHideAmounts); err != nil {
+		t.Fatalf("applyPrivacy returned error %v", err)
+	}
+	if pool.Contributors != nil {
+		t.Fatalf("applyPrivacy(hideAmounts) returned contributors %+v, but should omit them", pool.Contributors)
+	}
+}
+
+func TestInvalidExpectedSettings(t *testing.T) {
+	testTable := map[string]error{
+		`{"mode": "exact", "amount": {"base": 25}}`:                                                                           er.New("unknown mode exact, expected suggested or fixed"),
+		`{"amount": {"base": 0, "fraction": 0}}`:                                                                              er.New("expected amount must be a positive amount"),
+		`{"amount": {"base": 25}, "participants": [{"name": " "}]}`:                                                           er.New("participant 1: name must have between 1 and 200 characters"),
+		`{"amount": {"base": 25}, "participants": [{"name": "Anna", "email": "anna"}]}`:                                       er.New(`participant 1: invalid mail address "anna"`),
+		`{"amount": {"base": 25}, "participants": [{"name": "Anna"}, {"name": "anna"}]}`:                                      er.New("participant 2: anna is listed twice"),
+		`{"amount": {"base": 25}, "participants": [{"name": "Anna"}], "reminders": true}`:                                     er.New("reminders need participants with mail addresses"),
+		`{"amount": {"base": 25}, "participants": [{"name": "Anna", "email": "a@b.de"}, {"name": "Ben", "email": "A@b.de"}]}`: er.New("participant 2: Ben is listed twice"),
+	}
+	handler := NewHandler(testTables, NewFakeDynamoClient(), fakeVerifier)
+	if _, err := handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "paul", "title": "Concert tickets"}`)); err != nil {
+		t.Fatalf("CreateMoneyPool returned error %v", err)
+	}
+	for settings, expected := range testTable {
+		_, err := handler.UpdateMoneyPool(ownerUpdate(`{"expected": ` + settings + `}`))
+		if !compareErrors(err, expected) {
+			t.Fatalf("UpdateMoneyPool(%s) returned error %v, but expected %v", settings, err, expected)
+		}
+	}
+}
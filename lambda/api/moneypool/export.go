@@ -0,0 +1,279 @@
+package moneypool
+
+import (
+	"api/errors"
+	"api/xlsx"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats, as given in the format query parameter.
+const (
+	ExportCSV  = "csv"
+	ExportJSON = "json"
+	ExportXLSX = "xlsx"
+)
+
+// storedDateFormats are the formats of transaction dates, as written by the transaction lambda or by hand.
+var storedDateFormats = []string{"02.01.06", "02.01.2006", "2006-01-02"}
+
+var exportColumns = []string{"id", "date", "name", "amount", "currency", "status", "refundOf", "anonymous", "fee", "net"}
+
+// Export is a pool's transactions, encoded for download.
+type Export struct {
+	ContentType string
+	Filename    string
+	Body        []byte
+	// Binary is set for formats that have to be base64 encoded in api responses.
+	Binary bool
+}
+
+// ExportRow is a single transaction in an export. Amounts are signed decimals, so refunds are negative. Fee and net are
+// only set for payments whose mail showed PayPal's fee.
+type ExportRow struct {
+	Id        string `json:"id"`
+	Date      string `json:"date"`
+	Name      string `json:"name"`
+	Amount    string `json:"amount,omitempty"`
+	Currency  string `json:"currency"`
+	Status    string `json:"status,omitempty"`
+	RefundOf  string `json:"refundOf,omitempty"`
+	Anonymous bool   `json:"anonymous"`
+	Fee       string `json:"fee,omitempty"`
+	Net       string `json:"net,omitempty"`
+
+	date     time.Time
+	isoDate  bool
+	noAmount bool
+}
+
+// PoolExport is the json export of a pool. Totals leave out voided transactions and subtract refunds. Total is the total
+// in the pool's base currency, while totals lists the totals of all currencies and the converted total sums them up in
+// the base currency, leaving out the currencies listed in missing rates.
+type PoolExport struct {
+	Name           string            `json:"name"`
+	Title          string            `json:"title"`
+	Open           bool              `json:"open"`
+	Currency       string            `json:"currency"`
+	Total          string            `json:"total,omitempty"`
+	Totals         map[string]string `json:"totals,omitempty"`
+	ConvertedTotal string            `json:"convertedTotal,omitempty"`
+	MissingRates   []string          `json:"missingRates,omitempty"`
+	Transactions   []ExportRow       `json:"transactions"`
+}
+
+// ExportMoneyPool encodes a pool's transactions as csv, json or xlsx, given by the format query parameter. Readers get the
+// transactions as shown by GetMoneyPool, while admins get them without the pool's privacy mode applied.
+func (h *MoneyPoolsHandler) ExportMoneyPool(request events.APIGatewayProxyRequest) (Export, error) {
+	mpName, mpParamExists := request.PathParameters["moneyPool"]
+	if !mpParamExists {
+		return Export{}, errors.NewInvalidParametersError(fmt.Errorf("no moneyppol name given"))
+	}
+	format := strings.ToLower(request.QueryStringParameters["format"])
+	if format == "" {
+		format = ExportCSV
+	}
+	if format != ExportCSV && format != ExportJSON && format != ExportXLSX {
+		return Export{}, errors.NewInvalidParametersError(fmt.Errorf("unknown export format %s", format))
+	}
+	h.logger = log.WithFields(log.Fields{"requestedMP": mpName, "format": format})
+
+	item, err := h.getPoolItem(mpName)
+	if err != nil {
+		return Export{}, err
+	}
+	claims, err := h.authorize(request, item, mpName, AccessRead)
+	if err != nil {
+		return Export{}, err
+	}
+	token, fromHeader := requestToken(request)
+	access, err := accessLevel(item, claims.Subject, token, fromHeader)
+	if err != nil {
+		return Export{}, err
+	}
+	var pool MoneyPool
+	if access >= AccessAdmin {
+		pool, _, err = decodeUnredactedPool(item)
+	} else {
+		pool, err = decodePool(item)
+	}
+	if err != nil {
+		return Export{}, err
+	}
+	h.convertTotals(&pool)
+
+	rows := exportRows(pool)
+	export := Export{Filename: pool.Name + "." + format}
+	switch format {
+	case ExportCSV:
+		export.ContentType = "text/csv; charset=utf-8"
+		export.Body, err = exportCSV(rows)
+	case ExportJSON:
+		export.ContentType = "application/json"
+		export.Body, err = exportJSON(pool, rows)
+	case ExportXLSX:
+		export.ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		export.Binary = true
+		export.Body, err = exportXLSX(pool, rows)
+	}
+	if err != nil {
+		return Export{}, fmt.Errorf("could not encode %s export: %v", format, err)
+	}
+	h.logger.Infof("exported %d transactions", len(rows))
+	return export, nil
+}
+
+func exportRows(pool MoneyPool) []ExportRow {
+	rows := make([]ExportRow, 0, len(pool.Transactions))
+	for _, transaction := range pool.Transactions {
+		row := ExportRow{
+			Id:        transaction.Id,
+			Date:      transaction.Date,
+			Name:      transaction.Name,
+			Currency:  transaction.Currency,
+			RefundOf:  transaction.RefundOf,
+			Anonymous: transaction.Anonymous,
+			noAmount:  pool.AmountsHidden,
+		}
+		if transaction.RefundOf != "" {
+			row.Status = "refund"
+		}
+		if transaction.Voided {
+			row.Status = "voided"
+		}
+		if !row.noAmount {
+			amount := transaction.amount()
+			if transaction.RefundOf != "" {
+				amount = scaledAmount(-amount.scaled(amount.digits()), amount.digits())
+			}
+			row.Amount = formatAmount(amount, transaction.Currency)
+		}
+		if transaction.Fee != nil && transaction.Net != nil {
+			row.Fee = formatAmount(*transaction.Fee, transaction.Currency)
+			row.Net = formatAmount(*transaction.Net, transaction.Currency)
+		}
+		for _, format := range storedDateFormats {
+			if date, err := time.Parse(format, transaction.Date); err == nil {
+				row.date, row.isoDate = date, true
+				row.Date = date.Format("2006-01-02")
+				break
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// formatAmount formats an amount as signed decimal with the currency's decimals, e.g. -5.00 EUR as -5.00, 500 JPY as
+// 500 and 1.25 KWD as 1.250. Amounts of currencies without decimals are rounded half away from zero.
+func formatAmount(amount Amount, currency string) string {
+	digits := decimals(currency)
+	scaled, sign := amount.scaled(digits), ""
+	if scaled < 0 {
+		sign, scaled = "-", -scaled
+	}
+	if digits == 0 {
+		return fmt.Sprintf("%s%d", sign, scaled)
+	}
+	unit := pow10(digits)
+	return fmt.Sprintf("%s%d.%0*d", sign, scaled/unit, digits, scaled%unit)
+}
+
+// spreadsheetSafe keeps names from being run as formulas when a csv is opened in a spreadsheet.
+func spreadsheetSafe(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+func exportCSV(rows []ExportRow) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(exportColumns); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		record := []string{row.Id, row.Date, spreadsheetSafe(row.Name), row.Amount, row.Currency, row.Status, row.RefundOf, strconv.FormatBool(row.Anonymous),
+			row.Fee, row.Net}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+func exportJSON(pool MoneyPool, rows []ExportRow) ([]byte, error) {
+	export := PoolExport{
+		Name:         pool.Name,
+		Title:        pool.Title,
+		Open:         pool.Open,
+		Currency:     pool.BaseCurrency,
+		Totals:       map[string]string{},
+		MissingRates: pool.MissingRates,
+		Transactions: rows,
+	}
+	total := exportTotal(pool)
+	export.Total = formatAmount(total, pool.BaseCurrency)
+	for currency, total := range pool.Totals {
+		export.Totals[currency] = formatAmount(total, currency)
+	}
+	if pool.ConvertedTotal != nil {
+		export.ConvertedTotal = formatAmount(*pool.ConvertedTotal, pool.BaseCurrency)
+	}
+	return json.Marshal(export)
+}
+
+func exportXLSX(pool MoneyPool, rows []ExportRow) ([]byte, error) {
+	cells := make([][]xlsx.Cell, 0, len(rows))
+	for _, row := range rows {
+		date := xlsx.String(row.Date)
+		if row.isoDate {
+			date = xlsx.Date(row.date)
+		}
+		cells = append(cells, []xlsx.Cell{
+			xlsx.String(row.Id), date, xlsx.String(row.Name), decimalCell(row.Amount), xlsx.String(row.Currency),
+			xlsx.String(row.Status), xlsx.String(row.RefundOf), xlsx.String(strconv.FormatBool(row.Anonymous)),
+			decimalCell(row.Fee), decimalCell(row.Net),
+		})
+	}
+	var buf bytes.Buffer
+	if err := xlsx.Write(&buf, sheetName(pool.Name), exportColumns, cells); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decimalCell writes a formatted amount as number, or an empty cell for an empty amount.
+func decimalCell(amount string) xlsx.Cell {
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return xlsx.Cell{}
+	}
+	return xlsx.Number(value)
+}
+
+// exportTotal returns the pool's total in its base currency, which pools that hide amounts already carry.
+func exportTotal(pool MoneyPool) Amount {
+	if pool.Total != nil {
+		return *pool.Total
+	}
+	return sumTransactions(pool.Transactions, pool.BaseCurrency, pool.TotalsBasis)
+}
+
+// sheetName shortens a pool name to the 31 characters a sheet name may have.
+func sheetName(name string) string {
+	runes := []rune(name)
+	if len(runes) > 31 {
+		return string(runes[:31])
+	}
+	return name
+}
@@ -0,0 +1,136 @@
+package moneypool
+
+import (
+	"api/errors"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	er "errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+)
+
+func TestExportMoneyPoolCSV(t *testing.T) {
+	client := NewFakeDynamoClient(exportPoolItem())
+	export, err := NewHandler(testTables, client, nil).ExportMoneyPool(exportRequest("csv", ""))
+	if err != nil {
+		t.Fatalf("ExportMoneyPool(csv) returned error %v", err)
+	}
+	expected := "id,date,name,amount,currency,status,refundOf,anonymous,fee,net\n" +
+		"id-Paul,2022-03-01,Paul,10.50,EUR,,,false,0.50,10.00\n" +
+		"id-Anna,2022-03-02,Contributor #2,5.00,EUR,,,true,,\n" +
+		"id-=cmd(),2022-03-03,'=cmd(),1.00,EUR,voided,,false,,\n" +
+		"id-refund,2022-03-04,Contributor #4,-5.00,EUR,refund,id-Anna,true,,\n"
+	if string(export.Body) != expected || export.ContentType != "text/csv; charset=utf-8" || export.Filename != "paul.csv" || export.Binary {
+		t.Fatalf("ExportMoneyPool(csv) = %+v with body\n%s\nbut expected body\n%s", export, export.Body, expected)
+	}
+}
+
+func TestExportMoneyPoolJSON(t *testing.T) {
+	client := NewFakeDynamoClient(exportPoolItem())
+	export, err := NewHandler(testTables, client, nil).ExportMoneyPool(exportRequest("json", adminToken))
+	if err != nil {
+		t.Fatalf("ExportMoneyPool(json) returned error %v", err)
+	}
+	var pool PoolExport
+	if err := json.Unmarshal(export.Body, &pool); err != nil {
+		t.Fatalf("ExportMoneyPool(json) returned invalid json %s: %v", export.Body, err)
+	}
+	expected := PoolExport{
+		Name:           "paul",
+		Title:          "Gift for Paul",
+		Open:           true,
+		Currency:       "EUR",
+		Total:          "10.50",
+		Totals:         map[string]string{"EUR": "10.50"},
+		ConvertedTotal: "10.50",
+		Transactions: []ExportRow{
+			{Id: "id-Paul", Date: "2022-03-01", Name: "Paul", Amount: "10.50", Currency: "EUR", Fee: "0.50", Net: "10.00"},
+			// admins see the names of anonymous contributors
+			{Id: "id-Anna", Date: "2022-03-02", Name: "Anna", Amount: "5.00", Currency: "EUR", Anonymous: true},
+			{Id: "id-=cmd()", Date: "2022-03-03", Name: "=cmd()", Amount: "1.00", Currency: "EUR", Status: "voided"},
+			{Id: "id-refund", Date: "2022-03-04", Name: "Anna", Amount: "-5.00", Currency: "EUR", Status: "refund", RefundOf: "id-Anna", Anonymous: true},
+		},
+	}
+	if !reflect.DeepEqual(pool, expected) {
+		t.Fatalf("ExportMoneyPool(json) = %+v, but expected %+v", pool, expected)
+	}
+}
+
+func TestExportMoneyPoolXLSX(t *testing.T) {
+	client := NewFakeDynamoClient(exportPoolItem())
+	export, err := NewHandler(testTables, client, nil).ExportMoneyPool(exportRequest("xlsx", ""))
+	if err != nil {
+		t.Fatalf("ExportMoneyPool(xlsx) returned error %v", err)
+	}
+	if !export.Binary || export.Filename != "paul.xlsx" {
+		t.Fatalf("ExportMoneyPool(xlsx) = %+v, but expected a binary paul.xlsx", export)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(export.Body), int64(len(export.Body))); err != nil {
+		t.Fatalf("ExportMoneyPool(xlsx) returned no workbook: %v", err)
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	testTable := []struct {
+		amount   Amount
+		currency string
+		expected string
+	}{
+		{Amount{Base: 10, Fraction: 50}, "EUR", "10.50"},
+		{Amount{Base: -5}, "EUR", "-5.00"},
+		{Amount{Fraction: 7}, "USD", "0.07"},
+		{Amount{Base: 500}, "JPY", "500"},
+		{Amount{Base: -500}, "JPY", "-500"},
+		{Amount{Base: 123, Fraction: 50}, "JPY", "124"},
+		{Amount{Base: 1, Fraction: 25}, "KWD", "1.250"},
+		{Amount{Base: 1, Fraction: 255, Decimals: 3}, "KWD", "1.255"},
+		{Amount{Fraction: -50, Decimals: 3}, "KWD", "-0.050"},
+		{Amount{Base: 10, Fraction: 50}, "XYZ", "10.50"},
+	}
+	for _, test := range testTable {
+		if formatted := formatAmount(test.amount, test.currency); formatted != test.expected {
+			t.Errorf("formatAmount(%+v, %s) = %s, but expected %s", test.amount, test.currency, formatted, test.expected)
+		}
+	}
+}
+
+func TestExportMoneyPoolErrors(t *testing.T) {
+	handler := NewHandler(testTables, NewFakeDynamoClient(privatePoolItem()), nil)
+	_, err := handler.ExportMoneyPool(exportRequest("pdf", ""))
+	var expected error = errors.NewInvalidParametersError(er.New("unknown export format pdf"))
+	if !compareErrors(err, expected) {
+		t.Fatalf("ExportMoneyPool(pdf) = %v, but expected %v", err, expected)
+	}
+	_, err = handler.ExportMoneyPool(exportRequest("csv", ""))
+	expected = errors.NewNotFoundError(er.New("no moneypool found for given name paul"))
+	if !compareErrors(err, expected) {
+		t.Fatalf("ExportMoneyPool(private) = %v, but expected %v", err, expected)
+	}
+}
+
+func exportPoolItem() map[string]*dynamodb.AttributeValue {
+	item := testPoolItem("paul", "Gift for Paul", true,
+		withFee(testTransactionItem("Paul", "01.03.22", "10", "50"), "0", "50", "10", "0"),
+		withTransactionAttribute(testTransactionItem("Anna", "02.03.22", "5", "0"), "anonymous", &dynamodb.AttributeValue{BOOL: aws.Bool(true)}),
+		withTransactionAttribute(testTransactionItem("=cmd()", "03.03.22", "1", "0"), "voided", &dynamodb.AttributeValue{BOOL: aws.Bool(true)}),
+		withTransactionAttribute(withTransactionAttribute(withTransactionAttribute(testTransactionItem("Anna", "04.03.22", "5", "0"),
+			"id", &dynamodb.AttributeValue{S: aws.String("id-refund")}),
+			"refundOf", &dynamodb.AttributeValue{S: aws.String("id-Anna")}),
+			"anonymous", &dynamodb.AttributeValue{BOOL: aws.Bool(true)}),
+	)
+	item["adminTokenHash"] = &dynamodb.AttributeValue{S: aws.String(HashToken(adminToken))}
+	return item
+}
+
+func exportRequest(format, token string) events.APIGatewayProxyRequest {
+	request := poolRequest("paul")
+	request = withQuery(request, "format", format)
+	if token != "" {
+		request = withHeader(request, PoolTokenHeader, token)
+	}
+	return request
+}
@@ -0,0 +1,237 @@
+package moneypool
+
+import (
+	er "errors"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	tenantsTableName = "TenantsTable"
+	auditTableName   = "AuditTable"
+	deliveriesTable  = "WebhookDeliveriesTable"
+)
+
+var testTables = Tables{MoneyPools: tableName, Tenants: tenantsTableName, Audit: auditTableName, WebhookDeliveries: deliveriesTable}
+
+// FakeDynamoClient serves requests from in-memory sets of moneypool items keyed by name and tenant items keyed by tenant,
+// and an audit log and webhook delivery log in the order they were written. It understands the small subset of update and condition expressions the
+// handler uses.
+type FakeDynamoClient struct {
+	dynamodbiface.DynamoDBAPI
+	items      map[string]map[string]*dynamodb.AttributeValue
+	tenants    map[string]map[string]*dynamodb.AttributeValue
+	audit      []map[string]*dynamodb.AttributeValue
+	deliveries []map[string]*dynamodb.AttributeValue
+	getItemErr error
+	scanErr    error
+	updates    int
+}
+
+func NewFakeDynamoClient(items ...map[string]*dynamodb.AttributeValue) *FakeDynamoClient {
+	client := &FakeDynamoClient{
+		items:   map[string]map[string]*dynamodb.AttributeValue{},
+		tenants: map[string]map[string]*dynamodb.AttributeValue{},
+	}
+	for _, item := range items {
+		client.items[*item["key"].S] = item
+		delete(item, "key")
+	}
+	return client
+}
+
+// table returns the items of the table and the name of their key attribute.
+func (c *FakeDynamoClient) table(name string) (map[string]map[string]*dynamodb.AttributeValue, string, error) {
+	switch name {
+	case tableName:
+		return c.items, "name", nil
+	case tenantsTableName:
+		return c.tenants, "tenant", nil
+	}
+	return nil, "", er.New("unknown table " + name)
+}
+
+func (c *FakeDynamoClient) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	if c.getItemErr != nil {
+		return nil, c.getItemErr
+	}
+	items, keyName, err := c.table(*input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	item, exists := items[*input.Key[keyName].S]
+	if !exists {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	// copy the item, so updates don't change what the handler has read
+	read := make(map[string]*dynamodb.AttributeValue, len(item))
+	for attribute, value := range item {
+		read[attribute] = value
+	}
+	return &dynamodb.GetItemOutput{Item: read}, nil
+}
+
+func (c *FakeDynamoClient) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	if *input.TableName != tableName {
+		return nil, er.New("unknown table " + *input.TableName)
+	}
+	key := *input.Key["name"].S
+	item := c.items[key]
+	if input.ConditionExpression != nil && !evaluateCondition(*input.ConditionExpression, item, input.ExpressionAttributeNames, input.ExpressionAttributeValues) {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "condition failed", nil)
+	}
+	if item == nil {
+		item = map[string]*dynamodb.AttributeValue{"name": input.Key["name"]}
+		c.items[key] = item
+	}
+	applyUpdate(*input.UpdateExpression, item, input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	c.updates++
+	return &dynamodb.UpdateItemOutput{Attributes: item}, nil
+}
+
+func (c *FakeDynamoClient) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	if *input.TableName == auditTableName {
+		for _, entry := range c.audit {
+			if *entry["pool"].S == *input.Item["pool"].S && *entry["entryId"].S == *input.Item["entryId"].S {
+				return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "condition failed", nil)
+			}
+		}
+		c.audit = append(c.audit, input.Item)
+		return &dynamodb.PutItemOutput{}, nil
+	}
+	items, keyName, err := c.table(*input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	key := *input.Item[keyName].S
+	if input.ConditionExpression != nil && !evaluateCondition(*input.ConditionExpression, items[key], input.ExpressionAttributeNames, input.ExpressionAttributeValues) {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "condition failed", nil)
+	}
+	items[key] = input.Item
+	c.updates++
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// Query supports a single equality key condition, on the table or one of its indexes. Logs are returned in the order
+// they were written, or in reverse order if ScanIndexForward is false.
+func (c *FakeDynamoClient) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	var items []map[string]*dynamodb.AttributeValue
+	if *input.TableName == auditTableName {
+		items = c.audit
+	} else if *input.TableName == deliveriesTable {
+		items = c.deliveries
+	} else {
+		table, _, err := c.table(*input.TableName)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range table {
+			items = append(items, item)
+		}
+	}
+	if input.ScanIndexForward != nil && !*input.ScanIndexForward {
+		reversed := make([]map[string]*dynamodb.AttributeValue, len(items))
+		for i, item := range items {
+			reversed[len(items)-1-i] = item
+		}
+		items = reversed
+	}
+	out := &dynamodb.QueryOutput{}
+	for _, item := range items {
+		if input.Limit != nil && int64(len(out.Items)) >= *input.Limit {
+			break
+		}
+		if evaluateCondition(*input.KeyConditionExpression, item, input.ExpressionAttributeNames, input.ExpressionAttributeValues) {
+			out.Items = append(out.Items, item)
+		}
+	}
+	return out, nil
+}
+
+// ScanPages returns every item on its own page, so callers have to handle pagination.
+func (c *FakeDynamoClient) ScanPages(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	if c.scanErr != nil {
+		return c.scanErr
+	}
+	if *input.TableName != tableName {
+		return er.New("unknown table " + *input.TableName)
+	}
+	keys := make([]string, 0, len(c.items))
+	for key := range c.items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		page := &dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{c.items[key]}}
+		if !fn(page, i == len(keys)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+var clausePattern = regexp.MustCompile(`(SET|REMOVE) `)
+
+func applyUpdate(expression string, item map[string]*dynamodb.AttributeValue, names map[string]*string, values map[string]*dynamodb.AttributeValue) {
+	indices := clausePattern.FindAllStringSubmatchIndex(expression, -1)
+	for i, index := range indices {
+		end := len(expression)
+		if i+1 < len(indices) {
+			end = indices[i+1][0]
+		}
+		action := expression[index[2]:index[3]]
+		for _, part := range strings.Split(expression[index[1]:end], ",") {
+			part = strings.TrimSpace(part)
+			if action == "REMOVE" {
+				delete(item, attributeName(part, names))
+				continue
+			}
+			assignment := strings.SplitN(part, " = ", 2)
+			item[attributeName(assignment[0], names)] = values[strings.TrimSpace(assignment[1])]
+		}
+	}
+}
+
+// evaluateCondition supports conditions joined by either AND or OR, but not both.
+func evaluateCondition(expression string, item map[string]*dynamodb.AttributeValue, names map[string]*string, values map[string]*dynamodb.AttributeValue) bool {
+	if strings.Contains(expression, " OR ") {
+		for _, condition := range strings.Split(expression, " OR ") {
+			if evaluateCondition(condition, item, names, values) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, condition := range strings.Split(expression, " AND ") {
+		condition = strings.TrimSpace(condition)
+		switch {
+		case strings.HasPrefix(condition, "attribute_exists("):
+			if _, exists := item[attributeName(strings.TrimSuffix(strings.TrimPrefix(condition, "attribute_exists("), ")"), names)]; !exists {
+				return false
+			}
+		case strings.HasPrefix(condition, "attribute_not_exists("):
+			if _, exists := item[attributeName(strings.TrimSuffix(strings.TrimPrefix(condition, "attribute_not_exists("), ")"), names)]; exists {
+				return false
+			}
+		default:
+			comparison := strings.SplitN(condition, " = ", 2)
+			if !reflect.DeepEqual(item[attributeName(comparison[0], names)], values[strings.TrimSpace(comparison[1])]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func attributeName(placeholder string, names map[string]*string) string {
+	placeholder = strings.TrimSpace(placeholder)
+	if name, exists := names[placeholder]; exists {
+		return *name
+	}
+	return placeholder
+}
@@ -0,0 +1,70 @@
+package moneypool
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"strconv"
+)
+
+// Totals bases decide which amounts a moneypool's totals add up.
+const (
+	TotalsGross = "gross" // the amounts sent, before PayPal's fees
+	TotalsNet   = "net"   // the amounts received, after PayPal's fees
+)
+
+func validTotalsBasis(basis string) bool {
+	return basis == TotalsGross || basis == TotalsNet
+}
+
+// decodeFee returns the transaction's fee and net amount, or nil for both if they are not stored. They have to add up
+// to the transaction's amount.
+func decodeFee(ti transactionItem) (fee, net *Amount, err error) {
+	if ti.Fee == nil && ti.Net == nil {
+		return nil, nil, nil
+	}
+	if ti.Fee == nil || ti.Net == nil {
+		return nil, nil, fmt.Errorf("transaction has only one of fee and net")
+	}
+	for _, amount := range []*amountItem{ti.Fee, ti.Net} {
+		if amount.Base < 0 || amount.Decimals != ti.Decimals || validateFraction(amount.Fraction, amount.Decimals, ti.Currency) != nil {
+			return nil, nil, fmt.Errorf("transaction has invalid fee or net amount %d.%02d", amount.Base, amount.Fraction)
+		}
+	}
+	fee = &Amount{Base: ti.Fee.Base, Fraction: ti.Fee.Fraction, Decimals: ti.Fee.Decimals}
+	net = &Amount{Base: ti.Net.Base, Fraction: ti.Net.Fraction, Decimals: ti.Net.Decimals}
+	gross := Amount{Base: *ti.Base, Fraction: *ti.Fraction, Decimals: ti.Decimals}
+	if digits := gross.digits(); fee.scaled(digits)+net.scaled(digits) != gross.scaled(digits) {
+		return nil, nil, fmt.Errorf("transaction's fee and net don't add up to its amount")
+	}
+	return fee, net, nil
+}
+
+// correctNet recomputes the net amount of a transaction item whose amount was corrected from the new amount and the
+// stored fee, which PayPal charged and the correction doesn't change. If the fee exceeds the new amount, fee and net are
+// removed. Items that can't be decoded are left unchanged for the validation of the correction to reject them.
+func correctNet(item map[string]*dynamodb.AttributeValue) {
+	var ti transactionItem
+	if err := dynamodbattribute.UnmarshalMap(item, &ti); err != nil || ti.Fee == nil || ti.Net == nil || ti.Base == nil || ti.Fraction == nil {
+		return
+	}
+	gross := Amount{Base: *ti.Base, Fraction: *ti.Fraction, Decimals: ti.Decimals}
+	fee := Amount{Base: ti.Fee.Base, Fraction: ti.Fee.Fraction, Decimals: ti.Fee.Decimals}
+	digits := gross.digits()
+	scaledNet := gross.scaled(digits) - fee.scaled(digits)
+	if scaledNet < 0 {
+		delete(item, "fee")
+		delete(item, "net")
+		return
+	}
+	net := scaledAmount(scaledNet, digits)
+	// keep the other attributes of the stored net amount, e.g. its decimals
+	corrected := make(map[string]*dynamodb.AttributeValue, len(item["net"].M))
+	for attribute, value := range item["net"].M {
+		corrected[attribute] = value
+	}
+	corrected["base"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(net.Base))}
+	corrected["fraction"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(net.Fraction))}
+	item["net"] = &dynamodb.AttributeValue{M: corrected}
+}
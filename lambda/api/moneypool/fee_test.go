@@ -0,0 +1,102 @@
+package moneypool
+
+import (
+	er "errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+)
+
+type totalsBasisTest struct {
+	name           string
+	basis          string
+	expectedTotals map[string]Amount
+	expectedTotal  *Amount
+	expectedError  error
+}
+
+func TestGetMoneyPoolTotalsBasis(t *testing.T) {
+	testTable := []totalsBasisTest{
+		{"default", "", map[string]Amount{"EUR": {Base: 17, Fraction: 34}}, &Amount{Base: 17, Fraction: 34}, nil},
+		{"gross", TotalsGross, map[string]Amount{"EUR": {Base: 17, Fraction: 34}}, &Amount{Base: 17, Fraction: 34}, nil},
+		{"net", TotalsNet, map[string]Amount{"EUR": {Base: 16, Fraction: 69}}, &Amount{Base: 16, Fraction: 69}, nil},
+		{"unknown", "fees", nil, nil, er.New("moneypool item has unknown totals basis fees")},
+	}
+	for _, test := range testTable {
+		item := testPoolItem("paul", "Gift for Paul", true,
+			withFee(testTransactionItem("Sender Person", "01.02.22", "12", "34"), "0", "65", "11", "69"),
+			testTransactionItem("Cher", "02.02.22", "5", "0"),
+		)
+		if test.basis != "" {
+			item["totalsBasis"] = &dynamodb.AttributeValue{S: aws.String(test.basis)}
+		}
+		pool, err := NewHandler(testTables, NewFakeDynamoClient(item), nil).GetMoneyPool(poolRequest("paul"))
+		if !compareErrors(err, test.expectedError) {
+			t.Fatalf("GetMoneyPool(%s) returned error %v, but expected %v", test.name, err, test.expectedError)
+		}
+		if test.expectedError != nil {
+			continue
+		}
+		if !reflect.DeepEqual(pool.Totals, test.expectedTotals) || !reflect.DeepEqual(pool.ConvertedTotal, test.expectedTotal) {
+			t.Fatalf("GetMoneyPool(%s) returned totals %v, converted %v, but expected %v, %v",
+				test.name, pool.Totals, pool.ConvertedTotal, test.expectedTotals, test.expectedTotal)
+		}
+		fee, net := pool.Transactions[0].Fee, pool.Transactions[0].Net
+		if fee == nil || *fee != (Amount{Base: 0, Fraction: 65}) || net == nil || *net != (Amount{Base: 11, Fraction: 69}) {
+			t.Fatalf("GetMoneyPool(%s) returned fee %v and net %v, but expected 0.65 and 11.69", test.name, fee, net)
+		}
+	}
+}
+
+func TestGetMoneyPoolInvalidFee(t *testing.T) {
+	item := testPoolItem("paul", "Gift for Paul", true,
+		withFee(testTransactionItem("Sender Person", "01.02.22", "12", "34"), "1", "0", "11", "0"),
+		withoutTransactionAttribute(withFee(testTransactionItem("Cher", "02.02.22", "5", "0"), "0", "10", "4", "90"), "net"),
+	)
+	pool, err := NewHandler(testTables, NewFakeDynamoClient(item), nil).GetMoneyPool(poolRequest("paul"))
+	expected := []InvalidTransaction{
+		{Index: 0, Id: "id-Sender Person", Error: "transaction's fee and net don't add up to its amount"},
+		{Index: 1, Id: "id-Cher", Error: "transaction has only one of fee and net"},
+	}
+	if err != nil || !reflect.DeepEqual(pool.InvalidTransactions, expected) {
+		t.Fatalf("GetMoneyPool(invalid_fee) returned %+v, %v but expected invalid transactions %+v", pool.InvalidTransactions, err, expected)
+	}
+}
+
+func TestHideAmountsHidesFees(t *testing.T) {
+	item := testPoolItem("paul", "Gift for Paul", true,
+		withFee(testTransactionItem("Sender Person", "01.02.22", "12", "34"), "0", "65", "11", "69"),
+	)
+	item["privacy"] = &dynamodb.AttributeValue{S: aws.String(PrivacyHideAmounts)}
+	item["totalsBasis"] = &dynamodb.AttributeValue{S: aws.String(TotalsNet)}
+	pool, err := NewHandler(testTables, NewFakeDynamoClient(item), nil).GetMoneyPool(poolRequest("paul"))
+	if err != nil || pool.Transactions[0].Fee != nil || pool.Transactions[0].Net != nil || *pool.Total != (Amount{Base: 11, Fraction: 69}) {
+		t.Fatalf("GetMoneyPool(hide_amounts) returned %+v, %v but expected hidden fees and a net total of 11.69", pool, err)
+	}
+}
+
+func TestTotalsBasisSettings(t *testing.T) {
+	handler := NewHandler(testTables, NewFakeDynamoClient(), fakeVerifier)
+	pool, err := handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "paul", "title": "Gift for Paul", "totalsBasis": "net"}`))
+	if err != nil || pool.TotalsBasis != TotalsNet {
+		t.Fatalf("CreateMoneyPool(totals_basis) returned %+v, %v but expected totals basis net", pool, err)
+	}
+	pool, err = handler.UpdateMoneyPool(ownerUpdate(`{"totalsBasis": "gross"}`))
+	if err != nil || pool.TotalsBasis != TotalsGross {
+		t.Fatalf("UpdateMoneyPool(totals_basis) returned %+v, %v but expected totals basis gross", pool, err)
+	}
+	if _, err := handler.UpdateMoneyPool(ownerUpdate(`{"totalsBasis": "fees"}`)); !compareErrors(err, er.New("unknown totals basis fees")) {
+		t.Fatalf("UpdateMoneyPool(unknown_totals_basis) returned error %v", err)
+	}
+}
+
+func withFee(transaction *dynamodb.AttributeValue, feeBase, feeFraction, netBase, netFraction string) *dynamodb.AttributeValue {
+	transaction.M["fee"] = &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{
+		"base": {N: aws.String(feeBase)}, "fraction": {N: aws.String(feeFraction)},
+	}}
+	transaction.M["net"] = &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{
+		"base": {N: aws.String(netBase)}, "fraction": {N: aws.String(netFraction)},
+	}}
+	return transaction
+}
@@ -0,0 +1,118 @@
+package moneypool
+
+import (
+	"api/errors"
+	"api/headers"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	log "github.com/sirupsen/logrus"
+	"mime"
+	"strings"
+)
+
+// Import formats, as given in the format query parameter or by the content type.
+const (
+	ImportCSV  = "csv"
+	ImportJSON = "json"
+)
+
+// ImportRequest is an import of contributions, as read by the transaction lambda.
+type ImportRequest struct {
+	MoneyPool string `json:"moneyPool"`
+	Format    string `json:"format"`
+	Body      string `json:"body"`
+	Source    string `json:"source,omitempty"`
+	Apply     bool   `json:"apply"`
+	Actor     string `json:"actor"`
+	RequestId string `json:"requestId"`
+}
+
+// ImportResponse carries either the import's result, which is passed on as is, or the reason it was rejected.
+type ImportResponse struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// ContributionImporter runs imports. Contributions are added by the transaction lambda, so they are written the same way
+// as contributions received by mail.
+type ContributionImporter interface {
+	Import(request ImportRequest) (ImportResponse, error)
+}
+
+// WithImporter enables imports of contributions. Without importer, imports are rejected.
+func (h *MoneyPoolsHandler) WithImporter(importer ContributionImporter) *MoneyPoolsHandler {
+	h.importer = importer
+	return h
+}
+
+// ImportContributions imports the contributions in the request's body, given as csv or json. Imports are dry runs that
+// only report what they would add, unless the apply query parameter is true.
+func (h *MoneyPoolsHandler) ImportContributions(request events.APIGatewayProxyRequest) (json.RawMessage, error) {
+	mpName, mpParamExists := request.PathParameters["moneyPool"]
+	if !mpParamExists {
+		return nil, errors.NewInvalidParametersError(fmt.Errorf("no moneyppol name given"))
+	}
+	format, err := importFormat(request)
+	if err != nil {
+		return nil, err
+	}
+	apply := request.QueryStringParameters["apply"] == "true"
+	h.logger = log.WithFields(log.Fields{"requestedMP": mpName, "format": format, "apply": apply})
+
+	item, err := h.getPoolItem(mpName)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := h.authorize(request, item, mpName, AccessAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if h.importer == nil {
+		return nil, errors.NewStoreUnavailableError(fmt.Errorf("import is not configured"))
+	}
+	body := request.Body
+	if request.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, errors.NewInvalidParametersError(fmt.Errorf("invalid body: %v", err))
+		}
+		body = string(decoded)
+	}
+
+	response, err := h.importer.Import(ImportRequest{
+		MoneyPool: mpName,
+		Format:    format,
+		Body:      body,
+		Source:    request.QueryStringParameters["source"],
+		Apply:     apply,
+		Actor:     actor(claims),
+		RequestId: request.RequestContext.RequestID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error importing contributions: %v", err)
+	}
+	if response.Error != "" {
+		return nil, errors.NewInvalidParametersError(fmt.Errorf("%s", response.Error))
+	}
+	h.logger.Info("imported contributions")
+	return response.Result, nil
+}
+
+// importFormat returns the format query parameter or else the format of the body's content type, which is json unless
+// it is csv.
+func importFormat(request events.APIGatewayProxyRequest) (string, error) {
+	format := strings.ToLower(request.QueryStringParameters["format"])
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(headers.Get(request, "Content-Type"))
+		format = ImportJSON
+		if mediaType == "text/csv" {
+			format = ImportCSV
+		}
+	}
+	if format != ImportCSV && format != ImportJSON {
+		return "", errors.NewInvalidParametersError(fmt.Errorf("unknown import format %s", format))
+	}
+	return format, nil
+}
@@ -0,0 +1,95 @@
+package moneypool
+
+import (
+	"api/errors"
+	"encoding/json"
+	er "errors"
+	"github.com/aws/aws-lambda-go/events"
+	"testing"
+)
+
+func TestImportContributions(t *testing.T) {
+	importer := &FakeImporter{response: ImportResponse{Result: json.RawMessage(`{"dryRun":false,"added":1}`)}}
+	handler := NewHandler(testTables, NewFakeDynamoClient(correctablePoolItem()), fakeVerifier).WithImporter(importer)
+	request := withHeader(importRequest("", "name,amount,date\nAnna,5,2022-03-01\n"), "Authorization", "Bearer "+ownerJwt)
+	request = withHeader(withQuery(withQuery(request, "apply", "true"), "source", "manual"), "Content-Type", "text/csv; charset=utf-8")
+
+	result, err := handler.ImportContributions(withRequestId(request, "request-1"))
+	if err != nil {
+		t.Fatalf("ImportContributions returned error %v", err)
+	}
+	if string(result) != `{"dryRun":false,"added":1}` {
+		t.Fatalf("ImportContributions = %s, but expected the importer's result", result)
+	}
+	expected := ImportRequest{
+		MoneyPool: "paul",
+		Format:    ImportCSV,
+		Body:      "name,amount,date\nAnna,5,2022-03-01\n",
+		Source:    "manual",
+		Apply:     true,
+		Actor:     "owner:owner-sub",
+		RequestId: "request-1",
+	}
+	if len(importer.requests) != 1 || importer.requests[0] != expected {
+		t.Fatalf("ImportContributions sent %+v, but expected %+v", importer.requests, expected)
+	}
+}
+
+func TestImportContributionsDryRun(t *testing.T) {
+	importer := &FakeImporter{response: ImportResponse{Result: json.RawMessage(`{"dryRun":true}`)}}
+	handler := NewHandler(testTables, NewFakeDynamoClient(correctablePoolItem()), nil).WithImporter(importer)
+	if _, err := handler.ImportContributions(importRequest(adminToken, `[]`)); err != nil {
+		t.Fatalf("ImportContributions returned error %v", err)
+	}
+	if request := importer.requests[0]; request.Apply || request.Format != ImportJSON || request.Actor != "adminToken" {
+		t.Fatalf("ImportContributions sent %+v, but expected a json dry run", request)
+	}
+}
+
+func TestImportContributionsErrors(t *testing.T) {
+	rejecting := &FakeImporter{response: ImportResponse{Error: "no rows to import"}}
+	handler := NewHandler(testTables, NewFakeDynamoClient(correctablePoolItem()), nil).WithImporter(rejecting)
+
+	_, err := handler.ImportContributions(importRequest(readToken, `[]`))
+	var expected error = errors.NewForbiddenError(er.New("token does not allow to administrate moneypool paul"))
+	if !compareErrors(err, expected) {
+		t.Fatalf("ImportContributions(read_token) = %v, but expected %v", err, expected)
+	}
+	_, err = handler.ImportContributions(withQuery(importRequest(adminToken, `[]`), "format", "xml"))
+	expected = errors.NewInvalidParametersError(er.New("unknown import format xml"))
+	if !compareErrors(err, expected) {
+		t.Fatalf("ImportContributions(xml) = %v, but expected %v", err, expected)
+	}
+	_, err = handler.ImportContributions(importRequest(adminToken, `[]`))
+	expected = errors.NewInvalidParametersError(er.New("no rows to import"))
+	if !compareErrors(err, expected) {
+		t.Fatalf("ImportContributions(rejected) = %v, but expected %v", err, expected)
+	}
+
+	unconfigured := NewHandler(testTables, NewFakeDynamoClient(correctablePoolItem()), nil)
+	_, err = unconfigured.ImportContributions(importRequest(adminToken, `[]`))
+	expected = errors.NewStoreUnavailableError(er.New("import is not configured"))
+	if !compareErrors(err, expected) {
+		t.Fatalf("ImportContributions(unconfigured) = %v, but expected %v", err, expected)
+	}
+	if len(rejecting.requests) != 1 {
+		t.Fatalf("ImportContributions sent %d requests, but expected only the rejected one", len(rejecting.requests))
+	}
+}
+
+// FakeImporter records import requests and answers them with the given response.
+type FakeImporter struct {
+	response ImportResponse
+	requests []ImportRequest
+}
+
+func (i *FakeImporter) Import(request ImportRequest) (ImportResponse, error) {
+	i.requests = append(i.requests, request)
+	return i.response, nil
+}
+
+func importRequest(token, body string) events.APIGatewayProxyRequest {
+	request := updateRequest(token, body)
+	request.HTTPMethod = "POST"
+	return request
+}
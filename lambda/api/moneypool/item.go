@@ -0,0 +1,226 @@
+package moneypool
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// responseVersion is part of every ETag. Bump it whenever the response for an unchanged item changes,
+// e.g. because fields are added or decoding rules change, so clients don't keep outdated responses.
+const responseVersion = "4"
+
+// poolItem is the schema of a moneypool item in the moneypools table.
+// Pools are usually created by hand in the DynamoDB console, so everything except the name is optional.
+type poolItem struct {
+	Name    string `dynamodbav:"name"`
+	Title   string `dynamodbav:"title"`
+	Open    *bool  `dynamodbav:"open"`
+	Privacy string `dynamodbav:"privacy"`
+	// BaseCurrency is the currency totals are converted to, EUR if it is not set.
+	BaseCurrency string `dynamodbav:"baseCurrency"`
+	// TotalsBasis is "gross" or "net", gross if it is not set.
+	TotalsBasis string `dynamodbav:"totalsBasis"`
+	Metadata
+	Expected *Expected `dynamodbav:"expected"`
+}
+
+// transactionItem is the schema of a single entry in a moneypool's transactions list,
+// as written by the transaction lambda.
+type transactionItem struct {
+	Id       string `dynamodbav:"id"`
+	Name     string `dynamodbav:"name"`
+	Date     string `dynamodbav:"date"`
+	Base     *int   `dynamodbav:"base"`
+	Fraction *int   `dynamodbav:"fraction"`
+	// Decimals is set to the digits of the fraction for currencies with three decimals. Other amounts are in cents.
+	Decimals int `dynamodbav:"decimals"`
+	// Currency is the ISO code of the amount's currency. Transactions stored before currencies were are in EUR.
+	Currency string `dynamodbav:"currency"`
+	// Fee and Net are stored together, if the payment's mail showed PayPal's fee.
+	Fee *amountItem `dynamodbav:"fee"`
+	Net *amountItem `dynamodbav:"net"`
+	// Anonymous is set if the sender asked to not be named publicly.
+	Anonymous bool   `dynamodbav:"anonymous"`
+	Voided    bool   `dynamodbav:"voided"`
+	RefundOf  string `dynamodbav:"refundOf"`
+	// Participant is the id of the expected participant the transaction lambda linked the payment to.
+	Participant string `dynamodbav:"participant"`
+}
+
+// amountItem is the schema of the amounts stored besides a transaction's own.
+type amountItem struct {
+	Base     int `dynamodbav:"base"`
+	Fraction int `dynamodbav:"fraction"`
+	Decimals int `dynamodbav:"decimals"`
+}
+
+// decodePool reads a moneypool item and all of its transactions, as shown to the pool's readers.
+// An invalid pool results in an error, while invalid transactions are skipped and reported in the pool's InvalidTransactions.
+func decodePool(item map[string]*dynamodb.AttributeValue) (MoneyPool, error) {
+	pool, privacy, err := decodeUnredactedPool(item)
+	if err != nil {
+		return MoneyPool{}, err
+	}
+	return pool, applyPrivacy(&pool, privacy)
+}
+
+// decodeUnredactedPool reads a moneypool item like decodePool, but returns the pool's privacy mode instead of applying it.
+func decodeUnredactedPool(item map[string]*dynamodb.AttributeValue) (MoneyPool, string, error) {
+	var pi poolItem
+	if err := dynamodbattribute.UnmarshalMap(item, &pi); err != nil {
+		return MoneyPool{}, "", fmt.Errorf("could not decode moneypool item: %v", err)
+	}
+	if pi.Name == "" {
+		return MoneyPool{}, "", fmt.Errorf("moneypool item has no name field")
+	}
+	pool := MoneyPool{
+		Name:         pi.Name,
+		Title:        pi.Title,
+		Open:         true,
+		BaseCurrency: pi.BaseCurrency,
+		TotalsBasis:  pi.TotalsBasis,
+		Metadata:     pi.Metadata,
+		PayURL:       pi.Metadata.PayURL(),
+	}
+	if pool.Title == "" {
+		pool.Title = pi.Name
+	}
+	if pi.Open != nil {
+		pool.Open = *pi.Open
+	}
+	if pool.BaseCurrency == "" {
+		pool.BaseCurrency = defaultCurrency
+	}
+	if !validCurrency(pool.BaseCurrency) {
+		return MoneyPool{}, "", fmt.Errorf("moneypool item has invalid base currency %s", pool.BaseCurrency)
+	}
+	if pool.TotalsBasis == "" {
+		pool.TotalsBasis = TotalsGross
+	}
+	if !validTotalsBasis(pool.TotalsBasis) {
+		return MoneyPool{}, "", fmt.Errorf("moneypool item has unknown totals basis %s", pool.TotalsBasis)
+	}
+	privacy := pi.Privacy
+	if privacy == "" {
+		privacy = PrivacyFull
+	}
+	if !validPrivacy(privacy) {
+		return MoneyPool{}, "", fmt.Errorf("moneypool item has unknown privacy mode %s", privacy)
+	}
+
+	pool.Totals = map[string]Amount{}
+	var transactionItems []*dynamodb.AttributeValue
+	if transactions, exists := item["transactions"]; exists && transactions.NULL == nil {
+		if transactions.L == nil {
+			return MoneyPool{}, "", fmt.Errorf("moneypool item has invalid transactions field, expected a list")
+		}
+		transactionItems = transactions.L
+	}
+	for i, trItem := range transactionItems {
+		transaction, err := decodeTransaction(trItem)
+		if err != nil {
+			pool.InvalidTransactions = append(pool.InvalidTransactions, InvalidTransaction{
+				Index: i,
+				Id:    transactionId(trItem),
+				Error: err.Error(),
+			})
+			continue
+		}
+		pool.Transactions = append(pool.Transactions, transaction)
+	}
+	pool.Totals = totalsByCurrency(pool.Transactions, pool.TotalsBasis)
+	if pi.Expected != nil && pi.Expected.Amount != nil {
+		pool.Expected = &ExpectedAmount{Mode: pi.Expected.Mode, Amount: *pi.Expected.Amount}
+		pool.Contributors = contributorStatuses(*pi.Expected, pool.Transactions, pool.BaseCurrency)
+	}
+	return pool, privacy, nil
+}
+
+func decodeTransaction(item *dynamodb.AttributeValue) (Transaction, error) {
+	if item == nil || item.M == nil {
+		return Transaction{}, fmt.Errorf("transaction is not a map")
+	}
+	var ti transactionItem
+	if err := dynamodbattribute.UnmarshalMap(item.M, &ti); err != nil {
+		return Transaction{}, fmt.Errorf("could not decode transaction: %v", err)
+	}
+	if ti.Name == "" {
+		return Transaction{}, fmt.Errorf("transaction has no name field")
+	}
+	if ti.Base == nil {
+		return Transaction{}, fmt.Errorf("transaction has no base field")
+	}
+	if ti.Fraction == nil {
+		return Transaction{}, fmt.Errorf("transaction has no fraction field")
+	}
+	if *ti.Base < 0 {
+		return Transaction{}, fmt.Errorf("transaction has negative base %d", *ti.Base)
+	}
+	if ti.Currency == "" {
+		ti.Currency = defaultCurrency
+	}
+	if !validCurrency(ti.Currency) {
+		return Transaction{}, fmt.Errorf("transaction has invalid currency %s", ti.Currency)
+	}
+	if err := validateFraction(*ti.Fraction, ti.Decimals, ti.Currency); err != nil {
+		return Transaction{}, fmt.Errorf("transaction has %v", err)
+	}
+	fee, net, err := decodeFee(ti)
+	if err != nil {
+		return Transaction{}, err
+	}
+	return Transaction{
+		Id:          ti.Id,
+		Name:        ti.Name,
+		Date:        ti.Date,
+		Base:        *ti.Base,
+		Fraction:    *ti.Fraction,
+		Decimals:    ti.Decimals,
+		Currency:    ti.Currency,
+		Fee:         fee,
+		Net:         net,
+		Anonymous:   ti.Anonymous,
+		Voided:      ti.Voided,
+		RefundOf:    ti.RefundOf,
+		Participant: ti.Participant,
+	}, nil
+}
+
+// validateFraction checks that a stored fraction of the currency has the digits given by its decimals: three decimals
+// are only stored for currencies that have them, and fractions without decimals are in cents.
+func validateFraction(fraction, digits int, currency string) error {
+	if digits != 0 && (digits <= 2 || digits != decimals(currency)) {
+		return fmt.Errorf("%d decimals, but %s has %d", digits, currency, decimals(currency))
+	}
+	max := pow10(Amount{Decimals: digits}.digits()) - 1
+	if fraction < 0 || fraction > max {
+		return fmt.Errorf("fraction %d out of range 0-%d", fraction, max)
+	}
+	return nil
+}
+
+// transactionId returns the id of a possibly corrupt transaction item, if it has one.
+func transactionId(item *dynamodb.AttributeValue) string {
+	if item == nil || item.M == nil {
+		return ""
+	}
+	if id, exists := item.M["id"]; exists && id.S != nil {
+		return *id.S
+	}
+	return ""
+}
+
+// itemETag computes a strong entity tag from the raw moneypool item and the version of the exchange rates its totals
+// are converted with. encoding/json sorts map keys, so equal items always result in the same tag.
+func itemETag(item map[string]*dynamodb.AttributeValue, ratesVersion string) (string, error) {
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return "", fmt.Errorf("could not compute etag of moneypool item: %v", err)
+	}
+	hash := sha256.Sum256(append([]byte(responseVersion+":"+ratesVersion+":"), raw...))
+	return `"` + hex.EncodeToString(hash[:16]) + `"`, nil
+}
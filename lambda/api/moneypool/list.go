@@ -0,0 +1,191 @@
+package moneypool
+
+import (
+	"api/errors"
+	"encoding/base64"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// MoneyPoolsOwnerIndex is the index of the moneypools table by owner.
+const MoneyPoolsOwnerIndex = "owner-index"
+
+// States a pool listing can be filtered by.
+const (
+	StateOpen   = "open"
+	StateClosed = "closed"
+	StateAll    = "all"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 100
+)
+
+// PoolSummary is a pool as listed to its owner. Total is the sum of all totals in the base currency, leaving out the
+// currencies listed in MissingRates. Count is the number of contributions that count towards the total.
+type PoolSummary struct {
+	Name         string   `json:"name"`
+	Tenant       string   `json:"tenant,omitempty"`
+	Title        string   `json:"title"`
+	Open         bool     `json:"open"`
+	BaseCurrency string   `json:"baseCurrency"`
+	Total        Amount   `json:"total"`
+	MissingRates []string `json:"missingRates,omitempty"`
+	Count        int      `json:"count"`
+	// LastActivity is the date of the latest transaction, or the date the pool was created, e.g. 2022-03-01.
+	LastActivity string `json:"lastActivity,omitempty"`
+}
+
+// PoolList is a page of pools, sorted by name. Next is the cursor of the following page, empty on the last page.
+type PoolList struct {
+	Pools []PoolSummary `json:"pools"`
+	Next  string        `json:"next,omitempty"`
+}
+
+// ListMoneyPools returns the pools owned by the caller, optionally filtered by state. It requires a valid bearer token.
+// Pools whose items can't be decoded are skipped.
+func (h *MoneyPoolsHandler) ListMoneyPools(request events.APIGatewayProxyRequest) (PoolList, error) {
+	claims, err := h.authenticate(request)
+	if err != nil {
+		return PoolList{}, err
+	}
+	if claims.Subject == "" {
+		return PoolList{}, errors.NewUnauthorizedError(fmt.Errorf("listing moneypools requires a bearer token"))
+	}
+	h.logger = log.WithFields(log.Fields{"owner": claims.Subject})
+
+	params := request.QueryStringParameters
+	if owner := params["owner"]; owner != "" && owner != "me" && owner != claims.Subject {
+		return PoolList{}, errors.NewForbiddenError(fmt.Errorf("owners can only list their own moneypools"))
+	}
+	state := params["state"]
+	if state == "" {
+		state = StateAll
+	}
+	if state != StateOpen && state != StateClosed && state != StateAll {
+		return PoolList{}, errors.NewInvalidParametersError(fmt.Errorf("unknown state %s, expected open, closed or all", state))
+	}
+	limit := defaultListLimit
+	if params["limit"] != "" {
+		if limit, err = strconv.Atoi(params["limit"]); err != nil || limit < 1 || limit > maxListLimit {
+			return PoolList{}, errors.NewInvalidParametersError(fmt.Errorf("limit must be a number between 1 and %d", maxListLimit))
+		}
+	}
+	after, err := decodeCursor(params["cursor"])
+	if err != nil {
+		return PoolList{}, errors.NewInvalidParametersError(err)
+	}
+
+	items, err := h.ownedPoolItems(claims.Subject)
+	if err != nil {
+		return PoolList{}, err
+	}
+	summaries := make([]PoolSummary, 0, len(items))
+	for _, item := range items {
+		summary, err := h.summarize(item)
+		if err != nil {
+			h.logger.Warnf("skipping moneypool: %v", err)
+			continue
+		}
+		if summary.Name <= after || (state == StateOpen && !summary.Open) || (state == StateClosed && summary.Open) {
+			continue
+		}
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+
+	list := PoolList{Pools: summaries}
+	if len(summaries) > limit {
+		list.Pools = summaries[:limit]
+		list.Next = encodeCursor(summaries[limit-1].Name)
+	}
+	return list, nil
+}
+
+// ownedPoolItems reads all pool items of the owner from the owner index.
+func (h *MoneyPoolsHandler) ownedPoolItems(owner string) ([]map[string]*dynamodb.AttributeValue, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(h.tables.MoneyPools),
+		IndexName:              aws.String(MoneyPoolsOwnerIndex),
+		KeyConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]*string{
+			"#owner": aws.String("owner"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":owner": {S: aws.String(owner)},
+		},
+	}
+	var items []map[string]*dynamodb.AttributeValue
+	for {
+		out, err := h.dynamoClient.Query(input)
+		if err != nil {
+			return nil, errors.NewStoreUnavailableError(fmt.Errorf("error reading moneypools from db: %v", err))
+		}
+		items = append(items, out.Items...)
+		if len(out.LastEvaluatedKey) == 0 {
+			return items, nil
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+// summarize decodes a pool item for its owner, so totals are shown regardless of the pool's privacy mode.
+func (h *MoneyPoolsHandler) summarize(item map[string]*dynamodb.AttributeValue) (PoolSummary, error) {
+	pool, _, err := decodeUnredactedPool(item)
+	if err != nil {
+		return PoolSummary{}, err
+	}
+	h.convertTotals(&pool)
+	summary := PoolSummary{
+		Name:         pool.Name,
+		Title:        pool.Title,
+		Open:         pool.Open,
+		BaseCurrency: pool.BaseCurrency,
+		Total:        *pool.ConvertedTotal,
+		MissingRates: pool.MissingRates,
+	}
+	if tenant := item["tenant"]; tenant != nil && tenant.S != nil {
+		summary.Tenant = *tenant.S
+	}
+	var last time.Time
+	if createdAt := item["createdAt"]; createdAt != nil && createdAt.S != nil {
+		last, _ = time.Parse(time.RFC3339, *createdAt.S)
+	}
+	for _, transaction := range pool.Transactions {
+		if !transaction.Voided && transaction.RefundOf == "" {
+			summary.Count++
+		}
+		for _, format := range storedDateFormats {
+			if date, err := time.Parse(format, transaction.Date); err == nil {
+				if date.After(last) {
+					last = date
+				}
+				break
+			}
+		}
+	}
+	if !last.IsZero() {
+		summary.LastActivity = last.Format("2006-01-02")
+	}
+	return summary, nil
+}
+
+// encodeCursor hides the name of the last listed pool in an opaque cursor.
+func encodeCursor(name string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(name))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	name, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", fmt.Errorf("invalid cursor")
+	}
+	return string(name), nil
+}
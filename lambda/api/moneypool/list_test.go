@@ -0,0 +1,102 @@
+package moneypool
+
+import (
+	"api/errors"
+	er "errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+)
+
+func TestListMoneyPools(t *testing.T) {
+	owned := func(item map[string]*dynamodb.AttributeValue, owner string) map[string]*dynamodb.AttributeValue {
+		return withAttribute(item, "owner", &dynamodb.AttributeValue{S: aws.String(owner)})
+	}
+	client := NewFakeDynamoClient(
+		owned(testPoolItem("paul", "Gift for Paul", true,
+			testTransactionItem("Anna", "01.03.22", "10", "50"),
+			testTransactionItem("Ben", "03.03.22", "5", "0"),
+		), "owner-sub"),
+		owned(withAttribute(testPoolItem("anna", "Gift for Anna", false), "createdAt", &dynamodb.AttributeValue{S: aws.String("2022-02-01T10:00:00Z")}), "owner-sub"),
+		owned(testPoolItem("cleo", "Gift for Cleo", true), "owner-sub"),
+		owned(testPoolItem("dave", "Gift for Dave", true), "other-sub"),
+		testPoolItem("erin", "Gift for Erin", true),
+	)
+	handler := NewHandler(testTables, client, fakeVerifier)
+
+	list, err := handler.ListMoneyPools(listRequest(ownerJwt, nil))
+	expected := PoolList{Pools: []PoolSummary{
+		{Name: "anna", Title: "Gift for Anna", Open: false, BaseCurrency: "EUR", LastActivity: "2022-02-01"},
+		{Name: "cleo", Title: "Gift for Cleo", Open: true, BaseCurrency: "EUR"},
+		{Name: "paul", Title: "Gift for Paul", Open: true, BaseCurrency: "EUR", Total: Amount{Base: 15, Fraction: 50}, Count: 2, LastActivity: "2022-03-03"},
+	}}
+	if err != nil || !reflect.DeepEqual(list, expected) {
+		t.Fatalf("ListMoneyPools returned %+v, %v but expected %+v", list, err, expected)
+	}
+
+	list, err = handler.ListMoneyPools(listRequest(ownerJwt, map[string]string{"state": "open", "owner": "me"}))
+	if err != nil || len(list.Pools) != 2 || list.Pools[0].Name != "cleo" || list.Pools[1].Name != "paul" {
+		t.Fatalf("ListMoneyPools(open) returned %+v, %v", list, err)
+	}
+	list, err = handler.ListMoneyPools(listRequest(ownerJwt, map[string]string{"state": "closed"}))
+	if err != nil || len(list.Pools) != 1 || list.Pools[0].Name != "anna" {
+		t.Fatalf("ListMoneyPools(closed) returned %+v, %v", list, err)
+	}
+}
+
+func TestListMoneyPoolsPages(t *testing.T) {
+	var items []map[string]*dynamodb.AttributeValue
+	for _, name := range []string{"anna", "ben", "cleo", "dave", "erin"} {
+		items = append(items, withAttribute(testPoolItem(name, "Gift", true), "owner", &dynamodb.AttributeValue{S: aws.String("owner-sub")}))
+	}
+	handler := NewHandler(testTables, NewFakeDynamoClient(items...), fakeVerifier)
+
+	var names []string
+	cursor := ""
+	for pages := 0; pages < 5; pages++ {
+		list, err := handler.ListMoneyPools(listRequest(ownerJwt, map[string]string{"limit": "2", "cursor": cursor}))
+		if err != nil {
+			t.Fatalf("ListMoneyPools(page %d) returned error %v", pages, err)
+		}
+		for _, pool := range list.Pools {
+			names = append(names, pool.Name)
+		}
+		if cursor = list.Next; cursor == "" {
+			break
+		}
+	}
+	expected := []string{"anna", "ben", "cleo", "dave", "erin"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("listed pools %v, but expected %v", names, expected)
+	}
+}
+
+func TestListMoneyPoolsInvalid(t *testing.T) {
+	testTable := []struct {
+		name     string
+		request  events.APIGatewayProxyRequest
+		expected error
+	}{
+		{"no_bearer_token", listRequest("", nil), errors.NewUnauthorizedError(er.New("listing moneypools requires a bearer token"))},
+		{"other_owner", listRequest(ownerJwt, map[string]string{"owner": "other-sub"}), errors.NewForbiddenError(er.New("owners can only list their own moneypools"))},
+		{"unknown_state", listRequest(ownerJwt, map[string]string{"state": "deleted"}), errors.NewInvalidParametersError(er.New("unknown state deleted, expected open, closed or all"))},
+		{"invalid_limit", listRequest(ownerJwt, map[string]string{"limit": "0"}), errors.NewInvalidParametersError(er.New("limit must be a number between 1 and 100"))},
+		{"invalid_cursor", listRequest(ownerJwt, map[string]string{"cursor": "!"}), errors.NewInvalidParametersError(er.New("invalid cursor"))},
+	}
+	handler := NewHandler(testTables, NewFakeDynamoClient(), fakeVerifier)
+	for _, test := range testTable {
+		if _, err := handler.ListMoneyPools(test.request); !compareErrors(err, test.expected) {
+			t.Fatalf("ListMoneyPools(%s) returned error %v, but expected %v", test.name, err, test.expected)
+		}
+	}
+}
+
+func listRequest(jwt string, query map[string]string) events.APIGatewayProxyRequest {
+	request := events.APIGatewayProxyRequest{HTTPMethod: "GET", Resource: "/pools", QueryStringParameters: query}
+	if jwt != "" {
+		request = withHeader(request, "Authorization", "Bearer "+jwt)
+	}
+	return request
+}
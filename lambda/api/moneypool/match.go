@@ -0,0 +1,34 @@
+package moneypool
+
+// The name matching rules are those of the transaction lambda's roster package, which links payments when they are
+// stored, so payments that weren't linked are matched the same way here. names.go is a copy of its names.go.
+//go:generate sh -c "(echo '// Code generated from transaction/roster/names.go by go generate. DO NOT EDIT.'; echo; sed 's/^package roster$/package moneypool/' ../../transaction/roster/names.go) > names.go"
+
+// matchParticipant returns the index of the participant the sender's name belongs to, or -1 if the name matches no
+// participant, or several participants equally well. Names match despite differences in case, spacing, punctuation,
+// diacritics and word order, missing middle names and abbreviated first names, and aliases match like names.
+func matchParticipant(participants []Participant, name string) int {
+	if len(nameSpellings(name)[0]) == 0 {
+		return -1
+	}
+	best, bestStrength, ambiguous := -1, noMatch, false
+	for i, participant := range participants {
+		strength := nameStrength(participant.Name, name)
+		for _, alias := range participant.Aliases {
+			if aliasStrength := nameStrength(alias, name); aliasStrength > strength {
+				strength = aliasStrength
+			}
+		}
+		switch {
+		case strength == noMatch || strength < bestStrength:
+		case strength == bestStrength:
+			ambiguous = true
+		default:
+			best, bestStrength, ambiguous = i, strength, false
+		}
+	}
+	if ambiguous {
+		return -1
+	}
+	return best
+}
@@ -0,0 +1,76 @@
+package moneypool
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMatchParticipant(t *testing.T) {
+	participants := []Participant{
+		{Id: "anna", Name: "Anna Schmidt"},
+		{Id: "ben", Name: "Ben Müller", Aliases: []string{"Petra Müller"}},
+		{Id: "carla", Name: "Carla Maria Vogel"},
+		{Id: "lena", Name: "Lena Vogel"},
+		{Id: "leon", Name: "Leon Vogel"},
+		{Id: "michael", Name: "Michael Roth"},
+		{Id: "michal", Name: "Michal Roth"},
+		{Id: "samuel", Name: "Samuel Kraus"},
+	}
+	testTable := map[string]string{
+		"Anna Schmidt":      "anna",
+		"  anna   SCHMIDT ": "anna",
+		"Schmidt, Anna":     "anna",
+		"A. Schmidt":        "anna",
+		"Ben Mueller":       "ben",
+		"Petra Muller":      "ben",
+		"Carla M. Vogel":    "carla",
+		"L. Vogel":          "", // Lena and Leon
+		"Vogel":             "",
+		"Anna Meyer":        "",
+		"Michael Roth":      "michael",
+		"Michal Roth":       "michal",
+		"Samul Kraus":       "",
+		"":                  "",
+	}
+	for name, expected := range testTable {
+		id := ""
+		if index := matchParticipant(participants, name); index >= 0 {
+			id = participants[index].Id
+		}
+		if id != expected {
+			t.Fatalf("matchParticipant(%q) returned %q, but expected %q", name, id, expected)
+		}
+	}
+}
+
+func TestContributorStatusesMatchNameVariants(t *testing.T) {
+	expected := Expected{Amount: &Amount{Base: 10}, Participants: []Participant{{Id: "1", Name: "Anna Schmidt"}}}
+	transactions := []Transaction{
+		{Id: "t1", Name: "Schmidt, Anna", Base: 4, Currency: defaultCurrency},
+		{Id: "t2", Name: "A. Schmidt", Base: 6, Currency: defaultCurrency},
+		{Id: "t3", Name: "Ben Meyer", Base: 5, Currency: defaultCurrency},
+	}
+	statuses := contributorStatuses(expected, transactions, defaultCurrency)
+	if len(statuses) != 2 || statuses[0].Status != StatusPaid || statuses[1].Name != "Ben Meyer" {
+		t.Fatalf("contributorStatuses returned %+v, but expected Anna to have paid and Ben as other contributor", statuses)
+	}
+}
+
+func TestNamesAreGenerated(t *testing.T) {
+	source, err := ioutil.ReadFile("../../transaction/roster/names.go")
+	if os.IsNotExist(err) {
+		t.Skip("transaction lambda not checked out")
+	}
+	if err != nil {
+		t.Fatalf("could not read roster names: %v", err)
+	}
+	generated, err := ioutil.ReadFile("names.go")
+	if err != nil {
+		t.Fatalf("could not read names: %v", err)
+	}
+	if !strings.HasSuffix(string(generated), strings.Replace(string(source), "package roster\n", "package moneypool\n", 1)) {
+		t.Fatalf("names.go differs from the transaction lambda's roster/names.go, run go generate")
+	}
+}
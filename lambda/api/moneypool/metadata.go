@@ -0,0 +1,158 @@
+package moneypool
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxDescriptionLength  = 5000
+	maxInstructionsLength = 2000
+	maxRecipientLength    = 200
+	maxURLLength          = 2000
+)
+
+// paypalMePattern matches the handles of paypal.me links.
+var paypalMePattern = regexp.MustCompile(`^[A-Za-z0-9]{1,20}$`)
+
+// Metadata describes a pool and its payee to the pool's readers, so one deployment can host pools for different payees.
+// All fields are optional, and shown to everyone who can read the pool.
+type Metadata struct {
+	// Description is markdown, which the frontend has to sanitize when rendering it.
+	Description string `json:"description,omitempty" dynamodbav:"description,omitempty"`
+	ImageURL    string `json:"imageUrl,omitempty" dynamodbav:"imageUrl,omitempty"`
+	Recipient   string `json:"recipient,omitempty" dynamodbav:"recipient,omitempty"`
+	// PaypalMe is the payee's paypal.me handle. PaymentLink is any other link to pay to, and takes precedence.
+	PaypalMe     string `json:"paypalMe,omitempty" dynamodbav:"paypalMe,omitempty"`
+	PaymentLink  string `json:"paymentLink,omitempty" dynamodbav:"paymentLink,omitempty"`
+	Instructions string `json:"instructions,omitempty" dynamodbav:"instructions,omitempty"`
+}
+
+// MetadataUpdate changes a pool's metadata. Fields that are not set stay unchanged, empty fields are removed.
+type MetadataUpdate struct {
+	Description  *string `json:"description"`
+	ImageURL     *string `json:"imageUrl"`
+	Recipient    *string `json:"recipient"`
+	PaypalMe     *string `json:"paypalMe"`
+	PaymentLink  *string `json:"paymentLink"`
+	Instructions *string `json:"instructions"`
+}
+
+// attributes maps the attributes of the pool item to the metadata's values.
+func (m Metadata) attributes() map[string]string {
+	return map[string]string{
+		"description":  m.Description,
+		"imageUrl":     m.ImageURL,
+		"recipient":    m.Recipient,
+		"paypalMe":     m.PaypalMe,
+		"paymentLink":  m.PaymentLink,
+		"instructions": m.Instructions,
+	}
+}
+
+// attributes maps the attributes of the pool item to the changed values.
+func (u MetadataUpdate) attributes() map[string]*string {
+	changes := map[string]*string{
+		"description":  u.Description,
+		"imageUrl":     u.ImageURL,
+		"recipient":    u.Recipient,
+		"paypalMe":     u.PaypalMe,
+		"paymentLink":  u.PaymentLink,
+		"instructions": u.Instructions,
+	}
+	for attribute, value := range changes {
+		if value == nil {
+			delete(changes, attribute)
+		}
+	}
+	return changes
+}
+
+// changed returns the metadata the update sets, with empty values for fields it doesn't change.
+func (u MetadataUpdate) changed() Metadata {
+	value := func(field *string) string {
+		if field == nil {
+			return ""
+		}
+		return *field
+	}
+	return Metadata{
+		Description:  value(u.Description),
+		ImageURL:     value(u.ImageURL),
+		Recipient:    value(u.Recipient),
+		PaypalMe:     value(u.PaypalMe),
+		PaymentLink:  value(u.PaymentLink),
+		Instructions: value(u.Instructions),
+	}
+}
+
+// trimmed returns the metadata without surrounding whitespace.
+func (m Metadata) trimmed() Metadata {
+	return Metadata{
+		Description:  strings.TrimSpace(m.Description),
+		ImageURL:     strings.TrimSpace(m.ImageURL),
+		Recipient:    strings.TrimSpace(m.Recipient),
+		PaypalMe:     strings.TrimSpace(m.PaypalMe),
+		PaymentLink:  strings.TrimSpace(m.PaymentLink),
+		Instructions: strings.TrimSpace(m.Instructions),
+	}
+}
+
+// trimmed returns the update without whitespace surrounding its values.
+func (u MetadataUpdate) trimmed() MetadataUpdate {
+	trim := func(field *string) *string {
+		if field == nil {
+			return nil
+		}
+		return aws.String(strings.TrimSpace(*field))
+	}
+	return MetadataUpdate{
+		Description:  trim(u.Description),
+		ImageURL:     trim(u.ImageURL),
+		Recipient:    trim(u.Recipient),
+		PaypalMe:     trim(u.PaypalMe),
+		PaymentLink:  trim(u.PaymentLink),
+		Instructions: trim(u.Instructions),
+	}
+}
+
+func (m Metadata) validate() error {
+	if utf8.RuneCountInString(m.Description) > maxDescriptionLength {
+		return fmt.Errorf("description must have at most %d characters", maxDescriptionLength)
+	}
+	if utf8.RuneCountInString(m.Instructions) > maxInstructionsLength {
+		return fmt.Errorf("instructions must have at most %d characters", maxInstructionsLength)
+	}
+	if utf8.RuneCountInString(m.Recipient) > maxRecipientLength {
+		return fmt.Errorf("recipient must have at most %d characters", maxRecipientLength)
+	}
+	if m.ImageURL != "" && !validLinkURL(m.ImageURL) {
+		return fmt.Errorf("image url must be an https url")
+	}
+	if m.PaymentLink != "" && !validLinkURL(m.PaymentLink) {
+		return fmt.Errorf("payment link must be an https url")
+	}
+	if m.PaypalMe != "" && !paypalMePattern.MatchString(m.PaypalMe) {
+		return fmt.Errorf("invalid paypal.me handle %q, expected up to 20 letters and digits", m.PaypalMe)
+	}
+	return nil
+}
+
+// PayURL returns the link readers pay the pool with: the payment link, or else the paypal.me link of the handle.
+func (m Metadata) PayURL() string {
+	if m.PaymentLink != "" {
+		return m.PaymentLink
+	}
+	if m.PaypalMe != "" {
+		return "https://paypal.me/" + m.PaypalMe
+	}
+	return ""
+}
+
+// validLinkURL accepts absolute https urls, which are safe to use as link or image source in the frontend.
+func validLinkURL(link string) bool {
+	return len(link) <= maxURLLength && validWebhookURL(link)
+}
@@ -0,0 +1,62 @@
+package moneypool
+
+import (
+	er "errors"
+	"strings"
+	"testing"
+)
+
+func TestPoolMetadata(t *testing.T) {
+	client := NewFakeDynamoClient()
+	handler := NewHandler(testTables, client, fakeVerifier)
+	creation := `{"name": "paul", "title": "Gift for Paul", "description": "A **bike** for Paul", "recipient": " Paul ",
+		"paypalMe": "paulsmum", "imageUrl": "https://example.com/bike.png"}`
+	created, err := handler.CreateMoneyPool(createRequest(ownerJwt, creation))
+	if err != nil {
+		t.Fatalf("CreateMoneyPool returned error %v", err)
+	}
+	expected := Metadata{Description: "A **bike** for Paul", ImageURL: "https://example.com/bike.png", Recipient: "Paul", PaypalMe: "paulsmum"}
+	if created.Metadata != expected || created.PayURL != "https://paypal.me/paulsmum" {
+		t.Fatalf("CreateMoneyPool returned metadata %+v and pay url %s", created.Metadata, created.PayURL)
+	}
+
+	update := `{"paymentLink": "https://pay.example.com/paul", "instructions": "Use the note 'paul'", "imageUrl": ""}`
+	if _, err := handler.UpdateMoneyPool(ownerUpdate(update)); err != nil {
+		t.Fatalf("UpdateMoneyPool returned error %v", err)
+	}
+	if _, exists := client.items["paul"]["imageUrl"]; exists {
+		t.Fatalf("image url was not removed")
+	}
+	pool, err := handler.GetMoneyPool(poolRequest("paul"))
+	if err != nil {
+		t.Fatalf("GetMoneyPool returned error %v", err)
+	}
+	expected = Metadata{Description: "A **bike** for Paul", Recipient: "Paul", PaypalMe: "paulsmum",
+		PaymentLink: "https://pay.example.com/paul", Instructions: "Use the note 'paul'"}
+	if pool.Metadata != expected || pool.PayURL != "https://pay.example.com/paul" {
+		t.Fatalf("GetMoneyPool returned metadata %+v and pay url %s", pool.Metadata, pool.PayURL)
+	}
+}
+
+func TestInvalidPoolMetadata(t *testing.T) {
+	testTable := map[string]error{
+		`{"imageUrl": "http://example.com/bike.png"}`:       er.New("image url must be an https url"),
+		`{"paymentLink": "javascript:alert(1)"}`:            er.New("payment link must be an https url"),
+		`{"paypalMe": "paypal.me/paul"}`:                    er.New(`invalid paypal.me handle "paypal.me/paul", expected up to 20 letters and digits`),
+		`{"recipient": "` + strings.Repeat("p", 201) + `"}`: er.New("recipient must have at most 200 characters"),
+	}
+	handler := NewHandler(testTables, NewFakeDynamoClient(), fakeVerifier)
+	if _, err := handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "paul", "title": "Gift for Paul"}`)); err != nil {
+		t.Fatalf("CreateMoneyPool returned error %v", err)
+	}
+	for update, expected := range testTable {
+		_, err := handler.UpdateMoneyPool(ownerUpdate(update))
+		if !compareErrors(err, expected) {
+			t.Fatalf("UpdateMoneyPool(%s) returned error %v, but expected %v", update, err, expected)
+		}
+	}
+	_, err := handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "anna", "title": "Gift for Anna", "imageUrl": "bike.png"}`))
+	if !compareErrors(err, er.New("image url must be an https url")) {
+		t.Fatalf("CreateMoneyPool returned error %v", err)
+	}
+}
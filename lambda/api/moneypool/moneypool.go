@@ -1,38 +1,128 @@
 package moneypool
 
 import (
+	"api/auth"
 	"api/errors"
 	"fmt"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	log "github.com/sirupsen/logrus"
-	"strconv"
 )
 
 type Transaction struct {
+	Id       string `json:"id,omitempty"`
 	Name     string `json:"name"`
 	Base     int    `json:"base"`
 	Date     string `json:"date,omitempty"`
 	Fraction int    `json:"fraction"`
+	// Decimals is the number of digits of the fraction for currencies with three decimals, like KWD. Fractions of
+	// other currencies are in cents.
+	Decimals int    `json:"decimals,omitempty"`
+	Currency string `json:"currency"`
+	// Fee is what PayPal deducted, and Net what was received, if the payment's mail showed a fee.
+	Fee       *Amount `json:"fee,omitempty"`
+	Net       *Amount `json:"net,omitempty"`
+	Anonymous bool    `json:"-"`
+	// Voided transactions were cancelled by an admin. They are still listed, but don't count towards totals.
+	Voided bool `json:"voided,omitempty"`
+	// RefundOf is the id of the transaction this entry refunds. Its amount is subtracted from totals.
+	RefundOf string `json:"refundOf,omitempty"`
+	// Participant is the id of the expected participant the payment was linked to.
+	Participant string `json:"-"`
+}
+
+// Amount is an amount of money. Like a transaction's, its fraction is in cents unless Decimals says otherwise. Base and
+// fraction are never negative; totals below zero are Negative, e.g. -1.50 is 1.50 with Negative set.
+type Amount struct {
+	Base     int  `json:"base" dynamodbav:"base"`
+	Fraction int  `json:"fraction" dynamodbav:"fraction"`
+	Decimals int  `json:"decimals,omitempty" dynamodbav:"decimals,omitempty"`
+	Negative bool `json:"negative,omitempty" dynamodbav:"negative,omitempty"`
+}
+
+// amount returns the transaction's amount.
+func (t Transaction) amount() Amount {
+	return Amount{Base: t.Base, Fraction: t.Fraction, Decimals: t.Decimals}
+}
+
+// InvalidTransaction reports a stored transaction that could not be decoded.
+type InvalidTransaction struct {
+	Index int    `json:"index"`
+	Id    string `json:"id,omitempty"`
+	Error string `json:"error"`
 }
 
 type MoneyPool struct {
-	Transactions []Transaction `json:"transactions"`
-	Name         string        `json:"name"`
-	Title        string        `json:"title"`
-	Open         bool          `json:"open"`
+	Transactions        []Transaction        `json:"transactions"`
+	InvalidTransactions []InvalidTransaction `json:"invalidTransactions,omitempty"`
+	Name                string               `json:"name"`
+	Title               string               `json:"title"`
+	Open                bool                 `json:"open"`
+	AmountsHidden       bool                 `json:"amountsHidden,omitempty"`
+	// Total is the total in the base currency of pools that hide amounts.
+	Total *Amount `json:"total,omitempty"`
+	// BaseCurrency is the currency the pool's totals are converted to.
+	BaseCurrency string `json:"baseCurrency"`
+	// TotalsBasis tells whether totals add up the gross amounts sent or the net amounts received.
+	TotalsBasis string `json:"totalsBasis"`
+	// Totals are the pool's totals per currency, without conversion.
+	Totals map[string]Amount `json:"totals"`
+	// ConvertedTotal is the sum of all totals, converted to the base currency. It leaves out the currencies listed in
+	// MissingRates, for which no exchange rate is known.
+	ConvertedTotal *Amount  `json:"convertedTotal,omitempty"`
+	MissingRates   []string `json:"missingRates,omitempty"`
+	Metadata
+	// Expected is set for pools that expect an amount per person. Contributors then lists who paid it.
+	Expected     *ExpectedAmount     `json:"expected,omitempty"`
+	Contributors []ContributorStatus `json:"contributors,omitempty"`
+	// PayURL is the link to pay to the pool's payee, if its metadata has one.
+	PayURL string `json:"payUrl,omitempty"`
+	ETag   string `json:"-"`
+}
+
+// TokenVerifier validates bearer tokens of pool owners.
+type TokenVerifier interface {
+	Verify(token string) (auth.Claims, error)
+}
+
+// RateSource provides exchange rates to convert totals to a pool's base currency.
+type RateSource interface {
+	// Rate returns how many units of the target currency one unit of the source currency is worth, or false if it
+	// doesn't know either currency.
+	Rate(from, to string) (float64, bool)
+	// Version identifies the rates. It is part of the pools' entity tags, so clients revalidate converted totals once
+	// the rates change.
+	Version() string
+}
+
+// Tables holds the names of the tables the handler works on.
+type Tables struct {
+	MoneyPools string
+	Tenants    string
+	// Audit is the append-only log of changes to pools. Without it, changes are not audited.
+	Audit string
+	// WebhookDeliveries is the log of deliveries to the pools' webhooks. Without it, webhooks can't be registered.
+	WebhookDeliveries string
 }
 
 type MoneyPoolsHandler struct {
-	moneyPoolsTableName string
-	corsDomain          string
-	dynamoClient        dynamodb.DynamoDB
-	logger              *log.Entry
+	tables        Tables
+	dynamoClient  dynamodbiface.DynamoDBAPI
+	verifier      TokenVerifier
+	logger        *log.Entry
+	importer      ContributionImporter
+	rates         RateSource
+	webhookEvents WebhookEventSender
+	mailDomain    string
+	mailDefault   string
 }
 
-func NewHandler(moneyPoolsTableName string, corsDomain string, dynamoClient dynamodb.DynamoDB) *MoneyPoolsHandler {
-	return &MoneyPoolsHandler{moneyPoolsTableName: moneyPoolsTableName, corsDomain: corsDomain, dynamoClient: dynamoClient}
+// NewHandler creates a handler for moneypool requests. Without verifier, bearer tokens are rejected and pools can only be
+// administrated with their admin tokens.
+func NewHandler(tables Tables, dynamoClient dynamodbiface.DynamoDBAPI, verifier TokenVerifier) *MoneyPoolsHandler {
+	return &MoneyPoolsHandler{tables: tables, dynamoClient: dynamoClient, verifier: verifier}
 }
 
 func (h *MoneyPoolsHandler) GetMoneyPool(request events.APIGatewayProxyRequest) (MoneyPool, error) {
@@ -42,70 +132,47 @@ func (h *MoneyPoolsHandler) GetMoneyPool(request events.APIGatewayProxyRequest)
 	}
 
 	h.logger = log.WithFields(log.Fields{"requestedMP": mpName})
-	h.logger.Infof("search moneypool")
-	mpItem, err := h.dynamoClient.GetItem(&dynamodb.GetItemInput{
-		Key: map[string]*dynamodb.AttributeValue{
-			"name": {
-				S: aws.String(mpName),
-			},
-		},
-		TableName: aws.String(h.moneyPoolsTableName),
-	})
+	item, err := h.getPoolItem(mpName)
 	if err != nil {
-		return MoneyPool{}, fmt.Errorf("error getting moneypool from db: %v", err)
+		return MoneyPool{}, err
 	}
-	h.logger.Infof("found moneypool item")
-	item := mpItem.Item
-	if item == nil {
-		return MoneyPool{}, errors.NewNotFoundError(fmt.Errorf("no moneypool found for given name %s", mpName))
-	}
-
-	if _, exists := item["name"]; !exists {
-		return MoneyPool{}, fmt.Errorf("moneypool item has no name field")
+	_, err = h.authorize(request, item, mpName, AccessRead)
+	if err != nil {
+		return MoneyPool{}, err
 	}
 
-	if _, exists := item["title"]; !exists {
-		return MoneyPool{}, fmt.Errorf("moneypool item has no title field")
+	resp, err := decodePool(item)
+	if err != nil {
+		return MoneyPool{}, err
 	}
-
-	resp := MoneyPool{
-		Name:  *mpItem.Item["name"].S,
-		Title: *mpItem.Item["title"].S,
-		Open:  *mpItem.Item["open"].BOOL,
+	h.convertTotals(&resp)
+	resp.ETag, err = itemETag(item, h.ratesVersion())
+	if err != nil {
+		return MoneyPool{}, err
 	}
-
-	for _, transaction := range mpItem.Item["transactions"].L {
-		name, date, base, fraction, err := h.formatTransaction(transaction.M)
-		if err != nil {
-			h.logger.Errorf("Error getting transaction %v: %v", transaction, err)
-			continue
-		}
-		resp.Transactions = append(resp.Transactions, Transaction{
-			Name:     name,
-			Date:     date,
-			Base:     base,
-			Fraction: fraction,
-		})
+	for _, invalid := range resp.InvalidTransactions {
+		h.logger.Errorf("invalid transaction %d (%s): %s", invalid.Index, invalid.Id, invalid.Error)
 	}
 	h.logger.Infof("moneypool item: %+v", resp)
 	return resp, nil
 }
 
-func (h *MoneyPoolsHandler) formatTransaction(trItem map[string]*dynamodb.AttributeValue) (name, date string, base, fraction int, err error) {
-	name = *trItem["name"].S
-	if trItem["date"] != nil {
-		date = *trItem["date"].S
-	}
-	baseString := *trItem["base"].N
-	fractionString := *trItem["fraction"].N
-	h.logger.Infof("got parser: %s %s %s", name, baseString, fractionString)
-	base, err = strconv.Atoi(baseString)
+func (h *MoneyPoolsHandler) getPoolItem(mpName string) (map[string]*dynamodb.AttributeValue, error) {
+	h.logger.Infof("search moneypool")
+	mpItem, err := h.dynamoClient.GetItem(&dynamodb.GetItemInput{
+		Key: map[string]*dynamodb.AttributeValue{
+			"name": {
+				S: aws.String(mpName),
+			},
+		},
+		TableName: aws.String(h.tables.MoneyPools),
+	})
 	if err != nil {
-		return
+		return nil, errors.NewStoreUnavailableError(fmt.Errorf("error getting moneypool from db: %v", err))
 	}
-	fraction, err = strconv.Atoi(fractionString)
-	if err != nil {
-		return
+	if mpItem.Item == nil {
+		return nil, errors.NewNotFoundError(fmt.Errorf("no moneypool found for given name %s", mpName))
 	}
-	return
+	h.logger.Infof("found moneypool item")
+	return mpItem.Item, nil
 }
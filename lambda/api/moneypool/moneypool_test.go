@@ -0,0 +1,350 @@
+package moneypool
+
+import (
+	"api/errors"
+	"encoding/json"
+	er "errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const tableName = "MoneyPoolsTable"
+
+type getMoneyPoolTest struct {
+	name          string
+	client        *FakeDynamoClient
+	request       events.APIGatewayProxyRequest
+	expectedPool  MoneyPool
+	expectedError error
+}
+
+func TestGetMoneyPool(t *testing.T) {
+	testTable := []getMoneyPoolTest{
+		{
+			"valid",
+			NewFakeDynamoClient(testPoolItem("paul", "Gift for Paul", true,
+				testTransactionItem("Sender Person", "01.02.22", "12", "34"),
+				testTransactionItem("Other Person", "02.02.22", "5", "0"),
+			)),
+			poolRequest("paul"),
+			MoneyPool{
+				Name:  "paul",
+				Title: "Gift for Paul",
+				Open:  true,
+				Transactions: []Transaction{
+					{Id: "id-Sender Person", Name: "Sender Person", Date: "01.02.22", Base: 12, Fraction: 34},
+					{Id: "id-Other Person", Name: "Other Person", Date: "02.02.22", Base: 5, Fraction: 0},
+				},
+			},
+			nil,
+		},
+		{
+			"no_transactions",
+			NewFakeDynamoClient(testPoolItem("paul", "Gift for Paul", false)),
+			poolRequest("paul"),
+			MoneyPool{Name: "paul", Title: "Gift for Paul", Open: false},
+			nil,
+		},
+		{
+			"transaction_without_date",
+			NewFakeDynamoClient(testPoolItem("paul", "Gift for Paul", true,
+				testTransactionItem("Sender Person", "", "1", "99"),
+			)),
+			poolRequest("paul"),
+			MoneyPool{
+				Name:         "paul",
+				Title:        "Gift for Paul",
+				Open:         true,
+				Transactions: []Transaction{{Id: "id-Sender Person", Name: "Sender Person", Base: 1, Fraction: 99}},
+			},
+			nil,
+		},
+		{
+			"malformed_base",
+			NewFakeDynamoClient(testPoolItem("paul", "Gift for Paul", true,
+				testTransactionItem("Sender Person", "01.02.22", "twelve", "34"),
+				testTransactionItem("Other Person", "02.02.22", "5", "0"),
+			)),
+			poolRequest("paul"),
+			MoneyPool{
+				Name:         "paul",
+				Title:        "Gift for Paul",
+				Open:         true,
+				Transactions: []Transaction{{Id: "id-Other Person", Name: "Other Person", Date: "02.02.22", Base: 5, Fraction: 0}},
+				InvalidTransactions: []InvalidTransaction{
+					{Index: 0, Id: "id-Sender Person", Error: "could not decode transaction: strconv.ParseInt: parsing \"twelve\": invalid syntax"},
+				},
+			},
+			nil,
+		},
+		{
+			"malformed_fraction",
+			NewFakeDynamoClient(testPoolItem("paul", "Gift for Paul", true,
+				testTransactionItem("Sender Person", "01.02.22", "12", "3.4"),
+			)),
+			poolRequest("paul"),
+			MoneyPool{
+				Name:  "paul",
+				Title: "Gift for Paul",
+				Open:  true,
+				InvalidTransactions: []InvalidTransaction{
+					{Index: 0, Id: "id-Sender Person", Error: "could not decode transaction: strconv.ParseInt: parsing \"3.4\": invalid syntax"},
+				},
+			},
+			nil,
+		},
+		{
+			"fraction_out_of_range",
+			NewFakeDynamoClient(testPoolItem("paul", "Gift for Paul", true,
+				testTransactionItem("Sender Person", "01.02.22", "12", "100"),
+			)),
+			poolRequest("paul"),
+			MoneyPool{
+				Name:  "paul",
+				Title: "Gift for Paul",
+				Open:  true,
+				InvalidTransactions: []InvalidTransaction{
+					{Index: 0, Id: "id-Sender Person", Error: "transaction has fraction 100 out of range 0-99"},
+				},
+			},
+			nil,
+		},
+		{
+			"negative_base",
+			NewFakeDynamoClient(testPoolItem("paul", "Gift for Paul", true,
+				testTransactionItem("Sender Person", "01.02.22", "-12", "0"),
+			)),
+			poolRequest("paul"),
+			MoneyPool{
+				Name:  "paul",
+				Title: "Gift for Paul",
+				Open:  true,
+				InvalidTransactions: []InvalidTransaction{
+					{Index: 0, Id: "id-Sender Person", Error: "transaction has negative base -12"},
+				},
+			},
+			nil,
+		},
+		{
+			"transaction_missing_fields",
+			NewFakeDynamoClient(testPoolItem("paul", "Gift for Paul", true,
+				withoutTransactionAttribute(testTransactionItem("No Base", "01.02.22", "1", "0"), "base"),
+				withoutTransactionAttribute(testTransactionItem("No Fraction", "01.02.22", "1", "0"), "fraction"),
+				withoutTransactionAttribute(withoutTransactionAttribute(testTransactionItem("No Name", "01.02.22", "1", "0"), "name"), "id"),
+				&dynamodb.AttributeValue{S: aws.String("not a transaction")},
+				testTransactionItem("Sender Person", "01.02.22", "1", "50"),
+			)),
+			poolRequest("paul"),
+			MoneyPool{
+				Name:         "paul",
+				Title:        "Gift for Paul",
+				Open:         true,
+				Transactions: []Transaction{{Id: "id-Sender Person", Name: "Sender Person", Date: "01.02.22", Base: 1, Fraction: 50}},
+				InvalidTransactions: []InvalidTransaction{
+					{Index: 0, Id: "id-No Base", Error: "transaction has no base field"},
+					{Index: 1, Id: "id-No Fraction", Error: "transaction has no fraction field"},
+					{Index: 2, Error: "transaction has no name field"},
+					{Index: 3, Error: "transaction is not a map"},
+				},
+			},
+			nil,
+		},
+		{
+			"no_path_parameter",
+			NewFakeDynamoClient(testPoolItem("paul", "Gift for Paul", true)),
+			events.APIGatewayProxyRequest{},
+			MoneyPool{},
+			errors.NewInvalidParametersError(er.New("no moneyppol name given")),
+		},
+		{
+			"not_found",
+			NewFakeDynamoClient(testPoolItem("paul", "Gift for Paul", true)),
+			poolRequest("peter"),
+			MoneyPool{},
+			errors.NewNotFoundError(er.New("no moneypool found for given name peter")),
+		},
+		{
+			"db_error",
+			&FakeDynamoClient{getItemErr: er.New("connection refused")},
+			poolRequest("paul"),
+			MoneyPool{},
+			errors.NewStoreUnavailableError(er.New("error getting moneypool from db: connection refused")),
+		},
+		{
+			"missing_name",
+			NewFakeDynamoClient(withoutAttribute(testPoolItem("paul", "Gift for Paul", true), "name")),
+			poolRequest("paul"),
+			MoneyPool{},
+			er.New("moneypool item has no name field"),
+		},
+		{
+			"missing_title",
+			NewFakeDynamoClient(withoutAttribute(testPoolItem("paul", "Gift for Paul", true), "title")),
+			poolRequest("paul"),
+			MoneyPool{Name: "paul", Title: "paul", Open: true},
+			nil,
+		},
+		{
+			"missing_open",
+			NewFakeDynamoClient(withoutAttribute(testPoolItem("paul", "Gift for Paul", false), "open")),
+			poolRequest("paul"),
+			MoneyPool{Name: "paul", Title: "Gift for Paul", Open: true},
+			nil,
+		},
+		{
+			"invalid_open",
+			NewFakeDynamoClient(withAttribute(testPoolItem("paul", "Gift for Paul", true), "open", &dynamodb.AttributeValue{S: aws.String("yes")})),
+			poolRequest("paul"),
+			MoneyPool{},
+			er.New("could not decode moneypool item: UnmarshalTypeError: cannot unmarshal string into Go value of type bool"),
+		},
+		{
+			"invalid_transactions",
+			NewFakeDynamoClient(withAttribute(testPoolItem("paul", "Gift for Paul", true), "transactions", &dynamodb.AttributeValue{S: aws.String("none")})),
+			poolRequest("paul"),
+			MoneyPool{},
+			er.New("moneypool item has invalid transactions field, expected a list"),
+		},
+	}
+	for _, test := range testTable {
+		handler := NewHandler(testTables, test.client, nil)
+		pool, err := handler.GetMoneyPool(test.request)
+		if err == nil && pool.ETag == "" {
+			t.Fatalf("GetMoneyPool(%s) returned no etag", test.name)
+		}
+		pool.ETag = ""
+		pool = withoutCurrencies(pool)
+		if !compareErrors(err, test.expectedError) || !reflect.DeepEqual(pool, test.expectedPool) {
+			t.Fatalf("GetMoneyPool(%s) = %+v, %v but expected %+v, %v", test.name, pool, err, test.expectedPool, test.expectedError)
+		}
+	}
+}
+
+func TestGetMoneyPoolETag(t *testing.T) {
+	getETag := func(client *FakeDynamoClient) string {
+		pool, err := NewHandler(testTables, client, nil).GetMoneyPool(poolRequest("paul"))
+		if err != nil {
+			t.Fatalf("GetMoneyPool(paul) returned error %v", err)
+		}
+		return pool.ETag
+	}
+	newClient := func(transactions ...*dynamodb.AttributeValue) *FakeDynamoClient {
+		return NewFakeDynamoClient(testPoolItem("paul", "Gift for Paul", true, transactions...))
+	}
+
+	first := getETag(newClient(testTransactionItem("Sender Person", "01.02.22", "12", "34")))
+	second := getETag(newClient(testTransactionItem("Sender Person", "01.02.22", "12", "34")))
+	if first != second {
+		t.Fatalf("GetMoneyPool returned etags %s and %s for equal items", first, second)
+	}
+	if !strings.HasPrefix(first, `"`) || !strings.HasSuffix(first, `"`) {
+		t.Fatalf("GetMoneyPool returned etag %s that is not a quoted strong etag", first)
+	}
+
+	changed := getETag(newClient(
+		testTransactionItem("Sender Person", "01.02.22", "12", "34"),
+		testTransactionItem("Other Person", "02.02.22", "5", "0"),
+	))
+	if first == changed {
+		t.Fatalf("GetMoneyPool returned etag %s for different items", first)
+	}
+
+	getConvertedETag := func(rates FakeRates) string {
+		handler := NewHandler(testTables, newClient(testTransactionItem("Sender Person", "01.02.22", "12", "34")), nil).WithRates(rates)
+		pool, err := handler.GetMoneyPool(poolRequest("paul"))
+		if err != nil {
+			t.Fatalf("GetMoneyPool(paul) returned error %v", err)
+		}
+		return pool.ETag
+	}
+	if getConvertedETag(FakeRates{"USD": 0.9}) == getConvertedETag(FakeRates{"USD": 0.95}) {
+		t.Fatalf("GetMoneyPool returned the same etag for different exchange rates")
+	}
+}
+
+func TestGetMoneyPoolErrorTypes(t *testing.T) {
+	handler := NewHandler(testTables, NewFakeDynamoClient(testPoolItem("paul", "Gift for Paul", true)), nil)
+
+	var notFound *errors.NotFoundError
+	if _, err := handler.GetMoneyPool(poolRequest("peter")); !er.As(err, &notFound) {
+		t.Fatalf("GetMoneyPool(not_found) returned %T, but should return %T", err, notFound)
+	}
+
+	var invalidParams *errors.InvalidParametersError
+	if _, err := handler.GetMoneyPool(events.APIGatewayProxyRequest{}); !er.As(err, &invalidParams) {
+		t.Fatalf("GetMoneyPool(no_path_parameter) returned %T, but should return %T", err, invalidParams)
+	}
+}
+
+func poolRequest(name string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{PathParameters: map[string]string{"moneyPool": name}}
+}
+
+// testPoolItem builds a moneypool item as stored by the transaction lambda. The extra "key" attribute
+// is only used by NewFakeDynamoClient to index the item, so tests can drop the name attribute itself.
+func testPoolItem(name, title string, open bool, transactions ...*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{
+		"key":   {S: aws.String(name)},
+		"name":  {S: aws.String(name)},
+		"title": {S: aws.String(title)},
+		"open":  {BOOL: aws.Bool(open)},
+	}
+	if len(transactions) > 0 {
+		item["transactions"] = &dynamodb.AttributeValue{L: transactions}
+	}
+	return item
+}
+
+func testTransactionItem(name, date, base, fraction string) *dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{
+		"id":       {S: aws.String("id-" + name)},
+		"name":     {S: aws.String(name)},
+		"base":     {N: aws.String(base)},
+		"fraction": {N: aws.String(fraction)},
+	}
+	if date != "" {
+		item["date"] = &dynamodb.AttributeValue{S: aws.String(date)}
+	}
+	return &dynamodb.AttributeValue{M: item}
+}
+
+func withoutAttribute(item map[string]*dynamodb.AttributeValue, attribute string) map[string]*dynamodb.AttributeValue {
+	delete(item, attribute)
+	return item
+}
+
+func withAttribute(item map[string]*dynamodb.AttributeValue, attribute string, value *dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	item[attribute] = value
+	return item
+}
+
+func withoutTransactionAttribute(transaction *dynamodb.AttributeValue, attribute string) *dynamodb.AttributeValue {
+	delete(transaction.M, attribute)
+	return transaction
+}
+
+// responseCode returns the status and error code the api responds to the error with.
+func responseCode(err error) (int, string) {
+	response := errors.ToResponse(err, "req-1")
+	var body errors.ErrorBody
+	if jsonErr := json.Unmarshal([]byte(response.Body), &body); jsonErr != nil {
+		return response.StatusCode, ""
+	}
+	return response.StatusCode, body.Code
+}
+
+func hasCode(err error, code string) bool {
+	_, actual := responseCode(err)
+	return actual == code
+}
+
+func compareErrors(err1, err2 error) bool {
+	if err1 != nil && err2 != nil {
+		return err1.Error() == err2.Error()
+	}
+	return err1 == err2
+}
@@ -0,0 +1,133 @@
+package moneypool
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// Triggers of notifications, as chosen per channel.
+const (
+	NotifyPayment = "payment"
+	NotifyDaily   = "daily"
+	NotifyGoal    = "goal"
+)
+
+// Types of notification channels.
+const (
+	ChannelMail     = "mail"
+	ChannelWebhook  = "webhook"
+	ChannelTelegram = "telegram"
+	ChannelDiscord  = "discord"
+)
+
+const maxNotificationChannels = 10
+
+var (
+	mailAddressPattern   = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	telegramTokenPattern = regexp.MustCompile(`^[0-9]+:[A-Za-z0-9_-]+$`)
+	telegramChatPattern  = regexp.MustCompile(`^(-?[0-9]+|@[A-Za-z0-9_]+)$`)
+)
+
+// Notifications are the settings for notifying a pool's owner about contributions, which the transaction lambda reads
+// from the pool's notifications attribute. They contain secrets like webhook urls and bot tokens, so they are never
+// returned by the api.
+type Notifications struct {
+	Channels []NotificationChannel `json:"channels" dynamodbav:"channels"`
+	// Goal is the total in the pool's base currency that triggers goal notifications.
+	Goal *Amount `json:"goal,omitempty" dynamodbav:"goal,omitempty"`
+}
+
+// NotificationChannel is a destination of notifications: a mail address, a webhook, a Telegram chat or a
+// Discord-compatible chat webhook.
+type NotificationChannel struct {
+	Type     string   `json:"type" dynamodbav:"type"`
+	On       []string `json:"on" dynamodbav:"on"`
+	Address  string   `json:"address,omitempty" dynamodbav:"address,omitempty"`
+	URL      string   `json:"url,omitempty" dynamodbav:"url,omitempty"`
+	BotToken string   `json:"botToken,omitempty" dynamodbav:"botToken,omitempty"`
+	ChatId   string   `json:"chatId,omitempty" dynamodbav:"chatId,omitempty"`
+}
+
+func (n Notifications) validate() error {
+	if len(n.Channels) > maxNotificationChannels {
+		return fmt.Errorf("a pool can have at most %d notification channels", maxNotificationChannels)
+	}
+	if n.Goal != nil && (n.Goal.Negative || n.Goal.Base < 0 || n.Goal.Fraction < 0 || n.Goal.Fraction > 99 || n.Goal.Base+n.Goal.Fraction == 0) {
+		return fmt.Errorf("notification goal must be a positive amount")
+	}
+	for i, channel := range n.Channels {
+		if err := channel.validate(); err != nil {
+			return fmt.Errorf("notification channel %d: %v", i+1, err)
+		}
+		if n.Goal == nil && contains(channel.On, NotifyGoal) {
+			return fmt.Errorf("notification channel %d: goal notifications need a goal", i+1)
+		}
+	}
+	return nil
+}
+
+func (c NotificationChannel) validate() error {
+	if len(c.On) == 0 {
+		return fmt.Errorf("no triggers given, expected some of %s, %s and %s", NotifyPayment, NotifyDaily, NotifyGoal)
+	}
+	for _, trigger := range c.On {
+		if trigger != NotifyPayment && trigger != NotifyDaily && trigger != NotifyGoal {
+			return fmt.Errorf("unknown trigger %s", trigger)
+		}
+	}
+	switch c.Type {
+	case ChannelMail:
+		if !mailAddressPattern.MatchString(c.Address) {
+			return fmt.Errorf("invalid mail address %q", c.Address)
+		}
+	case ChannelWebhook, ChannelDiscord:
+		if !validWebhookURL(c.URL) {
+			return fmt.Errorf("%s url must be an https url", c.Type)
+		}
+	case ChannelTelegram:
+		if !telegramTokenPattern.MatchString(c.BotToken) {
+			return fmt.Errorf("invalid telegram bot token")
+		}
+		if !telegramChatPattern.MatchString(c.ChatId) {
+			return fmt.Errorf("invalid telegram chat id %q", c.ChatId)
+		}
+	default:
+		return fmt.Errorf("unknown type %s, expected %s, %s, %s or %s", c.Type, ChannelMail, ChannelWebhook, ChannelTelegram, ChannelDiscord)
+	}
+	return nil
+}
+
+func validWebhookURL(text string) bool {
+	parsed, err := url.Parse(text)
+	return err == nil && parsed.Scheme == "https" && parsed.Host != ""
+}
+
+// redactNotifications replaces the secrets of audited notification settings.
+func redactNotifications(notifications interface{}) {
+	settings, ok := notifications.(map[string]interface{})
+	if !ok {
+		return
+	}
+	channels, _ := settings["channels"].([]interface{})
+	for _, channel := range channels {
+		values, ok := channel.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, secret := range []string{"url", "botToken"} {
+			if _, exists := values[secret]; exists {
+				values[secret] = redacted
+			}
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
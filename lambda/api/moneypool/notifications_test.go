@@ -0,0 +1,71 @@
+package moneypool
+
+import (
+	er "errors"
+	"reflect"
+	"testing"
+)
+
+func TestNotificationSettings(t *testing.T) {
+	client := NewFakeDynamoClient()
+	handler := NewHandler(testTables, client, fakeVerifier)
+	if _, err := handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "paul", "title": "Gift for Paul"}`)); err != nil {
+		t.Fatalf("CreateMoneyPool returned error %v", err)
+	}
+	settings := `{"notifications": {"goal": {"base": 100, "fraction": 0}, "channels": [
+		{"type": "mail", "address": "owner@example.com", "on": ["daily", "goal"]},
+		{"type": "webhook", "url": "https://example.com/hook?secret=1", "on": ["payment"]},
+		{"type": "telegram", "botToken": "123:abc-DEF", "chatId": "-42", "on": ["payment", "goal"]},
+		{"type": "discord", "url": "https://discord.com/api/webhooks/1/secret", "on": ["goal"]}
+	]}}`
+	if _, err := handler.UpdateMoneyPool(ownerUpdate(settings)); err != nil {
+		t.Fatalf("UpdateMoneyPool(notifications) returned error %v", err)
+	}
+	stored := client.items["paul"]["notifications"]
+	if stored == nil || *stored.M["goal"].M["base"].N != "100" || len(stored.M["channels"].L) != 4 ||
+		*stored.M["channels"].L[2].M["botToken"].S != "123:abc-DEF" || stored.M["channels"].L[0].M["url"] != nil {
+		t.Fatalf("unexpected stored notifications %v", stored)
+	}
+
+	values := auditValues(client.items["paul"])
+	channels := values["notifications"].(map[string]interface{})["channels"].([]interface{})
+	expectedChannels := []interface{}{
+		map[string]interface{}{"type": "mail", "address": "owner@example.com", "on": []interface{}{"daily", "goal"}},
+		map[string]interface{}{"type": "webhook", "url": redacted, "on": []interface{}{"payment"}},
+		map[string]interface{}{"type": "telegram", "botToken": redacted, "chatId": "-42", "on": []interface{}{"payment", "goal"}},
+		map[string]interface{}{"type": "discord", "url": redacted, "on": []interface{}{"goal"}},
+	}
+	if !reflect.DeepEqual(channels, expectedChannels) {
+		t.Fatalf("audited channels %v, but expected %v", channels, expectedChannels)
+	}
+
+	if _, err := handler.UpdateMoneyPool(ownerUpdate(`{"notifications": {"channels": []}}`)); err != nil {
+		t.Fatalf("UpdateMoneyPool(no_notifications) returned error %v", err)
+	}
+	if _, exists := client.items["paul"]["notifications"]; exists {
+		t.Fatalf("notifications were not removed")
+	}
+}
+
+func TestInvalidNotificationSettings(t *testing.T) {
+	testTable := map[string]error{
+		`{"channels": [{"type": "sms", "on": ["payment"]}]}`:                                        er.New("notification channel 1: unknown type sms, expected mail, webhook, telegram or discord"),
+		`{"channels": [{"type": "mail", "address": "owner@example.com", "on": []}]}`:                er.New("notification channel 1: no triggers given, expected some of payment, daily and goal"),
+		`{"channels": [{"type": "mail", "address": "owner@example.com", "on": ["hourly"]}]}`:        er.New("notification channel 1: unknown trigger hourly"),
+		`{"channels": [{"type": "mail", "address": "owner", "on": ["daily"]}]}`:                     er.New(`notification channel 1: invalid mail address "owner"`),
+		`{"channels": [{"type": "webhook", "url": "http://example.com", "on": ["payment"]}]}`:       er.New("notification channel 1: webhook url must be an https url"),
+		`{"channels": [{"type": "telegram", "botToken": "abc", "chatId": "1", "on": ["payment"]}]}`: er.New("notification channel 1: invalid telegram bot token"),
+		`{"channels": [{"type": "mail", "address": "owner@example.com", "on": ["goal"]}]}`:          er.New("notification channel 1: goal notifications need a goal"),
+		`{"goal": {"base": 0, "fraction": 0}, "channels": []}`:                                      er.New("notification goal must be a positive amount"),
+	}
+	handler := NewHandler(testTables, NewFakeDynamoClient(), fakeVerifier)
+	if _, err := handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "paul", "title": "Gift for Paul"}`)); err != nil {
+		t.Fatalf("CreateMoneyPool returned error %v", err)
+	}
+	for notifications, expected := range testTable {
+		_, err := handler.UpdateMoneyPool(ownerUpdate(`{"notifications": ` + notifications + `}`))
+		if !compareErrors(err, expected) {
+			t.Fatalf("UpdateMoneyPool(%s) returned error %v, but expected %v", notifications, err, expected)
+		}
+	}
+}
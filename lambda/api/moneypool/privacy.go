@@ -0,0 +1,92 @@
+package moneypool
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Privacy modes decide how much of its contributors a moneypool reveals.
+const (
+	PrivacyFull        = "full"        // full sender names and amounts
+	PrivacyInitials    = "initials"    // first name plus initial of the last name, e.g. "Paul S."
+	PrivacyAnonymous   = "anonymous"   // no names, contributors are numbered
+	PrivacyHideAmounts = "hideAmounts" // full sender names, but only the total amount
+)
+
+func validPrivacy(mode string) bool {
+	switch mode {
+	case PrivacyFull, PrivacyInitials, PrivacyAnonymous, PrivacyHideAmounts:
+		return true
+	}
+	return false
+}
+
+// applyPrivacy masks the pool's transactions and contributor statuses according to the privacy mode.
+// Contributors that asked for anonymity are never named, regardless of the mode. Pools that hide amounts omit the
+// contributor statuses, since a status next to the expected amount reveals what was paid.
+func applyPrivacy(pool *MoneyPool, mode string) error {
+	if !validPrivacy(mode) {
+		return fmt.Errorf("unknown privacy mode %s", mode)
+	}
+	if mode == PrivacyHideAmounts {
+		total := sumTransactions(pool.Transactions, pool.BaseCurrency, pool.TotalsBasis)
+		pool.Total = &total
+		pool.AmountsHidden = true
+		pool.Contributors = nil
+	}
+	for i := range pool.Transactions {
+		transaction := &pool.Transactions[i]
+		switch {
+		case transaction.Anonymous || mode == PrivacyAnonymous:
+			transaction.Name = fmt.Sprintf("Contributor #%d", i+1)
+		case mode == PrivacyInitials:
+			transaction.Name = initials(transaction.Name)
+		}
+		if mode == PrivacyHideAmounts {
+			transaction.Base = 0
+			transaction.Fraction = 0
+			transaction.Decimals = 0
+			transaction.Fee = nil
+			transaction.Net = nil
+		}
+	}
+	for i := range pool.Contributors {
+		contributor := &pool.Contributors[i]
+		switch {
+		case contributor.anonymous || mode == PrivacyAnonymous:
+			contributor.Name = fmt.Sprintf("Contributor #%d", i+1)
+		case mode == PrivacyInitials:
+			contributor.Name = initials(contributor.Name)
+		}
+	}
+	return nil
+}
+
+// initials shortens a name to its first word and the initial of its last word.
+func initials(name string) string {
+	words := strings.Fields(name)
+	if len(words) < 2 {
+		return name
+	}
+	last := []rune(words[len(words)-1])
+	return fmt.Sprintf("%s %s.", words[0], string(last[0]))
+}
+
+// sumTransactions adds up all contributions in the currency, minus their refunds. Voided transactions don't count.
+// With the net basis, contributions count with the amount received after PayPal's fee, if it is known.
+func sumTransactions(transactions []Transaction, currency, basis string) Amount {
+	var total int
+	digits := precision(currency)
+	for _, transaction := range transactions {
+		switch {
+		case transaction.Voided || transaction.Currency != currency:
+		case transaction.RefundOf != "":
+			total -= transaction.amount().scaled(digits)
+		case basis == TotalsNet && transaction.Net != nil:
+			total += transaction.Net.scaled(digits)
+		default:
+			total += transaction.amount().scaled(digits)
+		}
+	}
+	return scaledAmount(total, digits)
+}
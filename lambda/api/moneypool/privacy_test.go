@@ -0,0 +1,125 @@
+package moneypool
+
+import (
+	er "errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+)
+
+type privacyTest struct {
+	name          string
+	privacy       string
+	expectedPool  MoneyPool
+	expectedError error
+}
+
+func TestGetMoneyPoolPrivacy(t *testing.T) {
+	testTable := []privacyTest{
+		{
+			"default",
+			"",
+			privacyPool([]Transaction{
+				{Id: "id-Sender Middle Person", Name: "Sender Middle Person", Date: "01.02.22", Base: 12, Fraction: 34},
+				{Id: "id-Hidden Person", Name: "Contributor #2", Date: "02.02.22", Base: 5, Fraction: 80, Anonymous: true},
+				{Id: "id-Cher", Name: "Cher", Date: "03.02.22", Base: 1, Fraction: 0},
+			}),
+			nil,
+		},
+		{
+			"full",
+			PrivacyFull,
+			privacyPool([]Transaction{
+				{Id: "id-Sender Middle Person", Name: "Sender Middle Person", Date: "01.02.22", Base: 12, Fraction: 34},
+				{Id: "id-Hidden Person", Name: "Contributor #2", Date: "02.02.22", Base: 5, Fraction: 80, Anonymous: true},
+				{Id: "id-Cher", Name: "Cher", Date: "03.02.22", Base: 1, Fraction: 0},
+			}),
+			nil,
+		},
+		{
+			"initials",
+			PrivacyInitials,
+			privacyPool([]Transaction{
+				{Id: "id-Sender Middle Person", Name: "Sender P.", Date: "01.02.22", Base: 12, Fraction: 34},
+				{Id: "id-Hidden Person", Name: "Contributor #2", Date: "02.02.22", Base: 5, Fraction: 80, Anonymous: true},
+				{Id: "id-Cher", Name: "Cher", Date: "03.02.22", Base: 1, Fraction: 0},
+			}),
+			nil,
+		},
+		{
+			"anonymous",
+			PrivacyAnonymous,
+			privacyPool([]Transaction{
+				{Id: "id-Sender Middle Person", Name: "Contributor #1", Date: "01.02.22", Base: 12, Fraction: 34},
+				{Id: "id-Hidden Person", Name: "Contributor #2", Date: "02.02.22", Base: 5, Fraction: 80, Anonymous: true},
+				{Id: "id-Cher", Name: "Contributor #3", Date: "03.02.22", Base: 1, Fraction: 0},
+			}),
+			nil,
+		},
+		{
+			"hide_amounts",
+			PrivacyHideAmounts,
+			MoneyPool{
+				Name:  "paul",
+				Title: "Gift for Paul",
+				Open:  true,
+				Transactions: []Transaction{
+					{Id: "id-Sender Middle Person", Name: "Sender Middle Person", Date: "01.02.22"},
+					{Id: "id-Hidden Person", Name: "Contributor #2", Date: "02.02.22", Anonymous: true},
+					{Id: "id-Cher", Name: "Cher", Date: "03.02.22"},
+				},
+				AmountsHidden: true,
+				Total:         &Amount{Base: 19, Fraction: 14},
+			},
+			nil,
+		},
+		{
+			"unknown",
+			"secret",
+			MoneyPool{},
+			er.New("moneypool item has unknown privacy mode secret"),
+		},
+	}
+	for _, test := range testTable {
+		item := testPoolItem("paul", "Gift for Paul", true,
+			testTransactionItem("Sender Middle Person", "01.02.22", "12", "34"),
+			withTransactionAttribute(testTransactionItem("Hidden Person", "02.02.22", "5", "80"), "anonymous", &dynamodb.AttributeValue{BOOL: aws.Bool(true)}),
+			testTransactionItem("Cher", "03.02.22", "1", "0"),
+		)
+		if test.privacy != "" {
+			item["privacy"] = &dynamodb.AttributeValue{S: aws.String(test.privacy)}
+		}
+		pool, err := NewHandler(testTables, NewFakeDynamoClient(item), nil).GetMoneyPool(poolRequest("paul"))
+		pool.ETag = ""
+		pool = withoutCurrencies(pool)
+		if !compareErrors(err, test.expectedError) || !reflect.DeepEqual(pool, test.expectedPool) {
+			t.Fatalf("GetMoneyPool(%s) = %+v, %v but expected %+v, %v", test.name, pool, err, test.expectedPool, test.expectedError)
+		}
+	}
+}
+
+func TestInitials(t *testing.T) {
+	testTable := map[string]string{
+		"Sender Person":        "Sender P.",
+		"Björk Guðmundsdóttir": "Björk G.",
+		"  Sender   Person ":   "Sender P.",
+		"Cher":                 "Cher",
+		"秀英 張":                 "秀英 張.",
+		"":                     "",
+	}
+	for name, expected := range testTable {
+		if masked := initials(name); masked != expected {
+			t.Fatalf("initials(%s) = %s, but expected %s", name, masked, expected)
+		}
+	}
+}
+
+func privacyPool(transactions []Transaction) MoneyPool {
+	return MoneyPool{Name: "paul", Title: "Gift for Paul", Open: true, Transactions: transactions}
+}
+
+func withTransactionAttribute(transaction *dynamodb.AttributeValue, attribute string, value *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	transaction.M[attribute] = value
+	return transaction
+}
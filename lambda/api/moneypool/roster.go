@@ -0,0 +1,92 @@
+package moneypool
+
+import (
+	"api/errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	log "github.com/sirupsen/logrus"
+)
+
+// Roster reports which of a pool's expected participants have paid, with their mail addresses and aliases. Amounts are
+// in the pool's base currency.
+type Roster struct {
+	// Expected is the amount expected per person, nil if the roster's participants only pay or don't.
+	Expected     *ExpectedAmount `json:"expected,omitempty"`
+	Participants []RosterEntry   `json:"participants"`
+	// Paid and Unpaid count the participants who paid in full and those with an open balance.
+	Paid   int `json:"paid"`
+	Unpaid int `json:"unpaid"`
+	// Unmatched lists who paid without being linked to a participant, e.g. under a name the roster doesn't know.
+	Unmatched []RosterEntry `json:"unmatched"`
+}
+
+// RosterEntry is a participant, or an unmatched contributor, with the payments that count for them.
+type RosterEntry struct {
+	Participant
+	Status       string   `json:"status"`
+	Paid         Amount   `json:"paid"`
+	Open         Amount   `json:"open"`
+	Transactions []string `json:"transactions"`
+}
+
+// GetRoster returns the pool's roster with the payment status of each participant. It requires admin access to the pool.
+func (h *MoneyPoolsHandler) GetRoster(request events.APIGatewayProxyRequest) (Roster, error) {
+	mpName, mpParamExists := request.PathParameters["moneyPool"]
+	if !mpParamExists {
+		return Roster{}, errors.NewInvalidParametersError(fmt.Errorf("no moneyppol name given"))
+	}
+	h.logger = log.WithFields(log.Fields{"requestedMP": mpName})
+
+	item, err := h.getPoolItem(mpName)
+	if err != nil {
+		return Roster{}, err
+	}
+	if _, err := h.authorize(request, item, mpName, AccessAdmin); err != nil {
+		return Roster{}, err
+	}
+	pool, _, err := decodeUnredactedPool(item)
+	if err != nil {
+		return Roster{}, err
+	}
+	var pi poolItem
+	if err := dynamodbattribute.UnmarshalMap(item, &pi); err != nil {
+		return Roster{}, fmt.Errorf("could not decode moneypool item: %v", err)
+	}
+	if pi.Expected == nil || len(pi.Expected.Participants) == 0 {
+		return Roster{}, errors.NewRosterNotFoundError(fmt.Errorf("moneypool %s has no roster", mpName))
+	}
+	return newRoster(*pi.Expected, contributorStatuses(*pi.Expected, pool.Transactions, pool.BaseCurrency)), nil
+}
+
+// newRoster pairs the participants with their statuses, which list the participants first.
+func newRoster(expected Expected, statuses []ContributorStatus) Roster {
+	roster := Roster{Participants: make([]RosterEntry, 0, len(expected.Participants)), Unmatched: make([]RosterEntry, 0)}
+	if expected.Amount != nil {
+		roster.Expected = &ExpectedAmount{Mode: expected.Mode, Amount: *expected.Amount}
+	}
+	for i, status := range statuses {
+		entry := RosterEntry{
+			Participant:  Participant{Name: status.Name},
+			Status:       status.Status,
+			Paid:         status.Paid,
+			Open:         status.Open,
+			Transactions: status.transactions,
+		}
+		if entry.Transactions == nil {
+			entry.Transactions = make([]string, 0)
+		}
+		if !status.Participant {
+			roster.Unmatched = append(roster.Unmatched, entry)
+			continue
+		}
+		entry.Participant = expected.Participants[i]
+		if entry.Status == StatusOpen || entry.Status == StatusUnderpaid {
+			roster.Unpaid++
+		} else {
+			roster.Paid++
+		}
+		roster.Participants = append(roster.Participants, entry)
+	}
+	return roster
+}
@@ -0,0 +1,79 @@
+package moneypool
+
+import (
+	"api/errors"
+	er "errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+)
+
+func TestGetRoster(t *testing.T) {
+	client := NewFakeDynamoClient()
+	handler := NewHandler(testTables, client, fakeVerifier)
+	if _, err := handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "paul", "title": "Class trip"}`)); err != nil {
+		t.Fatalf("CreateMoneyPool returned error %v", err)
+	}
+	if _, err := handler.GetRoster(ownerRoster()); !compareErrors(err, errors.NewRosterNotFoundError(er.New("moneypool paul has no roster"))) || !hasCode(err, errors.CodeRosterNotFound) {
+		t.Fatalf("GetRoster(no_roster) returned error %v", err)
+	}
+
+	roster := `{"expected": {"participants": [{"name": "Anna Schmidt", "email": "anna@example.com", "aliases": [" Maria Schmidt "]},
+		{"name": "Ben Meyer"}]}}`
+	if _, err := handler.UpdateMoneyPool(ownerUpdate(roster)); err != nil {
+		t.Fatalf("UpdateMoneyPool(roster) returned error %v", err)
+	}
+	linked := testTransactionItem("B. Meyer", "01.03.22", "10", "0")
+	linked.M["participant"] = &dynamodb.AttributeValue{S: aws.String(participantId(Participant{Name: "Ben Meyer"}))}
+	client.items["paul"]["transactions"] = &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{
+		linked,
+		testTransactionItem("Dora Klein", "02.03.22", "5", "0"),
+	}}
+
+	got, err := handler.GetRoster(ownerRoster())
+	if err != nil {
+		t.Fatalf("GetRoster returned error %v", err)
+	}
+	expected := Roster{
+		Participants: []RosterEntry{
+			{
+				Participant:  Participant{Id: participantId(Participant{Email: "anna@example.com"}), Name: "Anna Schmidt", Email: "anna@example.com", Aliases: []string{"Maria Schmidt"}},
+				Status:       StatusOpen,
+				Transactions: []string{},
+			},
+			{
+				Participant:  Participant{Id: participantId(Participant{Name: "Ben Meyer"}), Name: "Ben Meyer"},
+				Status:       StatusPaid,
+				Paid:         Amount{Base: 10},
+				Transactions: []string{"id-B. Meyer"},
+			},
+		},
+		Paid:      1,
+		Unpaid:    1,
+		Unmatched: []RosterEntry{{Participant: Participant{Name: "Dora Klein"}, Status: StatusPaid, Paid: Amount{Base: 5}, Transactions: []string{"id-Dora Klein"}}},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("GetRoster returned %+v, but expected %+v", got, expected)
+	}
+
+	// payments under an alias count for the participant, and the pool's public response doesn't list a roster without amount
+	client.items["paul"]["transactions"].L = append(client.items["paul"]["transactions"].L, testTransactionItem("maria schmidt", "03.03.22", "10", "0"))
+	if got, err = handler.GetRoster(ownerRoster()); err != nil || got.Participants[0].Status != StatusPaid || got.Unpaid != 0 {
+		t.Fatalf("GetRoster(alias) returned %+v, %v", got, err)
+	}
+	pool, err := handler.GetMoneyPool(poolRequest("paul"))
+	if err != nil || pool.Contributors != nil || pool.Expected != nil {
+		t.Fatalf("GetMoneyPool returned %+v, %v", pool, err)
+	}
+
+	_, err = handler.GetRoster(withHeader(ownerRoster(), "Authorization", "Bearer "+otherJwt))
+	if !compareErrors(err, errors.NewForbiddenError(er.New("token does not allow to administrate moneypool paul"))) {
+		t.Fatalf("GetRoster(other) returned error %v", err)
+	}
+}
+
+func ownerRoster() events.APIGatewayProxyRequest {
+	return withHeader(poolRequest("paul"), "Authorization", "Bearer "+ownerJwt)
+}
@@ -0,0 +1,213 @@
+package moneypool
+
+import (
+	"api/errors"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	log "github.com/sirupsen/logrus"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// TenantsAddressIndex is the index of the tenants table by receiving mail address.
+const TenantsAddressIndex = "address-index"
+
+// tenantSeparator joins a tenant's name and a pool's name to the pool's key. Neither of the names can contain it, so pool
+// names only need to be unique per tenant.
+const tenantSeparator = "."
+
+// Tenant is an owner's receiving mail address, together with the profile to parse the payment notifications sent to it.
+// Empty profile fields fall back to the deployment's defaults.
+type Tenant struct {
+	Name            string `json:"name" dynamodbav:"tenant"`
+	Address         string `json:"address" dynamodbav:"address"`
+	ExpectedSubject string `json:"expectedSubject,omitempty" dynamodbav:"expectedSubject,omitempty"`
+	NameAmountRegex string `json:"nameAmountRegex,omitempty" dynamodbav:"nameAmountRegex,omitempty"`
+	Owner           string `json:"-" dynamodbav:"owner"`
+}
+
+// poolKey returns the key of a pool in the moneypools table. Pools without tenant keep their plain name.
+func poolKey(tenant, name string) string {
+	if tenant == "" {
+		return name
+	}
+	return tenant + tenantSeparator + name
+}
+
+// WithTenantMail enables the registration of tenants with receiving addresses in the mail domain, which is either a
+// domain like 'example.com' or a subdomain pattern like '.example.com'. The default address, which receives the
+// notifications of pools without tenant, is never given to a tenant. Without mail domain, registrations are rejected.
+func (h *MoneyPoolsHandler) WithTenantMail(domain, defaultAddress string) *MoneyPoolsHandler {
+	h.mailDomain = strings.ToLower(strings.TrimSpace(domain))
+	h.mailDefault = strings.ToLower(strings.TrimSpace(defaultAddress))
+	return h
+}
+
+// RegisterTenant creates or changes the caller's tenant. It requires a valid bearer token.
+func (h *MoneyPoolsHandler) RegisterTenant(request events.APIGatewayProxyRequest) (Tenant, error) {
+	claims, err := h.authenticate(request)
+	if err != nil {
+		return Tenant{}, err
+	}
+	if claims.Subject == "" {
+		return Tenant{}, errors.NewUnauthorizedError(fmt.Errorf("registering a tenant requires a bearer token"))
+	}
+
+	name, nameParamExists := request.PathParameters["tenant"]
+	if !nameParamExists {
+		return Tenant{}, errors.NewInvalidParametersError(fmt.Errorf("no tenant name given"))
+	}
+	var tenant Tenant
+	if err := json.Unmarshal([]byte(request.Body), &tenant); err != nil {
+		return Tenant{}, errors.NewInvalidParametersError(fmt.Errorf("invalid tenant body: %v", err))
+	}
+	if h.mailDomain == "" {
+		return Tenant{}, errors.NewNotConfiguredError(fmt.Errorf("tenants are not configured"))
+	}
+	tenant.Name = strings.ToLower(name)
+	tenant.Owner = claims.Subject
+	if err := tenant.normalize(h.mailDomain, h.mailDefault); err != nil {
+		return Tenant{}, errors.NewInvalidParametersError(err)
+	}
+	h.logger = log.WithFields(log.Fields{"tenant": tenant.Name, "owner": claims.Subject})
+
+	existing, err := h.findTenantByAddress(tenant.Address)
+	if err != nil {
+		return Tenant{}, err
+	}
+	if existing != "" && existing != tenant.Name {
+		return Tenant{}, errors.NewConflictError(fmt.Errorf("address %s is already used by another tenant", tenant.Address))
+	}
+	if err := h.putTenantItem(tenant); err != nil {
+		return Tenant{}, err
+	}
+	h.logger.Infof("registered tenant")
+	return tenant, nil
+}
+
+func (t *Tenant) normalize(mailDomain, defaultAddress string) error {
+	length := utf8.RuneCountInString(t.Name)
+	if length < minNameLength || length > maxNameLength || !namePattern.MatchString(t.Name) {
+		return fmt.Errorf("tenant name must have between %d and %d letters, digits, '-' or '_'", minNameLength, maxNameLength)
+	}
+	address, err := mail.ParseAddress(t.Address)
+	if err != nil {
+		return fmt.Errorf("invalid address %s: %v", t.Address, err)
+	}
+	t.Address = strings.ToLower(address.Address)
+	if t.Address == defaultAddress {
+		return fmt.Errorf("address %s is reserved for pools without tenant", t.Address)
+	}
+	if !inMailDomain(t.Address, mailDomain) {
+		return fmt.Errorf("address %s is not in the tenant mail domain %s", t.Address, mailDomain)
+	}
+	t.ExpectedSubject = strings.TrimSpace(t.ExpectedSubject)
+	if t.NameAmountRegex == "" {
+		return nil
+	}
+	re, err := regexp.Compile(t.NameAmountRegex)
+	if err != nil {
+		return fmt.Errorf("invalid nameAmountRegex: %v", err)
+	}
+	if re.SubexpIndex("name") < 0 || re.SubexpIndex("amount") < 0 {
+		return fmt.Errorf("nameAmountRegex must contain the named groups 'name' and 'amount'")
+	}
+	return nil
+}
+
+// inMailDomain reports whether the address belongs to the domain, or to any subdomain if the domain starts with a dot.
+func inMailDomain(address, domain string) bool {
+	at := strings.LastIndex(address, "@")
+	if at < 0 || domain == "" {
+		return false
+	}
+	host := address[at+1:]
+	if strings.HasPrefix(domain, ".") {
+		return strings.HasSuffix(host, domain)
+	}
+	return host == domain
+}
+
+// findTenantByAddress returns the name of the tenant receiving mails at the address, or an empty name if there is none.
+func (h *MoneyPoolsHandler) findTenantByAddress(address string) (string, error) {
+	out, err := h.dynamoClient.Query(&dynamodb.QueryInput{
+		TableName:              aws.String(h.tables.Tenants),
+		IndexName:              aws.String(TenantsAddressIndex),
+		KeyConditionExpression: aws.String("#address = :address"),
+		ExpressionAttributeNames: map[string]*string{
+			"#address": aws.String("address"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":address": {S: aws.String(address)},
+		},
+	})
+	if err != nil {
+		return "", errors.NewStoreUnavailableError(fmt.Errorf("error reading tenants from db: %v", err))
+	}
+	for _, item := range out.Items {
+		if item["tenant"] != nil && item["tenant"].S != nil {
+			return *item["tenant"].S, nil
+		}
+	}
+	return "", nil
+}
+
+// putTenantItem writes the tenant, unless it was registered by another owner.
+func (h *MoneyPoolsHandler) putTenantItem(tenant Tenant) error {
+	item, err := dynamodbattribute.MarshalMap(tenant)
+	if err != nil {
+		return fmt.Errorf("could not encode tenant: %v", err)
+	}
+	item["updatedAt"] = &dynamodb.AttributeValue{S: aws.String(time.Now().UTC().Format(time.RFC3339))}
+	_, err = h.dynamoClient.PutItem(&dynamodb.PutItemInput{
+		TableName:           aws.String(h.tables.Tenants),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(#tenant) OR #owner = :owner"),
+		ExpressionAttributeNames: map[string]*string{
+			"#tenant": aws.String("tenant"),
+			"#owner":  aws.String("owner"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":owner": {S: aws.String(tenant.Owner)},
+		},
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return errors.NewConflictError(fmt.Errorf("tenant %s belongs to another owner", tenant.Name))
+		}
+		return errors.NewStoreUnavailableError(fmt.Errorf("error writing tenant to db: %v", err))
+	}
+	return nil
+}
+
+// getTenant reads a tenant and fails if it is not owned by the owner.
+func (h *MoneyPoolsHandler) getTenant(name, owner string) (Tenant, error) {
+	out, err := h.dynamoClient.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(h.tables.Tenants),
+		Key: map[string]*dynamodb.AttributeValue{
+			"tenant": {S: aws.String(name)},
+		},
+	})
+	if err != nil {
+		return Tenant{}, errors.NewStoreUnavailableError(fmt.Errorf("error reading tenant from db: %v", err))
+	}
+	if out.Item == nil {
+		return Tenant{}, errors.NewInvalidParametersError(fmt.Errorf("unknown tenant %s", name))
+	}
+	var tenant Tenant
+	if err := dynamodbattribute.UnmarshalMap(out.Item, &tenant); err != nil {
+		return Tenant{}, fmt.Errorf("could not decode tenant: %v", err)
+	}
+	if tenant.Owner != owner {
+		return Tenant{}, errors.NewForbiddenError(fmt.Errorf("tenant %s belongs to another owner", name))
+	}
+	return tenant, nil
+}
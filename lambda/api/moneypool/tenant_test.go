@@ -0,0 +1,178 @@
+package moneypool
+
+import (
+	"api/errors"
+	er "errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+)
+
+type tenantTest struct {
+	name           string
+	request        events.APIGatewayProxyRequest
+	expectedTenant Tenant
+	expectedError  error
+}
+
+func TestRegisterTenant(t *testing.T) {
+	testTable := []tenantTest{
+		{
+			"register",
+			tenantRequest(ownerJwt, "Smiths", `{"address": "Smith Pools <Pools+Smiths@Example.com>", "expectedSubject": " You received money "}`),
+			Tenant{Name: "smiths", Address: "pools+smiths@example.com", ExpectedSubject: "You received money", Owner: "owner-sub"},
+			nil,
+		},
+		{
+			"change_own_tenant",
+			tenantRequest(ownerJwt, "millers", `{"address": "millers@example.com", "nameAmountRegex": "(?P<name>.+) sent you (?P<amount>.+)"}`),
+			Tenant{Name: "millers", Address: "millers@example.com", NameAmountRegex: "(?P<name>.+) sent you (?P<amount>.+)", Owner: "owner-sub"},
+			nil,
+		},
+		{
+			"no_bearer_token",
+			tenantRequest("", "smiths", `{"address": "smiths@example.com"}`),
+			Tenant{},
+			errors.NewUnauthorizedError(er.New("registering a tenant requires a bearer token")),
+		},
+		{
+			"invalid_name",
+			tenantRequest(ownerJwt, "smith.family", `{"address": "smiths@example.com"}`),
+			Tenant{},
+			errors.NewInvalidParametersError(er.New("tenant name must have between 2 and 40 letters, digits, '-' or '_'")),
+		},
+		{
+			"invalid_address",
+			tenantRequest(ownerJwt, "smiths", `{"address": "smiths"}`),
+			Tenant{},
+			errors.NewInvalidParametersError(er.New("invalid address smiths: mail: missing '@' or angle-addr")),
+		},
+		{
+			"regex_without_groups",
+			tenantRequest(ownerJwt, "smiths", `{"address": "smiths@example.com", "nameAmountRegex": "(.+) sent you (.+)"}`),
+			Tenant{},
+			errors.NewInvalidParametersError(er.New("nameAmountRegex must contain the named groups 'name' and 'amount'")),
+		},
+		{
+			"address_outside_mail_domain",
+			tenantRequest(ownerJwt, "smiths", `{"address": "smiths@example.org"}`),
+			Tenant{},
+			errors.NewInvalidParametersError(er.New("address smiths@example.org is not in the tenant mail domain example.com")),
+		},
+		{
+			"default_address",
+			tenantRequest(ownerJwt, "smiths", `{"address": "Pools@Example.com"}`),
+			Tenant{},
+			errors.NewInvalidParametersError(er.New("address pools@example.com is reserved for pools without tenant")),
+		},
+		{
+			"address_of_other_tenant",
+			tenantRequest(ownerJwt, "smiths", `{"address": "Millers@example.com"}`),
+			Tenant{},
+			errors.NewConflictError(er.New("address millers@example.com is already used by another tenant")),
+		},
+		{
+			"tenant_of_other_owner",
+			tenantRequest(otherJwt, "millers", `{"address": "millers@example.com"}`),
+			Tenant{},
+			errors.NewConflictError(er.New("tenant millers belongs to another owner")),
+		},
+	}
+	for _, test := range testTable {
+		client := NewFakeDynamoClient()
+		client.tenants["millers"] = tenantItem("millers", "millers@example.com", "owner-sub")
+		handler := NewHandler(testTables, client, fakeVerifier).WithTenantMail("example.com", "pools@example.com")
+		tenant, err := handler.RegisterTenant(test.request)
+		if !compareErrors(err, test.expectedError) || !reflect.DeepEqual(tenant, test.expectedTenant) {
+			t.Fatalf("RegisterTenant(%s) = %+v, %v but expected %+v, %v", test.name, tenant, err, test.expectedTenant, test.expectedError)
+		}
+		if err != nil && client.updates > 0 {
+			t.Fatalf("RegisterTenant(%s) failed, but still wrote the tenant", test.name)
+		}
+	}
+}
+
+func TestRegisterTenantWithoutMailDomain(t *testing.T) {
+	client := NewFakeDynamoClient()
+	_, err := NewHandler(testTables, client, fakeVerifier).RegisterTenant(tenantRequest(ownerJwt, "smiths", `{"address": "smiths@example.com"}`))
+	expected := errors.NewNotConfiguredError(er.New("tenants are not configured"))
+	if !compareErrors(err, expected) || !hasCode(err, errors.CodeNotConfigured) || client.updates > 0 {
+		t.Fatalf("RegisterTenant(no_mail_domain) returned error %v, but should return %v", err, expected)
+	}
+}
+
+func TestInMailDomain(t *testing.T) {
+	testTable := []struct {
+		address, domain string
+		expected        bool
+	}{
+		{"smiths@example.com", "example.com", true},
+		{"smiths@pools.example.com", "example.com", false},
+		{"smiths@pools.example.com", ".example.com", true},
+		{"smiths@example.com", ".example.com", false},
+		{"smiths@badexample.com", "example.com", false},
+		{"smiths@badexample.com", ".example.com", false},
+		{"smiths@example.com", "", false},
+	}
+	for _, test := range testTable {
+		if actual := inMailDomain(test.address, test.domain); actual != test.expected {
+			t.Errorf("inMailDomain(%s, %s) = %v, but expected %v", test.address, test.domain, actual, test.expected)
+		}
+	}
+}
+
+func TestCreateMoneyPoolInTenant(t *testing.T) {
+	client := NewFakeDynamoClient(testPoolItem("mom", "Gift for Mom", true))
+	client.tenants["smiths"] = tenantItem("smiths", "smiths@example.com", "owner-sub")
+	client.tenants["millers"] = tenantItem("millers", "millers@example.com", "other-sub")
+	handler := NewHandler(testTables, client, fakeVerifier)
+
+	pool, err := handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "mom", "title": "Gift for Mom", "tenant": "smiths"}`))
+	if err != nil || pool.Name != "smiths.mom" {
+		t.Fatalf("CreateMoneyPool(tenant_pool) = %+v, %v but expected pool smiths.mom", pool, err)
+	}
+	if tenant := client.items["smiths.mom"]["tenant"]; tenant == nil || *tenant.S != "smiths" {
+		t.Fatalf("CreateMoneyPool(tenant_pool) stored tenant %v, but should store smiths", tenant)
+	}
+
+	_, err = handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "mo", "title": "Another gift", "tenant": "smiths"}`))
+	var expected error = errors.NewConflictError(er.New("name mo collides with existing moneypool mom"))
+	if !compareErrors(err, expected) {
+		t.Fatalf("CreateMoneyPool(colliding_tenant_pool) returned error %v, but should return %v", err, expected)
+	}
+
+	_, err = handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "dad", "title": "Gift for Dad", "tenant": "millers"}`))
+	expected = errors.NewForbiddenError(er.New("tenant millers belongs to another owner"))
+	if !compareErrors(err, expected) {
+		t.Fatalf("CreateMoneyPool(other_owners_tenant) returned error %v, but should return %v", err, expected)
+	}
+
+	_, err = handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "dad", "title": "Gift for Dad", "tenant": "joneses"}`))
+	expected = errors.NewInvalidParametersError(er.New("unknown tenant joneses"))
+	if !compareErrors(err, expected) {
+		t.Fatalf("CreateMoneyPool(unknown_tenant) returned error %v, but should return %v", err, expected)
+	}
+}
+
+func tenantRequest(jwt, tenant, body string) events.APIGatewayProxyRequest {
+	request := events.APIGatewayProxyRequest{
+		HTTPMethod:     "PUT",
+		Resource:       "/tenants/{tenant}",
+		PathParameters: map[string]string{"tenant": tenant},
+		Body:           body,
+	}
+	if jwt != "" {
+		request = withHeader(request, "Authorization", "Bearer "+jwt)
+	}
+	return request
+}
+
+func tenantItem(tenant, address, owner string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"tenant":  {S: aws.String(tenant)},
+		"address": {S: aws.String(address)},
+		"owner":   {S: aws.String(owner)},
+	}
+}
@@ -0,0 +1,56 @@
+package moneypool
+
+import (
+	"fmt"
+	"io/ioutil"
+	"text/template"
+)
+
+const (
+	maxThankYouSubjectLength  = 200
+	maxThankYouTemplateLength = 5000
+)
+
+// ThankYou is the mail that thanks contributors once their payment is credited, which the transaction lambda reads from
+// the pool's thankYou attribute. Subject and template are Go text/templates of thankYouData; if they are empty, the
+// transaction lambda's defaults are used.
+type ThankYou struct {
+	Enabled  bool   `json:"enabled" dynamodbav:"enabled"`
+	Subject  string `json:"subject,omitempty" dynamodbav:"subject,omitempty"`
+	Template string `json:"template,omitempty" dynamodbav:"template,omitempty"`
+}
+
+// thankYouData has the fields the transaction lambda executes thank-you templates with.
+type thankYouData struct {
+	Title  string
+	Name   string
+	Amount string
+	Total  string
+}
+
+func (t ThankYou) validate() error {
+	if len(t.Subject) > maxThankYouSubjectLength {
+		return fmt.Errorf("thank-you subject must have at most %d characters", maxThankYouSubjectLength)
+	}
+	if len(t.Template) > maxThankYouTemplateLength {
+		return fmt.Errorf("thank-you template must have at most %d characters", maxThankYouTemplateLength)
+	}
+	if err := validateThankYouTemplate("subject", t.Subject); err != nil {
+		return err
+	}
+	return validateThankYouTemplate("template", t.Template)
+}
+
+// validateThankYouTemplate parses the template and executes it with sample data, so templates using unknown fields are
+// rejected before any contributor is thanked.
+func validateThankYouTemplate(name, text string) error {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid thank-you %s: %v", name, err)
+	}
+	sample := thankYouData{Title: "Gift", Name: "Anna", Amount: "10.00 EUR", Total: "50.00 EUR"}
+	if err := tmpl.Execute(ioutil.Discard, sample); err != nil {
+		return fmt.Errorf("invalid thank-you %s: %v", name, err)
+	}
+	return nil
+}
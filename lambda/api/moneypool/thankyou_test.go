@@ -0,0 +1,55 @@
+package moneypool
+
+import (
+	er "errors"
+	"testing"
+)
+
+func TestThankYouSettings(t *testing.T) {
+	client := NewFakeDynamoClient()
+	handler := NewHandler(testTables, client, fakeVerifier)
+	if _, err := handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "paul", "title": "Gift for Paul"}`)); err != nil {
+		t.Fatalf("CreateMoneyPool returned error %v", err)
+	}
+	settings := `{"thankYou": {"enabled": true, "subject": "Danke für {{.Title}}", "template": "Danke {{.Name}} für {{.Amount}}!"}}`
+	if _, err := handler.UpdateMoneyPool(ownerUpdate(settings)); err != nil {
+		t.Fatalf("UpdateMoneyPool(thank_you) returned error %v", err)
+	}
+	stored := client.items["paul"]["thankYou"]
+	if stored == nil || !*stored.M["enabled"].BOOL || *stored.M["subject"].S != "Danke für {{.Title}}" ||
+		*stored.M["template"].S != "Danke {{.Name}} für {{.Amount}}!" {
+		t.Fatalf("unexpected stored thank-you mail %v", stored)
+	}
+
+	if _, err := handler.UpdateMoneyPool(ownerUpdate(`{"thankYou": {"enabled": true}}`)); err != nil {
+		t.Fatalf("UpdateMoneyPool(default_thank_you) returned error %v", err)
+	}
+	if stored := client.items["paul"]["thankYou"]; stored.M["subject"] != nil || stored.M["template"] != nil {
+		t.Fatalf("unexpected stored thank-you mail %v", stored)
+	}
+
+	if _, err := handler.UpdateMoneyPool(ownerUpdate(`{"thankYou": {"enabled": false}}`)); err != nil {
+		t.Fatalf("UpdateMoneyPool(no_thank_you) returned error %v", err)
+	}
+	if _, exists := client.items["paul"]["thankYou"]; exists {
+		t.Fatalf("thank-you mail was not removed")
+	}
+}
+
+func TestInvalidThankYouSettings(t *testing.T) {
+	testTable := map[string]error{
+		`{"enabled": true, "template": "Danke {{.Name"}`:      er.New("invalid thank-you template: template: template:1: unclosed action"),
+		`{"enabled": true, "template": "Danke {{.Address}}"}`: er.New(`invalid thank-you template: template: template:1:8: executing "template" at <.Address>: can't evaluate field Address in type moneypool.thankYouData`),
+		`{"enabled": true, "subject": "{{end}}"}`:             er.New("invalid thank-you subject: template: subject:1: unexpected {{end}}"),
+	}
+	handler := NewHandler(testTables, NewFakeDynamoClient(), fakeVerifier)
+	if _, err := handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "paul", "title": "Gift for Paul"}`)); err != nil {
+		t.Fatalf("CreateMoneyPool returned error %v", err)
+	}
+	for thankYou, expected := range testTable {
+		_, err := handler.UpdateMoneyPool(ownerUpdate(`{"thankYou": ` + thankYou + `}`))
+		if !compareErrors(err, expected) {
+			t.Fatalf("UpdateMoneyPool(%s) returned error %v, but expected %v", thankYou, err, expected)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package moneypool
+
+import (
+	"api/errors"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxReasonLength = 500
+
+// maxCorrections limits the corrections kept in the pool item, which can't grow beyond 400 KB. Older corrections are
+// dropped; the audit log keeps all of them.
+const maxCorrections = 50
+
+// Correction actions, as recorded in a pool's corrections.
+const (
+	CorrectionEdit   = "edit"
+	CorrectionVoid   = "void"
+	CorrectionUnvoid = "unvoid"
+	CorrectionDelete = "delete"
+)
+
+// TransactionCorrection holds the changes an admin makes to a transaction. Fields that are not set stay unchanged.
+type TransactionCorrection struct {
+	Name     *string `json:"name"`
+	Base     *int    `json:"base"`
+	Fraction *int    `json:"fraction"`
+	Voided   *bool   `json:"voided"`
+	// Reason is required and kept in the pool's corrections, together with the caller and the time.
+	Reason string `json:"reason"`
+}
+
+// CorrectTransaction edits, voids or restores a transaction by its stored id. It requires admin access to the pool.
+func (h *MoneyPoolsHandler) CorrectTransaction(request events.APIGatewayProxyRequest) (MoneyPool, error) {
+	var correction TransactionCorrection
+	if err := json.Unmarshal([]byte(request.Body), &correction); err != nil {
+		return MoneyPool{}, errors.NewInvalidParametersError(fmt.Errorf("invalid correction body: %v", err))
+	}
+	if err := correction.validate(); err != nil {
+		return MoneyPool{}, errors.NewInvalidParametersError(err)
+	}
+	return h.changeTransaction(request, correction.Reason, correction.apply)
+}
+
+// DeleteTransaction removes a transaction by its stored id. The reason is passed as ?reason= query parameter.
+// The removed transaction is kept in the pool's corrections. It requires admin access to the pool.
+func (h *MoneyPoolsHandler) DeleteTransaction(request events.APIGatewayProxyRequest) (MoneyPool, error) {
+	reason := request.QueryStringParameters["reason"]
+	if err := validateReason(reason); err != nil {
+		return MoneyPool{}, errors.NewInvalidParametersError(err)
+	}
+	return h.changeTransaction(request, reason, func(map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, string, error) {
+		return nil, CorrectionDelete, nil
+	})
+}
+
+func (c TransactionCorrection) validate() error {
+	if c.Name == nil && c.Base == nil && c.Fraction == nil && c.Voided == nil {
+		return fmt.Errorf("correction contains no changes")
+	}
+	if c.Name != nil && (strings.TrimSpace(*c.Name) == "" || len(*c.Name) > maxTitleLength) {
+		return fmt.Errorf("name must have between 1 and %d characters", maxTitleLength)
+	}
+	if c.Base != nil && *c.Base < 0 {
+		return fmt.Errorf("base must not be negative")
+	}
+	if c.Fraction != nil && *c.Fraction < 0 {
+		return fmt.Errorf("fraction must not be negative")
+	}
+	return validateReason(c.Reason)
+}
+
+func validateReason(reason string) error {
+	if strings.TrimSpace(reason) == "" || len(reason) > maxReasonLength {
+		return fmt.Errorf("reason must have between 1 and %d characters", maxReasonLength)
+	}
+	return nil
+}
+
+// apply returns a corrected copy of the transaction item and the kind of the correction. Corrected amounts keep their
+// fee, and their net amount is recomputed.
+func (c TransactionCorrection) apply(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, string, error) {
+	corrected := make(map[string]*dynamodb.AttributeValue, len(item))
+	for attribute, value := range item {
+		corrected[attribute] = value
+	}
+	action := CorrectionEdit
+	if c.Name != nil {
+		corrected["name"] = &dynamodb.AttributeValue{S: aws.String(strings.TrimSpace(*c.Name))}
+	}
+	if c.Base != nil {
+		corrected["base"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(*c.Base))}
+	}
+	if c.Fraction != nil {
+		// the fraction has the digits of the stored amount, e.g. thousandths for currencies like KWD
+		var stored Amount
+		if digits := item["decimals"]; digits != nil && digits.N != nil {
+			stored.Decimals, _ = strconv.Atoi(*digits.N)
+		}
+		if max := pow10(stored.digits()) - 1; *c.Fraction > max {
+			return nil, "", fmt.Errorf("fraction must be between 0 and %d", max)
+		}
+		corrected["fraction"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(*c.Fraction))}
+	}
+	if c.Base != nil || c.Fraction != nil {
+		correctNet(corrected)
+	}
+	if c.Voided != nil {
+		wasVoided := item["voided"] != nil && item["voided"].BOOL != nil && *item["voided"].BOOL
+		if *c.Voided == wasVoided && c.Name == nil && c.Base == nil && c.Fraction == nil {
+			return nil, "", fmt.Errorf("transaction is already voided=%t", wasVoided)
+		}
+		if *c.Voided {
+			corrected["voided"] = &dynamodb.AttributeValue{BOOL: aws.Bool(true)}
+			action = CorrectionVoid
+		} else {
+			delete(corrected, "voided")
+			if wasVoided {
+				action = CorrectionUnvoid
+			}
+		}
+	}
+	// validate the result the same way transactions are decoded, so corrections can't corrupt a pool
+	if _, err := decodeTransaction(&dynamodb.AttributeValue{M: corrected}); err != nil {
+		return nil, "", fmt.Errorf("corrected transaction is invalid: %v", err)
+	}
+	return corrected, action, nil
+}
+
+// changeTransaction replaces or, if change returns nil, removes the transaction given by the path parameters and
+// records the change in the pool's corrections.
+func (h *MoneyPoolsHandler) changeTransaction(request events.APIGatewayProxyRequest, reason string, change func(map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, string, error)) (MoneyPool, error) {
+	mpName, mpParamExists := request.PathParameters["moneyPool"]
+	if !mpParamExists {
+		return MoneyPool{}, errors.NewInvalidParametersError(fmt.Errorf("no moneyppol name given"))
+	}
+	trId, idParamExists := request.PathParameters["transactionId"]
+	if !idParamExists {
+		return MoneyPool{}, errors.NewInvalidParametersError(fmt.Errorf("no transaction id given"))
+	}
+	h.logger = log.WithFields(log.Fields{"requestedMP": mpName, "transactionId": trId})
+
+	item, err := h.getPoolItem(mpName)
+	if err != nil {
+		return MoneyPool{}, err
+	}
+	claims, err := h.authorize(request, item, mpName, AccessAdmin)
+	if err != nil {
+		return MoneyPool{}, err
+	}
+
+	transactions := item["transactions"]
+	index := -1
+	if transactions != nil {
+		for i, transaction := range transactions.L {
+			if trId != "" && trId == transactionId(transaction) {
+				index = i
+				break
+			}
+		}
+	}
+	if index < 0 {
+		return MoneyPool{}, errors.NewTransactionNotFoundError(fmt.Errorf("no transaction found for given id %s in moneypool %s", trId, mpName))
+	}
+
+	original := transactions.L[index].M
+	changed, action, err := change(original)
+	if err != nil {
+		return MoneyPool{}, errors.NewInvalidParametersError(err)
+	}
+	updatedTransactions := make([]*dynamodb.AttributeValue, 0, len(transactions.L))
+	updatedTransactions = append(updatedTransactions, transactions.L[:index]...)
+	if changed != nil {
+		updatedTransactions = append(updatedTransactions, &dynamodb.AttributeValue{M: changed})
+	}
+	updatedTransactions = append(updatedTransactions, transactions.L[index+1:]...)
+
+	record := map[string]*dynamodb.AttributeValue{
+		"transactionId": {S: aws.String(trId)},
+		"action":        {S: aws.String(action)},
+		"actor":         {S: aws.String(actor(claims))},
+		"at":            {S: aws.String(time.Now().UTC().Format(time.RFC3339))},
+		"reason":        {S: aws.String(strings.TrimSpace(reason))},
+		"before":        {M: original},
+	}
+	if changed != nil {
+		record["after"] = &dynamodb.AttributeValue{M: changed}
+	}
+	var corrections []*dynamodb.AttributeValue
+	if existing := item["corrections"]; existing != nil {
+		corrections = append(corrections, existing.L...)
+	}
+	corrections = append(corrections, &dynamodb.AttributeValue{M: record})
+	if len(corrections) > maxCorrections {
+		corrections = corrections[len(corrections)-maxCorrections:]
+	}
+
+	updated, err := h.writeTransactions(mpName, transactions, updatedTransactions, corrections)
+	if err != nil {
+		return MoneyPool{}, err
+	}
+	h.logger.Infof("corrected transaction: %s", action)
+	h.audit(request, AuditEntry{
+		Pool:          mpName,
+		Action:        correctionAuditActions[action],
+		Actor:         actor(claims),
+		TransactionId: trId,
+		Old:           auditValues(original),
+		New:           auditValues(changed),
+	})
+
+	pool, err := decodePool(updated)
+	if err != nil {
+		return MoneyPool{}, err
+	}
+	h.convertTotals(&pool)
+	pool.ETag, err = itemETag(updated, h.ratesVersion())
+	if err != nil {
+		return MoneyPool{}, err
+	}
+	return pool, nil
+}
+
+// writeTransactions replaces the pool's transactions and corrections, unless the transactions were changed since they
+// were read, e.g. by a new contribution.
+func (h *MoneyPoolsHandler) writeTransactions(mpName string, old *dynamodb.AttributeValue, transactions, corrections []*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+	out, err := h.dynamoClient.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(h.tables.MoneyPools),
+		Key: map[string]*dynamodb.AttributeValue{
+			"name": {S: aws.String(mpName)},
+		},
+		UpdateExpression:    aws.String("SET #transactions = :transactions, #corrections = :corrections"),
+		ConditionExpression: aws.String("#transactions = :oldTransactions"),
+		ExpressionAttributeNames: map[string]*string{
+			"#transactions": aws.String("transactions"),
+			"#corrections":  aws.String("corrections"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":transactions":    {L: transactions},
+			":corrections":     {L: corrections},
+			":oldTransactions": old,
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return nil, errors.NewConflictError(fmt.Errorf("transactions of moneypool %s were changed concurrently", mpName))
+		}
+		return nil, errors.NewStoreUnavailableError(fmt.Errorf("error updating transactions in db: %v", err))
+	}
+	return out.Attributes, nil
+}
@@ -0,0 +1,274 @@
+package moneypool
+
+import (
+	"api/errors"
+	er "errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+)
+
+type correctionTest struct {
+	name                 string
+	request              events.APIGatewayProxyRequest
+	expectedTransactions []Transaction
+	expectedError        error
+}
+
+func TestCorrectTransaction(t *testing.T) {
+	testTable := []correctionTest{
+		{
+			"fix_name_and_amount",
+			correctionRequest(adminToken, "id-Paul", `{"name": "Paula", "base": 12, "fraction": 50, "reason": "typo"}`),
+			[]Transaction{
+				{Id: "id-Paul", Name: "Paula", Base: 12, Fraction: 50},
+				{Id: "id-Anna", Name: "Anna", Base: 5, Fraction: 0},
+			},
+			nil,
+		},
+		{
+			"void",
+			correctionRequest(adminToken, "id-Anna", `{"voided": true, "reason": "duplicate"}`),
+			[]Transaction{
+				{Id: "id-Paul", Name: "Paul", Base: 10, Fraction: 0},
+				{Id: "id-Anna", Name: "Anna", Base: 5, Fraction: 0, Voided: true},
+			},
+			nil,
+		},
+		{
+			"owner",
+			withHeader(correctionRequest("", "id-Anna", `{"voided": true, "reason": "duplicate"}`), "Authorization", "Bearer "+ownerJwt),
+			[]Transaction{
+				{Id: "id-Paul", Name: "Paul", Base: 10, Fraction: 0},
+				{Id: "id-Anna", Name: "Anna", Base: 5, Fraction: 0, Voided: true},
+			},
+			nil,
+		},
+		{
+			"unvoid_not_voided",
+			correctionRequest(adminToken, "id-Anna", `{"voided": false, "reason": "oops"}`),
+			nil,
+			errors.NewInvalidParametersError(er.New("transaction is already voided=false")),
+		},
+		{
+			"read_token_only",
+			correctionRequest(readToken, "id-Anna", `{"voided": true, "reason": "duplicate"}`),
+			nil,
+			errors.NewForbiddenError(er.New("token does not allow to administrate moneypool paul")),
+		},
+		{
+			"unknown_transaction",
+			correctionRequest(adminToken, "id-Otto", `{"voided": true, "reason": "duplicate"}`),
+			nil,
+			errors.NewTransactionNotFoundError(er.New("no transaction found for given id id-Otto in moneypool paul")),
+		},
+		{
+			"no_reason",
+			correctionRequest(adminToken, "id-Anna", `{"voided": true}`),
+			nil,
+			errors.NewInvalidParametersError(er.New("reason must have between 1 and 500 characters")),
+		},
+		{
+			"no_changes",
+			correctionRequest(adminToken, "id-Anna", `{"reason": "nothing"}`),
+			nil,
+			errors.NewInvalidParametersError(er.New("correction contains no changes")),
+		},
+		{
+			"fraction_out_of_range",
+			correctionRequest(adminToken, "id-Anna", `{"fraction": 100, "reason": "cents"}`),
+			nil,
+			errors.NewInvalidParametersError(er.New("fraction must be between 0 and 99")),
+		},
+		{
+			"negative_fraction",
+			correctionRequest(adminToken, "id-Anna", `{"fraction": -1, "reason": "cents"}`),
+			nil,
+			errors.NewInvalidParametersError(er.New("fraction must not be negative")),
+		},
+	}
+	for _, test := range testTable {
+		client := NewFakeDynamoClient(correctablePoolItem())
+		pool, err := NewHandler(testTables, client, fakeVerifier).CorrectTransaction(test.request)
+		pool = withoutCurrencies(pool)
+		if !compareErrors(err, test.expectedError) || !reflect.DeepEqual(pool.Transactions, test.expectedTransactions) {
+			t.Fatalf("CorrectTransaction(%s) = %+v, %v but expected %+v, %v", test.name, pool.Transactions, err, test.expectedTransactions, test.expectedError)
+		}
+		if err != nil && client.updates > 0 {
+			t.Fatalf("CorrectTransaction(%s) failed, but still updated the moneypool", test.name)
+		}
+	}
+}
+
+func TestCorrectTransactionRecordsCorrection(t *testing.T) {
+	client := NewFakeDynamoClient(correctablePoolItem())
+	handler := NewHandler(testTables, client, nil)
+	if _, err := handler.CorrectTransaction(correctionRequest(adminToken, "id-Anna", `{"base": 6, "reason": "wrong amount"}`)); err != nil {
+		t.Fatalf("CorrectTransaction(edit) returned error %v", err)
+	}
+	if _, err := handler.DeleteTransaction(withQuery(deletionRequest(adminToken, "id-Paul"), "reason", "test payment")); err != nil {
+		t.Fatalf("DeleteTransaction(delete) returned error %v", err)
+	}
+
+	corrections := client.items["paul"]["corrections"].L
+	if len(corrections) != 2 {
+		t.Fatalf("expected 2 corrections, but got %d", len(corrections))
+	}
+	edit, deletion := corrections[0].M, corrections[1].M
+	if *edit["action"].S != CorrectionEdit || *edit["actor"].S != "adminToken" || *edit["reason"].S != "wrong amount" ||
+		*edit["before"].M["base"].N != "5" || *edit["after"].M["base"].N != "6" {
+		t.Fatalf("unexpected edit correction %v", edit)
+	}
+	if *deletion["action"].S != CorrectionDelete || *deletion["transactionId"].S != "id-Paul" ||
+		*deletion["before"].M["name"].S != "Paul" || deletion["after"] != nil {
+		t.Fatalf("unexpected delete correction %v", deletion)
+	}
+	if transactions := client.items["paul"]["transactions"].L; len(transactions) != 1 || transactionId(transactions[0]) != "id-Anna" {
+		t.Fatalf("expected only transaction id-Anna to remain, but got %v", transactions)
+	}
+}
+
+func TestCorrectTransactionThreeDecimals(t *testing.T) {
+	item := correctablePoolItem()
+	anna := item["transactions"].L[1].M
+	anna["currency"] = &dynamodb.AttributeValue{S: aws.String("KWD")}
+	anna["decimals"] = &dynamodb.AttributeValue{N: aws.String("3")}
+	handler := NewHandler(testTables, NewFakeDynamoClient(item), nil)
+
+	pool, err := handler.CorrectTransaction(correctionRequest(adminToken, "id-Anna", `{"fraction": 250, "reason": "fils"}`))
+	if err != nil {
+		t.Fatalf("CorrectTransaction returned error %v", err)
+	}
+	if corrected := pool.Transactions[1].amount(); corrected != (Amount{Base: 5, Fraction: 250, Decimals: 3}) {
+		t.Fatalf("CorrectTransaction returned amount %+v, but expected 5.250 KWD", corrected)
+	}
+	_, err = handler.CorrectTransaction(correctionRequest(adminToken, "id-Anna", `{"fraction": 1000, "reason": "fils"}`))
+	expected := errors.NewInvalidParametersError(er.New("fraction must be between 0 and 999"))
+	if !compareErrors(err, expected) {
+		t.Fatalf("CorrectTransaction(fraction 1000) returned error %v, but expected %v", err, expected)
+	}
+}
+
+func TestCorrectTransactionRecomputesNet(t *testing.T) {
+	testTable := []struct {
+		name     string
+		body     string
+		fee, net *Amount
+	}{
+		{"base", `{"base": 12, "reason": "wrong amount"}`, &Amount{Base: 0, Fraction: 35}, &Amount{Base: 11, Fraction: 65}},
+		{"fraction", `{"fraction": 20, "reason": "wrong amount"}`, &Amount{Base: 0, Fraction: 35}, &Amount{Base: 4, Fraction: 85}},
+		{"below_fee", `{"base": 0, "fraction": 20, "reason": "wrong amount"}`, nil, nil},
+		{"name", `{"name": "Anne", "reason": "typo"}`, &Amount{Base: 0, Fraction: 35}, &Amount{Base: 4, Fraction: 65}},
+	}
+	for _, test := range testTable {
+		item := correctablePoolItem()
+		withFee(item["transactions"].L[1], "0", "35", "4", "65")
+		pool, err := NewHandler(testTables, NewFakeDynamoClient(item), nil).CorrectTransaction(correctionRequest(adminToken, "id-Anna", test.body))
+		if err != nil {
+			t.Fatalf("CorrectTransaction(%s) returned error %v", test.name, err)
+		}
+		if fee, net := pool.Transactions[1].Fee, pool.Transactions[1].Net; !reflect.DeepEqual(fee, test.fee) || !reflect.DeepEqual(net, test.net) {
+			t.Fatalf("CorrectTransaction(%s) returned fee %v and net %v, but expected %v and %v", test.name, fee, net, test.fee, test.net)
+		}
+	}
+}
+
+func TestCorrectUnknownTransactionResponse(t *testing.T) {
+	client := NewFakeDynamoClient(correctablePoolItem())
+	_, err := NewHandler(testTables, client, nil).CorrectTransaction(correctionRequest(adminToken, "id-Otto", `{"voided": true, "reason": "duplicate"}`))
+	if status, code := responseCode(err); status != 404 || code != errors.CodeTransactionNotFound {
+		t.Fatalf("CorrectTransaction(unknown_transaction) responded %d %s, but expected 404 %s", status, code, errors.CodeTransactionNotFound)
+	}
+}
+
+func TestCorrectTransactionLimitsCorrections(t *testing.T) {
+	item := correctablePoolItem()
+	var corrections []*dynamodb.AttributeValue
+	for i := 0; i < maxCorrections; i++ {
+		corrections = append(corrections, &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{
+			"reason": {S: aws.String(fmt.Sprintf("correction %d", i))},
+		}})
+	}
+	item["corrections"] = &dynamodb.AttributeValue{L: corrections}
+	client := NewFakeDynamoClient(item)
+	if _, err := NewHandler(testTables, client, nil).CorrectTransaction(correctionRequest(adminToken, "id-Anna", `{"base": 6, "reason": "wrong amount"}`)); err != nil {
+		t.Fatalf("CorrectTransaction returned error %v", err)
+	}
+	stored := client.items["paul"]["corrections"].L
+	if len(stored) != maxCorrections || *stored[0].M["reason"].S != "correction 1" || *stored[maxCorrections-1].M["reason"].S != "wrong amount" {
+		t.Fatalf("expected the latest %d corrections, but got %d starting with %v", maxCorrections, len(stored), stored[0].M)
+	}
+}
+
+func TestDeleteTransaction(t *testing.T) {
+	testTable := []correctionTest{
+		{
+			"delete",
+			withQuery(deletionRequest(adminToken, "id-Paul"), "reason", "test payment"),
+			[]Transaction{{Id: "id-Anna", Name: "Anna", Base: 5, Fraction: 0}},
+			nil,
+		},
+		{
+			"no_reason",
+			deletionRequest(adminToken, "id-Paul"),
+			nil,
+			errors.NewInvalidParametersError(er.New("reason must have between 1 and 500 characters")),
+		},
+		{
+			"no_token",
+			withQuery(deletionRequest("", "id-Paul"), "reason", "test payment"),
+			nil,
+			errors.NewNotFoundError(er.New("no moneypool found for given name paul")),
+		},
+	}
+	for _, test := range testTable {
+		client := NewFakeDynamoClient(correctablePoolItem())
+		pool, err := NewHandler(testTables, client, nil).DeleteTransaction(test.request)
+		pool = withoutCurrencies(pool)
+		if !compareErrors(err, test.expectedError) || !reflect.DeepEqual(pool.Transactions, test.expectedTransactions) {
+			t.Fatalf("DeleteTransaction(%s) = %+v, %v but expected %+v, %v", test.name, pool.Transactions, err, test.expectedTransactions, test.expectedError)
+		}
+		if err != nil && client.updates > 0 {
+			t.Fatalf("DeleteTransaction(%s) failed, but still updated the moneypool", test.name)
+		}
+	}
+}
+
+func TestCorrectTransactionConcurrentChange(t *testing.T) {
+	client := NewFakeDynamoClient(correctablePoolItem())
+	read := client.items["paul"]["transactions"]
+	// a contribution arriving between reading and writing the pool must not be lost
+	client.items["paul"]["transactions"] = &dynamodb.AttributeValue{L: append(append([]*dynamodb.AttributeValue{}, read.L...), testTransactionItem("Otto", "", "3", "0"))}
+
+	_, err := NewHandler(testTables, client, nil).writeTransactions("paul", read, read.L[1:], nil)
+	expected := errors.NewConflictError(er.New("transactions of moneypool paul were changed concurrently"))
+	if !compareErrors(err, expected) || client.updates > 0 {
+		t.Fatalf("writeTransactions(concurrent) = %v but expected %v", err, expected)
+	}
+}
+
+func correctablePoolItem() map[string]*dynamodb.AttributeValue {
+	item := testPoolItem("paul", "Gift for Paul", true,
+		testTransactionItem("Paul", "", "10", "0"),
+		testTransactionItem("Anna", "", "5", "0"),
+	)
+	item["readTokenHash"] = &dynamodb.AttributeValue{S: aws.String(HashToken(readToken))}
+	item["adminTokenHash"] = &dynamodb.AttributeValue{S: aws.String(HashToken(adminToken))}
+	item["owner"] = &dynamodb.AttributeValue{S: aws.String("owner-sub")}
+	return item
+}
+
+func correctionRequest(token, transactionId, body string) events.APIGatewayProxyRequest {
+	request := updateRequest(token, body)
+	request.PathParameters["transactionId"] = transactionId
+	return request
+}
+
+func deletionRequest(token, transactionId string) events.APIGatewayProxyRequest {
+	request := correctionRequest(token, transactionId, "")
+	request.HTTPMethod = "DELETE"
+	return request
+}
@@ -0,0 +1,267 @@
+package moneypool
+
+import (
+	"api/errors"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+const maxTitleLength = 200
+
+// PoolUpdate holds the settings an admin can change. Fields that are not set stay unchanged.
+type PoolUpdate struct {
+	Title   *string `json:"title"`
+	Open    *bool   `json:"open"`
+	Privacy *string `json:"privacy"`
+	// ReadToken makes the pool private, only readable with this token. An empty token makes the pool public again.
+	ReadToken *string `json:"readToken"`
+	// BaseCurrency changes the currency the pool's totals are converted to. The stored amounts are not changed.
+	BaseCurrency *string `json:"baseCurrency"`
+	// TotalsBasis switches the totals between gross and net amounts.
+	TotalsBasis *string `json:"totalsBasis"`
+	// Notifications replace the pool's notification settings. Settings without channels turn notifications off.
+	Notifications *Notifications `json:"notifications"`
+	// ThankYou replaces the pool's thank-you mail to contributors. Disabled settings are removed.
+	ThankYou *ThankYou `json:"thankYou"`
+	// Expected replaces the amount expected per person and the roster of expected participants. Settings with neither
+	// remove them.
+	Expected *Expected `json:"expected"`
+	// MetadataUpdate changes the pool's description, image and payee.
+	MetadataUpdate
+}
+
+// UpdateMoneyPool changes a pool's settings. It requires the pool's admin token or a bearer token of the pool's owner.
+func (h *MoneyPoolsHandler) UpdateMoneyPool(request events.APIGatewayProxyRequest) (MoneyPool, error) {
+	mpName, mpParamExists := request.PathParameters["moneyPool"]
+	if !mpParamExists {
+		return MoneyPool{}, errors.NewInvalidParametersError(fmt.Errorf("no moneyppol name given"))
+	}
+	h.logger = log.WithFields(log.Fields{"requestedMP": mpName})
+
+	var update PoolUpdate
+	if err := json.Unmarshal([]byte(request.Body), &update); err != nil {
+		return MoneyPool{}, errors.NewInvalidParametersError(fmt.Errorf("invalid update body: %v", err))
+	}
+	update.MetadataUpdate = update.MetadataUpdate.trimmed()
+	if update.Expected != nil && !update.Expected.empty() {
+		normalized := update.Expected.normalized()
+		update.Expected = &normalized
+	}
+	if err := update.validate(); err != nil {
+		return MoneyPool{}, errors.NewInvalidParametersError(err)
+	}
+
+	item, err := h.getPoolItem(mpName)
+	if err != nil {
+		return MoneyPool{}, err
+	}
+	claims, err := h.authorize(request, item, mpName, AccessAdmin)
+	if err != nil {
+		return MoneyPool{}, err
+	}
+
+	updated, err := h.updatePoolItem(mpName, item, update)
+	if err != nil {
+		return MoneyPool{}, err
+	}
+	h.logger.Infof("updated moneypool")
+	oldValues, newValues := changedValues(item, updated)
+	h.audit(request, AuditEntry{Pool: mpName, Action: AuditPoolUpdate, Actor: actor(claims), Old: oldValues, New: newValues})
+	h.sendPoolClosed(mpName, item, updated)
+
+	pool, err := decodePool(updated)
+	if err != nil {
+		return MoneyPool{}, err
+	}
+	h.convertTotals(&pool)
+	pool.ETag, err = itemETag(updated, h.ratesVersion())
+	if err != nil {
+		return MoneyPool{}, err
+	}
+	return pool, nil
+}
+
+func (u PoolUpdate) validate() error {
+	if u.Title == nil && u.Open == nil && u.Privacy == nil && u.ReadToken == nil && u.BaseCurrency == nil &&
+		u.TotalsBasis == nil && u.Notifications == nil && u.ThankYou == nil && u.Expected == nil &&
+		len(u.MetadataUpdate.attributes()) == 0 {
+		return fmt.Errorf("update contains no changes")
+	}
+	if u.Title != nil && (strings.TrimSpace(*u.Title) == "" || len(*u.Title) > maxTitleLength) {
+		return fmt.Errorf("title must have between 1 and %d characters", maxTitleLength)
+	}
+	if u.Privacy != nil && !validPrivacy(*u.Privacy) {
+		return fmt.Errorf("unknown privacy mode %s", *u.Privacy)
+	}
+	if u.ReadToken != nil && *u.ReadToken != "" && len(*u.ReadToken) < 8 {
+		return fmt.Errorf("read token must have at least 8 characters")
+	}
+	if u.BaseCurrency != nil && !validCurrency(*u.BaseCurrency) {
+		return fmt.Errorf("invalid base currency %q, expected a code like EUR", *u.BaseCurrency)
+	}
+	if u.TotalsBasis != nil && !validTotalsBasis(*u.TotalsBasis) {
+		return fmt.Errorf("unknown totals basis %s", *u.TotalsBasis)
+	}
+	if u.Notifications != nil {
+		if err := u.Notifications.validate(); err != nil {
+			return err
+		}
+	}
+	if u.ThankYou != nil {
+		if err := u.ThankYou.validate(); err != nil {
+			return err
+		}
+	}
+	if u.Expected != nil && !u.Expected.empty() {
+		if err := u.Expected.validate(); err != nil {
+			return err
+		}
+	}
+	return u.MetadataUpdate.changed().validate()
+}
+
+// updatePoolItem writes the update, unless the pool's owner or admin token were changed since the pool was authorized.
+func (h *MoneyPoolsHandler) updatePoolItem(mpName string, item map[string]*dynamodb.AttributeValue, update PoolUpdate) (map[string]*dynamodb.AttributeValue, error) {
+	var set, remove []string
+	names := map[string]*string{
+		"#name": aws.String("name"),
+	}
+	values := map[string]*dynamodb.AttributeValue{}
+	conditions := accessUnchanged(item, names, values)
+	if update.Title != nil {
+		set = append(set, "#title = :title")
+		names["#title"] = aws.String("title")
+		values[":title"] = &dynamodb.AttributeValue{S: aws.String(strings.TrimSpace(*update.Title))}
+	}
+	if update.Open != nil {
+		set = append(set, "#open = :open")
+		names["#open"] = aws.String("open")
+		values[":open"] = &dynamodb.AttributeValue{BOOL: update.Open}
+	}
+	if update.Privacy != nil {
+		set = append(set, "#privacy = :privacy")
+		names["#privacy"] = aws.String("privacy")
+		values[":privacy"] = &dynamodb.AttributeValue{S: update.Privacy}
+	}
+	if update.BaseCurrency != nil {
+		set = append(set, "#baseCurrency = :baseCurrency")
+		names["#baseCurrency"] = aws.String("baseCurrency")
+		values[":baseCurrency"] = &dynamodb.AttributeValue{S: update.BaseCurrency}
+	}
+	if update.TotalsBasis != nil {
+		set = append(set, "#totalsBasis = :totalsBasis")
+		names["#totalsBasis"] = aws.String("totalsBasis")
+		values[":totalsBasis"] = &dynamodb.AttributeValue{S: update.TotalsBasis}
+	}
+	if update.Notifications != nil {
+		names["#notifications"] = aws.String("notifications")
+		if len(update.Notifications.Channels) == 0 {
+			remove = append(remove, "#notifications")
+		} else {
+			notifications, err := dynamodbattribute.Marshal(update.Notifications)
+			if err != nil {
+				return nil, fmt.Errorf("could not encode notifications: %v", err)
+			}
+			set = append(set, "#notifications = :notifications")
+			values[":notifications"] = notifications
+		}
+	}
+	if update.ThankYou != nil {
+		names["#thankYou"] = aws.String("thankYou")
+		if !update.ThankYou.Enabled {
+			remove = append(remove, "#thankYou")
+		} else {
+			thankYou, err := dynamodbattribute.Marshal(update.ThankYou)
+			if err != nil {
+				return nil, fmt.Errorf("could not encode thank-you mail: %v", err)
+			}
+			set = append(set, "#thankYou = :thankYou")
+			values[":thankYou"] = thankYou
+		}
+	}
+	if update.Expected != nil {
+		names["#expected"] = aws.String("expected")
+		if update.Expected.empty() {
+			remove = append(remove, "#expected")
+		} else {
+			expected, err := dynamodbattribute.Marshal(update.Expected)
+			if err != nil {
+				return nil, fmt.Errorf("could not encode expected amount: %v", err)
+			}
+			set = append(set, "#expected = :expected")
+			values[":expected"] = expected
+		}
+	}
+	for attribute, value := range update.MetadataUpdate.attributes() {
+		names["#"+attribute] = aws.String(attribute)
+		if *value == "" {
+			remove = append(remove, "#"+attribute)
+		} else {
+			set = append(set, fmt.Sprintf("#%s = :%s", attribute, attribute))
+			values[":"+attribute] = &dynamodb.AttributeValue{S: value}
+		}
+	}
+	if update.ReadToken != nil {
+		names["#readTokenHash"] = aws.String("readTokenHash")
+		if *update.ReadToken == "" {
+			remove = append(remove, "#readTokenHash")
+		} else {
+			set = append(set, "#readTokenHash = :readTokenHash")
+			values[":readTokenHash"] = &dynamodb.AttributeValue{S: aws.String(HashToken(*update.ReadToken))}
+		}
+	}
+
+	expression := ""
+	if len(set) > 0 {
+		expression += "SET " + strings.Join(set, ", ")
+	}
+	if len(remove) > 0 {
+		expression += " REMOVE " + strings.Join(remove, ", ")
+	}
+
+	if len(values) == 0 {
+		values = nil
+	}
+
+	out, err := h.dynamoClient.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(h.tables.MoneyPools),
+		Key: map[string]*dynamodb.AttributeValue{
+			"name": {S: aws.String(mpName)},
+		},
+		UpdateExpression:          aws.String(strings.TrimSpace(expression)),
+		ConditionExpression:       aws.String(strings.Join(conditions, " AND ")),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return nil, errors.NewConflictError(fmt.Errorf("moneypool %s was changed concurrently", mpName))
+		}
+		return nil, errors.NewStoreUnavailableError(fmt.Errorf("error updating moneypool in db: %v", err))
+	}
+	return out.Attributes, nil
+}
+
+// accessUnchanged returns the conditions that the pool still exists and its owner and admin token are the same as in
+// the item, and adds the attribute names and values they use.
+func accessUnchanged(item map[string]*dynamodb.AttributeValue, names map[string]*string, values map[string]*dynamodb.AttributeValue) []string {
+	conditions := []string{"attribute_exists(#name)"}
+	for _, attribute := range []string{"owner", "adminTokenHash"} {
+		names["#"+attribute] = aws.String(attribute)
+		if value, exists := item[attribute]; exists {
+			conditions = append(conditions, fmt.Sprintf("#%s = :%s", attribute, attribute))
+			values[":"+attribute] = value
+		} else {
+			conditions = append(conditions, fmt.Sprintf("attribute_not_exists(#%s)", attribute))
+		}
+	}
+	return conditions
+}
@@ -0,0 +1,313 @@
+package moneypool
+
+import (
+	"api/auth"
+	"api/errors"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// Types of webhook events.
+const (
+	WebhookTransactionAdded = "transaction.added"
+	WebhookPoolClosed       = "pool.closed"
+	WebhookPaymentUnmatched = "payment.unmatched"
+	WebhookGoalReached      = "goal.reached"
+)
+
+var webhookEvents = []string{WebhookTransactionAdded, WebhookPoolClosed, WebhookPaymentUnmatched, WebhookGoalReached}
+
+const maxWebhooks = 10
+
+// maxDeliveries limits the deliveries returned by GetWebhookDeliveries.
+const maxDeliveries = 100
+
+// WebhookSubscription is a webhook that receives a pool's events, signed with its secret. The secret is only returned
+// when the webhook is registered.
+type WebhookSubscription struct {
+	Id     string   `json:"id" dynamodbav:"id"`
+	URL    string   `json:"url" dynamodbav:"url"`
+	Events []string `json:"events" dynamodbav:"events"`
+	Secret string   `json:"secret,omitempty" dynamodbav:"secret"`
+}
+
+// WebhookDelivery is an entry of a pool's delivery log, as written by the transaction lambda.
+type WebhookDelivery struct {
+	Id             string `json:"id" dynamodbav:"deliveryId"`
+	Webhook        string `json:"webhook" dynamodbav:"subscription"`
+	Event          string `json:"event" dynamodbav:"event"`
+	Status         string `json:"status" dynamodbav:"status"`
+	Attempts       int    `json:"attempts" dynamodbav:"attempts"`
+	ResponseStatus int    `json:"responseStatus,omitempty" dynamodbav:"responseStatus"`
+	Error          string `json:"error,omitempty" dynamodbav:"error"`
+	CreatedAt      string `json:"createdAt" dynamodbav:"createdAt"`
+	LastAttempt    string `json:"lastAttempt,omitempty" dynamodbav:"lastAttempt"`
+	NextAttempt    string `json:"nextAttempt,omitempty" dynamodbav:"nextAttempt"`
+}
+
+// WebhookEvent is an event the api asks the transaction lambda to deliver.
+type WebhookEvent struct {
+	Type      string `json:"type"`
+	MoneyPool string `json:"moneyPool"`
+}
+
+// WebhookEventSender passes events to the transaction lambda, which delivers them to the pool's webhooks.
+type WebhookEventSender interface {
+	SendWebhookEvent(event WebhookEvent) error
+}
+
+// WithWebhookEvents enables delivering the api's events, like closing a pool. Without sender, they are not delivered.
+func (h *MoneyPoolsHandler) WithWebhookEvents(sender WebhookEventSender) *MoneyPoolsHandler {
+	h.webhookEvents = sender
+	return h
+}
+
+// CreateWebhook registers a webhook for some of the pool's events. It requires admin access to the pool.
+func (h *MoneyPoolsHandler) CreateWebhook(request events.APIGatewayProxyRequest) (WebhookSubscription, error) {
+	var subscription WebhookSubscription
+	if err := json.Unmarshal([]byte(request.Body), &subscription); err != nil {
+		return WebhookSubscription{}, errors.NewInvalidParametersError(fmt.Errorf("invalid webhook body: %v", err))
+	}
+	if err := subscription.validate(); err != nil {
+		return WebhookSubscription{}, errors.NewInvalidParametersError(err)
+	}
+	mpName, item, claims, err := h.authorizeWebhooks(request)
+	if err != nil {
+		return WebhookSubscription{}, err
+	}
+	subscriptions, err := decodeWebhooks(item)
+	if err != nil {
+		return WebhookSubscription{}, err
+	}
+	if len(subscriptions) >= maxWebhooks {
+		return WebhookSubscription{}, errors.NewConflictError(fmt.Errorf("a pool can have at most %d webhooks", maxWebhooks))
+	}
+	if subscription.Id, err = randomHex(16); err != nil {
+		return WebhookSubscription{}, err
+	}
+	if subscription.Secret, err = randomHex(32); err != nil {
+		return WebhookSubscription{}, err
+	}
+
+	if err := h.writeWebhooks(mpName, item["webhooks"], append(subscriptions, subscription)); err != nil {
+		return WebhookSubscription{}, err
+	}
+	h.logger.WithField("webhook", subscription.Id).Infof("registered webhook")
+	h.audit(request, AuditEntry{Pool: mpName, Action: AuditWebhookAdd, Actor: actor(claims), New: subscription.auditValues()})
+	return subscription, nil
+}
+
+// GetWebhooks returns the pool's webhooks without their secrets. It requires admin access to the pool.
+func (h *MoneyPoolsHandler) GetWebhooks(request events.APIGatewayProxyRequest) ([]WebhookSubscription, error) {
+	_, item, _, err := h.authorizeWebhooks(request)
+	if err != nil {
+		return nil, err
+	}
+	subscriptions, err := decodeWebhooks(item)
+	if err != nil {
+		return nil, err
+	}
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+	return subscriptions, nil
+}
+
+// DeleteWebhook removes a webhook. Its pending deliveries fail on their next attempt. It requires admin access to the pool.
+func (h *MoneyPoolsHandler) DeleteWebhook(request events.APIGatewayProxyRequest) error {
+	webhookId := request.PathParameters["webhookId"]
+	mpName, item, claims, err := h.authorizeWebhooks(request)
+	if err != nil {
+		return err
+	}
+	subscriptions, err := decodeWebhooks(item)
+	if err != nil {
+		return err
+	}
+	remaining := make([]WebhookSubscription, 0, len(subscriptions))
+	var removed *WebhookSubscription
+	for i, subscription := range subscriptions {
+		if subscription.Id == webhookId {
+			removed = &subscriptions[i]
+		} else {
+			remaining = append(remaining, subscription)
+		}
+	}
+	if removed == nil {
+		return errors.NewWebhookNotFoundError(fmt.Errorf("webhook %s not found", webhookId))
+	}
+
+	if err := h.writeWebhooks(mpName, item["webhooks"], remaining); err != nil {
+		return err
+	}
+	h.logger.WithField("webhook", webhookId).Infof("removed webhook")
+	h.audit(request, AuditEntry{Pool: mpName, Action: AuditWebhookRemove, Actor: actor(claims), Old: removed.auditValues()})
+	return nil
+}
+
+// GetWebhookDeliveries returns the latest deliveries to the pool's webhooks, newest first. It requires admin access to
+// the pool.
+func (h *MoneyPoolsHandler) GetWebhookDeliveries(request events.APIGatewayProxyRequest) ([]WebhookDelivery, error) {
+	mpName, _, _, err := h.authorizeWebhooks(request)
+	if err != nil {
+		return nil, err
+	}
+	out, err := h.dynamoClient.Query(&dynamodb.QueryInput{
+		TableName:              aws.String(h.tables.WebhookDeliveries),
+		KeyConditionExpression: aws.String("#pool = :pool"),
+		ExpressionAttributeNames: map[string]*string{
+			"#pool": aws.String("pool"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":pool": {S: aws.String(mpName)},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int64(maxDeliveries),
+	})
+	if err != nil {
+		return nil, errors.NewStoreUnavailableError(fmt.Errorf("error reading webhook deliveries from db: %v", err))
+	}
+	deliveries := make([]WebhookDelivery, 0, len(out.Items))
+	if err := dynamodbattribute.UnmarshalListOfMaps(out.Items, &deliveries); err != nil {
+		return nil, fmt.Errorf("could not decode webhook deliveries: %v", err)
+	}
+	return deliveries, nil
+}
+
+// authorizeWebhooks reads the pool of a webhooks request, and checks that the caller has admin access to it.
+func (h *MoneyPoolsHandler) authorizeWebhooks(request events.APIGatewayProxyRequest) (string, map[string]*dynamodb.AttributeValue, auth.Claims, error) {
+	mpName, mpParamExists := request.PathParameters["moneyPool"]
+	if !mpParamExists {
+		return "", nil, auth.Claims{}, errors.NewInvalidParametersError(fmt.Errorf("no moneyppol name given"))
+	}
+	h.logger = log.WithFields(log.Fields{"requestedMP": mpName})
+
+	item, err := h.getPoolItem(mpName)
+	if err != nil {
+		return "", nil, auth.Claims{}, err
+	}
+	claims, err := h.authorize(request, item, mpName, AccessAdmin)
+	if err != nil {
+		return "", nil, auth.Claims{}, err
+	}
+	if h.tables.WebhookDeliveries == "" {
+		return "", nil, auth.Claims{}, errors.NewNotConfiguredError(fmt.Errorf("webhooks are not configured"))
+	}
+	return mpName, item, claims, nil
+}
+
+func (s WebhookSubscription) validate() error {
+	if !validWebhookURL(s.URL) {
+		return fmt.Errorf("webhook url must be an https url")
+	}
+	if len(s.Events) == 0 {
+		return fmt.Errorf("no events given, expected some of %s", strings.Join(webhookEvents, ", "))
+	}
+	for _, event := range s.Events {
+		if !contains(webhookEvents, event) {
+			return fmt.Errorf("unknown event %s, expected some of %s", event, strings.Join(webhookEvents, ", "))
+		}
+	}
+	return nil
+}
+
+// auditValues are the audited values of a webhook. Its url may contain a secret too, so it is redacted like the secret.
+func (s WebhookSubscription) auditValues() map[string]interface{} {
+	return map[string]interface{}{"id": s.Id, "url": redacted, "events": s.Events}
+}
+
+// redactWebhooks redacts the urls and secrets of stored webhooks in audited values.
+func redactWebhooks(webhooks interface{}) {
+	subscriptions, _ := webhooks.([]interface{})
+	for _, subscription := range subscriptions {
+		values, ok := subscription.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, secret := range []string{"url", "secret"} {
+			if _, exists := values[secret]; exists {
+				values[secret] = redacted
+			}
+		}
+	}
+}
+
+func decodeWebhooks(item map[string]*dynamodb.AttributeValue) ([]WebhookSubscription, error) {
+	subscriptions := make([]WebhookSubscription, 0)
+	if attribute, exists := item["webhooks"]; exists {
+		if err := dynamodbattribute.Unmarshal(attribute, &subscriptions); err != nil {
+			return nil, fmt.Errorf("could not decode webhooks: %v", err)
+		}
+	}
+	return subscriptions, nil
+}
+
+// writeWebhooks replaces the pool's webhooks, unless they were changed since they were read.
+func (h *MoneyPoolsHandler) writeWebhooks(mpName string, old *dynamodb.AttributeValue, subscriptions []WebhookSubscription) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(h.tables.MoneyPools),
+		Key: map[string]*dynamodb.AttributeValue{
+			"name": {S: aws.String(mpName)},
+		},
+		UpdateExpression:         aws.String("REMOVE #webhooks"),
+		ConditionExpression:      aws.String("attribute_not_exists(#webhooks)"),
+		ExpressionAttributeNames: map[string]*string{"#webhooks": aws.String("webhooks")},
+	}
+	values := map[string]*dynamodb.AttributeValue{}
+	if old != nil {
+		input.ConditionExpression = aws.String("#webhooks = :oldWebhooks")
+		values[":oldWebhooks"] = old
+	}
+	if len(subscriptions) > 0 {
+		attribute, err := dynamodbattribute.Marshal(subscriptions)
+		if err != nil {
+			return fmt.Errorf("could not encode webhooks: %v", err)
+		}
+		input.UpdateExpression = aws.String("SET #webhooks = :webhooks")
+		values[":webhooks"] = attribute
+	}
+	if len(values) > 0 {
+		input.ExpressionAttributeValues = values
+	}
+	if _, err := h.dynamoClient.UpdateItem(input); err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return errors.NewConflictError(fmt.Errorf("webhooks of moneypool %s were changed concurrently", mpName))
+		}
+		return errors.NewStoreUnavailableError(fmt.Errorf("error updating webhooks in db: %v", err))
+	}
+	return nil
+}
+
+// sendPoolClosed asks for the pool.closed event to be delivered, if the update closed a pool with webhooks. The pool is
+// already closed, so failing to send the event is only logged.
+func (h *MoneyPoolsHandler) sendPoolClosed(mpName string, old, updated map[string]*dynamodb.AttributeValue) {
+	if h.webhookEvents == nil || updated["webhooks"] == nil || !isOpen(old) || isOpen(updated) {
+		return
+	}
+	if err := h.webhookEvents.SendWebhookEvent(WebhookEvent{Type: WebhookPoolClosed, MoneyPool: mpName}); err != nil {
+		h.logger.Errorf("error sending pool.closed event: %v", err)
+	}
+}
+
+// isOpen tells whether the pool item is open, which pools are unless they were closed.
+func isOpen(item map[string]*dynamodb.AttributeValue) bool {
+	open := item["open"]
+	return open == nil || open.BOOL == nil || *open.BOOL
+}
+
+func randomHex(length int) (string, error) {
+	bytes := make([]byte, length)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("could not generate random bytes: %v", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,177 @@
+package moneypool
+
+import (
+	"api/errors"
+	er "errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+)
+
+func TestWebhooks(t *testing.T) {
+	client := NewFakeDynamoClient()
+	handler := NewHandler(testTables, client, fakeVerifier)
+	if _, err := handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "paul", "title": "Gift for Paul"}`)); err != nil {
+		t.Fatalf("CreateMoneyPool returned error %v", err)
+	}
+	created, err := handler.CreateWebhook(webhookRequest("POST", "", `{"url": "https://example.com/hook", "events": ["transaction.added", "pool.closed"]}`))
+	if err != nil {
+		t.Fatalf("CreateWebhook returned error %v", err)
+	}
+	if len(created.Id) != 32 || len(created.Secret) != 64 || created.URL != "https://example.com/hook" {
+		t.Fatalf("CreateWebhook returned unexpected webhook %+v", created)
+	}
+	stored := client.items["paul"]["webhooks"]
+	if stored == nil || len(stored.L) != 1 || *stored.L[0].M["secret"].S != created.Secret {
+		t.Fatalf("unexpected stored webhooks %v", stored)
+	}
+	entry := client.audit[len(client.audit)-1]
+	if *entry["action"].S != AuditWebhookAdd || *entry["new"].M["url"].S != redacted || entry["new"].M["secret"] != nil {
+		t.Fatalf("unexpected audit entry %v", entry)
+	}
+	if values := auditValues(client.items["paul"]); values["webhooks"].([]interface{})[0].(map[string]interface{})["secret"] != redacted {
+		t.Fatalf("webhook secret is not redacted in audited values %v", values)
+	}
+
+	webhooks, err := handler.GetWebhooks(webhookRequest("GET", "", ""))
+	expected := []WebhookSubscription{{Id: created.Id, URL: "https://example.com/hook", Events: []string{"transaction.added", "pool.closed"}}}
+	if err != nil || !reflect.DeepEqual(webhooks, expected) {
+		t.Fatalf("GetWebhooks returned %+v, %v but expected %+v", webhooks, err, expected)
+	}
+
+	err = handler.DeleteWebhook(webhookRequest("DELETE", "unknown", ""))
+	if status, code := responseCode(err); !compareErrors(err, er.New("webhook unknown not found")) || status != 404 || code != errors.CodeWebhookNotFound {
+		t.Fatalf("DeleteWebhook(unknown) returned error %v, responded %d %s", err, status, code)
+	}
+	if err := handler.DeleteWebhook(webhookRequest("DELETE", created.Id, "")); err != nil {
+		t.Fatalf("DeleteWebhook returned error %v", err)
+	}
+	if _, exists := client.items["paul"]["webhooks"]; exists {
+		t.Fatalf("webhooks were not removed")
+	}
+	if entry := client.audit[len(client.audit)-1]; *entry["action"].S != AuditWebhookRemove {
+		t.Fatalf("unexpected audit entry %v", entry)
+	}
+}
+
+func TestInvalidWebhooks(t *testing.T) {
+	testTable := map[string]error{
+		`{"url": "http://example.com", "events": ["pool.closed"]}`:  er.New("webhook url must be an https url"),
+		`{"url": "https://example.com", "events": []}`:              er.New("no events given, expected some of transaction.added, pool.closed, payment.unmatched, goal.reached"),
+		`{"url": "https://example.com", "events": ["pool.opened"]}`: er.New("unknown event pool.opened, expected some of transaction.added, pool.closed, payment.unmatched, goal.reached"),
+	}
+	handler := NewHandler(testTables, NewFakeDynamoClient(), fakeVerifier)
+	if _, err := handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "paul", "title": "Gift for Paul"}`)); err != nil {
+		t.Fatalf("CreateMoneyPool returned error %v", err)
+	}
+	for body, expected := range testTable {
+		if _, err := handler.CreateWebhook(webhookRequest("POST", "", body)); !compareErrors(err, expected) {
+			t.Fatalf("CreateWebhook(%s) returned error %v, but expected %v", body, err, expected)
+		}
+	}
+
+	request := webhookRequest("POST", "", `{"url": "https://example.com", "events": ["pool.closed"]}`)
+	request.Headers["Authorization"] = "Bearer " + otherJwt
+	if _, err := handler.CreateWebhook(request); err == nil {
+		t.Fatalf("CreateWebhook(other_owner) returned no error")
+	}
+	for i := 0; i < maxWebhooks; i++ {
+		if _, err := handler.CreateWebhook(webhookRequest("POST", "", `{"url": "https://example.com", "events": ["pool.closed"]}`)); err != nil {
+			t.Fatalf("CreateWebhook(%d) returned error %v", i, err)
+		}
+	}
+	_, err := handler.CreateWebhook(webhookRequest("POST", "", `{"url": "https://example.com", "events": ["pool.closed"]}`))
+	if !compareErrors(err, er.New("a pool can have at most 10 webhooks")) {
+		t.Fatalf("CreateWebhook(too_many) returned error %v", err)
+	}
+}
+
+func TestGetWebhookDeliveries(t *testing.T) {
+	client := NewFakeDynamoClient()
+	handler := NewHandler(testTables, client, fakeVerifier)
+	if _, err := handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "paul", "title": "Gift for Paul"}`)); err != nil {
+		t.Fatalf("CreateMoneyPool returned error %v", err)
+	}
+	client.deliveries = []map[string]*dynamodb.AttributeValue{
+		testDelivery("paul", "1", "delivered"),
+		testDelivery("anna", "2", "delivered"),
+		testDelivery("paul", "3", "pending"),
+	}
+	deliveries, err := handler.GetWebhookDeliveries(webhookRequest("GET", "", ""))
+	expected := []WebhookDelivery{
+		{Id: "3", Webhook: "hook", Event: "transaction.added", Status: "pending", Attempts: 1, CreatedAt: "2022-03-01T10:00:00Z"},
+		{Id: "1", Webhook: "hook", Event: "transaction.added", Status: "delivered", Attempts: 1, CreatedAt: "2022-03-01T10:00:00Z"},
+	}
+	if err != nil || !reflect.DeepEqual(deliveries, expected) {
+		t.Fatalf("GetWebhookDeliveries returned %+v, %v but expected %+v", deliveries, err, expected)
+	}
+
+	withoutLog := NewHandler(Tables{MoneyPools: tableName}, client, fakeVerifier)
+	_, err = withoutLog.GetWebhookDeliveries(webhookRequest("GET", "", ""))
+	if status, code := responseCode(err); !compareErrors(err, er.New("webhooks are not configured")) || status != 501 || code != errors.CodeNotConfigured {
+		t.Fatalf("GetWebhookDeliveries(not_configured) returned error %v, responded %d %s", err, status, code)
+	}
+}
+
+func TestPoolClosedEvent(t *testing.T) {
+	client := NewFakeDynamoClient()
+	sender := &FakeWebhookEventSender{}
+	handler := NewHandler(testTables, client, fakeVerifier).WithWebhookEvents(sender)
+	if _, err := handler.CreateMoneyPool(createRequest(ownerJwt, `{"name": "paul", "title": "Gift for Paul"}`)); err != nil {
+		t.Fatalf("CreateMoneyPool returned error %v", err)
+	}
+	if _, err := handler.UpdateMoneyPool(ownerUpdate(`{"open": false}`)); err != nil || len(sender.events) != 0 {
+		t.Fatalf("UpdateMoneyPool(without_webhooks) returned error %v and sent %v", err, sender.events)
+	}
+	if _, err := handler.UpdateMoneyPool(ownerUpdate(`{"open": true}`)); err != nil {
+		t.Fatalf("UpdateMoneyPool(open) returned error %v", err)
+	}
+	if _, err := handler.CreateWebhook(webhookRequest("POST", "", `{"url": "https://example.com", "events": ["pool.closed"]}`)); err != nil {
+		t.Fatalf("CreateWebhook returned error %v", err)
+	}
+	if _, err := handler.UpdateMoneyPool(ownerUpdate(`{"open": false}`)); err != nil {
+		t.Fatalf("UpdateMoneyPool(close) returned error %v", err)
+	}
+	if _, err := handler.UpdateMoneyPool(ownerUpdate(`{"open": false}`)); err != nil {
+		t.Fatalf("UpdateMoneyPool(close_again) returned error %v", err)
+	}
+	expected := []WebhookEvent{{Type: WebhookPoolClosed, MoneyPool: "paul"}}
+	if !reflect.DeepEqual(sender.events, expected) {
+		t.Fatalf("sent events %v, but expected %v", sender.events, expected)
+	}
+}
+
+type FakeWebhookEventSender struct {
+	events []WebhookEvent
+}
+
+func (s *FakeWebhookEventSender) SendWebhookEvent(event WebhookEvent) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+// webhookRequest is a request of the pool's owner to the webhooks of pool paul, or to one of them if webhookId is set.
+func webhookRequest(method, webhookId, body string) events.APIGatewayProxyRequest {
+	request := withHeader(poolRequest("paul"), "Authorization", "Bearer "+ownerJwt)
+	request.HTTPMethod = method
+	request.Body = body
+	if webhookId != "" {
+		request.PathParameters["webhookId"] = webhookId
+	}
+	return request
+}
+
+func testDelivery(pool, id, status string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"pool":         {S: aws.String(pool)},
+		"deliveryId":   {S: aws.String(id)},
+		"subscription": {S: aws.String("hook")},
+		"event":        {S: aws.String("transaction.added")},
+		"payload":      {S: aws.String(`{"id":"1"}`)},
+		"status":       {S: aws.String(status)},
+		"attempts":     {N: aws.String("1")},
+		"createdAt":    {S: aws.String("2022-03-01T10:00:00Z")},
+	}
+}
@@ -0,0 +1,87 @@
+// Package rates provides exchange rates to convert pool totals to a pool's base currency.
+package rates
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// currencyPattern matches ISO currency codes.
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+type ratesFile struct {
+	Base  string             `json:"base"`
+	Date  string             `json:"date"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// Static holds fixed exchange rates, so pools can be converted offline. The rates are given relative to a base
+// currency, like the reference rates published by the ECB:
+//
+//	{"base": "EUR", "date": "2022-03-01", "rates": {"USD": 1.1139, "GBP": 0.83}}
+type Static struct {
+	base string
+	date string
+	// rates are the units of a currency per unit of the base currency
+	rates map[string]float64
+}
+
+// Load reads static rates from a json file, optionally prefixed with file://.
+func Load(path string) (*Static, error) {
+	content, err := ioutil.ReadFile(strings.TrimPrefix(path, "file://"))
+	if err != nil {
+		return nil, fmt.Errorf("could not read rates file: %v", err)
+	}
+	return Parse(content)
+}
+
+// Parse reads static rates from json.
+func Parse(content []byte) (*Static, error) {
+	var file ratesFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("invalid rates: %v", err)
+	}
+	if !currencyPattern.MatchString(file.Base) {
+		return nil, fmt.Errorf("invalid base currency %q", file.Base)
+	}
+	rates := map[string]float64{file.Base: 1}
+	for currency, rate := range file.Rates {
+		if !currencyPattern.MatchString(currency) {
+			return nil, fmt.Errorf("invalid currency %q", currency)
+		}
+		if rate <= 0 {
+			return nil, fmt.Errorf("rate of %s must be positive", currency)
+		}
+		rates[currency] = rate
+	}
+	return &Static{base: file.Base, date: file.Date, rates: rates}, nil
+}
+
+// Rate returns how many units of the target currency one unit of the source currency is worth. It reports false if
+// either currency is unknown.
+func (s *Static) Rate(from, to string) (float64, bool) {
+	fromRate, fromExists := s.rates[from]
+	toRate, toExists := s.rates[to]
+	if !fromExists || !toExists {
+		return 0, false
+	}
+	return toRate / fromRate, true
+}
+
+// Date returns the day the rates were published, if the file states it.
+func (s *Static) Date() string {
+	return s.date
+}
+
+// Version is a hash of the base currency, the date and the rates, so it changes with the rates even if the date was
+// not updated.
+func (s *Static) Version() string {
+	raw, _ := json.Marshal(ratesFile{Base: s.base, Date: s.date, Rates: s.rates})
+	hash := sha256.Sum256(raw)
+	return hex.EncodeToString(hash[:8])
+}
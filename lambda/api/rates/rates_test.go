@@ -0,0 +1,88 @@
+package rates
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type rateTest struct {
+	from     string
+	to       string
+	expected float64
+	exists   bool
+}
+
+func TestRate(t *testing.T) {
+	rates, err := Parse([]byte(`{"base": "EUR", "date": "2022-03-01", "rates": {"USD": 1.25, "GBP": 0.8}}`))
+	if err != nil {
+		t.Fatalf("Parse returned error %v", err)
+	}
+	testTable := []rateTest{
+		{"USD", "EUR", 0.8, true},
+		{"EUR", "USD", 1.25, true},
+		{"GBP", "USD", 1.5625, true},
+		{"EUR", "EUR", 1, true},
+		{"CHF", "EUR", 0, false},
+		{"EUR", "CHF", 0, false},
+	}
+	for _, test := range testTable {
+		rate, exists := rates.Rate(test.from, test.to)
+		if exists != test.exists || math.Abs(rate-test.expected) > 1e-9 {
+			t.Fatalf("Rate(%s, %s) = %v, %v but expected %v, %v", test.from, test.to, rate, exists, test.expected, test.exists)
+		}
+	}
+	if rates.Date() != "2022-03-01" {
+		t.Fatalf("Date() = %s, but expected 2022-03-01", rates.Date())
+	}
+}
+
+func TestVersion(t *testing.T) {
+	parse := func(content string) *Static {
+		rates, err := Parse([]byte(content))
+		if err != nil {
+			t.Fatalf("Parse returned error %v", err)
+		}
+		return rates
+	}
+	first := parse(`{"base": "EUR", "date": "2022-03-01", "rates": {"USD": 1.25, "GBP": 0.8}}`)
+	same := parse(`{"base": "EUR", "date": "2022-03-01", "rates": {"GBP": 0.8, "USD": 1.25}}`)
+	if first.Version() != same.Version() {
+		t.Fatalf("Version() differs for equal rates: %s, %s", first.Version(), same.Version())
+	}
+	for _, content := range []string{
+		`{"base": "EUR", "date": "2022-03-02", "rates": {"USD": 1.25, "GBP": 0.8}}`,
+		`{"base": "EUR", "date": "2022-03-01", "rates": {"USD": 1.2, "GBP": 0.8}}`,
+	} {
+		if parse(content).Version() == first.Version() {
+			t.Fatalf("Version() of %s equals the version of different rates", content)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for content, expected := range map[string]string{
+		`{"base": "euro"}`:                     `invalid base currency "euro"`,
+		`{"base": "EUR", "rates": {"usd": 1}}`: `invalid currency "usd"`,
+		`{"base": "EUR", "rates": {"USD": 0}}`: "rate of USD must be positive",
+	} {
+		if _, err := Parse([]byte(content)); err == nil || err.Error() != expected {
+			t.Fatalf("Parse(%s) = %v, but expected %s", content, err, expected)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	if err := os.WriteFile(path, []byte(`{"base": "EUR", "rates": {"USD": 2}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	rates, err := Load("file://" + path)
+	if err != nil {
+		t.Fatalf("Load returned error %v", err)
+	}
+	if rate, _ := rates.Rate("USD", "EUR"); rate != 0.5 {
+		t.Fatalf("Rate(USD, EUR) = %v, but expected 0.5", rate)
+	}
+}
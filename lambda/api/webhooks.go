@@ -0,0 +1,37 @@
+package main
+
+import (
+	"api/moneypool"
+	"encoding/json"
+	"github.com/aws/aws-sdk-go/aws"
+	awslambda "github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+)
+
+// lambdaWebhookSender passes webhook events to the transaction lambda, which delivers them. The lambda is invoked
+// asynchronously, so requests don't wait for the deliveries.
+type lambdaWebhookSender struct {
+	functionName string
+	client       lambdaiface.LambdaAPI
+}
+
+// newWebhookSender returns nil, which disables the api's webhook events, if no function is configured.
+func newWebhookSender(functionName string) moneypool.WebhookEventSender {
+	if functionName == "" {
+		return nil
+	}
+	return &lambdaWebhookSender{functionName: functionName, client: awslambda.New(awsSession)}
+}
+
+func (s *lambdaWebhookSender) SendWebhookEvent(event moneypool.WebhookEvent) error {
+	payload, err := json.Marshal(map[string]moneypool.WebhookEvent{"webhook": event})
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Invoke(&awslambda.InvokeInput{
+		FunctionName:   aws.String(s.functionName),
+		InvocationType: aws.String(awslambda.InvocationTypeEvent),
+		Payload:        payload,
+	})
+	return err
+}
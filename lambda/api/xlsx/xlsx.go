@@ -0,0 +1,166 @@
+// Package xlsx writes single sheet spreadsheets in the Office Open XML format, as read by Excel, LibreOffice and
+// Google Sheets.
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+type cellKind int
+
+const (
+	kindEmpty cellKind = iota
+	kindString
+	kindNumber
+	kindDate
+)
+
+// style ids of the cell formats in stylesXml
+const (
+	styleDefault = 0
+	styleHeader  = 1
+	styleNumber  = 2
+	styleDate    = 3
+)
+
+// excelEpoch is day 0 of spreadsheet dates.
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// Cell is a single value of a row. The zero value is an empty cell.
+type Cell struct {
+	kind   cellKind
+	text   string
+	number float64
+	date   time.Time
+}
+
+// String returns a text cell.
+func String(text string) Cell {
+	return Cell{kind: kindString, text: text}
+}
+
+// Number returns a numeric cell shown with two decimals.
+func Number(number float64) Cell {
+	return Cell{kind: kindNumber, number: number}
+}
+
+// Date returns a cell with the day of the time, shown as yyyy-mm-dd.
+func Date(date time.Time) Cell {
+	return Cell{kind: kindDate, date: date}
+}
+
+// Write writes a workbook with a single sheet, starting with a bold header row.
+func Write(w io.Writer, sheetName string, header []string, rows [][]Cell) error {
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{"[Content_Types].xml", []byte(contentTypesXml)},
+		{"_rels/.rels", []byte(relsXml)},
+		{"xl/workbook.xml", []byte(fmt.Sprintf(workbookXml, escape(sheetName)))},
+		{"xl/_rels/workbook.xml.rels", []byte(workbookRelsXml)},
+		{"xl/styles.xml", []byte(stylesXml)},
+		{"xl/worksheets/sheet1.xml", sheetXml(header, rows)},
+	}
+	for _, file := range files {
+		fw, err := archive.Create(file.name)
+		if err != nil {
+			return fmt.Errorf("could not create %s: %v", file.name, err)
+		}
+		if _, err := fw.Write(file.content); err != nil {
+			return fmt.Errorf("could not write %s: %v", file.name, err)
+		}
+	}
+	return archive.Close()
+}
+
+func sheetXml(header []string, rows [][]Cell) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	headerCells := make([]Cell, len(header))
+	for i, title := range header {
+		headerCells[i] = String(title)
+	}
+	writeRow(&buf, 1, headerCells, styleHeader)
+	for i, row := range rows {
+		writeRow(&buf, i+2, row, styleDefault)
+	}
+	buf.WriteString(`</sheetData></worksheet>`)
+	return buf.Bytes()
+}
+
+func writeRow(buf *bytes.Buffer, index int, cells []Cell, textStyle int) {
+	fmt.Fprintf(buf, `<row r="%d">`, index)
+	for i, cell := range cells {
+		ref := columnName(i) + strconv.Itoa(index)
+		switch cell.kind {
+		case kindString:
+			fmt.Fprintf(buf, `<c r="%s" t="inlineStr" s="%d"><is><t xml:space="preserve">%s</t></is></c>`, ref, textStyle, escape(cell.text))
+		case kindNumber:
+			fmt.Fprintf(buf, `<c r="%s" s="%d"><v>%s</v></c>`, ref, styleNumber, strconv.FormatFloat(cell.number, 'f', -1, 64))
+		case kindDate:
+			days := cell.date.UTC().Truncate(24*time.Hour).Sub(excelEpoch).Hours() / 24
+			fmt.Fprintf(buf, `<c r="%s" s="%d"><v>%d</v></c>`, ref, styleDate, int(days))
+		}
+	}
+	buf.WriteString(`</row>`)
+}
+
+// columnName returns the letters of the column with the zero based index, e.g. A, Z, AA.
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+func escape(text string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
+
+const contentTypesXml = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const relsXml = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const workbookXml = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const workbookRelsXml = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// stylesXml defines the cell formats default, header, number and date, in the order of the style ids.
+const stylesXml = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="2" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs></styleSheet>`
@@ -0,0 +1,79 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type columnNameTest struct {
+	index    int
+	expected string
+}
+
+func TestColumnName(t *testing.T) {
+	testTable := []columnNameTest{{0, "A"}, {25, "Z"}, {26, "AA"}, {27, "AB"}, {701, "ZZ"}, {702, "AAA"}}
+	for _, test := range testTable {
+		if name := columnName(test.index); name != test.expected {
+			t.Fatalf("columnName(%d) = %s, but expected %s", test.index, name, test.expected)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	rows := [][]Cell{
+		{Date(time.Date(2022, 3, 1, 15, 0, 0, 0, time.UTC)), String("Anna & <Otto>"), Number(12.5)},
+		{{}, String("Paul"), Number(-5)},
+	}
+	if err := Write(&buf, "mom", []string{"date", "name", "amount"}, rows); err != nil {
+		t.Fatalf("Write returned error %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("written workbook is no zip archive: %v", err)
+	}
+	files := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatalf("could not open %s: %v", file.Name, err)
+		}
+		content, _ := io.ReadAll(reader)
+		files[file.Name] = string(content)
+		// every part has to be well-formed, or spreadsheet programs refuse the whole workbook
+		decoder := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := decoder.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well-formed: %v", file.Name, err)
+			}
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		if _, exists := files[name]; !exists {
+			t.Fatalf("workbook misses %s", name)
+		}
+	}
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, expected := range []string{
+		`<c r="A1" t="inlineStr" s="1"><is><t xml:space="preserve">date</t></is></c>`,
+		`<c r="A2" s="3"><v>44621</v></c>`,
+		`<t xml:space="preserve">Anna &amp; &lt;Otto&gt;</t>`,
+		`<c r="C2" s="2"><v>12.5</v></c>`,
+		`<c r="C3" s="2"><v>-5</v></c>`,
+	} {
+		if !strings.Contains(sheet, expected) {
+			t.Fatalf("sheet %s does not contain %s", sheet, expected)
+		}
+	}
+	if strings.Contains(sheet, `r="A3"`) {
+		t.Fatalf("sheet %s contains empty cell A3", sheet)
+	}
+}
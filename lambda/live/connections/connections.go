@@ -0,0 +1,149 @@
+package connections
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"time"
+)
+
+// connectionTTL is slightly longer than the maximum lifetime of an api gateway websocket connection.
+// DynamoDB removes registrations of connections that never sent a disconnect after this time.
+const connectionTTL = 3 * time.Hour
+
+type Registry struct {
+	connectionsTableName string
+	moneyPoolsTableName  string
+	dynamoClient         dynamodbiface.DynamoDBAPI
+	now                  func() time.Time
+}
+
+func NewRegistry(connectionsTableName, moneyPoolsTableName string, dynamoClient dynamodbiface.DynamoDBAPI) *Registry {
+	return &Registry{
+		connectionsTableName: connectionsTableName,
+		moneyPoolsTableName:  moneyPoolsTableName,
+		dynamoClient:         dynamoClient,
+		now:                  time.Now,
+	}
+}
+
+// Handle dispatches websocket lifecycle events. Clients subscribe to a moneypool by connecting with ?moneyPool=name, and
+// with &token= for pools with read token. Unknown pools and wrong tokens are both rejected with 404, so private pools
+// can't be discovered.
+func (r *Registry) Handle(request events.APIGatewayWebsocketProxyRequest) events.APIGatewayProxyResponse {
+	connectionId := request.RequestContext.ConnectionID
+	logger := log.WithFields(log.Fields{"connectionId": connectionId, "route": request.RequestContext.RouteKey})
+
+	switch request.RequestContext.RouteKey {
+	case "$connect":
+		moneyPool := request.QueryStringParameters["moneyPool"]
+		if moneyPool == "" {
+			logger.Infof("rejecting connection without moneypool")
+			return response(400)
+		}
+		logger = logger.WithFields(log.Fields{"pool": moneyPool})
+		readable, err := r.readable(moneyPool, request.QueryStringParameters["token"])
+		if err != nil {
+			logger.Errorf("error checking moneypool: %v", err)
+			return response(500)
+		}
+		if !readable {
+			logger.Infof("rejecting connection to unknown moneypool or with wrong token")
+			return response(404)
+		}
+		if err := r.register(connectionId, moneyPool); err != nil {
+			logger.Errorf("error registering connection: %v", err)
+			return response(500)
+		}
+		logger.Infof("registered connection")
+	case "$disconnect":
+		if err := r.unregister(connectionId); err != nil {
+			logger.Errorf("error removing connection: %v", err)
+			return response(500)
+		}
+		logger.Infof("removed connection")
+	}
+	return response(200)
+}
+
+// readable reports whether the moneypool exists and the token allows to read it, like the api does. Pools without read
+// token are public, and admin tokens allow to read too.
+func (r *Registry) readable(moneyPool, token string) (bool, error) {
+	out, err := r.dynamoClient.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(r.moneyPoolsTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"name": {S: aws.String(moneyPool)},
+		},
+		ProjectionExpression: aws.String("#name, readTokenHash, adminTokenHash"),
+		ExpressionAttributeNames: map[string]*string{
+			"#name": aws.String("name"),
+		},
+	})
+	if err != nil {
+		return false, fmt.Errorf("error getting moneypool from db: %v", err)
+	}
+	if out.Item == nil {
+		return false, nil
+	}
+	var hashes tokenHashes
+	if err := dynamodbattribute.UnmarshalMap(out.Item, &hashes); err != nil {
+		return false, fmt.Errorf("could not decode moneypool tokens: %v", err)
+	}
+	return hashes.ReadTokenHash == "" || tokenMatches(hashes.ReadTokenHash, token) || tokenMatches(hashes.AdminTokenHash, token), nil
+}
+
+// tokenHashes are the sha256 hashes of a pool's tokens, as stored by the api.
+type tokenHashes struct {
+	ReadTokenHash  string `dynamodbav:"readTokenHash"`
+	AdminTokenHash string `dynamodbav:"adminTokenHash"`
+}
+
+func tokenMatches(hash, token string) bool {
+	if hash == "" || token == "" {
+		return false
+	}
+	tokenHash := sha256.Sum256([]byte(token))
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(hex.EncodeToString(tokenHash[:]))) == 1
+}
+
+func (r *Registry) register(connectionId, moneyPool string) error {
+	now := r.now()
+	_, err := r.dynamoClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(r.connectionsTableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			"connectionId": {S: aws.String(connectionId)},
+			"pool":         {S: aws.String(moneyPool)},
+			"connectedAt":  {S: aws.String(now.UTC().Format(time.RFC3339))},
+			"expiresAt":    {N: aws.String(strconv.FormatInt(now.Add(connectionTTL).Unix(), 10))},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error putting connection item: %v", err)
+	}
+	return nil
+}
+
+func (r *Registry) unregister(connectionId string) error {
+	_, err := r.dynamoClient.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(r.connectionsTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"connectionId": {S: aws.String(connectionId)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting connection item: %v", err)
+	}
+	return nil
+}
+
+func response(status int) events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{StatusCode: status}
+}
@@ -0,0 +1,203 @@
+package connections
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type handleTest struct {
+	name                string
+	client              *FakeDynamoClient
+	request             events.APIGatewayWebsocketProxyRequest
+	expectedStatus      int
+	expectedConnections map[string]map[string]string
+}
+
+var testTime = time.Date(2022, 2, 18, 10, 0, 0, 0, time.UTC)
+
+func TestHandle(t *testing.T) {
+	testTable := []handleTest{
+		{
+			"connect",
+			NewFakeDynamoClient("paul"),
+			connectRequest("c1", "paul"),
+			200,
+			map[string]map[string]string{
+				"c1": {"pool": "paul", "connectedAt": "2022-02-18T10:00:00Z", "expiresAt": "1645189200"},
+			},
+		},
+		{
+			"connect_unknown_pool",
+			NewFakeDynamoClient("paul"),
+			connectRequest("c1", "peter"),
+			404,
+			map[string]map[string]string{},
+		},
+		{
+			"connect_private_pool",
+			NewFakeDynamoClient("paul").withReadToken("paul", "read-secret"),
+			withToken(connectRequest("c1", "paul"), "read-secret"),
+			200,
+			map[string]map[string]string{
+				"c1": {"pool": "paul", "connectedAt": "2022-02-18T10:00:00Z", "expiresAt": "1645189200"},
+			},
+		},
+		{
+			"connect_private_pool_with_admin_token",
+			NewFakeDynamoClient("paul").withReadToken("paul", "read-secret").withAdminToken("paul", "admin-secret"),
+			withToken(connectRequest("c1", "paul"), "admin-secret"),
+			200,
+			map[string]map[string]string{
+				"c1": {"pool": "paul", "connectedAt": "2022-02-18T10:00:00Z", "expiresAt": "1645189200"},
+			},
+		},
+		{
+			"connect_private_pool_wrong_token",
+			NewFakeDynamoClient("paul").withReadToken("paul", "read-secret"),
+			withToken(connectRequest("c1", "paul"), "guess"),
+			404,
+			map[string]map[string]string{},
+		},
+		{
+			"connect_private_pool_without_token",
+			NewFakeDynamoClient("paul").withReadToken("paul", "read-secret"),
+			connectRequest("c1", "paul"),
+			404,
+			map[string]map[string]string{},
+		},
+		{
+			"connect_without_pool",
+			NewFakeDynamoClient("paul"),
+			connectRequest("c1", ""),
+			400,
+			map[string]map[string]string{},
+		},
+		{
+			"connect_db_error",
+			&FakeDynamoClient{err: errors.New("timeout"), connections: map[string]map[string]string{}},
+			connectRequest("c1", "paul"),
+			500,
+			map[string]map[string]string{},
+		},
+		{
+			"disconnect",
+			NewFakeDynamoClient("paul").withConnection("c1", "paul").withConnection("c2", "paul"),
+			routeRequest("c1", "$disconnect"),
+			200,
+			map[string]map[string]string{
+				"c2": {"pool": "paul"},
+			},
+		},
+		{
+			"default_route",
+			NewFakeDynamoClient("paul").withConnection("c1", "paul"),
+			routeRequest("c1", "$default"),
+			200,
+			map[string]map[string]string{
+				"c1": {"pool": "paul"},
+			},
+		},
+	}
+	for _, test := range testTable {
+		registry := NewRegistry("LiveConnectionsTable", "MoneyPoolsTable", test.client)
+		registry.now = func() time.Time { return testTime }
+		response := registry.Handle(test.request)
+		if response.StatusCode != test.expectedStatus || !reflect.DeepEqual(test.client.connections, test.expectedConnections) {
+			t.Fatalf("Handle(%s) = %d with connections %v, but expected %d with connections %v", test.name, response.StatusCode, test.client.connections, test.expectedStatus, test.expectedConnections)
+		}
+	}
+}
+
+// FakeDynamoClient knows a set of moneypools with their token hashes and stores connection items as plain string maps.
+type FakeDynamoClient struct {
+	dynamodbiface.DynamoDBAPI
+	pools       map[string]map[string]*dynamodb.AttributeValue
+	connections map[string]map[string]string
+	err         error
+}
+
+func NewFakeDynamoClient(pools ...string) *FakeDynamoClient {
+	client := &FakeDynamoClient{pools: map[string]map[string]*dynamodb.AttributeValue{}, connections: map[string]map[string]string{}}
+	for _, pool := range pools {
+		client.pools[pool] = map[string]*dynamodb.AttributeValue{"name": {S: aws.String(pool)}}
+	}
+	return client
+}
+
+func (c *FakeDynamoClient) withReadToken(pool, token string) *FakeDynamoClient {
+	c.pools[pool]["readTokenHash"] = &dynamodb.AttributeValue{S: aws.String(hashToken(token))}
+	return c
+}
+
+func (c *FakeDynamoClient) withAdminToken(pool, token string) *FakeDynamoClient {
+	c.pools[pool]["adminTokenHash"] = &dynamodb.AttributeValue{S: aws.String(hashToken(token))}
+	return c
+}
+
+func (c *FakeDynamoClient) withConnection(connectionId, pool string) *FakeDynamoClient {
+	c.connections[connectionId] = map[string]string{"pool": pool}
+	return c
+}
+
+func (c *FakeDynamoClient) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &dynamodb.GetItemOutput{Item: c.pools[*input.Key["name"].S]}, nil
+}
+
+func (c *FakeDynamoClient) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	item := map[string]string{}
+	for key, value := range input.Item {
+		if key == "connectionId" {
+			continue
+		}
+		if value.S != nil {
+			item[key] = *value.S
+		} else {
+			item[key] = *value.N
+		}
+	}
+	c.connections[*input.Item["connectionId"].S] = item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (c *FakeDynamoClient) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	delete(c.connections, *input.Key["connectionId"].S)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func connectRequest(connectionId, pool string) events.APIGatewayWebsocketProxyRequest {
+	request := routeRequest(connectionId, "$connect")
+	if pool != "" {
+		request.QueryStringParameters = map[string]string{"moneyPool": pool}
+	}
+	return request
+}
+
+func withToken(request events.APIGatewayWebsocketProxyRequest, token string) events.APIGatewayWebsocketProxyRequest {
+	request.QueryStringParameters["token"] = token
+	return request
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func routeRequest(connectionId, route string) events.APIGatewayWebsocketProxyRequest {
+	return events.APIGatewayWebsocketProxyRequest{
+		RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+			ConnectionID: connectionId,
+			RouteKey:     route,
+		},
+	}
+}
@@ -0,0 +1,9 @@
+require (
+	github.com/aws/aws-lambda-go v1.23.0
+	github.com/aws/aws-sdk-go v1.40.59
+	github.com/sirupsen/logrus v1.8.1
+)
+
+module live
+
+go 1.16
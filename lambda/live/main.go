@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	log "github.com/sirupsen/logrus"
+	"live/connections"
+	"os"
+)
+
+func init() {
+	log.SetFormatter(&log.JSONFormatter{})
+	log.SetOutput(os.Stdout)
+	log.SetLevel(log.InfoLevel)
+}
+
+var (
+	moneyPoolsTableName      = os.Getenv("MoneyPoolsTableName")
+	liveConnectionsTableName = os.Getenv("LiveConnectionsTableName")
+	awsSession               = session.Must(session.NewSession())
+	dynamoClient             = dynamodb.New(awsSession, aws.NewConfig())
+)
+
+func handler(request events.APIGatewayWebsocketProxyRequest) (events.APIGatewayProxyResponse, error) {
+	registry := connections.NewRegistry(liveConnectionsTableName, moneyPoolsTableName, dynamoClient)
+	return registry.Handle(request), nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -0,0 +1,50 @@
+package aws
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"time"
+	"transaction/data"
+)
+
+// auditTimeFormat has a fixed width, so entry ids sort by time.
+const auditTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// AuditLog appends to the audit log that the api serves to pool admins.
+type AuditLog struct {
+	AuditLogTableName string
+	DynamoClient      dynamodbiface.DynamoDBAPI
+}
+
+func NewAuditLog(auditLogTableName string, dynamoClient dynamodbiface.DynamoDBAPI) *AuditLog {
+	return &AuditLog{
+		AuditLogTableName: auditLogTableName,
+		DynamoClient:      dynamoClient,
+	}
+}
+
+// Append writes the entry with the current time. Existing entries are never overwritten.
+func (l *AuditLog) Append(entry data.AuditEntry) error {
+	now := time.Now().UTC()
+	entry.At = now.Format(time.RFC3339)
+	entry.EntryId = now.Format(auditTimeFormat) + "#" + entry.Source
+	item, err := dynamodbattribute.MarshalMap(entry)
+	if err != nil {
+		return fmt.Errorf("could not encode audit entry: %v", err)
+	}
+	_, err = l.DynamoClient.PutItem(&dynamodb.PutItemInput{
+		TableName:           aws.String(l.AuditLogTableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(#entryId)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#entryId": aws.String("entryId"),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not write audit entry: %v", err)
+	}
+	return nil
+}
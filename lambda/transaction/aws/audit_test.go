@@ -0,0 +1,59 @@
+package aws
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"strings"
+	"testing"
+	"transaction/data"
+)
+
+func TestAppendAuditEntry(t *testing.T) {
+	db := &FakeAuditTable{}
+	log := NewAuditLog("AuditLogTable", db)
+	entry := data.AuditEntry{
+		Pool:          "mom",
+		Action:        data.AuditTransactionAdd,
+		Actor:         data.AuditActorMail,
+		Source:        "message-1",
+		TransactionId: "id-1",
+		New:           map[string]interface{}{"name": "Anna", "base": 5},
+	}
+	if err := log.Append(entry); err != nil {
+		t.Fatalf("Append returned error %v", err)
+	}
+	if len(db.entries) != 1 {
+		t.Fatalf("expected 1 audit entry, but got %d", len(db.entries))
+	}
+	item := db.entries[0]
+	if *item["pool"].S != "mom" || *item["source"].S != "message-1" || *item["at"].S == "" ||
+		!strings.HasSuffix(*item["entryId"].S, "#message-1") || *item["new"].M["name"].S != "Anna" || *item["new"].M["base"].N != "5" {
+		t.Fatalf("unexpected audit item %v", item)
+	}
+
+	db.err = awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "condition failed", nil)
+	expected := errors.New("could not write audit entry: ConditionalCheckFailedException: condition failed")
+	if err := log.Append(entry); !compareErrors(err, expected) {
+		t.Fatalf("Append(existing) = %v, but expected %v", err, expected)
+	}
+}
+
+// FakeAuditTable keeps the written entries, or fails with err if set.
+type FakeAuditTable struct {
+	dynamodbiface.DynamoDBAPI
+	entries []map[string]*dynamodb.AttributeValue
+	err     error
+}
+
+func (t *FakeAuditTable) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	if *input.ConditionExpression != "attribute_not_exists(#entryId)" {
+		return nil, errors.New("audit entries must not be overwritten")
+	}
+	t.entries = append(t.entries, input.Item)
+	return &dynamodb.PutItemOutput{}, nil
+}
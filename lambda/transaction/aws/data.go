@@ -5,6 +5,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/google/uuid"
 	"strconv"
 	"strings"
@@ -23,43 +24,91 @@ func NewDataStore(moneyPoolsTableName string) *DataStore {
 	return &DataStore{MoneyPoolsTableName: moneyPoolsTableName}
 }
 
-func (s *DataStore) FindMoneyPoolsByPrefix(name string) ([]string, error) {
+// tenantSeparator joins a tenant's name and a pool's name to the key of the tenant's pool.
+const tenantSeparator = "."
+
+// FindMoneyPoolsByPrefix returns the keys of the tenant's pools whose names the note starts with.
+func (s *DataStore) FindMoneyPoolsByPrefix(tenant, name string) ([]string, error) {
 	allMoneyPools, err := s.getAllMoneyPools()
 	if err != nil {
 		return nil, err
 	}
 	moneyPools := make([]string, 0)
-	for _, mpName := range allMoneyPools {
-		if strings.HasPrefix(strings.ToLower(name), strings.ToLower(*mpName)) {
-			moneyPools = append(moneyPools, *mpName)
+	for _, mp := range allMoneyPools {
+		if mp.tenant != tenant {
+			continue
+		}
+		mpName := mp.key
+		if tenant != "" {
+			mpName = strings.TrimPrefix(mp.key, tenant+tenantSeparator)
+		}
+		if strings.HasPrefix(strings.ToLower(name), strings.ToLower(mpName)) {
+			moneyPools = append(moneyPools, mp.key)
 		}
 	}
 	return moneyPools, nil
 }
 
-func (s *DataStore) AddTransaction(moneyPool, name, date string, amount data.Amount) error {
+func amountAttribute(amount data.Amount) *dynamodb.AttributeValue {
+	attribute := &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{
+		"base":     {N: aws.String(strconv.Itoa(amount.Base))},
+		"fraction": {N: aws.String(strconv.Itoa(amount.Fraction))},
+	}}
+	if amount.Decimals > 0 {
+		attribute.M["decimals"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(amount.Decimals))}
+	}
+	return attribute
+}
+
+func (s *DataStore) AddTransaction(moneyPool string, contribution data.Contribution) (string, error) {
 
 	uid := uuid.New().String()
 
+	transaction := map[string]*dynamodb.AttributeValue{
+		"id": {
+			S: aws.String(uid),
+		},
+		"base": {
+			N: aws.String(strconv.Itoa(contribution.Amount.Base)),
+		},
+		"fraction": {
+			N: aws.String(strconv.Itoa(contribution.Amount.Fraction)),
+		},
+		"name": {
+			S: aws.String(contribution.Name),
+		},
+		"date": {
+			S: aws.String(contribution.Date),
+		},
+	}
+	if contribution.Amount.Decimals > 0 {
+		transaction["decimals"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(contribution.Amount.Decimals))}
+	}
+	if contribution.Currency != "" {
+		transaction["currency"] = &dynamodb.AttributeValue{S: aws.String(contribution.Currency)}
+	}
+	if contribution.Fee != nil && contribution.Net != nil {
+		transaction["fee"] = amountAttribute(*contribution.Fee)
+		transaction["net"] = amountAttribute(*contribution.Net)
+	}
+	if contribution.Anonymous {
+		transaction["anonymous"] = &dynamodb.AttributeValue{BOOL: aws.Bool(true)}
+	}
+	if contribution.PaypalId != "" {
+		transaction["paypalId"] = &dynamodb.AttributeValue{S: aws.String(contribution.PaypalId)}
+	}
+	if contribution.RefundOf != "" {
+		transaction["refundOf"] = &dynamodb.AttributeValue{S: aws.String(contribution.RefundOf)}
+	}
+	if contribution.Source != "" {
+		transaction["source"] = &dynamodb.AttributeValue{S: aws.String(contribution.Source)}
+	}
+	if contribution.Participant != "" {
+		transaction["participant"] = &dynamodb.AttributeValue{S: aws.String(contribution.Participant)}
+	}
 	transactions := []*dynamodb.AttributeValue{
 		{
-			M: map[string]*dynamodb.AttributeValue{
-				"id": {
-					S: aws.String(uid),
-				},
-				"base": {
-					N: aws.String(strconv.Itoa(amount.Base)),
-				},
-				"fraction": {
-					N: aws.String(strconv.Itoa(amount.Fraction)),
-				},
-				"name": {
-					S: aws.String(name),
-				},
-				"date": {
-					S: aws.String(date),
-				},
-			},
+			M: transaction,
 		},
 	}
 
@@ -83,16 +132,175 @@ func (s *DataStore) AddTransaction(moneyPool, name, date string, amount data.Amo
 
 	_, err := dynamoClient.UpdateItem(input)
 	if err != nil {
-		return fmt.Errorf("error updating moneypool item: %v", err)
+		return "", fmt.Errorf("error updating moneypool item: %v", err)
+	}
+	return uid, nil
+}
+
+// GetContributions returns the transactions stored in the moneypool. It fails if there is no such moneypool.
+func (s *DataStore) GetContributions(moneyPool string) ([]data.Contribution, error) {
+	out, err := dynamoClient.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(s.MoneyPoolsTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"name": {S: aws.String(moneyPool)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get moneypool %s: %v", moneyPool, err)
+	}
+	if out.Item == nil {
+		return nil, fmt.Errorf("moneypool %s not found", moneyPool)
 	}
-	return nil
+	var pool storedPool
+	if err := dynamodbattribute.UnmarshalMap(out.Item, &pool); err != nil {
+		return nil, fmt.Errorf("could not decode moneypool %s: %v", moneyPool, err)
+	}
+	return pool.contributions(), nil
 }
 
-func (s *DataStore) getAllMoneyPools() (names []*string, err error) {
+// GetMoneyPools returns the tenant's pools with their contributions.
+func (s *DataStore) GetMoneyPools(tenant string) ([]data.StoredPool, error) {
+	pools, err := s.scanPools(tenant)
+	if err != nil {
+		return nil, err
+	}
+	storedPools := make([]data.StoredPool, 0, len(pools))
+	for _, pool := range pools {
+		storedPools = append(storedPools, data.StoredPool{Name: pool.Name, Tenant: pool.Tenant, Contributions: pool.contributions()})
+	}
+	return storedPools, nil
+}
+
+// storedTransaction is the part of a stored transaction needed to find the transaction a refund belongs to,
+// duplicates of imported contributions, the transactions of a PayPal activity report, or what participants paid.
+type storedTransaction struct {
+	Id        string `dynamodbav:"id"`
+	Name      string `dynamodbav:"name"`
+	Date      string `dynamodbav:"date"`
+	Base      int    `dynamodbav:"base"`
+	Fraction  int    `dynamodbav:"fraction"`
+	Decimals  int    `dynamodbav:"decimals"`
+	Currency  string `dynamodbav:"currency"`
+	Anonymous bool   `dynamodbav:"anonymous"`
+	Voided    bool   `dynamodbav:"voided"`
+	PaypalId  string `dynamodbav:"paypalId"`
+	RefundOf  string `dynamodbav:"refundOf"`
+	Source    string `dynamodbav:"source"`
+	// Fee and net are set for transactions whose mail showed PayPal's fee.
+	Fee *data.Amount `dynamodbav:"fee"`
+	Net *data.Amount `dynamodbav:"net"`
+	// Participant is the id of the expected participant the transaction was linked to.
+	Participant string `dynamodbav:"participant"`
+}
+
+type storedPool struct {
+	Name         string              `dynamodbav:"name"`
+	Tenant       string              `dynamodbav:"tenant"`
+	Transactions []storedTransaction `dynamodbav:"transactions"`
+}
+
+func (p storedPool) contributions() []data.Contribution {
+	contributions := make([]data.Contribution, 0, len(p.Transactions))
+	for _, transaction := range p.Transactions {
+		contributions = append(contributions, data.Contribution{
+			Id:          transaction.Id,
+			Name:        transaction.Name,
+			Date:        transaction.Date,
+			Amount:      data.Amount{Base: transaction.Base, Fraction: transaction.Fraction, Decimals: transaction.Decimals},
+			Currency:    transaction.Currency,
+			Anonymous:   transaction.Anonymous,
+			PaypalId:    transaction.PaypalId,
+			RefundOf:    transaction.RefundOf,
+			Source:      transaction.Source,
+			Voided:      transaction.Voided,
+			Fee:         transaction.Fee,
+			Net:         transaction.Net,
+			Participant: transaction.Participant,
+		})
+	}
+	return contributions
+}
+
+// FindRefundedTransaction returns the tenant's transaction that the refund sends back, or nil if there is none.
+func (s *DataStore) FindRefundedTransaction(tenant string, refund data.Refund) (*data.StoredTransaction, error) {
+	pools, err := s.scanPools(tenant)
+	if err != nil {
+		return nil, err
+	}
+	return matchRefund(pools, refund), nil
+}
+
+// scanPools reads all pools of the tenant.
+func (s *DataStore) scanPools(tenant string) ([]storedPool, error) {
+	var pools []storedPool
+	err := dynamoClient.ScanPages(&dynamodb.ScanInput{
+		TableName: aws.String(s.MoneyPoolsTableName),
+	}, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			var pool storedPool
+			if err := dynamodbattribute.UnmarshalMap(item, &pool); err != nil {
+				// pools with malformed transactions can't be matched, but shouldn't hide the other pools
+				continue
+			}
+			if pool.Tenant == tenant {
+				pools = append(pools, pool)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get all moneypools %v", err)
+	}
+	return pools, nil
+}
+
+// matchRefund finds the refunded transaction by its PayPal transaction code. Without known code, it takes the latest
+// transaction of the sender with the same amount and currency. Voided and already refunded transactions are never matched.
+func matchRefund(pools []storedPool, refund data.Refund) *data.StoredTransaction {
+	refunded := map[string]bool{}
+	for _, pool := range pools {
+		for _, transaction := range pool.Transactions {
+			if transaction.RefundOf != "" {
+				refunded[transaction.RefundOf] = true
+			}
+		}
+	}
+
+	var match *data.StoredTransaction
+	for _, pool := range pools {
+		for _, transaction := range pool.Transactions {
+			if transaction.RefundOf != "" {
+				continue
+			}
+			refundable := !transaction.Voided && !refunded[transaction.Id]
+			found := &data.StoredTransaction{MoneyPool: pool.Name, Id: transaction.Id, Anonymous: transaction.Anonymous, Participant: transaction.Participant}
+			if refund.OriginalPaypalId != "" && transaction.PaypalId == refund.OriginalPaypalId {
+				if !refundable {
+					return nil
+				}
+				return found
+			}
+			if refundable && strings.EqualFold(strings.TrimSpace(transaction.Name), strings.TrimSpace(refund.Name)) &&
+				transaction.Base == refund.Base && transaction.Fraction == refund.Fraction && transaction.Decimals == refund.Decimals &&
+				data.CurrencyOrDefault(transaction.Currency) == data.CurrencyOrDefault(refund.Currency) {
+				match = found
+			}
+		}
+	}
+	return match
+}
+
+type poolKey struct {
+	key    string
+	tenant string
+}
+
+func (s *DataStore) getAllMoneyPools() (keys []poolKey, err error) {
 	input := &dynamodb.ScanInput{
 		TableName: aws.String(s.MoneyPoolsTableName),
 		AttributesToGet: []*string{
 			aws.String("name"),
+			aws.String("tenant"),
 		},
 	}
 	results, err := dynamoClient.Scan(input)
@@ -101,8 +309,11 @@ func (s *DataStore) getAllMoneyPools() (names []*string, err error) {
 	}
 
 	for _, result := range results.Items {
-		name := result["name"]
-		names = append(names, name.S)
+		key := poolKey{key: *result["name"].S}
+		if tenant, exists := result["tenant"]; exists && tenant.S != nil {
+			key.tenant = *tenant.S
+		}
+		keys = append(keys, key)
 	}
 	return
 }
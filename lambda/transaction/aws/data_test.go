@@ -0,0 +1,77 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+	"transaction/data"
+)
+
+type matchRefundTest struct {
+	name          string
+	refund        data.Refund
+	expectedMatch *data.StoredTransaction
+}
+
+func TestMatchRefund(t *testing.T) {
+	pools := []storedPool{
+		{
+			Name: "paul",
+			Transactions: []storedTransaction{
+				{Id: "t1", Name: "Sender Person", Base: 10, Fraction: 99, PaypalId: "3K6613774G352493Y"},
+				{Id: "t2", Name: "Other Person", Base: 5, Fraction: 0, Anonymous: true},
+				{Id: "t3", Name: "Voided Person", Base: 5, Fraction: 0, Voided: true},
+				{Id: "t4", Name: "Refunded Person", Base: 7, Fraction: 50, PaypalId: "1AB"},
+				{Id: "r1", Name: "Refunded Person", Base: 7, Fraction: 50, RefundOf: "t4"},
+			},
+		},
+		{
+			Name: "peter",
+			Transactions: []storedTransaction{
+				{Id: "t5", Name: "Sender Person", Base: 10, Fraction: 99},
+			},
+		},
+	}
+	testTable := []matchRefundTest{
+		{
+			"by_paypal_id",
+			data.Refund{Name: "Someone Else", Base: 1, OriginalPaypalId: "3K6613774G352493Y"},
+			&data.StoredTransaction{MoneyPool: "paul", Id: "t1"},
+		},
+		{
+			"by_name_and_amount_latest",
+			data.Refund{Name: "sender person ", Base: 10, Fraction: 99},
+			&data.StoredTransaction{MoneyPool: "peter", Id: "t5"},
+		},
+		{
+			"unknown_paypal_id_falls_back",
+			data.Refund{Name: "Other Person", Base: 5, OriginalPaypalId: "UNKNOWN"},
+			&data.StoredTransaction{MoneyPool: "paul", Id: "t2", Anonymous: true},
+		},
+		{
+			"voided",
+			data.Refund{Name: "Voided Person", Base: 5},
+			nil,
+		},
+		{
+			"already_refunded_by_paypal_id",
+			data.Refund{Name: "Refunded Person", Base: 7, Fraction: 50, OriginalPaypalId: "1AB"},
+			nil,
+		},
+		{
+			"already_refunded_by_name",
+			data.Refund{Name: "Refunded Person", Base: 7, Fraction: 50},
+			nil,
+		},
+		{
+			"different_amount",
+			data.Refund{Name: "Sender Person", Base: 10, Fraction: 98},
+			nil,
+		},
+	}
+	for _, test := range testTable {
+		match := matchRefund(pools, test.refund)
+		if !reflect.DeepEqual(match, test.expectedMatch) {
+			t.Fatalf("matchRefund(%s) = %+v, but expected %+v", test.name, match, test.expectedMatch)
+		}
+	}
+}
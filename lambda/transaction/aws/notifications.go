@@ -0,0 +1,133 @@
+package aws
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/service/ses/sesiface"
+	"transaction/data"
+)
+
+// notifiedPool is the part of a moneypool item needed to notify its owner, its webhooks and its contributors.
+type notifiedPool struct {
+	storedPool
+	Owner         string                     `dynamodbav:"owner"`
+	Title         string                     `dynamodbav:"title"`
+	Open          *bool                      `dynamodbav:"open"`
+	BaseCurrency  string                     `dynamodbav:"baseCurrency"`
+	TotalsBasis   string                     `dynamodbav:"totalsBasis"`
+	PaymentLink   string                     `dynamodbav:"paymentLink"`
+	PaypalMe      string                     `dynamodbav:"paypalMe"`
+	Notifications data.Notifications         `dynamodbav:"notifications"`
+	Webhooks      []data.WebhookSubscription `dynamodbav:"webhooks"`
+	ThankYou      *data.ThankYou             `dynamodbav:"thankYou"`
+	Expected      *data.Expected             `dynamodbav:"expected"`
+}
+
+func (p notifiedPool) notifiedPool() data.NotifiedPool {
+	pool := data.NotifiedPool{
+		Name:          p.Name,
+		Tenant:        p.Tenant,
+		Owner:         p.Owner,
+		Title:         p.Title,
+		Open:          p.Open == nil || *p.Open,
+		BaseCurrency:  p.BaseCurrency,
+		TotalsBasis:   p.TotalsBasis,
+		PayURL:        p.PaymentLink,
+		Notifications: p.Notifications,
+		Webhooks:      p.Webhooks,
+		ThankYou:      p.ThankYou,
+		Expected:      p.Expected,
+		Contributions: p.contributions(),
+	}
+	if pool.PayURL == "" && p.PaypalMe != "" {
+		pool.PayURL = "https://paypal.me/" + p.PaypalMe
+	}
+	return pool
+}
+
+// GetNotifiedPool returns the moneypool with its notification settings, or nil if there is no such moneypool.
+func (s *DataStore) GetNotifiedPool(moneyPool string) (*data.NotifiedPool, error) {
+	out, err := dynamoClient.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(s.MoneyPoolsTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"name": {S: aws.String(moneyPool)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get moneypool %s: %v", moneyPool, err)
+	}
+	if out.Item == nil {
+		return nil, nil
+	}
+	var pool notifiedPool
+	if err := dynamodbattribute.UnmarshalMap(out.Item, &pool); err != nil {
+		return nil, fmt.Errorf("could not decode moneypool %s: %v", moneyPool, err)
+	}
+	notified := pool.notifiedPool()
+	return &notified, nil
+}
+
+// GetExpected returns the pool's expected participants, or nil if the pool doesn't expect an amount per person.
+func (s *DataStore) GetExpected(moneyPool string) (*data.Expected, error) {
+	pool, err := s.GetNotifiedPool(moneyPool)
+	if err != nil || pool == nil {
+		return nil, err
+	}
+	return pool.Expected, nil
+}
+
+// GetNotifiedPools returns all pools of all tenants that have notification settings, webhooks or expected participants.
+func (s *DataStore) GetNotifiedPools() ([]data.NotifiedPool, error) {
+	var pools []data.NotifiedPool
+	err := dynamoClient.ScanPages(&dynamodb.ScanInput{
+		TableName:        aws.String(s.MoneyPoolsTableName),
+		FilterExpression: aws.String("attribute_exists(notifications) OR attribute_exists(webhooks) OR attribute_exists(expected)"),
+	}, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			var pool notifiedPool
+			if err := dynamodbattribute.UnmarshalMap(item, &pool); err != nil {
+				// a malformed pool can't be notified, but shouldn't keep the other pools from being notified
+				continue
+			}
+			pools = append(pools, pool.notifiedPool())
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get notified moneypools %v", err)
+	}
+	return pools, nil
+}
+
+// SESMailer sends notification mails via SES.
+type SESMailer struct {
+	Sender string
+	Client sesiface.SESAPI
+}
+
+func NewSESMailer(sender string, client sesiface.SESAPI) *SESMailer {
+	return &SESMailer{
+		Sender: sender,
+		Client: client,
+	}
+}
+
+func (m *SESMailer) SendMail(to, subject, text string) error {
+	_, err := m.Client.SendEmail(&ses.SendEmailInput{
+		Source:      aws.String(m.Sender),
+		Destination: &ses.Destination{ToAddresses: []*string{aws.String(to)}},
+		Message: &ses.Message{
+			Subject: &ses.Content{Charset: aws.String("UTF-8"), Data: aws.String(subject)},
+			Body: &ses.Body{
+				Text: &ses.Content{Charset: aws.String("UTF-8"), Data: aws.String(text)},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not send mail to %s: %v", to, err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package aws
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/service/ses/sesiface"
+	"reflect"
+	"testing"
+	"transaction/data"
+)
+
+func TestDecodeNotifiedPool(t *testing.T) {
+	item := map[string]*dynamodb.AttributeValue{
+		"name":         {S: aws.String("smiths.mom")},
+		"tenant":       {S: aws.String("smiths")},
+		"owner":        {S: aws.String("owner-sub")},
+		"title":        {S: aws.String("Gift for mom")},
+		"baseCurrency": {S: aws.String("USD")},
+		"totalsBasis":  {S: aws.String("net")},
+		"paypalMe":     {S: aws.String("smiths")},
+		"expected": {M: map[string]*dynamodb.AttributeValue{
+			"mode":   {S: aws.String("fixed")},
+			"amount": {M: map[string]*dynamodb.AttributeValue{"base": {N: aws.String("25")}, "fraction": {N: aws.String("0")}}},
+			"participants": {L: []*dynamodb.AttributeValue{{M: map[string]*dynamodb.AttributeValue{
+				"id":    {S: aws.String("a1")},
+				"name":  {S: aws.String("Anna")},
+				"email": {S: aws.String("anna@example.com")},
+			}}}},
+		}},
+		"notifications": {M: map[string]*dynamodb.AttributeValue{
+			"goal": {M: map[string]*dynamodb.AttributeValue{"base": {N: aws.String("50")}, "fraction": {N: aws.String("0")}}},
+			"channels": {L: []*dynamodb.AttributeValue{{M: map[string]*dynamodb.AttributeValue{
+				"type":    {S: aws.String("mail")},
+				"address": {S: aws.String("owner@example.com")},
+				"on":      {L: []*dynamodb.AttributeValue{{S: aws.String("payment")}, {S: aws.String("daily")}}},
+			}}}},
+		}},
+		"webhooks": {L: []*dynamodb.AttributeValue{{M: map[string]*dynamodb.AttributeValue{
+			"id":     {S: aws.String("hook-1")},
+			"url":    {S: aws.String("https://example.com/hook")},
+			"secret": {S: aws.String("secret")},
+			"events": {L: []*dynamodb.AttributeValue{{S: aws.String("transaction.added")}}},
+		}}}},
+		"transactions": {L: []*dynamodb.AttributeValue{{M: map[string]*dynamodb.AttributeValue{
+			"id":          {S: aws.String("1")},
+			"name":        {S: aws.String("Anna")},
+			"date":        {S: aws.String("01.03.22")},
+			"base":        {N: aws.String("5")},
+			"fraction":    {N: aws.String("50")},
+			"participant": {S: aws.String("a1")},
+			"fee":         {M: map[string]*dynamodb.AttributeValue{"base": {N: aws.String("0")}, "fraction": {N: aws.String("50")}}},
+			"net":         {M: map[string]*dynamodb.AttributeValue{"base": {N: aws.String("5")}, "fraction": {N: aws.String("0")}}},
+		}}}},
+	}
+	var pool notifiedPool
+	if err := dynamodbattribute.UnmarshalMap(item, &pool); err != nil {
+		t.Fatalf("could not decode pool: %v", err)
+	}
+	expected := data.NotifiedPool{
+		Name:         "smiths.mom",
+		Tenant:       "smiths",
+		Owner:        "owner-sub",
+		Title:        "Gift for mom",
+		Open:         true,
+		BaseCurrency: "USD",
+		TotalsBasis:  data.TotalsNet,
+		PayURL:       "https://paypal.me/smiths",
+		Expected: &data.Expected{
+			Mode:         data.ModeFixed,
+			Amount:       &data.Amount{Base: 25},
+			Participants: []data.Participant{{Id: "a1", Name: "Anna", Email: "anna@example.com"}},
+		},
+		Notifications: data.Notifications{
+			Goal: &data.Amount{Base: 50},
+			Channels: []data.NotificationChannel{
+				{Type: data.ChannelMail, Address: "owner@example.com", On: []string{data.NotifyPayment, data.NotifyDaily}},
+			},
+		},
+		Webhooks: []data.WebhookSubscription{
+			{Id: "hook-1", URL: "https://example.com/hook", Secret: "secret", Events: []string{data.WebhookTransactionAdded}},
+		},
+		Contributions: []data.Contribution{{Id: "1", Name: "Anna", Date: "01.03.22", Amount: data.Amount{Base: 5, Fraction: 50},
+			Fee: &data.Amount{Fraction: 50}, Net: &data.Amount{Base: 5}, Participant: "a1"}},
+	}
+	if notified := pool.notifiedPool(); !reflect.DeepEqual(notified, expected) {
+		t.Fatalf("decoded pool %+v, but expected %+v", notified, expected)
+	}
+}
+
+func TestSendMail(t *testing.T) {
+	client := &FakeSES{}
+	mailer := NewSESMailer("pools@example.com", client)
+	if err := mailer.SendMail("owner@example.com", "New contribution", "Anna: 5.00 EUR"); err != nil {
+		t.Fatalf("SendMail returned error %v", err)
+	}
+	input := client.input
+	if *input.Source != "pools@example.com" || *input.Destination.ToAddresses[0] != "owner@example.com" ||
+		*input.Message.Subject.Data != "New contribution" || *input.Message.Body.Text.Data != "Anna: 5.00 EUR" {
+		t.Fatalf("unexpected mail %v", input)
+	}
+
+	client.err = errors.New("MessageRejected: address not verified")
+	expected := errors.New("could not send mail to owner@example.com: MessageRejected: address not verified")
+	if err := mailer.SendMail("owner@example.com", "New contribution", "text"); !compareErrors(err, expected) {
+		t.Fatalf("SendMail = %v, but expected %v", err, expected)
+	}
+}
+
+// FakeSES keeps the last sent mail, or fails with err if set.
+type FakeSES struct {
+	sesiface.SESAPI
+	input *ses.SendEmailInput
+	err   error
+}
+
+func (s *FakeSES) SendEmail(input *ses.SendEmailInput) (*ses.SendEmailOutput, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.input = input
+	return &ses.SendEmailOutput{}, nil
+}
@@ -0,0 +1,99 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/apigatewaymanagementapi"
+	"github.com/aws/aws-sdk-go/service/apigatewaymanagementapi/apigatewaymanagementapiiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"transaction/data"
+)
+
+// ConnectionsPoolIndex is the index of the live connections table that finds all connections watching a moneypool.
+const ConnectionsPoolIndex = "pool-index"
+
+// EventPublisher pushes moneypool events to all websocket connections that are registered for the moneypool.
+type EventPublisher struct {
+	ConnectionsTableName string
+	DynamoClient         dynamodbiface.DynamoDBAPI
+	Connections          apigatewaymanagementapiiface.ApiGatewayManagementApiAPI
+}
+
+func NewEventPublisher(connectionsTableName string, dynamoClient dynamodbiface.DynamoDBAPI, connections apigatewaymanagementapiiface.ApiGatewayManagementApiAPI) *EventPublisher {
+	return &EventPublisher{
+		ConnectionsTableName: connectionsTableName,
+		DynamoClient:         dynamoClient,
+		Connections:          connections,
+	}
+}
+
+func (p *EventPublisher) Publish(event data.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("could not marshal event %+v: %v", event, err)
+	}
+	connectionIds, err := p.getConnections(event.MoneyPool)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, connectionId := range connectionIds {
+		_, err := p.Connections.PostToConnection(&apigatewaymanagementapi.PostToConnectionInput{
+			ConnectionId: aws.String(connectionId),
+			Data:         payload,
+		})
+		if err == nil {
+			continue
+		}
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == apigatewaymanagementapi.ErrCodeGoneException {
+			// the client went away without a proper disconnect, so we clean up for it
+			_ = p.removeConnection(connectionId)
+			continue
+		}
+		failed++
+	}
+	if failed > 0 {
+		return fmt.Errorf("could not send event to %d of %d connections", failed, len(connectionIds))
+	}
+	return nil
+}
+
+func (p *EventPublisher) getConnections(moneyPool string) ([]string, error) {
+	var connectionIds []string
+	err := p.DynamoClient.QueryPages(&dynamodb.QueryInput{
+		TableName:              aws.String(p.ConnectionsTableName),
+		IndexName:              aws.String(ConnectionsPoolIndex),
+		KeyConditionExpression: aws.String("pool = :pool"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":pool": {S: aws.String(moneyPool)},
+		},
+	}, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			if id, exists := item["connectionId"]; exists && id.S != nil {
+				connectionIds = append(connectionIds, *id.S)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get connections for moneypool %s: %v", moneyPool, err)
+	}
+	return connectionIds, nil
+}
+
+func (p *EventPublisher) removeConnection(connectionId string) error {
+	_, err := p.DynamoClient.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(p.ConnectionsTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"connectionId": {S: aws.String(connectionId)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not remove connection %s: %v", connectionId, err)
+	}
+	return nil
+}
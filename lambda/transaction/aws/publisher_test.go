@@ -0,0 +1,166 @@
+package aws
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/apigatewaymanagementapi"
+	"github.com/aws/aws-sdk-go/service/apigatewaymanagementapi/apigatewaymanagementapiiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"reflect"
+	"sort"
+	"testing"
+	"transaction/data"
+)
+
+type publishTest struct {
+	name                string
+	connections         map[string]string
+	goneConnections     []string
+	failingConnections  []string
+	expectedSent        []string
+	expectedConnections []string
+	expectedError       error
+}
+
+var testEvent = data.Event{
+	Type:          data.EventContributionAdded,
+	MoneyPool:     "paul",
+	TransactionId: "id",
+}
+
+const testEventPayload = `{"type":"contribution.added","moneyPool":"paul","transactionId":"id"}`
+
+func TestPublish(t *testing.T) {
+	testTable := []publishTest{
+		{
+			"no_connections",
+			map[string]string{"c1": "peter"},
+			nil,
+			nil,
+			nil,
+			[]string{"c1"},
+			nil,
+		},
+		{
+			"only_pool_connections",
+			map[string]string{"c1": "paul", "c2": "peter", "c3": "paul"},
+			nil,
+			nil,
+			[]string{"c1", "c3"},
+			[]string{"c1", "c2", "c3"},
+			nil,
+		},
+		{
+			"gone_connection_removed",
+			map[string]string{"c1": "paul", "c2": "paul"},
+			[]string{"c2"},
+			nil,
+			[]string{"c1"},
+			[]string{"c1"},
+			nil,
+		},
+		{
+			"failing_connection",
+			map[string]string{"c1": "paul", "c2": "paul"},
+			nil,
+			[]string{"c1"},
+			[]string{"c2"},
+			[]string{"c1", "c2"},
+			errors.New("could not send event to 1 of 2 connections"),
+		},
+	}
+	for _, test := range testTable {
+		db := &FakeConnectionsTable{connections: test.connections}
+		api := &FakeConnectionsApi{gone: test.goneConnections, failing: test.failingConnections}
+		publisher := NewEventPublisher("LiveConnectionsTable", db, api)
+
+		err := publisher.Publish(testEvent)
+		sort.Strings(api.sent)
+		if !compareErrors(err, test.expectedError) || !reflect.DeepEqual(api.sent, test.expectedSent) {
+			t.Fatalf("Publish(%s) sent to %v with error %v, but should send to %v with error %v", test.name, api.sent, err, test.expectedSent, test.expectedError)
+		}
+		if remaining := db.connectionIds(); !reflect.DeepEqual(remaining, test.expectedConnections) {
+			t.Fatalf("Publish(%s) left connections %v, but should leave %v", test.name, remaining, test.expectedConnections)
+		}
+		for _, payload := range api.payloads {
+			if payload != testEventPayload {
+				t.Fatalf("Publish(%s) sent payload %s, but should send %s", test.name, payload, testEventPayload)
+			}
+		}
+	}
+}
+
+func TestPublishQueryError(t *testing.T) {
+	publisher := NewEventPublisher("LiveConnectionsTable", &FakeConnectionsTable{queryErr: errors.New("timeout")}, &FakeConnectionsApi{})
+	err := publisher.Publish(testEvent)
+	expected := errors.New("could not get connections for moneypool paul: timeout")
+	if !compareErrors(err, expected) {
+		t.Fatalf("Publish(query_error) returned error %v, but should return %v", err, expected)
+	}
+}
+
+// FakeConnectionsTable holds connection ids mapped to the moneypool they are watching.
+type FakeConnectionsTable struct {
+	dynamodbiface.DynamoDBAPI
+	connections map[string]string
+	queryErr    error
+}
+
+func (t *FakeConnectionsTable) QueryPages(input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool) error {
+	if t.queryErr != nil {
+		return t.queryErr
+	}
+	pool := *input.ExpressionAttributeValues[":pool"].S
+	var items []map[string]*dynamodb.AttributeValue
+	for id, connectionPool := range t.connections {
+		if connectionPool == pool {
+			items = append(items, map[string]*dynamodb.AttributeValue{
+				"connectionId": {S: aws.String(id)},
+				"pool":         {S: aws.String(connectionPool)},
+			})
+		}
+	}
+	fn(&dynamodb.QueryOutput{Items: items}, true)
+	return nil
+}
+
+func (t *FakeConnectionsTable) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	delete(t.connections, *input.Key["connectionId"].S)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (t *FakeConnectionsTable) connectionIds() []string {
+	var ids []string
+	for id := range t.connections {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+type FakeConnectionsApi struct {
+	apigatewaymanagementapiiface.ApiGatewayManagementApiAPI
+	gone     []string
+	failing  []string
+	sent     []string
+	payloads []string
+}
+
+func (a *FakeConnectionsApi) PostToConnection(input *apigatewaymanagementapi.PostToConnectionInput) (*apigatewaymanagementapi.PostToConnectionOutput, error) {
+	id := *input.ConnectionId
+	for _, gone := range a.gone {
+		if gone == id {
+			return nil, awserr.New(apigatewaymanagementapi.ErrCodeGoneException, "gone", nil)
+		}
+	}
+	for _, failing := range a.failing {
+		if failing == id {
+			return nil, errors.New("internal error")
+		}
+	}
+	a.sent = append(a.sent, id)
+	a.payloads = append(a.payloads, string(input.Data))
+	return &apigatewaymanagementapi.PostToConnectionOutput{}, nil
+}
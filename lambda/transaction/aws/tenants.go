@@ -0,0 +1,53 @@
+package aws
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"strings"
+	"transaction/data"
+)
+
+// TenantsAddressIndex is the index of the tenants table that finds a tenant by its receiving address.
+const TenantsAddressIndex = "address-index"
+
+// TenantStore finds the tenants that owners registered via the api.
+type TenantStore struct {
+	TenantsTableName string
+	DynamoClient     dynamodbiface.DynamoDBAPI
+}
+
+func NewTenantStore(tenantsTableName string, dynamoClient dynamodbiface.DynamoDBAPI) *TenantStore {
+	return &TenantStore{
+		TenantsTableName: tenantsTableName,
+		DynamoClient:     dynamoClient,
+	}
+}
+
+// FindTenantByAddress returns the tenant receiving mails at the address, or nil if there is none.
+func (s *TenantStore) FindTenantByAddress(address string) (*data.Tenant, error) {
+	out, err := s.DynamoClient.Query(&dynamodb.QueryInput{
+		TableName:              aws.String(s.TenantsTableName),
+		IndexName:              aws.String(TenantsAddressIndex),
+		KeyConditionExpression: aws.String("#address = :address"),
+		ExpressionAttributeNames: map[string]*string{
+			"#address": aws.String("address"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":address": {S: aws.String(strings.ToLower(address))},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get tenant for address %s: %v", address, err)
+	}
+	if len(out.Items) == 0 {
+		return nil, nil
+	}
+	var tenant data.Tenant
+	if err := dynamodbattribute.UnmarshalMap(out.Items[0], &tenant); err != nil {
+		return nil, fmt.Errorf("could not decode tenant for address %s: %v", address, err)
+	}
+	return &tenant, nil
+}
@@ -0,0 +1,76 @@
+package aws
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"reflect"
+	"testing"
+	"transaction/data"
+)
+
+type tenantTest struct {
+	name           string
+	address        string
+	expectedTenant *data.Tenant
+	expectedError  error
+}
+
+func TestFindTenantByAddress(t *testing.T) {
+	testTable := []tenantTest{
+		{
+			"known_address",
+			"Pools+Smiths@example.com",
+			&data.Tenant{Name: "smiths", Address: "pools+smiths@example.com", NameAmountRegex: "(?P<name>.+) sent you (?P<amount>.+)", Owner: "owner-sub"},
+			nil,
+		},
+		{
+			"unknown_address",
+			"pools+millers@example.com",
+			nil,
+			nil,
+		},
+		{
+			"query_error",
+			"broken@example.com",
+			nil,
+			errors.New("could not get tenant for address broken@example.com: timeout"),
+		},
+	}
+	db := &FakeTenantsTable{tenants: []map[string]*dynamodb.AttributeValue{
+		{
+			"tenant":          {S: aws.String("smiths")},
+			"address":         {S: aws.String("pools+smiths@example.com")},
+			"owner":           {S: aws.String("owner-sub")},
+			"nameAmountRegex": {S: aws.String("(?P<name>.+) sent you (?P<amount>.+)")},
+		},
+	}}
+	store := NewTenantStore("TenantsTable", db)
+	for _, test := range testTable {
+		tenant, err := store.FindTenantByAddress(test.address)
+		if !compareErrors(err, test.expectedError) || !reflect.DeepEqual(tenant, test.expectedTenant) {
+			t.Fatalf("FindTenantByAddress(%s) = %+v, %v but expected %+v, %v", test.name, tenant, err, test.expectedTenant, test.expectedError)
+		}
+	}
+}
+
+// FakeTenantsTable answers queries on the address index. Queries for broken@example.com fail.
+type FakeTenantsTable struct {
+	dynamodbiface.DynamoDBAPI
+	tenants []map[string]*dynamodb.AttributeValue
+}
+
+func (t *FakeTenantsTable) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	address := *input.ExpressionAttributeValues[":address"].S
+	if address == "broken@example.com" {
+		return nil, errors.New("timeout")
+	}
+	out := &dynamodb.QueryOutput{}
+	for _, tenant := range t.tenants {
+		if *input.IndexName == TenantsAddressIndex && *tenant["address"].S == address {
+			out.Items = append(out.Items, tenant)
+		}
+	}
+	return out, nil
+}
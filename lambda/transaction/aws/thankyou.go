@@ -0,0 +1,88 @@
+package aws
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"strconv"
+	"time"
+)
+
+// thankYouRetention is how long thanked transactions are remembered.
+const thankYouRetention = 30 * 24 * time.Hour
+
+// ThankYouLog remembers the thanked transactions and counts the sent thank-you mails for rate limits. The table removes
+// its entries once they expire.
+type ThankYouLog struct {
+	TableName    string
+	DynamoClient dynamodbiface.DynamoDBAPI
+	now          func() time.Time
+}
+
+func NewThankYouLog(tableName string, dynamoClient dynamodbiface.DynamoDBAPI) *ThankYouLog {
+	return &ThankYouLog{
+		TableName:    tableName,
+		DynamoClient: dynamoClient,
+		now:          time.Now,
+	}
+}
+
+// Claim records that the transaction is thanked. It returns false if the transaction was thanked before.
+func (l *ThankYouLog) Claim(moneyPool, transactionId string) (bool, error) {
+	now := l.now().UTC()
+	_, err := l.DynamoClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(l.TableName),
+		Item: map[string]*dynamodb.AttributeValue{
+			"key":       {S: aws.String("transaction#" + moneyPool + "#" + transactionId)},
+			"createdAt": {S: aws.String(now.Format(time.RFC3339))},
+			"expiresAt": {N: aws.String(strconv.FormatInt(now.Add(thankYouRetention).Unix(), 10))},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(#key)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#key": aws.String("key"),
+		},
+	})
+	if isConditionFailed(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not record thanked transaction %s: %v", transactionId, err)
+	}
+	return true, nil
+}
+
+// Allow counts a mail for the key in the current window of the given length. It returns false, without counting the
+// mail, if the limit of the window is reached.
+func (l *ThankYouLog) Allow(key string, limit int, window time.Duration) (bool, error) {
+	start := l.now().UTC().Truncate(window)
+	_, err := l.DynamoClient.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: aws.String(l.TableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"key": {S: aws.String("limit#" + key + "#" + start.Format(time.RFC3339))},
+		},
+		UpdateExpression:    aws.String("SET expiresAt = :expiresAt ADD #count :one"),
+		ConditionExpression: aws.String("attribute_not_exists(#count) OR #count < :limit"),
+		ExpressionAttributeNames: map[string]*string{
+			"#count": aws.String("count"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":one":       {N: aws.String("1")},
+			":limit":     {N: aws.String(strconv.Itoa(limit))},
+			":expiresAt": {N: aws.String(strconv.FormatInt(start.Add(window).Unix(), 10))},
+		},
+	})
+	if isConditionFailed(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not count thank-you mail: %v", err)
+	}
+	return true, nil
+}
+
+func isConditionFailed(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
@@ -0,0 +1,81 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestThankYouLogClaim(t *testing.T) {
+	db := &FakeThankYouTable{items: map[string]int{}}
+	log := NewThankYouLog("ThankYouMailsTable", db)
+	log.now = func() time.Time { return time.Date(2022, 3, 2, 10, 30, 0, 0, time.UTC) }
+
+	if claimed, err := log.Claim("mom", "1"); err != nil || !claimed {
+		t.Fatalf("Claim returned %v, %v but expected the transaction to be claimed", claimed, err)
+	}
+	if claimed, err := log.Claim("mom", "1"); err != nil || claimed {
+		t.Fatalf("Claim(again) returned %v, %v but expected the transaction to be claimed before", claimed, err)
+	}
+	if claimed, err := log.Claim("mom", "2"); err != nil || !claimed {
+		t.Fatalf("Claim(other) returned %v, %v but expected the transaction to be claimed", claimed, err)
+	}
+	if *db.put.Item["expiresAt"].N != "1648809000" {
+		t.Fatalf("unexpected claim %v", db.put.Item)
+	}
+}
+
+func TestThankYouLogAllow(t *testing.T) {
+	db := &FakeThankYouTable{items: map[string]int{}}
+	log := NewThankYouLog("ThankYouMailsTable", db)
+	log.now = func() time.Time { return time.Date(2022, 3, 2, 10, 30, 0, 0, time.UTC) }
+
+	for i := 0; i < 2; i++ {
+		if allowed, err := log.Allow("pool#mom", 2, time.Hour); err != nil || !allowed {
+			t.Fatalf("Allow(%d) returned %v, %v but expected the mail to be allowed", i, allowed, err)
+		}
+	}
+	if allowed, err := log.Allow("pool#mom", 2, time.Hour); err != nil || allowed {
+		t.Fatalf("Allow(over_limit) returned %v, %v but expected the mail to be limited", allowed, err)
+	}
+	if *db.update.Key["key"].S != "limit#pool#mom#2022-03-02T10:00:00Z" || *db.update.ExpressionAttributeValues[":expiresAt"].N != "1646218800" {
+		t.Fatalf("unexpected update %v", db.update)
+	}
+
+	log.now = func() time.Time { return time.Date(2022, 3, 2, 11, 0, 0, 0, time.UTC) }
+	if allowed, err := log.Allow("pool#mom", 2, time.Hour); err != nil || !allowed {
+		t.Fatalf("Allow(next_window) returned %v, %v but expected the mail to be allowed", allowed, err)
+	}
+}
+
+// FakeThankYouTable keeps the keys of claims and the counts of limits, and checks the conditions the log uses.
+type FakeThankYouTable struct {
+	dynamodbiface.DynamoDBAPI
+	items  map[string]int
+	put    *dynamodb.PutItemInput
+	update *dynamodb.UpdateItemInput
+}
+
+func (t *FakeThankYouTable) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	t.put = input
+	key := *input.Item["key"].S
+	if _, exists := t.items[key]; exists {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "condition failed", nil)
+	}
+	t.items[key] = 0
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (t *FakeThankYouTable) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	t.update = input
+	key := *input.Key["key"].S
+	limit, _ := strconv.Atoi(*input.ExpressionAttributeValues[":limit"].N)
+	if t.items[key] >= limit {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "condition failed", nil)
+	}
+	t.items[key]++
+	return &dynamodb.UpdateItemOutput{}, nil
+}
@@ -0,0 +1,72 @@
+package aws
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"time"
+	"transaction/data"
+)
+
+// DeliveryLog keeps the deliveries of webhook events, which the api serves to pool admins. The table removes deliveries
+// once they expire.
+type DeliveryLog struct {
+	DeliveriesTableName string
+	DynamoClient        dynamodbiface.DynamoDBAPI
+}
+
+func NewDeliveryLog(deliveriesTableName string, dynamoClient dynamodbiface.DynamoDBAPI) *DeliveryLog {
+	return &DeliveryLog{
+		DeliveriesTableName: deliveriesTableName,
+		DynamoClient:        dynamoClient,
+	}
+}
+
+// Put writes the delivery, replacing its earlier attempts.
+func (l *DeliveryLog) Put(delivery data.WebhookDelivery) error {
+	item, err := dynamodbattribute.MarshalMap(delivery)
+	if err != nil {
+		return fmt.Errorf("could not encode delivery: %v", err)
+	}
+	_, err = l.DynamoClient.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(l.DeliveriesTableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("could not write delivery: %v", err)
+	}
+	return nil
+}
+
+// DueDeliveries returns the pending deliveries of all pools whose next attempt is due.
+func (l *DeliveryLog) DueDeliveries(now time.Time) ([]data.WebhookDelivery, error) {
+	var deliveries []data.WebhookDelivery
+	var decodeErr error
+	err := l.DynamoClient.ScanPages(&dynamodb.ScanInput{
+		TableName:        aws.String(l.DeliveriesTableName),
+		FilterExpression: aws.String("#status = :pending AND nextAttempt <= :now"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status": aws.String("status"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":pending": {S: aws.String(data.DeliveryPending)},
+			":now":     {S: aws.String(now.UTC().Format(time.RFC3339))},
+		},
+	}, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		var pageDeliveries []data.WebhookDelivery
+		if decodeErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &pageDeliveries); decodeErr != nil {
+			return false
+		}
+		deliveries = append(deliveries, pageDeliveries...)
+		return true
+	})
+	if err == nil {
+		err = decodeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get due deliveries: %v", err)
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,58 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"testing"
+	"time"
+	"transaction/data"
+)
+
+func TestDeliveryLog(t *testing.T) {
+	db := &FakeDeliveriesTable{}
+	log := NewDeliveryLog("WebhookDeliveriesTable", db)
+	delivery := data.WebhookDelivery{
+		Pool:         "mom",
+		DeliveryId:   "2022-03-02T10:00:00.000000000Z#1",
+		Subscription: "spreadsheet",
+		Event:        data.WebhookTransactionAdded,
+		Status:       data.DeliveryPending,
+		Attempts:     1,
+		NextAttempt:  "2022-03-02T10:01:00Z",
+	}
+	if err := log.Put(delivery); err != nil {
+		t.Fatalf("Put returned error %v", err)
+	}
+	item := db.items[0]
+	if *item["pool"].S != "mom" || *item["status"].S != "pending" || *item["attempts"].N != "1" || item["responseStatus"] != nil {
+		t.Fatalf("unexpected delivery item %v", item)
+	}
+
+	deliveries, err := log.DueDeliveries(time.Date(2022, 3, 2, 10, 1, 0, 0, time.UTC))
+	if err != nil || len(deliveries) != 1 || deliveries[0] != delivery {
+		t.Fatalf("DueDeliveries = %+v, %v but expected %+v", deliveries, err, delivery)
+	}
+	scan := db.scan
+	if *scan.TableName != "WebhookDeliveriesTable" || *scan.ExpressionAttributeValues[":now"].S != "2022-03-02T10:01:00Z" ||
+		*scan.ExpressionAttributeValues[":pending"].S != "pending" {
+		t.Fatalf("unexpected scan %v", scan)
+	}
+}
+
+// FakeDeliveriesTable keeps the written items, and returns all of them on scans.
+type FakeDeliveriesTable struct {
+	dynamodbiface.DynamoDBAPI
+	items []map[string]*dynamodb.AttributeValue
+	scan  *dynamodb.ScanInput
+}
+
+func (t *FakeDeliveriesTable) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	t.items = append(t.items, input.Item)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (t *FakeDeliveriesTable) ScanPages(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	t.scan = input
+	fn(&dynamodb.ScanOutput{Items: t.items}, true)
+	return nil
+}
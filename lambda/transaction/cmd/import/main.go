@@ -0,0 +1,83 @@
+// Command import adds contributions from a csv or json file to a moneypool, e.g. cash collected before the pool existed.
+// Without -apply, it only prints what it would add.
+//
+//	go run ./cmd/import -pool mom -file contributions.csv [-source manual] [-apply]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"transaction/aws"
+	"transaction/importer"
+)
+
+func main() {
+	pool := flag.String("pool", "", "name of the moneypool")
+	file := flag.String("file", "", "csv or json file with the columns name, amount, date and optionally currency and note")
+	format := flag.String("format", "", "csv or json, by default the file's extension")
+	source := flag.String("source", importer.SourceImport, "source stored with the contributions, import or manual")
+	apply := flag.Bool("apply", false, "add the new contributions, instead of only printing them")
+	table := flag.String("table", os.Getenv("MoneyPoolsTableName"), "moneypools table")
+	auditTable := flag.String("audit-table", os.Getenv("AuditLogTableName"), "audit log table, leave empty to not audit the import")
+	flag.Parse()
+
+	if *pool == "" || *file == "" || *table == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+	}
+	body, err := os.ReadFile(*file)
+	if err != nil {
+		fail(err)
+	}
+
+	var auditLog importer.AuditLog
+	if *auditTable != "" {
+		auditLog = aws.NewAuditLog(*auditTable, dynamodb.New(session.Must(session.NewSession())))
+	}
+	actor := "cli"
+	if current, err := user.Current(); err == nil {
+		actor = "cli:" + current.Username
+	}
+	result, err := importer.New(aws.NewDataStore(*table), auditLog).Import(importer.Request{
+		MoneyPool: *pool,
+		Format:    *format,
+		Body:      string(body),
+		Source:    *source,
+		Apply:     *apply,
+		Actor:     actor,
+		RequestId: fmt.Sprintf("cli-%d", os.Getpid()),
+	})
+	if err != nil {
+		fail(err)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "ROW\tSTATUS\tNAME\tDATE\tAMOUNT\tID\tERROR")
+	for _, row := range result.Rows {
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", row.Row, row.Status, row.Name, row.Date, row.Amount, row.Id, row.Error)
+	}
+	writer.Flush()
+	if result.DryRun {
+		fmt.Printf("dry run: %d new, %d duplicates, %d invalid; run with -apply to add them\n", result.New, result.Duplicates, result.Invalid)
+	} else {
+		fmt.Printf("%d added, %d duplicates, %d invalid, %d failed\n", result.Added, result.Duplicates, result.Invalid, result.Failed)
+	}
+	if result.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "import:", err)
+	os.Exit(1)
+}
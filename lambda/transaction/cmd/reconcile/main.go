@@ -0,0 +1,144 @@
+// Command reconcile compares PayPal's activity report, downloaded as csv, with the stored transactions. It lists the
+// payments missing from the pools, the contributions missing from PayPal and the amounts that differ. With -import,
+// the missing payments are imported into the pools their notes name; without -apply, that import is a dry run.
+//
+//	go run ./cmd/reconcile -file Download.CSV [-tenant name] [-import [-apply]]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"os"
+	"os/user"
+	"sort"
+	"text/tabwriter"
+	"transaction/aws"
+	"transaction/data"
+	"transaction/importer"
+	"transaction/reconcile"
+)
+
+func main() {
+	file := flag.String("file", "", "PayPal activity report as csv")
+	tenant := flag.String("tenant", "", "tenant whose pools the PayPal account belongs to, empty for the default pools")
+	importMissing := flag.Bool("import", false, "import the payments missing from the pools into the pools their notes name")
+	apply := flag.Bool("apply", false, "add the imported payments, instead of only printing them")
+	table := flag.String("table", os.Getenv("MoneyPoolsTableName"), "moneypools table")
+	auditTable := flag.String("audit-table", os.Getenv("AuditLogTableName"), "audit log table, leave empty to not audit the import")
+	flag.Parse()
+
+	if *file == "" || *table == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	report, err := os.Open(*file)
+	if err != nil {
+		fail(err)
+	}
+	rows, skipped, err := reconcile.ParseActivity(report)
+	report.Close()
+	if err != nil {
+		fail(err)
+	}
+	store := aws.NewDataStore(*table)
+	pools, err := store.GetMoneyPools(*tenant)
+	if err != nil {
+		fail(err)
+	}
+
+	result := reconcile.Reconcile(rows, pools)
+	fmt.Printf("%d payments in the report, %d matched, %d other rows skipped\n\n", len(rows), result.Matched, skipped)
+	printReport(result)
+
+	if !*importMissing {
+		return
+	}
+	var auditLog importer.AuditLog
+	if *auditTable != "" {
+		auditLog = aws.NewAuditLog(*auditTable, dynamodb.New(session.Must(session.NewSession())))
+	}
+	actor := "cli"
+	if current, err := user.Current(); err == nil {
+		actor = "cli:" + current.Username
+	}
+	poolRows := result.ImportRows()
+	poolNames := make([]string, 0, len(poolRows))
+	for pool := range poolRows {
+		poolNames = append(poolNames, pool)
+	}
+	sort.Strings(poolNames)
+	failed := false
+	for _, pool := range poolNames {
+		body, err := json.Marshal(poolRows[pool])
+		if err != nil {
+			fail(err)
+		}
+		imported, err := importer.New(store, auditLog).Import(importer.Request{
+			MoneyPool: pool,
+			Format:    "json",
+			Body:      string(body),
+			Source:    importer.SourceImport,
+			Apply:     *apply,
+			Actor:     actor,
+			RequestId: fmt.Sprintf("reconcile-%d", os.Getpid()),
+		})
+		if err != nil {
+			fail(fmt.Errorf("import into %s: %v", pool, err))
+		}
+		fmt.Printf("\nimport into %s: %d new, %d added, %d duplicates, %d invalid, %d failed\n",
+			pool, imported.New, imported.Added, imported.Duplicates, imported.Invalid, imported.Failed)
+		for _, row := range imported.Rows {
+			if row.Error != "" {
+				fmt.Printf("  %s %s: %s\n", row.Name, row.Amount, row.Error)
+			}
+		}
+		failed = failed || imported.Failed > 0
+	}
+	if !*apply && len(poolNames) > 0 {
+		fmt.Println("\ndry run; run with -apply to add the missing payments")
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func printReport(result reconcile.Report) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(writer, "MISSING FROM POOLS (%d)\n", len(result.MissingFromPools))
+	fmt.Fprintln(writer, "ROW\tDATE\tNAME\tAMOUNT\tPAYPAL ID\tNOTE\tPOOL")
+	for _, missing := range result.MissingFromPools {
+		pool := missing.Pool
+		if pool == "" {
+			pool = "? " + missing.Problem
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", missing.Line, missing.Date.Format("2006-01-02"), missing.Name,
+			formatAmount(missing.Cents, missing.Currency), missing.PaypalId, missing.Note, pool)
+	}
+	fmt.Fprintf(writer, "\nMISSING FROM PAYPAL (%d)\n", len(result.MissingFromPayPal))
+	fmt.Fprintln(writer, "POOL\tID\tDATE\tNAME\tAMOUNT\tPAYPAL ID")
+	for _, missing := range result.MissingFromPayPal {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n", missing.Pool, missing.Id, missing.Date, missing.Name,
+			formatAmount(missing.Amount.Cents(), missing.Currency), missing.PaypalId)
+	}
+	fmt.Fprintf(writer, "\nAMOUNT MISMATCHES (%d)\n", len(result.AmountMismatches))
+	fmt.Fprintln(writer, "ROW\tPAYPAL ID\tNAME\tPAYPAL\tPOOL\tID\tSTORED")
+	for _, mismatch := range result.AmountMismatches {
+		stored := mismatch.Stored
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", mismatch.Row.Line, mismatch.Row.PaypalId, mismatch.Row.Name,
+			formatAmount(mismatch.Row.Cents, mismatch.Row.Currency), stored.Pool, stored.Id,
+			formatAmount(stored.Amount.Cents(), stored.Currency))
+	}
+	writer.Flush()
+}
+
+func formatAmount(cents int, currency string) string {
+	return fmt.Sprintf("%d.%02d %s", cents/100, cents%100, data.CurrencyOrDefault(currency))
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "reconcile:", err)
+	os.Exit(1)
+}
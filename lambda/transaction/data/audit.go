@@ -0,0 +1,23 @@
+package data
+
+// Audit actions of the transactions written from payment notifications, as in the api's audit log.
+const (
+	AuditTransactionAdd    = "transaction.add"
+	AuditTransactionRefund = "transaction.refund"
+	AuditTransactionImport = "transaction.import"
+)
+
+// AuditActorMail is the actor of changes made by payment notifications.
+const AuditActorMail = "mail"
+
+// AuditEntry is one change of a pool in the append-only audit log shared with the api.
+type AuditEntry struct {
+	Pool          string                 `dynamodbav:"pool"`
+	EntryId       string                 `dynamodbav:"entryId"`
+	At            string                 `dynamodbav:"at"`
+	Action        string                 `dynamodbav:"action"`
+	Actor         string                 `dynamodbav:"actor"`
+	Source        string                 `dynamodbav:"source"` // the SES message id of the notification
+	TransactionId string                 `dynamodbav:"transactionId,omitempty"`
+	New           map[string]interface{} `dynamodbav:"new,omitempty"`
+}
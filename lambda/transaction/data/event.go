@@ -0,0 +1,12 @@
+package data
+
+const EventContributionAdded = "contribution.added"
+
+// Event is pushed to everyone watching a moneypool whenever its state changes.
+// It carries no personal data, since the moneypool's privacy settings are only applied by the api.
+// Clients reload the moneypool to get the details.
+type Event struct {
+	Type          string `json:"type"`
+	MoneyPool     string `json:"moneyPool"`
+	TransactionId string `json:"transactionId,omitempty"`
+}
@@ -0,0 +1,37 @@
+package data
+
+import "strings"
+
+// Modes of pools that expect an amount per person.
+const (
+	ModeSuggested = "suggested" // reminders only go to participants who haven't paid at all
+	ModeFixed     = "fixed"     // reminders go to all participants with an open balance
+)
+
+// Expected are a pool's settings for an amount per person or a roster of participants, as stored in the pool's expected
+// attribute.
+type Expected struct {
+	Mode         string        `dynamodbav:"mode"`
+	Amount       *Amount       `dynamodbav:"amount"` // in the pool's base currency, nil for rosters without amount
+	Participants []Participant `dynamodbav:"participants"`
+	Reminders    bool          `dynamodbav:"reminders"`
+}
+
+// Participant is a person expected to pay. The id is derived by the api from the mail address or name. Aliases are
+// other names the participant's payments may be sent under, e.g. the names of a child's parents.
+type Participant struct {
+	Id      string   `dynamodbav:"id"`
+	Name    string   `dynamodbav:"name"`
+	Email   string   `dynamodbav:"email"`
+	Aliases []string `dynamodbav:"aliases"`
+}
+
+// ParticipantByAddress returns the participant with the mail address, or nil if there is none.
+func (e Expected) ParticipantByAddress(address string) *Participant {
+	for i := range e.Participants {
+		if e.Participants[i].Email != "" && strings.EqualFold(e.Participants[i].Email, address) {
+			return &e.Participants[i]
+		}
+	}
+	return nil
+}
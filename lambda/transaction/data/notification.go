@@ -0,0 +1,113 @@
+package data
+
+// Triggers of notifications, chosen per channel.
+const (
+	NotifyPayment = "payment" // every contribution, as soon as it is stored
+	NotifyDaily   = "daily"   // a digest of the contributions of the previous day
+	NotifyGoal    = "goal"    // once, when the pool's total reaches its goal
+)
+
+// Types of notification channels.
+const (
+	ChannelMail     = "mail"
+	ChannelWebhook  = "webhook"
+	ChannelTelegram = "telegram"
+	ChannelDiscord  = "discord"
+)
+
+// Bases of pool totals, as stored in the pool's totalsBasis attribute.
+const (
+	TotalsGross = "gross" // the amounts sent, the default
+	TotalsNet   = "net"   // the amounts received, after PayPal's fees
+)
+
+// Notifications are a pool's settings for notifying its owner, as stored in the pool's notifications attribute.
+type Notifications struct {
+	Channels []NotificationChannel `dynamodbav:"channels"`
+	// Goal is the total in the pool's base currency that triggers goal notifications. Without it, none are sent.
+	Goal *Amount `dynamodbav:"goal"`
+}
+
+// NotificationChannel is a destination of notifications. Which of its fields are used depends on its type.
+type NotificationChannel struct {
+	Type     string   `dynamodbav:"type"`
+	On       []string `dynamodbav:"on"`       // the triggers the channel is notified on
+	Address  string   `dynamodbav:"address"`  // mail address of mail channels
+	URL      string   `dynamodbav:"url"`      // url of webhooks and Discord-compatible chat webhooks
+	BotToken string   `dynamodbav:"botToken"` // token of the Telegram bot that sends to the chat
+	ChatId   string   `dynamodbav:"chatId"`
+}
+
+// NotifiedPool is a pool with its notification settings, webhook subscriptions and stored contributions.
+type NotifiedPool struct {
+	Name          string
+	Tenant        string
+	Owner         string
+	Title         string
+	Open          bool
+	BaseCurrency  string // the currency of the pool's goal, empty for the default currency
+	TotalsBasis   string // TotalsNet if totals count the amounts received after PayPal's fees, else gross amounts
+	PayURL        string // the link to pay to the pool's payee, empty if the pool has none
+	Notifications Notifications
+	Webhooks      []WebhookSubscription
+	ThankYou      *ThankYou // nil if the pool doesn't thank its contributors
+	Expected      *Expected // nil if the pool doesn't expect an amount per person
+	Contributions []Contribution
+}
+
+// Total returns the cents of the pool's contributions in its base currency, like the api sums them up. Voided
+// contributions are left out and refunds are subtracted. With the net basis, contributions count with the amount
+// received after PayPal's fee, if it is known.
+func (p NotifiedPool) Total() int {
+	currency := CurrencyOrDefault(p.BaseCurrency)
+	cents := 0
+	for _, contribution := range p.Contributions {
+		if contribution.Voided || CurrencyOrDefault(contribution.Currency) != currency {
+			continue
+		}
+		cents += p.counted(contribution)
+	}
+	return cents
+}
+
+// counted returns the cents the contribution adds to the pool's total, which are negative for refunds.
+func (p NotifiedPool) counted(contribution Contribution) int {
+	switch {
+	case contribution.RefundOf != "":
+		return -contribution.Amount.Cents()
+	case p.TotalsBasis == TotalsNet && contribution.Net != nil:
+		return contribution.Net.Cents()
+	default:
+		return contribution.Amount.Cents()
+	}
+}
+
+// ReachesGoal tells whether the stored contribution made the pool's total reach its goal.
+func (p NotifiedPool) ReachesGoal(contribution Contribution) bool {
+	goal := p.Notifications.Goal
+	if goal == nil || contribution.RefundOf != "" || CurrencyOrDefault(contribution.Currency) != CurrencyOrDefault(p.BaseCurrency) {
+		return false
+	}
+	total := p.Total()
+	return total >= goal.Cents() && total-p.counted(contribution) < goal.Cents()
+}
+
+// Contribution returns the pool's contribution with the id, or nil if there is none.
+func (p NotifiedPool) Contribution(id string) *Contribution {
+	for i := range p.Contributions {
+		if p.Contributions[i].Id == id {
+			return &p.Contributions[i]
+		}
+	}
+	return nil
+}
+
+// Notifies tells whether the channel is notified on the trigger.
+func (c NotificationChannel) Notifies(trigger string) bool {
+	for _, on := range c.On {
+		if on == trigger {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package data
+
+import "testing"
+
+func TestTotal(t *testing.T) {
+	pool := NotifiedPool{
+		BaseCurrency: "EUR",
+		Contributions: []Contribution{
+			{Id: "1", Amount: Amount{Base: 10, Fraction: 50}, Currency: "EUR", Fee: &Amount{Fraction: 50}, Net: &Amount{Base: 10}},
+			{Id: "2", Amount: Amount{Base: 5}, Currency: "EUR"},
+			{Id: "3", Amount: Amount{Base: 2}, Currency: "EUR", RefundOf: "2"},
+			{Id: "4", Amount: Amount{Base: 7}, Currency: "EUR", Voided: true},
+			{Id: "5", Amount: Amount{Base: 9}, Currency: "USD"},
+		},
+	}
+	testTable := map[string]int{"": 1350, TotalsGross: 1350, TotalsNet: 1300}
+	for basis, expected := range testTable {
+		pool.TotalsBasis = basis
+		if total := pool.Total(); total != expected {
+			t.Fatalf("Total(%q) = %d, but expected %d", basis, total, expected)
+		}
+	}
+
+	pool.Notifications.Goal = &Amount{Base: 13, Fraction: 1}
+	pool.TotalsBasis = TotalsGross
+	if !pool.ReachesGoal(pool.Contributions[1]) {
+		t.Fatalf("ReachesGoal(gross) = false, but the gross total reached the goal")
+	}
+	pool.TotalsBasis = TotalsNet
+	if pool.ReachesGoal(pool.Contributions[1]) {
+		t.Fatalf("ReachesGoal(net) = true, but the net total is below the goal")
+	}
+}
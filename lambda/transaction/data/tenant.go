@@ -0,0 +1,11 @@
+package data
+
+// Tenant receives the payment notifications of its owner's pools at its own address.
+// The default tenant, receiving at the deployment's address, has an empty name.
+type Tenant struct {
+	Name            string `dynamodbav:"tenant"`
+	Address         string `dynamodbav:"address"`
+	ExpectedSubject string `dynamodbav:"expectedSubject"` // overrides the deployment's expected subject if set
+	NameAmountRegex string `dynamodbav:"nameAmountRegex"` // overrides the deployment's name and amount regex if set
+	Owner           string `dynamodbav:"owner"`
+}
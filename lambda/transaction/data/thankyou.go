@@ -0,0 +1,9 @@
+package data
+
+// ThankYou is a pool's thank-you mail to its contributors, as stored in the pool's thankYou attribute. Subject and
+// Template are text/templates; empty ones are replaced by the defaults.
+type ThankYou struct {
+	Enabled  bool   `dynamodbav:"enabled"`
+	Subject  string `dynamodbav:"subject"`
+	Template string `dynamodbav:"template"`
+}
@@ -1,13 +1,156 @@
 package data
 
+import "fmt"
+
+// DefaultCurrency is the currency of contributions stored before currencies were, and of pools that don't declare one.
+const DefaultCurrency = "EUR"
+
+// AnonymousMarker in a transaction's note asks for the sender's name to be hidden on the website.
+const AnonymousMarker = "#anon"
+
 type Transaction struct {
 	Name     string
 	Base     int
 	Fraction int
+	Decimals int // digits of the fraction if there are more than two, see Amount
 	Note     string
+	PaypalId string  // PayPal's transaction code, empty if the mail doesn't show it
+	Currency string  // ISO code of the amount's currency, e.g. EUR
+	Fee      *Amount // fee PayPal deducted, nil if the mail doesn't show it
+	Net      *Amount // amount received after the fee, nil if the mail doesn't show the fee
+	// SenderAddress is the sender's mail address, empty if the mail doesn't show it. It is not stored in the pool.
+	SenderAddress string
+}
+
+// Refund is a payment that was sent back to its sender, either by a refund or a reversal.
+type Refund struct {
+	Name             string
+	Base             int
+	Fraction         int
+	Decimals         int // digits of the fraction if there are more than two, see Amount
+	PaypalId         string
+	OriginalPaypalId string // code of the refunded transaction, empty if the mail doesn't show it
+	Currency         string
+}
+
+// StoredTransaction identifies a transaction in a moneypool.
+type StoredTransaction struct {
+	MoneyPool   string
+	Id          string
+	Anonymous   bool
+	Participant string // id of the expected participant the transaction was linked to, if any
+}
+
+// Amount returns the transaction's amount.
+func (t Transaction) Amount() Amount {
+	return Amount{Base: t.Base, Fraction: t.Fraction, Decimals: t.Decimals}
 }
 
+// Amount returns the refunded amount.
+func (r Refund) Amount() Amount {
+	return Amount{Base: r.Base, Fraction: r.Fraction, Decimals: r.Decimals}
+}
+
+// Amount is an amount of money. Its fraction is in cents, unless Decimals says that it has more digits, as amounts of
+// currencies like KWD with three decimals have. Amounts stored without decimals are in cents.
 type Amount struct {
-	Base     int // e.g. eur, usd
-	Fraction int // e.g. cents
+	Base     int `dynamodbav:"base"`               // e.g. eur, usd
+	Fraction int `dynamodbav:"fraction"`           // e.g. cents
+	Decimals int `dynamodbav:"decimals,omitempty"` // digits of the fraction if there are more than two
+}
+
+// CentsAmount returns the amount of the given cents.
+func CentsAmount(cents int) Amount {
+	return Amount{Base: cents / 100, Fraction: cents % 100}
+}
+
+// ScaledAmount returns the amount of the given units with the given decimals, as money.Amount.Scaled and
+// money.Precision return them.
+func ScaledAmount(scaled, decimals int) Amount {
+	if decimals <= 2 {
+		return CentsAmount(scaled * pow10(2-decimals))
+	}
+	unit := pow10(decimals)
+	return Amount{Base: scaled / unit, Fraction: scaled % unit, Decimals: decimals}
+}
+
+// digits returns the number of digits of the fraction.
+func (a Amount) digits() int {
+	if a.Decimals > 2 {
+		return a.Decimals
+	}
+	return 2
+}
+
+// Scaled returns the amount in units of its fraction, e.g. cents, or thousandths for amounts with three decimals.
+func (a Amount) Scaled() int {
+	return a.Base*pow10(a.digits()) + a.Fraction
+}
+
+// Cents returns the amount in cents. Amounts with more decimals are rounded half away from zero.
+func (a Amount) Cents() int {
+	scaled, divisor := a.Scaled(), pow10(a.digits()-2)
+	if scaled < 0 {
+		return -((-scaled + divisor/2) / divisor)
+	}
+	return (scaled + divisor/2) / divisor
+}
+
+// Values returns the amount's stored attributes, e.g. for audit entries.
+func (a Amount) Values() map[string]int {
+	values := map[string]int{"base": a.Base, "fraction": a.Fraction}
+	if a.Decimals > 0 {
+		values["decimals"] = a.Decimals
+	}
+	return values
+}
+
+// String writes the amount like "12.50", or "12.500" for amounts with three decimals.
+func (a Amount) String() string {
+	scaled, sign, unit := a.Scaled(), "", pow10(a.digits())
+	if scaled < 0 {
+		scaled, sign = -scaled, "-"
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, scaled/unit, a.digits(), scaled%unit)
+}
+
+func pow10(exponent int) int {
+	result := 1
+	for i := 0; i < exponent; i++ {
+		result *= 10
+	}
+	return result
+}
+
+// Contribution is a transaction as it is stored in a moneypool.
+type Contribution struct {
+	Id        string // set for stored contributions
+	Name      string
+	Date      string
+	Amount    Amount
+	Currency  string  // ISO code of the amount's currency, empty for contributions stored before currencies were
+	Fee       *Amount // fee PayPal deducted, nil if unknown
+	Net       *Amount // amount received after the fee, nil if unknown
+	Anonymous bool    // the sender asked to not be named publicly
+	PaypalId  string  // PayPal's transaction code, if known
+	RefundOf  string  // id of the transaction this contribution refunds; its amount is subtracted
+	Source    string  // "manual" or "import" for contributions not read from a payment notification
+	Voided    bool    // set for stored contributions that were voided by a correction
+	// Participant is the id of the pool's expected participant the contribution was linked to, if any.
+	Participant string
+}
+
+// StoredPool is a moneypool with its stored contributions.
+type StoredPool struct {
+	Name          string // the pool's key, which is prefixed with the tenant for tenants' pools
+	Tenant        string
+	Contributions []Contribution
+}
+
+// CurrencyOrDefault returns the stored currency, or the default currency if none is stored.
+func CurrencyOrDefault(currency string) string {
+	if currency == "" {
+		return DefaultCurrency
+	}
+	return currency
 }
@@ -0,0 +1,25 @@
+package data
+
+import "testing"
+
+func TestScaledAmount(t *testing.T) {
+	testTable := []struct {
+		scaled   int
+		decimals int
+		expected Amount
+		cents    int
+		text     string
+	}{
+		{1250, 2, Amount{Base: 12, Fraction: 50}, 1250, "12.50"},
+		{1250, 3, Amount{Base: 1, Fraction: 250, Decimals: 3}, 125, "1.250"},
+		{1255, 3, Amount{Base: 1, Fraction: 255, Decimals: 3}, 126, "1.255"},
+		{5, 0, Amount{Base: 5}, 500, "5.00"},
+	}
+	for _, test := range testTable {
+		amount := ScaledAmount(test.scaled, test.decimals)
+		if amount != test.expected || amount.Cents() != test.cents || amount.String() != test.text {
+			t.Fatalf("ScaledAmount(%d, %d) = %+v (%d cents, %s), but expected %+v (%d cents, %s)", test.scaled, test.decimals,
+				amount, amount.Cents(), amount, test.expected, test.cents, test.text)
+		}
+	}
+}
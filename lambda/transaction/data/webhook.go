@@ -0,0 +1,51 @@
+package data
+
+// Types of webhook events.
+const (
+	WebhookTransactionAdded = "transaction.added"
+	WebhookPoolClosed       = "pool.closed"
+	WebhookPaymentUnmatched = "payment.unmatched"
+	WebhookGoalReached      = "goal.reached"
+)
+
+// Statuses of webhook deliveries.
+const (
+	DeliveryDelivered = "delivered"
+	DeliveryPending   = "pending" // failed so far, and retried at the delivery's next attempt
+	DeliveryFailed    = "failed"  // failed on every attempt, or its subscription was removed
+)
+
+// WebhookSubscription is a webhook registered via the api, as stored in the pool's webhooks attribute.
+type WebhookSubscription struct {
+	Id     string   `dynamodbav:"id"`
+	URL    string   `dynamodbav:"url"`
+	Events []string `dynamodbav:"events"`
+	Secret string   `dynamodbav:"secret"` // key of the HMAC-SHA256 signatures of the payloads
+}
+
+// Subscribes tells whether the subscription receives events of the type.
+func (s WebhookSubscription) Subscribes(eventType string) bool {
+	for _, event := range s.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is the delivery of an event to a subscription, as kept in the delivery log.
+type WebhookDelivery struct {
+	Pool           string `dynamodbav:"pool"`
+	DeliveryId     string `dynamodbav:"deliveryId"` // starts with the creation time, so deliveries sort by time
+	Subscription   string `dynamodbav:"subscription"`
+	Event          string `dynamodbav:"event"`
+	Payload        string `dynamodbav:"payload"`
+	Status         string `dynamodbav:"status"`
+	Attempts       int    `dynamodbav:"attempts"`
+	ResponseStatus int    `dynamodbav:"responseStatus,omitempty"` // status of the last response, if there was one
+	Error          string `dynamodbav:"error,omitempty"`          // why the last attempt failed
+	CreatedAt      string `dynamodbav:"createdAt"`
+	LastAttempt    string `dynamodbav:"lastAttempt,omitempty"`
+	NextAttempt    string `dynamodbav:"nextAttempt,omitempty"` // set for pending deliveries
+	ExpiresAt      int64  `dynamodbav:"expiresAt"`             // unix time when the log entry is removed
+}
@@ -1,16 +1,20 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"github.com/DusanKasan/parsemail"
 	"github.com/sirupsen/logrus"
 	"strings"
 	"time"
 	"transaction/data"
+	"transaction/roster"
+	"transaction/thankyou"
 )
 
 type MailParser interface {
 	GetTransactionInfo(email parsemail.Email) (*data.Transaction, error)
+	GetRefundInfo(email parsemail.Email) (*data.Refund, error)
 }
 
 type MailGetter interface {
@@ -18,20 +22,75 @@ type MailGetter interface {
 }
 
 type DataStore interface {
-	FindMoneyPoolsByPrefix(name string) ([]string, error)
-	AddTransaction(moneyPool, name, date string, amount data.Amount) error
+	FindMoneyPoolsByPrefix(tenant, name string) ([]string, error)
+	AddTransaction(moneyPool string, contribution data.Contribution) (string, error)
+	FindRefundedTransaction(tenant string, refund data.Refund) (*data.StoredTransaction, error)
 }
 
+type EventPublisher interface {
+	Publish(event data.Event) error
+}
+
+type AuditLog interface {
+	Append(entry data.AuditEntry) error
+}
+
+type Notifier interface {
+	Contribution(moneyPool, transactionId string) error
+}
+
+type Webhooks interface {
+	TransactionAdded(moneyPool, transactionId string) error
+	PaymentUnmatched(tenant data.Tenant, payment data.Transaction, pools []string) error
+}
+
+type ThankYou interface {
+	Send(moneyPool, transactionId, address string) error
+}
+
+type ParticipantStore interface {
+	GetExpected(moneyPool string) (*data.Expected, error)
+}
+
+type TenantStore interface {
+	FindTenantByAddress(address string) (*data.Tenant, error)
+}
+
+// AnonymousMarker in a transaction's note asks for the sender's name to be hidden on the website.
+const AnonymousMarker = data.AnonymousMarker
+
 type Config struct {
 	ExpectedSubject string
 	MailParser      MailParser
 	MailGetter      MailGetter
 	DataStore       DataStore
+	EventPublisher  EventPublisher
+	// RefundSubjects are the subjects of refund and reversal mails, which are stored as negative entries.
+	RefundSubjects []string
+	// TenantStore routes mails to the tenant registered for their recipient. Without it, all mails go to the default tenant.
+	TenantStore TenantStore
+	// DefaultAddress receives the mails of the default tenant. If set, mails to other addresses without tenant are ignored.
+	DefaultAddress string
+	// NewMailParser creates the parser for tenants with their own name and amount regex.
+	NewMailParser func(nameAmountRegex string) MailParser
+	// AuditLog records every stored transaction. Without it, transactions are not audited.
+	AuditLog AuditLog
+	// Notifier notifies pool owners about new contributions, as configured per pool. Without it, no one is notified.
+	Notifier Notifier
+	// Webhooks delivers events to the webhooks subscribed to a pool. Without it, no events are delivered.
+	Webhooks Webhooks
+	// ThankYou thanks contributors whose mail address the payment mail shows, if their pool enabled it. Without it,
+	// no one is thanked.
+	ThankYou ThankYou
+	// Participants links contributions to the pool's expected participant with the sender's mail address or name.
+	// Without it, contributions are only matched to participants with exactly the sender's name.
+	Participants ParticipantStore
 }
 
 type MailEventProcessor struct {
 	Config
-	logger *logrus.Logger
+	logger    *logrus.Logger
+	messageId string
 }
 
 func NewMailEventProcessor(config Config) MailEventProcessor {
@@ -44,6 +103,18 @@ func (h *MailEventProcessor) WriteTransactionToMoneyPool(record EmailEventRecord
 	h.logger = logrus.New()
 	h.logger = h.logger.WithFields(logrus.Fields{"messageId": record.Ses.Mail.MessageId}).Logger
 	h.logger.Infof("processing record")
+	h.messageId = record.Ses.Mail.MessageId
+
+	tenant, err := h.findTenant(record.Ses.Receipt.Recipients)
+	if err != nil {
+		h.logger.Errorf("error finding tenant: %v", err)
+		return
+	}
+	if tenant == nil {
+		h.logger.Infof("no tenant found for recipients %v", record.Ses.Receipt.Recipients)
+		return
+	}
+	h.logger = h.logger.WithFields(logrus.Fields{"tenant": tenant.Name}).Logger
 
 	email, err := h.MailGetter.GetMail(record.Ses.Mail.MessageId)
 	if err != nil {
@@ -51,66 +122,342 @@ func (h *MailEventProcessor) WriteTransactionToMoneyPool(record EmailEventRecord
 		return
 	}
 
-	if strings.ToLower(email.Subject) != strings.ToLower(h.ExpectedSubject) {
-		h.logger.Infof("subject %s not matching expected %s", email.Subject, h.ExpectedSubject)
-		return
+	expectedSubject := h.ExpectedSubject
+	if tenant.ExpectedSubject != "" {
+		expectedSubject = tenant.ExpectedSubject
 	}
+	switch {
+	case strings.EqualFold(email.Subject, expectedSubject):
+		h.writeContribution(*tenant, *email)
+	case h.isRefundSubject(email.Subject):
+		h.writeRefund(*tenant, *email)
+	default:
+		h.logger.Infof("subject %s not matching expected %s", email.Subject, expectedSubject)
+	}
+}
 
-	transactionInfo, err := h.getTransactionInfoFromMail(*email)
+func (h *MailEventProcessor) isRefundSubject(subject string) bool {
+	for _, refundSubject := range h.RefundSubjects {
+		refundSubject = strings.TrimSpace(refundSubject)
+		if refundSubject != "" && strings.EqualFold(subject, refundSubject) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *MailEventProcessor) writeContribution(tenant data.Tenant, email parsemail.Email) {
+	transactionInfo, err := h.getTransactionInfoFromMail(h.mailParser(tenant), email)
 	if err != nil {
 		h.logger.Errorf("error getting parser info from mail: %v", err)
 		return
 	}
 
-	moneyPools, err := h.findMoneyPoolsByPrefix(transactionInfo.Note)
+	moneyPools, err := h.findMoneyPoolsByPrefix(tenant.Name, transactionInfo.Note)
 	if err != nil {
 		h.logger.Errorf("error finding moneypool: %v", err)
 		return
 	}
 	if len(moneyPools) > 1 {
 		h.logger.Errorf("ambiguous note, found multiple moneypools: %v", moneyPools)
+		h.deliverUnmatched(tenant, transactionInfo, moneyPools)
 		return
 	}
 	if len(moneyPools) < 1 {
 		h.logger.Infof("no moneypools found")
+		h.deliverUnmatched(tenant, transactionInfo, nil)
 		return
 	}
 	moneyPool := moneyPools[0]
 	h.logger = h.logger.WithFields(logrus.Fields{"pool": moneyPool}).Logger
 
-	err = h.addToMoneyPool(moneyPool, transactionInfo)
+	contribution := newContribution(transactionInfo)
+	h.linkParticipant(moneyPool, &contribution, transactionInfo.SenderAddress)
+	transactionId, err := h.addToMoneyPool(moneyPool, contribution)
 	if err != nil {
 		h.logger.Errorf("error adding parser to moneypool: %v", err)
 		return
 	}
+	h.audit(moneyPool, data.AuditTransactionAdd, transactionId, contribution)
+	h.processContribution(moneyPool, transactionId, transactionInfo.SenderAddress)
+}
+
+// processContribution publishes a stored contribution to live viewers, notifies the pool's owner, delivers it to the
+// webhooks and thanks the contributor, if their address is known. The contribution is already stored at this point, so
+// failing steps are only logged.
+func (h *MailEventProcessor) processContribution(moneyPool, transactionId, address string) {
+	err := h.publishContribution(moneyPool, transactionId)
+	if err != nil {
+		h.logger.Errorf("error publishing contribution: %v", err)
+	}
+	if h.Notifier != nil {
+		if err := h.Notifier.Contribution(moneyPool, transactionId); err != nil {
+			h.logger.Errorf("error notifying owner: %v", err)
+		}
+	}
+	h.deliverTransaction(moneyPool, transactionId)
+	h.thankContributor(moneyPool, transactionId, address)
+}
+
+// writeRefund stores a refund or reversal as negative entry in the pool of the refunded transaction.
+func (h *MailEventProcessor) writeRefund(tenant data.Tenant, email parsemail.Email) {
+	refund, err := h.mailParser(tenant).GetRefundInfo(email)
+	if err != nil {
+		h.logger.Errorf("error getting refund info from mail: %v", err)
+		return
+	}
+	h.logger = h.logger.WithFields(logrus.Fields{"refundTo": refund.Name, "amount": fmt.Sprintf("%s %s", refund.Amount(), refund.Currency), "originalPaypalId": refund.OriginalPaypalId}).Logger
+
+	original, err := h.DataStore.FindRefundedTransaction(tenant.Name, *refund)
+	if err != nil {
+		h.logger.Errorf("error finding refunded transaction: %v", err)
+		return
+	}
+	if original == nil {
+		h.logger.Infof("no refunded transaction found")
+		return
+	}
+	h.logger = h.logger.WithFields(logrus.Fields{"pool": original.MoneyPool, "refundOf": original.Id}).Logger
+
+	contribution := data.Contribution{
+		Name:     refund.Name,
+		Date:     time.Now().Format("02.01.06"),
+		Amount:   refund.Amount(),
+		Currency: refund.Currency,
+		// a refund must not reveal the name of an anonymous contribution
+		Anonymous: original.Anonymous,
+		PaypalId:  refund.PaypalId,
+		RefundOf:  original.Id,
+		// the refund is subtracted from what the participant of the refunded transaction paid
+		Participant: original.Participant,
+	}
+	transactionId, err := h.DataStore.AddTransaction(original.MoneyPool, contribution)
+	if err != nil {
+		h.logger.Errorf("error adding refund to moneypool: %v", err)
+		return
+	}
+	h.logger.Infof("added refund")
+	h.audit(original.MoneyPool, data.AuditTransactionRefund, transactionId, contribution)
+
+	err = h.publishContribution(original.MoneyPool, transactionId)
+	if err != nil {
+		h.logger.Errorf("error publishing refund: %v", err)
+	}
+	h.deliverTransaction(original.MoneyPool, transactionId)
+}
+
+// findTenant returns the tenant of the first recipient that is either the default address or registered for a tenant, or
+// nil if the mail is not meant for this deployment. The default address always belongs to the default tenant, even if a
+// tenant registered it.
+func (h *MailEventProcessor) findTenant(recipients []string) (*data.Tenant, error) {
+	isDefault := h.DefaultAddress == ""
+	for _, recipient := range recipients {
+		if h.DefaultAddress != "" && strings.EqualFold(recipient, h.DefaultAddress) {
+			return &data.Tenant{}, nil
+		}
+		if h.TenantStore != nil {
+			tenant, err := h.TenantStore.FindTenantByAddress(recipient)
+			if err != nil {
+				return nil, err
+			}
+			if tenant != nil {
+				return tenant, nil
+			}
+		}
+	}
+	if !isDefault {
+		return nil, nil
+	}
+	return &data.Tenant{}, nil
+}
+
+func (h *MailEventProcessor) mailParser(tenant data.Tenant) MailParser {
+	if tenant.NameAmountRegex == "" || h.NewMailParser == nil {
+		return h.MailParser
+	}
+	return h.NewMailParser(tenant.NameAmountRegex)
 }
 
-func (h *MailEventProcessor) getTransactionInfoFromMail(email parsemail.Email) (data.Transaction, error) {
-	info, err := h.MailParser.GetTransactionInfo(email)
+func (h *MailEventProcessor) getTransactionInfoFromMail(parser MailParser, email parsemail.Email) (data.Transaction, error) {
+	info, err := parser.GetTransactionInfo(email)
 	if err != nil {
 		return data.Transaction{}, fmt.Errorf("error while reading parser infos form mail: %v", err)
 	}
-	h.logger = h.logger.WithFields(logrus.Fields{"sender": info.Name, "note": info.Note, "amount": fmt.Sprintf("%d.%d", info.Base, info.Fraction)}).Logger
+	h.logger = h.logger.WithFields(logrus.Fields{"sender": info.Name, "note": info.Note, "amount": info.Amount().String()}).Logger
 	h.logger.Infof("found parser info")
 	return *info, err
 }
 
-func (h *MailEventProcessor) findMoneyPoolsByPrefix(note string) ([]string, error) {
-	moneyPools, err := h.DataStore.FindMoneyPoolsByPrefix(note)
+func (h *MailEventProcessor) findMoneyPoolsByPrefix(tenant, note string) ([]string, error) {
+	moneyPools, err := h.DataStore.FindMoneyPoolsByPrefix(tenant, note)
 	if err != nil {
 		return nil, fmt.Errorf("error while searching suitable moneypool: %v", err)
 	}
 	return moneyPools, nil
 }
 
-func (h *MailEventProcessor) addToMoneyPool(moneyPool string, transactionInfo data.Transaction) error {
-	today := time.Now().Format("02.01.06")
-	err := h.DataStore.AddTransaction(moneyPool, transactionInfo.Name, today, data.Amount{
-		Base:     transactionInfo.Base,
-		Fraction: transactionInfo.Fraction,
+func newContribution(transactionInfo data.Transaction) data.Contribution {
+	return data.Contribution{
+		Name:      transactionInfo.Name,
+		Date:      time.Now().Format("02.01.06"),
+		Amount:    transactionInfo.Amount(),
+		Currency:  transactionInfo.Currency,
+		Fee:       transactionInfo.Fee,
+		Net:       transactionInfo.Net,
+		Anonymous: strings.Contains(strings.ToLower(transactionInfo.Note), AnonymousMarker),
+		PaypalId:  transactionInfo.PaypalId,
+	}
+}
+
+// linkParticipant links the contribution to the pool's expected participant with the sender's mail address, if known, or
+// else to the participant the sender's name matches. Failing to read the participants leaves the contribution unlinked,
+// so it is still stored.
+func (h *MailEventProcessor) linkParticipant(moneyPool string, contribution *data.Contribution, address string) {
+	if h.Participants == nil {
+		return
+	}
+	expected, err := h.Participants.GetExpected(moneyPool)
+	if err != nil {
+		h.logger.Errorf("error reading expected participants: %v", err)
+		return
+	}
+	if expected == nil {
+		return
+	}
+	participant := expected.ParticipantByAddress(address)
+	if participant == nil {
+		participant = roster.Match(expected.Participants, contribution.Name)
+	}
+	if participant != nil {
+		contribution.Participant = participant.Id
+		h.logger.Infof("linked contribution to participant %s", participant.Id)
+	}
+}
+
+func (h *MailEventProcessor) addToMoneyPool(moneyPool string, contribution data.Contribution) (string, error) {
+	id, err := h.DataStore.AddTransaction(moneyPool, contribution)
+	if err != nil {
+		return "", fmt.Errorf("error adding parser to database: %v", err)
+	}
+	return id, nil
+}
+
+// audit records a stored transaction. The transaction is already stored, so failing to write the entry is only logged.
+func (h *MailEventProcessor) audit(moneyPool, action, transactionId string, contribution data.Contribution) {
+	if h.AuditLog == nil {
+		return
+	}
+	values := map[string]interface{}{
+		"id":       transactionId,
+		"name":     contribution.Name,
+		"date":     contribution.Date,
+		"base":     contribution.Amount.Base,
+		"fraction": contribution.Amount.Fraction,
+	}
+	if contribution.Amount.Decimals > 0 {
+		values["decimals"] = contribution.Amount.Decimals
+	}
+	if contribution.Currency != "" {
+		values["currency"] = contribution.Currency
+	}
+	if contribution.Fee != nil && contribution.Net != nil {
+		values["fee"] = contribution.Fee.Values()
+		values["net"] = contribution.Net.Values()
+	}
+	if contribution.Anonymous {
+		values["anonymous"] = true
+	}
+	if contribution.PaypalId != "" {
+		values["paypalId"] = contribution.PaypalId
+	}
+	if contribution.RefundOf != "" {
+		values["refundOf"] = contribution.RefundOf
+	}
+	if contribution.Participant != "" {
+		values["participant"] = contribution.Participant
+	}
+	err := h.AuditLog.Append(data.AuditEntry{
+		Pool:          moneyPool,
+		Action:        action,
+		Actor:         data.AuditActorMail,
+		Source:        h.messageId,
+		TransactionId: transactionId,
+		New:           values,
 	})
 	if err != nil {
-		return fmt.Errorf("error adding parser to database: %v", err)
+		h.logger.Errorf("error writing audit entry: %v", err)
+	}
+}
+
+func (h *MailEventProcessor) publishContribution(moneyPool, transactionId string) error {
+	if h.EventPublisher == nil {
+		return nil
+	}
+	err := h.EventPublisher.Publish(data.Event{
+		Type:          data.EventContributionAdded,
+		MoneyPool:     moneyPool,
+		TransactionId: transactionId,
+	})
+	if err != nil {
+		return fmt.Errorf("error publishing event to live connections: %v", err)
 	}
 	return nil
 }
+
+// deliverTransaction sends the stored transaction to the pool's webhooks. Failed deliveries are retried later, so only
+// errors of the delivery log are logged here.
+func (h *MailEventProcessor) deliverTransaction(moneyPool, transactionId string) {
+	if h.Webhooks == nil {
+		return
+	}
+	if err := h.Webhooks.TransactionAdded(moneyPool, transactionId); err != nil {
+		h.logger.Errorf("error delivering transaction to webhooks: %v", err)
+	}
+}
+
+// thankContributor sends the pool's thank-you mail to the contributor. Mails skipped because the transaction was
+// already thanked or a rate limit was reached are not errors.
+func (h *MailEventProcessor) thankContributor(moneyPool, transactionId, address string) {
+	if h.ThankYou == nil || address == "" {
+		return
+	}
+	err := h.ThankYou.Send(moneyPool, transactionId, address)
+	switch {
+	case errors.Is(err, thankyou.ErrAlreadyThanked) || errors.Is(err, thankyou.ErrRateLimited):
+		h.logger.Infof("skipped thank-you mail: %v", err)
+	case err != nil:
+		h.logger.Errorf("error sending thank-you mail: %v", err)
+	}
+}
+
+// deliverUnmatched sends a payment that could not be stored, because its note names no pool or several pools, to the
+// webhooks of the tenant owner's pools.
+func (h *MailEventProcessor) deliverUnmatched(tenant data.Tenant, payment data.Transaction, moneyPools []string) {
+	if h.Webhooks == nil {
+		return
+	}
+	if err := h.Webhooks.PaymentUnmatched(tenant, payment, moneyPools); err != nil {
+		h.logger.Errorf("error delivering unmatched payment to webhooks: %v", err)
+	}
+}
+
+// importProcessor runs imported contributions through the steps of contributions from payment mails, except the audit
+// entry, which the importer writes itself. Imports know no sender addresses, so contributions are linked by name and no
+// one is thanked.
+type importProcessor struct {
+	MailEventProcessor
+}
+
+func newImportProcessor(config Config, requestId string) *importProcessor {
+	processor := importProcessor{MailEventProcessor: NewMailEventProcessor(config)}
+	processor.logger = logrus.New().WithFields(logrus.Fields{"requestId": requestId}).Logger
+	return &processor
+}
+
+func (p *importProcessor) Prepare(moneyPool string, contribution *data.Contribution) {
+	p.linkParticipant(moneyPool, contribution, "")
+}
+
+func (p *importProcessor) Stored(moneyPool, transactionId string) {
+	p.processContribution(moneyPool, transactionId, "")
+}
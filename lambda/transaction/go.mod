@@ -4,7 +4,6 @@ require (
 	github.com/aws/aws-sdk-go v1.40.59
 	github.com/ericchiang/css v1.1.0
 	github.com/google/uuid v1.3.0
-	github.com/leekchan/accounting v1.0.0
 	github.com/sirupsen/logrus v1.8.1
 	golang.org/x/net v0.0.0-20211216030914-fe4d6282115f
 )
@@ -0,0 +1,257 @@
+// Package importer adds contributions that were not paid via PayPal, e.g. cash or bank transfers, or that were collected
+// before the pool existed. Imports are dry runs unless they are applied.
+package importer
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"time"
+	"transaction/data"
+)
+
+// Sources of imported contributions, stored with each contribution.
+const (
+	SourceManual = "manual"
+	SourceImport = "import"
+)
+
+// Statuses of imported rows.
+const (
+	StatusNew       = "new" // would be added by the import, if it was applied
+	StatusAdded     = "added"
+	StatusDuplicate = "duplicate"
+	StatusInvalid   = "invalid"
+	StatusFailed    = "failed"
+)
+
+// MaxRows limits the rows of a single import.
+const MaxRows = 1000
+
+// storedDateFormat is the date format of contributions written by the transaction lambda.
+const storedDateFormat = "02.01.06"
+
+type Store interface {
+	GetContributions(moneyPool string) ([]data.Contribution, error)
+	AddTransaction(moneyPool string, contribution data.Contribution) (string, error)
+}
+
+type AuditLog interface {
+	Append(entry data.AuditEntry) error
+}
+
+// Processor runs imported contributions through the same steps as contributions from payment mails.
+type Processor interface {
+	// Prepare links a contribution to the pool's expected participant before it is stored.
+	Prepare(moneyPool string, contribution *data.Contribution)
+	// Stored publishes a stored contribution to live viewers, notifies the pool's owner and delivers it to webhooks.
+	Stored(moneyPool, transactionId string)
+}
+
+// Request is an import of rows into a moneypool, as sent by the api or the import command.
+type Request struct {
+	MoneyPool string `json:"moneyPool"`
+	Format    string `json:"format"` // csv or json
+	Body      string `json:"body"`
+	Source    string `json:"source"`
+	// Apply writes the new rows. Without it, the import is a dry run that only reports what it would do.
+	Apply     bool   `json:"apply"`
+	Actor     string `json:"actor"`
+	RequestId string `json:"requestId"`
+}
+
+// RowResult reports what the import did with a row. Rows are numbered from 1.
+type RowResult struct {
+	Row    int    `json:"row"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Id     string `json:"id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Date   string `json:"date,omitempty"`
+	Amount string `json:"amount,omitempty"`
+}
+
+type Result struct {
+	DryRun     bool        `json:"dryRun"`
+	Rows       []RowResult `json:"rows"`
+	Added      int         `json:"added"`
+	New        int         `json:"new"`
+	Duplicates int         `json:"duplicates"`
+	Invalid    int         `json:"invalid"`
+	Failed     int         `json:"failed"`
+}
+
+// Response is the answer to an import sent to the transaction lambda. It carries either the result, or the reason the
+// import was rejected.
+type Response struct {
+	Result *Result `json:"result,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// RequestError rejects an import as a whole, before anything is written.
+type RequestError struct {
+	message string
+}
+
+func (e *RequestError) Error() string {
+	return e.message
+}
+
+func requestErrorf(format string, args ...interface{}) *RequestError {
+	return &RequestError{message: fmt.Sprintf(format, args...)}
+}
+
+type Importer struct {
+	Store    Store
+	AuditLog AuditLog
+	// Processor handles contributions before and after they are stored. Without it, contributions are only stored.
+	Processor Processor
+}
+
+// New creates an importer without processor. Without audit log, imported contributions are not audited.
+func New(store Store, auditLog AuditLog) *Importer {
+	return &Importer{Store: store, AuditLog: auditLog}
+}
+
+// Import adds the request's rows to its moneypool, skipping invalid rows and rows that duplicate a stored contribution
+// or an earlier row. Contributions are duplicates if they have the same name, amount and date, or the same PayPal
+// transaction code.
+func (i *Importer) Import(request Request) (Result, error) {
+	if request.MoneyPool == "" {
+		return Result{}, requestErrorf("no moneypool given")
+	}
+	if request.Source == "" {
+		request.Source = SourceImport
+	}
+	if request.Source != SourceImport && request.Source != SourceManual {
+		return Result{}, requestErrorf("unknown source %s, expected %s or %s", request.Source, SourceImport, SourceManual)
+	}
+	rows, err := ParseRows(request.Format, request.Body)
+	if err != nil {
+		return Result{}, err
+	}
+
+	stored, err := i.Store.GetContributions(request.MoneyPool)
+	if err != nil {
+		return Result{}, fmt.Errorf("error reading stored contributions: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, contribution := range stored {
+		for _, key := range duplicateKeys(contribution) {
+			seen[key] = true
+		}
+	}
+
+	result := Result{DryRun: !request.Apply, Rows: make([]RowResult, 0, len(rows))}
+	for index, row := range rows {
+		rowResult := RowResult{Row: index + 1, Name: strings.TrimSpace(row.Name), Date: row.Date, Amount: row.Amount}
+		contribution, err := row.contribution(request.Source)
+		switch {
+		case err != nil:
+			rowResult.Status, rowResult.Error = StatusInvalid, err.Error()
+			result.Invalid++
+		case isDuplicate(seen, contribution):
+			rowResult.Status = StatusDuplicate
+			result.Duplicates++
+		case !request.Apply:
+			rowResult.Status = StatusNew
+			result.New++
+		default:
+			if i.Processor != nil {
+				i.Processor.Prepare(request.MoneyPool, &contribution)
+			}
+			rowResult.Id, err = i.Store.AddTransaction(request.MoneyPool, contribution)
+			if err != nil {
+				rowResult.Status, rowResult.Error = StatusFailed, err.Error()
+				result.Failed++
+				break
+			}
+			rowResult.Status = StatusAdded
+			result.Added++
+			i.audit(request, rowResult.Id, contribution)
+			if i.Processor != nil {
+				i.Processor.Stored(request.MoneyPool, rowResult.Id)
+			}
+		}
+		result.Rows = append(result.Rows, rowResult)
+	}
+	return result, nil
+}
+
+// duplicateKeys identify a contribution by its sender, amount and day, and by PayPal's transaction code if it is known.
+func duplicateKeys(contribution data.Contribution) []string {
+	keys := []string{fmt.Sprintf("%s|%s %s|%s", strings.ToLower(strings.TrimSpace(contribution.Name)),
+		contribution.Amount, data.CurrencyOrDefault(contribution.Currency), contribution.Date)}
+	if contribution.PaypalId != "" {
+		keys = append(keys, "paypal|"+contribution.PaypalId)
+	}
+	return keys
+}
+
+// isDuplicate reports whether a contribution was seen before, and marks it as seen.
+func isDuplicate(seen map[string]bool, contribution data.Contribution) bool {
+	keys := duplicateKeys(contribution)
+	duplicate := false
+	for _, key := range keys {
+		duplicate = duplicate || seen[key]
+	}
+	for _, key := range keys {
+		seen[key] = true
+	}
+	return duplicate
+}
+
+// audit records an imported contribution. The contribution is already stored, so failing to write the entry is only
+// logged.
+func (i *Importer) audit(request Request, transactionId string, contribution data.Contribution) {
+	if i.AuditLog == nil {
+		return
+	}
+	values := map[string]interface{}{
+		"id":       transactionId,
+		"name":     contribution.Name,
+		"date":     contribution.Date,
+		"base":     contribution.Amount.Base,
+		"fraction": contribution.Amount.Fraction,
+		"source":   contribution.Source,
+	}
+	if contribution.Amount.Decimals > 0 {
+		values["decimals"] = contribution.Amount.Decimals
+	}
+	if contribution.Currency != "" {
+		values["currency"] = contribution.Currency
+	}
+	if contribution.Anonymous {
+		values["anonymous"] = true
+	}
+	if contribution.PaypalId != "" {
+		values["paypalId"] = contribution.PaypalId
+	}
+	actor := request.Actor
+	if actor == "" {
+		actor = request.Source
+	}
+	// audit entries of one request are told apart by their transaction
+	err := i.AuditLog.Append(data.AuditEntry{
+		Pool:          request.MoneyPool,
+		Action:        data.AuditTransactionImport,
+		Actor:         actor,
+		Source:        request.RequestId + "#" + transactionId,
+		TransactionId: transactionId,
+		New:           values,
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"pool": request.MoneyPool, "transactionId": transactionId}).Errorf("error writing audit entry: %v", err)
+	}
+}
+
+// parseDate accepts ISO and German dates and returns the date in the stored format.
+func parseDate(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	for _, format := range []string{"2006-01-02", "02.01.2006", storedDateFormat} {
+		if date, err := time.Parse(format, text); err == nil {
+			return date.Format(storedDateFormat), nil
+		}
+	}
+	return "", fmt.Errorf("invalid date %q, expected e.g. 2022-03-01", text)
+}
@@ -0,0 +1,204 @@
+package importer
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"transaction/data"
+)
+
+type parseRowsTest struct {
+	name          string
+	format        string
+	body          string
+	expectedRows  []Row
+	expectedError error
+}
+
+func TestParseRows(t *testing.T) {
+	testTable := []parseRowsTest{
+		{
+			"csv",
+			"csv",
+			"\uFEFFName,Amount,Date,Note\nAnna,\"12,50\",2022-03-01,cash #anon\n,,,\nPaul,5,01.03.2022,\n",
+			[]Row{
+				{Name: "Anna", Amount: "12,50", Date: "2022-03-01", Note: "cash #anon"},
+				{Name: "Paul", Amount: "5", Date: "01.03.2022"},
+			},
+			nil,
+		},
+		{
+			"json",
+			"json",
+			`[{"name": "Anna", "amount": "12.50", "currency": "EUR", "date": "2022-03-01"}]`,
+			[]Row{{Name: "Anna", Amount: "12.50", Currency: "EUR", Date: "2022-03-01"}},
+			nil,
+		},
+		{
+			"csv_missing_column",
+			"csv",
+			"name,amount\nAnna,12\n",
+			nil,
+			errors.New("csv header misses column date"),
+		},
+		{
+			"empty",
+			"json",
+			`[]`,
+			nil,
+			errors.New("no rows to import"),
+		},
+		{
+			"unknown_format",
+			"xml",
+			`<rows/>`,
+			nil,
+			errors.New("unknown format xml, expected csv or json"),
+		},
+	}
+	for _, test := range testTable {
+		rows, err := ParseRows(test.format, test.body)
+		if !compareErrors(err, test.expectedError) || !reflect.DeepEqual(rows, test.expectedRows) {
+			t.Fatalf("ParseRows(%s) = %+v, %v but expected %+v, %v", test.name, rows, err, test.expectedRows, test.expectedError)
+		}
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	store := &FakeStore{contributions: []data.Contribution{
+		{Name: "Anna", Date: "01.03.22", Amount: data.Amount{Base: 12, Fraction: 50}, PaypalId: "PP-1"},
+	}}
+	result, err := New(store, nil).Import(Request{MoneyPool: "mom", Format: "json", Body: `[
+		{"name": "anna ", "amount": "12,5", "date": "2022-03-01"},
+		{"name": "Paul", "amount": "5", "date": "2022-03-02", "note": "#anon"},
+		{"name": "Paul", "amount": "5.00", "date": "02.03.22"},
+		{"name": "Otto", "amount": "-3", "date": "2022-03-02"},
+		{"name": "Eve", "amount": "3", "currency": "US$", "date": "2022-03-02"},
+		{"name": "Eve", "amount": "3", "currency": "usd", "date": "2022-03-02"},
+		{"name": "Anna Smith", "amount": "12.50", "date": "2022-02-28", "paypalId": "PP-1"}
+	]`})
+	if err != nil {
+		t.Fatalf("Import returned error %v", err)
+	}
+	expectedStatuses := []string{StatusDuplicate, StatusNew, StatusDuplicate, StatusInvalid, StatusInvalid, StatusNew, StatusDuplicate}
+	for i, row := range result.Rows {
+		if row.Status != expectedStatuses[i] {
+			t.Fatalf("Import row %d has status %s, but expected %s: %+v", i+1, row.Status, expectedStatuses[i], result.Rows)
+		}
+	}
+	if !result.DryRun || result.New != 2 || result.Duplicates != 3 || result.Invalid != 2 || len(store.added) != 0 {
+		t.Fatalf("Import = %+v and added %v, but expected a dry run without changes", result, store.added)
+	}
+	if result.Rows[3].Error != `invalid amount "-3", expected e.g. 12.50` || result.Rows[4].Error != `invalid currency "US$", expected a code like EUR` {
+		t.Fatalf("Import reported unexpected errors %+v", result.Rows)
+	}
+}
+
+func TestImportApply(t *testing.T) {
+	store := &FakeStore{}
+	audit := &FakeAuditLog{}
+	result, err := New(store, audit).Import(Request{
+		MoneyPool: "mom",
+		Format:    "csv",
+		Body:      "name,amount,date,note\nPaul,5,2022-03-02,#anon\nPaul,5,2022-03-02,\nAnna,7.5,2022-03-03,\n",
+		Source:    SourceManual,
+		Apply:     true,
+		Actor:     "owner:owner-sub",
+		RequestId: "request-1",
+	})
+	if err != nil {
+		t.Fatalf("Import returned error %v", err)
+	}
+	expectedAdded := []data.Contribution{
+		{Name: "Paul", Date: "02.03.22", Amount: data.Amount{Base: 5}, Anonymous: true, Source: SourceManual},
+		{Name: "Anna", Date: "03.03.22", Amount: data.Amount{Base: 7, Fraction: 50}, Source: SourceManual},
+	}
+	if result.DryRun || result.Added != 2 || result.Duplicates != 1 || !reflect.DeepEqual(store.added, expectedAdded) {
+		t.Fatalf("Import = %+v and added %+v, but expected to add %+v", result, store.added, expectedAdded)
+	}
+	if result.Rows[0].Id != "id-1" || result.Rows[2].Id != "id-2" {
+		t.Fatalf("Import returned unexpected ids %+v", result.Rows)
+	}
+	if len(audit.entries) != 2 || audit.entries[0].Action != data.AuditTransactionImport || audit.entries[0].Actor != "owner:owner-sub" ||
+		audit.entries[0].Source != "request-1#id-1" || audit.entries[1].New["source"] != SourceManual {
+		t.Fatalf("Import wrote unexpected audit entries %+v", audit.entries)
+	}
+}
+
+func TestImportProcessesAddedRows(t *testing.T) {
+	request := Request{MoneyPool: "mom", Format: "csv", Body: "name,amount,date\nPaul,5,2022-03-02\nPaul,5,2022-03-02\n"}
+	for _, apply := range []bool{false, true} {
+		store, processor := &FakeStore{}, &FakeProcessor{}
+		importer := New(store, nil)
+		importer.Processor = processor
+		request.Apply = apply
+		if _, err := importer.Import(request); err != nil {
+			t.Fatalf("Import(apply=%t) returned error %v", apply, err)
+		}
+		if !apply && (len(processor.prepared) != 0 || len(processor.stored) != 0) {
+			t.Fatalf("Import(dry run) processed %v and %v, but expected no processing", processor.prepared, processor.stored)
+		}
+		if apply && (!reflect.DeepEqual(processor.prepared, []string{"Paul"}) || !reflect.DeepEqual(processor.stored, []string{"mom/id-1"}) ||
+			store.added[0].Participant != "paul") {
+			t.Fatalf("Import processed %v and %v and added %+v, but expected to process the added row", processor.prepared, processor.stored, store.added)
+		}
+	}
+}
+
+func TestImportRejectsRequest(t *testing.T) {
+	_, err := New(&FakeStore{}, nil).Import(Request{MoneyPool: "mom", Format: "json", Body: `[{}]`, Source: "paypal"})
+	expected := errors.New("unknown source paypal, expected import or manual")
+	if !compareErrors(err, expected) {
+		t.Fatalf("Import(unknown_source) = %v, but expected %v", err, expected)
+	}
+	if _, ok := err.(*RequestError); !ok {
+		t.Fatalf("Import(unknown_source) returned %T, but expected a RequestError", err)
+	}
+}
+
+// FakeStore keeps added contributions and returns the given ones as stored.
+type FakeStore struct {
+	contributions []data.Contribution
+	added         []data.Contribution
+}
+
+func (s *FakeStore) GetContributions(string) ([]data.Contribution, error) {
+	return s.contributions, nil
+}
+
+func (s *FakeStore) AddTransaction(_ string, contribution data.Contribution) (string, error) {
+	s.added = append(s.added, contribution)
+	return "id-" + string(rune('0'+len(s.added))), nil
+}
+
+type FakeAuditLog struct {
+	entries []data.AuditEntry
+}
+
+func (l *FakeAuditLog) Append(entry data.AuditEntry) error {
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+// FakeProcessor links contributions to the participant with their name in lower case and records what it processed.
+type FakeProcessor struct {
+	prepared []string
+	stored   []string
+}
+
+func (p *FakeProcessor) Prepare(_ string, contribution *data.Contribution) {
+	p.prepared = append(p.prepared, contribution.Name)
+	contribution.Participant = strings.ToLower(contribution.Name)
+}
+
+func (p *FakeProcessor) Stored(moneyPool, transactionId string) {
+	p.stored = append(p.stored, moneyPool+"/"+transactionId)
+}
+
+func compareErrors(err1, err2 error) bool {
+	if err1 != nil && err2 != nil {
+		return err1.Error() == err2.Error()
+	}
+	return err1 == err2
+}
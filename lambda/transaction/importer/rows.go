@@ -0,0 +1,152 @@
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"transaction/data"
+	"transaction/money"
+)
+
+// Row is a contribution to import. Currency, note and PayPal's transaction code are optional. Rows without currency are
+// in the default currency.
+type Row struct {
+	Name     string `json:"name"`
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+	Date     string `json:"date"`
+	Note     string `json:"note"`
+	PaypalId string `json:"paypalId,omitempty"`
+}
+
+// currencyPattern matches ISO currency codes.
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// byteOrderMark starts csv files written by some spreadsheet programs.
+const byteOrderMark = "\uFEFF"
+
+var requiredColumns = []string{"name", "amount", "date"}
+
+// ParseRows reads csv with a header row, or a json array of rows.
+func ParseRows(format, body string) ([]Row, error) {
+	var rows []Row
+	switch strings.ToLower(format) {
+	case "csv":
+		parsed, err := parseCSV(body)
+		if err != nil {
+			return nil, err
+		}
+		rows = parsed
+	case "json":
+		if err := json.Unmarshal([]byte(body), &rows); err != nil {
+			return nil, requestErrorf("invalid json rows: %v", err)
+		}
+	default:
+		return nil, requestErrorf("unknown format %s, expected csv or json", format)
+	}
+	if len(rows) == 0 {
+		return nil, requestErrorf("no rows to import")
+	}
+	if len(rows) > MaxRows {
+		return nil, requestErrorf("%d rows exceed the limit of %d rows per import", len(rows), MaxRows)
+	}
+	return rows, nil
+}
+
+func parseCSV(body string) ([]Row, error) {
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(body, byteOrderMark)))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, requestErrorf("no rows to import")
+	}
+	if err != nil {
+		return nil, requestErrorf("invalid csv header: %v", err)
+	}
+	columns := map[string]int{}
+	for index, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = index
+	}
+	for _, column := range requiredColumns {
+		if _, exists := columns[column]; !exists {
+			return nil, requestErrorf("csv header misses column %s", column)
+		}
+	}
+	value := func(record []string, column string) string {
+		if index, exists := columns[column]; exists && index < len(record) {
+			return record[index]
+		}
+		return ""
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, requestErrorf("invalid csv: %v", err)
+		}
+		if len(strings.TrimSpace(strings.Join(record, ""))) == 0 {
+			continue
+		}
+		rows = append(rows, Row{
+			Name:     value(record, "name"),
+			Amount:   value(record, "amount"),
+			Currency: value(record, "currency"),
+			Date:     value(record, "date"),
+			Note:     value(record, "note"),
+			PaypalId: value(record, "paypalid"),
+		})
+	}
+}
+
+// contribution validates the row and converts it to the stored contribution.
+func (r Row) contribution(source string) (data.Contribution, error) {
+	name := strings.TrimSpace(r.Name)
+	if name == "" {
+		return data.Contribution{}, fmt.Errorf("name is missing")
+	}
+	currency := strings.ToUpper(strings.TrimSpace(r.Currency))
+	if currency != "" && !currencyPattern.MatchString(currency) {
+		return data.Contribution{}, fmt.Errorf("invalid currency %q, expected a code like %s", r.Currency, data.DefaultCurrency)
+	}
+	amount, err := parseAmount(r.Amount, data.CurrencyOrDefault(currency))
+	if err != nil {
+		return data.Contribution{}, err
+	}
+	date, err := parseDate(r.Date)
+	if err != nil {
+		return data.Contribution{}, err
+	}
+	return data.Contribution{
+		Name:      name,
+		Date:      date,
+		Amount:    amount,
+		Currency:  currency,
+		Anonymous: strings.Contains(strings.ToLower(r.Note), data.AnonymousMarker),
+		PaypalId:  strings.TrimSpace(r.PaypalId),
+		Source:    source,
+	}, nil
+}
+
+// parseAmount reads a positive amount of the currency, like "12,50" or "1.234,50 €". Amounts of currencies with three
+// decimals keep them.
+func parseAmount(text, currency string) (data.Amount, error) {
+	amount, err := money.ParseIn(text, currency)
+	if err != nil {
+		return data.Amount{}, err
+	}
+	if amount.Minor < 0 {
+		return data.Amount{}, fmt.Errorf("invalid amount %q, expected e.g. 12.50", text)
+	}
+	if amount.Minor == 0 {
+		return data.Amount{}, fmt.Errorf("amount must be positive")
+	}
+	return data.ScaledAmount(amount.Scaled(), money.Precision(currency)), nil
+}
@@ -2,25 +2,49 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"github.com/aws/aws-lambda-go/lambda"
+	awssdk "github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/apigatewaymanagementapi"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/ses"
 	"github.com/sirupsen/logrus"
 	"os"
+	"strings"
 	"transaction/aws"
+	"transaction/importer"
+	"transaction/notify"
 	"transaction/parser"
+	"transaction/remind"
+	"transaction/thankyou"
+	"transaction/webhook"
 )
 
 type EmailEvent struct {
 	Records []EmailEventRecord `json:"Records"`
 }
 
+// Event is either a batch of received mails, an import of contributions sent by the api, a webhook event sent by the
+// api, or one of the scheduled requests for the daily digests, the weekly reminders and for retrying webhook deliveries.
+type Event struct {
+	EmailEvent
+	Import    *importer.Request     `json:"import"`
+	Digest    *notify.DigestRequest `json:"digest"`
+	Webhook   *webhook.Request      `json:"webhook"`
+	Reminders *remind.Request       `json:"reminders"`
+}
+
 type EmailEventRecord struct {
 	Ses struct {
 		Mail struct {
 			Timestamp string `json:"timestamp"`
 			MessageId string `json:"messageId"`
 		} `json:"mail"`
+		Receipt struct {
+			Recipients []string `json:"recipients"`
+		} `json:"receipt"`
 	} `json:"ses"`
 }
 
@@ -31,22 +55,170 @@ func init() {
 }
 
 var (
-	nameAmountRegex     = os.Getenv("NameAmountRegex")
-	moneyPoolsTableName = os.Getenv("MoneyPoolsTableName")
+	nameAmountRegex          = os.Getenv("NameAmountRegex")
+	refundRegex              = os.Getenv("RefundRegex")
+	refundSubjects           = os.Getenv("EmailRefundSubjects")
+	moneyPoolsTableName      = os.Getenv("MoneyPoolsTableName")
+	liveConnectionsTableName = os.Getenv("LiveConnectionsTableName")
+	liveConnectionsEndpoint  = os.Getenv("LiveConnectionsEndpoint")
+	tenantsTableName         = os.Getenv("TenantsTableName")
+	defaultMailAddress       = os.Getenv("DefaultMailAddress")
+	auditLogTableName        = os.Getenv("AuditLogTableName")
+	notificationSender       = os.Getenv("NotificationSender")
+	smtpServer               = os.Getenv("SmtpServer")
+	smtpUser                 = os.Getenv("SmtpUser")
+	smtpPassword             = os.Getenv("SmtpPassword")
+	webhookDeliveriesTable   = os.Getenv("WebhookDeliveriesTableName")
+	defaultTenantOwner       = os.Getenv("DefaultTenantOwner")
+	thankYouTableName        = os.Getenv("ThankYouMailsTableName")
+	mailDirectory            = os.Getenv("MailDirectory")
 )
 
-func HandleRequest(_ context.Context, event EmailEvent) (string, error) {
+func newMailParser(nameAmountRegex string) MailParser {
+	return parser.NewTransactionMailParser(nameAmountRegex, refundRegex)
+}
+
+// newMailer returns the mailer of notifications and thank-you mails. Mails are written to MailDirectory if it is set,
+// sent via the smtp server if one is set, else via SES, and not at all without sender address.
+func newMailer(awsSession *session.Session) notify.Mailer {
+	switch {
+	case notificationSender == "":
+		return nil
+	case mailDirectory != "":
+		return &notify.FileMailer{Dir: mailDirectory, Sender: notificationSender}
+	case smtpServer != "":
+		return &notify.SMTPMailer{Addr: smtpServer, Username: smtpUser, Password: smtpPassword, Sender: notificationSender}
+	default:
+		return aws.NewSESMailer(notificationSender, ses.New(awsSession))
+	}
+}
+
+// newNotifier creates the notifier of pool owners. Without mailer, mail channels fail.
+func newNotifier(awsSession *session.Session) *notify.Notifier {
+	return notify.New(aws.NewDataStore(moneyPoolsTableName), newMailer(awsSession))
+}
+
+// newThankYou returns nil, which disables thank-you mails, if no mailer or no log of thanked transactions is configured.
+func newThankYou(awsSession *session.Session) *thankyou.Sender {
+	mailer := newMailer(awsSession)
+	if mailer == nil || thankYouTableName == "" {
+		return nil
+	}
+	return thankyou.New(aws.NewDataStore(moneyPoolsTableName), mailer, aws.NewThankYouLog(thankYouTableName, dynamodb.New(awsSession)))
+}
+
+// newWebhooks returns nil, which disables webhooks, if no delivery log is configured.
+func newWebhooks(awsSession *session.Session) *webhook.Dispatcher {
+	if webhookDeliveriesTable == "" {
+		return nil
+	}
+	dispatcher := webhook.New(aws.NewDataStore(moneyPoolsTableName), aws.NewDeliveryLog(webhookDeliveriesTable, dynamodb.New(awsSession)))
+	dispatcher.DefaultOwner = defaultTenantOwner
+	return dispatcher
+}
+
+func HandleRequest(_ context.Context, event Event) (interface{}, error) {
 	awsSession := session.Must(session.NewSession())
+	if event.Import != nil {
+		return handleImport(awsSession, *event.Import)
+	}
+	if event.Digest != nil {
+		// failing channels are logged, so the digests of other pools aren't sent again by a retry
+		if err := newNotifier(awsSession).Digest(*event.Digest); err != nil {
+			logrus.Errorf("error sending daily digests: %v", err)
+		}
+		return "ok", nil
+	}
+	if event.Webhook != nil {
+		return handleWebhook(awsSession, *event.Webhook)
+	}
+	if event.Reminders != nil {
+		return handleReminders(awsSession, *event.Reminders)
+	}
+	proc := NewMailEventProcessor(newConfig(awsSession))
+
+	for _, record := range event.Records {
+		proc.WriteTransactionToMoneyPool(record)
+	}
+	return "ok", nil
+}
+
+// newConfig configures the processing of payment mails, which imported contributions go through as well.
+func newConfig(awsSession *session.Session) Config {
 	config := Config{
 		ExpectedSubject: os.Getenv("EmailExpectedSubject"),
 		MailGetter:      aws.NewMailGetter(s3manager.NewDownloader(awsSession)),
-		MailParser:      parser.NewTransactionMailParser(nameAmountRegex),
+		RefundSubjects:  strings.Split(refundSubjects, ","),
+		MailParser:      parser.NewTransactionMailParser(nameAmountRegex, refundRegex),
 		DataStore:       aws.NewDataStore(moneyPoolsTableName),
+		DefaultAddress:  defaultMailAddress,
+		NewMailParser:   newMailParser,
+		Notifier:        newNotifier(awsSession),
+		Participants:    aws.NewDataStore(moneyPoolsTableName),
+	}
+	if tenantsTableName != "" {
+		config.TenantStore = aws.NewTenantStore(tenantsTableName, dynamodb.New(awsSession))
+	}
+	if auditLogTableName != "" {
+		config.AuditLog = aws.NewAuditLog(auditLogTableName, dynamodb.New(awsSession))
+	}
+	if webhooks := newWebhooks(awsSession); webhooks != nil {
+		config.Webhooks = webhooks
 	}
-	proc := NewMailEventProcessor(config)
+	if thankYou := newThankYou(awsSession); thankYou != nil {
+		config.ThankYou = thankYou
+	}
+	if liveConnectionsEndpoint != "" {
+		config.EventPublisher = aws.NewEventPublisher(liveConnectionsTableName,
+			dynamodb.New(awsSession),
+			apigatewaymanagementapi.New(awsSession, awssdk.NewConfig().WithEndpoint(liveConnectionsEndpoint)),
+		)
+	}
+	return config
+}
 
-	for _, record := range event.Records {
-		proc.WriteTransactionToMoneyPool(record)
+// handleImport returns the import's result, or the reason the import was rejected. Other errors fail the invocation.
+func handleImport(awsSession *session.Session, request importer.Request) (importer.Response, error) {
+	var auditLog importer.AuditLog
+	if auditLogTableName != "" {
+		auditLog = aws.NewAuditLog(auditLogTableName, dynamodb.New(awsSession))
+	}
+	imports := importer.New(aws.NewDataStore(moneyPoolsTableName), auditLog)
+	imports.Processor = newImportProcessor(newConfig(awsSession), request.RequestId)
+	result, err := imports.Import(request)
+	if requestErr, ok := err.(*importer.RequestError); ok {
+		return importer.Response{Error: requestErr.Error()}, nil
+	}
+	if err != nil {
+		return importer.Response{}, err
+	}
+	logrus.WithFields(logrus.Fields{"pool": request.MoneyPool, "dryRun": result.DryRun}).Infof("imported %d of %d rows", result.Added, len(result.Rows))
+	return importer.Response{Result: &result}, nil
+}
+
+// handleWebhook delivers an event sent by the api, or retries the due deliveries. Errors are only logged: a retry of the
+// invocation by lambda would deliver the event again to subscriptions that already got it, and failed deliveries are
+// retried from the delivery log anyway.
+func handleWebhook(awsSession *session.Session, request webhook.Request) (string, error) {
+	webhooks := newWebhooks(awsSession)
+	if webhooks == nil {
+		return "", fmt.Errorf("webhooks are not configured")
+	}
+	if err := webhooks.Handle(request); err != nil {
+		logrus.WithFields(logrus.Fields{"pool": request.MoneyPool, "type": request.Type, "retry": request.Retry}).Errorf("error delivering webhooks: %v", err)
+	}
+	return "ok", nil
+}
+
+// handleReminders reminds participants of their open balances. Failing mails are logged, so the participants that were
+// reminded aren't reminded again by a retry.
+func handleReminders(awsSession *session.Session, request remind.Request) (string, error) {
+	mailer := newMailer(awsSession)
+	if mailer == nil {
+		return "", fmt.Errorf("sending mails is not configured")
+	}
+	if err := remind.New(aws.NewDataStore(moneyPoolsTableName), mailer).Send(request); err != nil {
+		logrus.WithFields(logrus.Fields{"pool": request.MoneyPool}).Errorf("error sending reminders: %v", err)
 	}
 	return "ok", nil
 }
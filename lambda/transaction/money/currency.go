@@ -0,0 +1,94 @@
+package money
+
+import "regexp"
+
+// currency describes how amounts of a currency are written.
+type currency struct {
+	// decimals is the number of digits of the minor unit, e.g. 2 for cents.
+	decimals int
+	// symbols are written instead of the ISO code, the preferred one first.
+	symbols []string
+}
+
+// currencies are the currencies whose symbols are known or that don't have two decimals. Other ISO codes are read as
+// currencies with two decimals.
+var currencies = map[string]currency{
+	"EUR": {2, []string{"€"}},
+	"USD": {2, []string{"$", "US$"}},
+	"GBP": {2, []string{"£"}},
+	"CHF": {2, []string{"Fr."}},
+	"JPY": {0, []string{"¥", "￥"}},
+	"CNY": {2, []string{"CN¥"}},
+	"CAD": {2, []string{"CA$", "C$"}},
+	"AUD": {2, []string{"A$", "AU$"}},
+	"NZD": {2, []string{"NZ$"}},
+	"HKD": {2, []string{"HK$"}},
+	"SGD": {2, []string{"S$"}},
+	"TWD": {2, []string{"NT$"}},
+	"MXN": {2, []string{"MX$"}},
+	"BRL": {2, []string{"R$"}},
+	"INR": {2, []string{"₹"}},
+	"RUB": {2, []string{"₽"}},
+	"TRY": {2, []string{"₺"}},
+	"ILS": {2, []string{"₪"}},
+	"THB": {2, []string{"฿"}},
+	"PHP": {2, []string{"₱"}},
+	"PLN": {2, []string{"zł"}},
+	"CZK": {2, []string{"Kč"}},
+	"HUF": {2, []string{"Ft"}},
+	"SEK": {2, nil},
+	"NOK": {2, nil},
+	"DKK": {2, nil},
+	"KRW": {0, []string{"₩"}},
+	"VND": {0, []string{"₫"}},
+	"CLP": {0, nil},
+	"ISK": {0, nil},
+	"BHD": {3, nil},
+	"IQD": {3, nil},
+	"JOD": {3, nil},
+	"KWD": {3, nil},
+	"LYD": {3, nil},
+	"OMR": {3, nil},
+	"TND": {3, nil},
+}
+
+// sharedSymbols are used by several currencies. They stand for the first of them, unless the amount also names one
+// of the others by its ISO code, like "$ CAD".
+var sharedSymbols = map[string][]string{
+	"$": {"USD", "CAD", "AUD", "NZD", "HKD", "SGD", "TWD", "MXN"},
+	"¥": {"JPY", "CNY"},
+	"￥": {"JPY", "CNY"},
+}
+
+// symbolCurrencies maps the symbols only used by one currency to its ISO code.
+var symbolCurrencies = map[string]string{}
+
+func init() {
+	for code, currency := range currencies {
+		for _, symbol := range currency.symbols {
+			if _, shared := sharedSymbols[symbol]; !shared {
+				symbolCurrencies[symbol] = code
+			}
+		}
+	}
+}
+
+// codePattern matches ISO currency codes.
+var codePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Decimals returns the number of decimals of the currency's minor unit, 2 for unknown currencies.
+func Decimals(code string) int {
+	if currency, known := currencies[code]; known {
+		return currency.decimals
+	}
+	return 2
+}
+
+// Precision returns the decimals amounts of the currency are stored with: its decimals, but at least two, so amounts of
+// currencies without minor unit are stored in cents like most others.
+func Precision(code string) int {
+	if decimals := Decimals(code); decimals > 2 {
+		return decimals
+	}
+	return 2
+}
@@ -0,0 +1,27 @@
+//go:build go1.18
+// +build go1.18
+
+package money
+
+import "testing"
+
+// FuzzParse checks that every amount Parse accepts is written by String in a form that parses to the same amount, and
+// that Parse never panics. Run it with go test -fuzz FuzzParse ./money.
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{
+		"1.234,56 € EUR", "€5,00", "-5,00 €", "CHF 1'250.00", "¥1,000", "1\u202F234,56 €", "1.250 KWD", "$ 5.00 CAD",
+		"1.00002 $ USD", "12.5.0 €", "1,99 XXXX",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, text string) {
+		amount, err := Parse(text)
+		if err != nil {
+			return
+		}
+		parsed, err := Parse(amount.String())
+		if err != nil || parsed != amount {
+			t.Fatalf("Parse(%q) = %+v, but Parse(%q) = %+v, %v", text, amount, amount.String(), parsed, err)
+		}
+	})
+}
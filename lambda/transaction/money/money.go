@@ -0,0 +1,278 @@
+// Package money reads amounts of money as PayPal, banks and spreadsheets write them, e.g. "1.234,56 € EUR", "€5,00",
+// "CHF 1'250.00" or "¥1,000".
+package money
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// maxIntegerDigits keeps the minor units of parsed amounts far from overflowing.
+const maxIntegerDigits = 15
+
+// numberPattern matches the digits of an amount with their separators. Numbers start and end with a digit.
+var numberPattern = regexp.MustCompile(`[0-9](?:[0-9.,'\x{2019} \x{00A0}\x{202F}\x{2009}]*[0-9])?`)
+
+// signs are the minus signs written before or after an amount or its currency.
+const signs = "-\u2212"
+
+// groupSeparators only group thousands, unlike points and commas that may also separate decimals.
+const groupSeparators = "'\u2019 \u00A0\u202F\u2009"
+
+// Amount is an amount of money in the minor unit of its currency.
+type Amount struct {
+	// Minor is the amount in the currency's minor unit, e.g. cents for EUR or yen for JPY. It is negative for amounts
+	// like "-5,00 €".
+	Minor int
+	// Currency is the ISO code of the currency.
+	Currency string
+}
+
+// Cents returns the amount in hundredths of its currency's unit, e.g. to compare it with goals. Amounts of currencies
+// with three decimals are rounded half away from zero.
+func (a Amount) Cents() int {
+	switch decimals := Decimals(a.Currency); {
+	case decimals < 2:
+		return a.Minor * pow10(2-decimals)
+	case decimals > 2:
+		return roundDiv(a.Minor, pow10(decimals-2))
+	}
+	return a.Minor
+}
+
+// Scaled returns the amount in units of its currency's precision: cents for most currencies, thousandths for
+// currencies with three decimals.
+func (a Amount) Scaled() int {
+	if decimals := Decimals(a.Currency); decimals < 2 {
+		return a.Minor * pow10(2-decimals)
+	}
+	return a.Minor
+}
+
+// String writes the amount like "-1234.56 EUR", which Parse reads back.
+func (a Amount) String() string {
+	minor, sign := a.Minor, ""
+	if minor < 0 {
+		minor, sign = -minor, "-"
+	}
+	decimals := Decimals(a.Currency)
+	if decimals == 0 {
+		return fmt.Sprintf("%s%d %s", sign, minor, a.Currency)
+	}
+	unit := pow10(decimals)
+	return fmt.Sprintf("%s%d.%0*d %s", sign, minor/unit, decimals, minor%unit, a.Currency)
+}
+
+// Parse reads an amount and its currency, named by ISO code or symbol before or after the number. The decimal separator
+// is a point or a comma; thousands are grouped by the other one, apostrophes or spaces. A single separator followed by
+// three digits groups thousands, unless the currency has three decimals. Decimals beyond those of the currency are
+// rounded.
+func Parse(text string) (Amount, error) {
+	return parse(text, "")
+}
+
+// ParseIn reads an amount of the given currency. The text may name the currency too, but no other one.
+func ParseIn(text, currency string) (Amount, error) {
+	if !codePattern.MatchString(currency) {
+		return Amount{}, fmt.Errorf("invalid currency %q", currency)
+	}
+	return parse(text, currency)
+}
+
+func parse(text, expected string) (Amount, error) {
+	numbers := numberPattern.FindAllStringIndex(text, -1)
+	if len(numbers) == 0 {
+		return Amount{}, fmt.Errorf("no number in amount %q", text)
+	}
+	if len(numbers) > 1 {
+		return Amount{}, fmt.Errorf("several numbers in amount %q", text)
+	}
+	start, end := numbers[0][0], numbers[0][1]
+
+	negative := false
+	var labels []string
+	for _, field := range strings.Fields(text[:start] + " " + text[end:]) {
+		label := strings.Trim(field, signs)
+		if label != field {
+			if negative || strings.Count(field, "-")+strings.Count(field, "\u2212") > 1 {
+				return Amount{}, fmt.Errorf("several signs in amount %q", text)
+			}
+			negative = true
+		}
+		if label != "" {
+			labels = append(labels, label)
+		}
+	}
+	currency, err := currencyOf(labels, expected)
+	if err != nil {
+		return Amount{}, fmt.Errorf("%v in amount %q", err, text)
+	}
+
+	minor, err := parseNumber(text[start:end], Decimals(currency))
+	if err != nil {
+		return Amount{}, fmt.Errorf("%v in amount %q", err, text)
+	}
+	if negative {
+		minor = -minor
+	}
+	return Amount{Minor: minor, Currency: currency}, nil
+}
+
+// currencyOf returns the currency the labels around the number name. Without labels, it is the expected currency.
+func currencyOf(labels []string, expected string) (string, error) {
+	var named string
+	var shared []string
+	for _, label := range labels {
+		if candidates, isShared := sharedSymbols[label]; isShared {
+			shared = candidates
+			continue
+		}
+		code, known := symbolCurrencies[label]
+		if !known {
+			code = strings.ToUpper(label)
+			if _, listed := currencies[code]; !listed && !codePattern.MatchString(label) {
+				return "", fmt.Errorf("unknown currency %q", label)
+			}
+		}
+		if named != "" && named != code {
+			return "", fmt.Errorf("currencies %s and %s", named, code)
+		}
+		named = code
+	}
+	if named == "" && shared != nil {
+		named = shared[0]
+		if contains(shared, expected) {
+			named = expected
+		}
+	}
+	if named != "" && shared != nil && !contains(shared, named) {
+		return "", fmt.Errorf("symbol of other currency than %s", named)
+	}
+	switch {
+	case named == "" && expected == "":
+		return "", fmt.Errorf("no currency")
+	case named == "":
+		return expected, nil
+	case expected != "" && named != expected:
+		return "", fmt.Errorf("currency %s instead of %s", named, expected)
+	}
+	return named, nil
+}
+
+// parseNumber returns the number in minor units of a currency with the given decimals.
+func parseNumber(number string, decimals int) (int, error) {
+	integer, fraction := number, ""
+	if separator := decimalSeparator(number, decimals); separator >= 0 {
+		integer, fraction = number[:separator], number[separator+1:]
+	}
+	if strings.IndexFunc(fraction, notDigit) >= 0 {
+		return 0, fmt.Errorf("misplaced separator")
+	}
+	integer, err := ungroup(integer)
+	if err != nil {
+		return 0, err
+	}
+	integer = strings.TrimLeft(integer, "0")
+	if len(integer) > maxIntegerDigits {
+		return 0, fmt.Errorf("too many digits")
+	}
+
+	roundUp := len(fraction) > decimals && fraction[decimals] >= '5'
+	if len(fraction) > decimals {
+		fraction = fraction[:decimals]
+	}
+	fraction += strings.Repeat("0", decimals-len(fraction))
+	minor := 0
+	for _, digit := range integer + fraction {
+		minor = minor*10 + int(digit-'0')
+	}
+	if roundUp {
+		minor++
+	}
+	return minor, nil
+}
+
+// decimalSeparator returns the index of the number's decimal separator, or -1 if it has none. With points and commas,
+// the last one separates the decimals. A single point or comma does, unless it is followed by exactly three digits
+// that are more likely thousands.
+func decimalSeparator(number string, decimals int) int {
+	last := strings.LastIndexAny(number, ".,")
+	points, commas := strings.Count(number, "."), strings.Count(number, ",")
+	switch {
+	case last < 0:
+		return -1
+	case points > 0 && commas > 0:
+		return last
+	case points+commas > 1:
+		return -1
+	case strings.ContainsAny(number, groupSeparators):
+		// thousands are grouped by apostrophes or spaces
+		return last
+	case len(number)-last-1 != 3 || decimals == 3 || strings.Trim(number[:last], "0") == "":
+		return last
+	}
+	return -1
+}
+
+// ungroup removes the thousands separators from the integer part of a number. All groups but the first must have
+// three digits, and all separators must be alike.
+func ungroup(integer string) (string, error) {
+	var digits strings.Builder
+	separator, group, groups := "", 0, 0
+	for _, char := range integer {
+		if !notDigit(char) {
+			digits.WriteRune(char)
+			group++
+			continue
+		}
+		kind := string(char)
+		switch {
+		case unicode.IsSpace(char):
+			kind = " "
+		case char == '\u2019':
+			kind = "'"
+		}
+		if separator != "" && separator != kind {
+			return "", fmt.Errorf("mixed thousands separators")
+		}
+		if group == 0 || group > 3 || (groups > 0 && group != 3) {
+			return "", fmt.Errorf("misplaced thousands separator")
+		}
+		separator, group, groups = kind, 0, groups+1
+	}
+	if groups > 0 && group != 3 {
+		return "", fmt.Errorf("misplaced thousands separator")
+	}
+	return digits.String(), nil
+}
+
+func notDigit(char rune) bool {
+	return char < '0' || char > '9'
+}
+
+func contains(codes []string, code string) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func pow10(exponent int) int {
+	result := 1
+	for i := 0; i < exponent; i++ {
+		result *= 10
+	}
+	return result
+}
+
+// roundDiv divides and rounds half away from zero.
+func roundDiv(dividend, divisor int) int {
+	if dividend < 0 {
+		return -roundDiv(-dividend, divisor)
+	}
+	return (dividend + divisor/2) / divisor
+}
@@ -0,0 +1,129 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+type parseTest struct {
+	text          string
+	expected      Amount
+	expectedError error
+}
+
+func TestParse(t *testing.T) {
+	testTable := []parseTest{
+		{"1.234,56 € EUR", Amount{123456, "EUR"}, nil},
+		{"00030,00 € EUR", Amount{3000, "EUR"}, nil},
+		{"20 € EUR", Amount{2000, "EUR"}, nil},
+		{"12,345.67 $ USD", Amount{1234567, "USD"}, nil},
+		{"1.00002 $ USD", Amount{100, "USD"}, nil},
+		{"1.005 $ USD", Amount{100500, "USD"}, nil},
+		{"0.125 USD", Amount{13, "USD"}, nil},
+		{"€5,00", Amount{500, "EUR"}, nil},
+		{"5,00 EUR", Amount{500, "EUR"}, nil},
+		{"5,5€", Amount{550, "EUR"}, nil},
+		{"-€5,00", Amount{-500, "EUR"}, nil},
+		{"−5,00 €", Amount{-500, "EUR"}, nil},
+		{"CHF 1'250.00", Amount{125000, "CHF"}, nil},
+		{"CHF 1’250.00", Amount{125000, "CHF"}, nil},
+		{"Fr. 12.50", Amount{1250, "CHF"}, nil},
+		{"¥1,000", Amount{1000, "JPY"}, nil},
+		{"¥1,000 CNY", Amount{100000, "CNY"}, nil},
+		{"1 000 000 ₩", Amount{1000000, "KRW"}, nil},
+		{"1\u202F234,56 €", Amount{123456, "EUR"}, nil},
+		{"1\u00A0234,56\u00A0€", Amount{123456, "EUR"}, nil},
+		{"1.250 KWD", Amount{1250, "KWD"}, nil},
+		{"1.234,500 KWD", Amount{1234500, "KWD"}, nil},
+		{"$ 5.00 CAD", Amount{500, "CAD"}, nil},
+		{"CA$5.00", Amount{500, "CAD"}, nil},
+		{"US$5", Amount{500, "USD"}, nil},
+		{"R$ 1.234,56", Amount{123456, "BRL"}, nil},
+		{"12,34 zł", Amount{1234, "PLN"}, nil},
+		{"5.00 usd", Amount{500, "USD"}, nil},
+		{"5.00 XAU", Amount{500, "XAU"}, nil},
+		{"1,99", Amount{}, errors.New(`no currency in amount "1,99"`)},
+		{"1,99 XXXX", Amount{}, errors.New(`unknown currency "XXXX" in amount "1,99 XXXX"`)},
+		{"€ EUR", Amount{}, errors.New(`no number in amount "€ EUR"`)},
+		{"5 € 10 €", Amount{}, errors.New(`several numbers in amount "5 € 10 €"`)},
+		{"5,00 € USD", Amount{}, errors.New(`currencies EUR and USD in amount "5,00 € USD"`)},
+		{"$5.00 EUR", Amount{}, errors.New(`symbol of other currency than EUR in amount "$5.00 EUR"`)},
+		{"-5,00 -€", Amount{}, errors.New(`several signs in amount "-5,00 -€"`)},
+		{"12.5.0 €", Amount{}, errors.New(`misplaced thousands separator in amount "12.5.0 €"`)},
+		{"1.234'567,00 €", Amount{}, errors.New(`mixed thousands separators in amount "1.234'567,00 €"`)},
+		{"1,23,456.00 INR", Amount{}, errors.New(`misplaced thousands separator in amount "1,23,456.00 INR"`)},
+		{"1,5 000 €", Amount{}, errors.New(`misplaced separator in amount "1,5 000 €"`)},
+		{"1234567890123456 €", Amount{}, errors.New(`too many digits in amount "1234567890123456 €"`)},
+	}
+	for _, test := range testTable {
+		amount, err := Parse(test.text)
+		if !compareErrors(err, test.expectedError) || amount != test.expected {
+			t.Fatalf("Parse(%s) = %+v, %v but expected %+v, %v", test.text, amount, err, test.expected, test.expectedError)
+		}
+	}
+}
+
+func TestParseIn(t *testing.T) {
+	testTable := []parseTest{
+		{"12,5", Amount{1250, "EUR"}, nil},
+		{"1.000", Amount{100000, "EUR"}, nil},
+		{"12,50 €", Amount{1250, "EUR"}, nil},
+		{"-3", Amount{-300, "EUR"}, nil},
+		{"12.50 USD", Amount{}, errors.New(`currency USD instead of EUR in amount "12.50 USD"`)},
+		{"$12.50", Amount{}, errors.New(`currency USD instead of EUR in amount "$12.50"`)},
+	}
+	for _, test := range testTable {
+		amount, err := ParseIn(test.text, "EUR")
+		if !compareErrors(err, test.expectedError) || amount != test.expected {
+			t.Fatalf("ParseIn(%s, EUR) = %+v, %v but expected %+v, %v", test.text, amount, err, test.expected, test.expectedError)
+		}
+	}
+	if amount, err := ParseIn("$12.50", "CAD"); err != nil || amount != (Amount{1250, "CAD"}) {
+		t.Fatalf("ParseIn($12.50, CAD) = %+v, %v but expected 12.50 CAD", amount, err)
+	}
+	if _, err := ParseIn("12.50", "euro"); !compareErrors(err, errors.New(`invalid currency "euro"`)) {
+		t.Fatalf("ParseIn(12.50, euro) returned error %v", err)
+	}
+}
+
+func TestCents(t *testing.T) {
+	testTable := map[Amount]int{
+		{1234, "EUR"}:    1234,
+		{1000, "JPY"}:    100000,
+		{1255, "KWD"}:    126,
+		{-1255, "KWD"}:   -126,
+		{1254, "KWD"}:    125,
+		{500, "XAU"}:     500,
+		{-1000, "JPY"}:   -100000,
+		{1000000, "BHD"}: 100000,
+	}
+	for amount, expected := range testTable {
+		if cents := amount.Cents(); cents != expected {
+			t.Fatalf("%+v.Cents() = %d, but expected %d", amount, cents, expected)
+		}
+	}
+}
+
+func TestScaled(t *testing.T) {
+	testTable := map[Amount]int{
+		{1234, "EUR"}:  1234,
+		{1000, "JPY"}:  100000,
+		{1255, "KWD"}:  1255,
+		{-1255, "BHD"}: -1255,
+	}
+	for amount, expected := range testTable {
+		if scaled := amount.Scaled(); scaled != expected {
+			t.Fatalf("%+v.Scaled() = %d, but expected %d", amount, scaled, expected)
+		}
+	}
+	if Precision("JPY") != 2 || Precision("EUR") != 2 || Precision("KWD") != 3 {
+		t.Fatalf("Precision(JPY, EUR, KWD) = %d, %d, %d, but expected 2, 2, 3", Precision("JPY"), Precision("EUR"), Precision("KWD"))
+	}
+}
+
+func compareErrors(err1, err2 error) bool {
+	if err1 != nil && err2 != nil {
+		return err1.Error() == err2.Error()
+	}
+	return err1 == err2
+}
@@ -0,0 +1,107 @@
+package money
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// style is a way to write amounts, as used in some locale.
+type style struct {
+	decimal  string
+	grouping string
+}
+
+var styles = []style{
+	{".", ","},
+	{",", "."},
+	{".", "'"},
+	{".", "’"},
+	{",", " "},
+	{",", "\u00A0"},
+	{",", "\u202F"},
+	{".", "\u2009"},
+	{".", ""},
+	{",", ""},
+}
+
+// currencyCodes lists the known currencies in a fixed order, so failures can be reproduced.
+var currencyCodes = func() []string {
+	codes := make([]string, 0, len(currencies))
+	for code := range currencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}()
+
+// format writes the amount in the given style, with its currency's symbol or code before or after the number.
+func format(amount Amount, s style, symbol, before, space bool) string {
+	minor, sign := amount.Minor, ""
+	if minor < 0 {
+		minor, sign = -minor, "-"
+	}
+	decimals := Decimals(amount.Currency)
+	unit := pow10(decimals)
+	digits := strconv.Itoa(minor / unit)
+	var number strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			number.WriteString(s.grouping)
+		}
+		number.WriteRune(digit)
+	}
+	if decimals > 0 {
+		fraction := strconv.Itoa(minor % unit)
+		number.WriteString(s.decimal + strings.Repeat("0", decimals-len(fraction)) + fraction)
+	}
+
+	label := amount.Currency
+	if symbols := currencies[amount.Currency].symbols; symbol && len(symbols) > 0 {
+		label = symbols[0]
+	}
+	separator := ""
+	if space {
+		separator = " "
+	}
+	if before {
+		return sign + label + separator + number.String()
+	}
+	return sign + number.String() + separator + label
+}
+
+func TestParseFormatted(t *testing.T) {
+	roundTrip := func(minor int64, negative bool, currency, styleIndex uint8, symbol, before, space bool) bool {
+		amount := Amount{Minor: int(uint64(minor) % 1e15), Currency: currencyCodes[int(currency)%len(currencyCodes)]}
+		if negative {
+			amount.Minor = -amount.Minor
+		}
+		text := format(amount, styles[int(styleIndex)%len(styles)], symbol, before, space)
+		parsed, err := Parse(text)
+		if err != nil || parsed != amount {
+			t.Logf("Parse(%s) = %+v, %v but expected %+v", text, parsed, err, amount)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 10000}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseString(t *testing.T) {
+	roundTrip := func(minor int64, currency uint8) bool {
+		amount := Amount{Minor: int(minor % 1e15), Currency: currencyCodes[int(currency)%len(currencyCodes)]}
+		parsed, err := Parse(amount.String())
+		if err != nil || parsed != amount {
+			t.Logf("Parse(%s) = %+v, %v but expected %+v", amount.String(), parsed, err, amount)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 10000}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,33 @@
+package notify
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileMailer writes mails to files in a directory instead of sending them, for tests and local runs.
+type FileMailer struct {
+	Dir    string
+	Sender string
+	mutex  sync.Mutex
+	count  int
+}
+
+// SendMail writes the mail as .eml file, named after the time it was written.
+func (m *FileMailer) SendMail(to, subject, text string) error {
+	if strings.ContainsAny(to, "\r\n") {
+		return fmt.Errorf("invalid mail address %q", to)
+	}
+	m.mutex.Lock()
+	m.count++
+	name := fmt.Sprintf("%s-%d.eml", time.Now().UTC().Format("20060102T150405.000000000"), m.count)
+	m.mutex.Unlock()
+	if err := ioutil.WriteFile(filepath.Join(m.Dir, name), mailContent(m.Sender, to, subject, text), 0644); err != nil {
+		return fmt.Errorf("could not write mail to %s: %v", to, err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"transaction/data"
+)
+
+// Types of messages, as sent to webhooks.
+const (
+	MessagePayment = data.EventContributionAdded
+	MessageGoal    = "pool.goalReached"
+	MessageDigest  = "pool.dailyDigest"
+)
+
+// maxDiscordLength is the maximum length of a Discord message.
+const maxDiscordLength = 2000
+
+// Message is a notification. It is sent as json to webhooks, and written as text to mails and chats. Names of anonymous
+// contributions are left out, since chats are often shared with others than the owner.
+type Message struct {
+	Type          string                `json:"type"`
+	MoneyPool     string                `json:"moneyPool"`
+	Title         string                `json:"title"`
+	Date          string                `json:"date,omitempty"` // the day of a digest
+	Contributions []MessageContribution `json:"contributions"`
+	Total         string                `json:"total"` // the pool's total in its base currency
+	Goal          string                `json:"goal,omitempty"`
+}
+
+type MessageContribution struct {
+	Id        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	Date      string `json:"date"`
+	Amount    string `json:"amount"` // negative for refunds
+	Anonymous bool   `json:"anonymous,omitempty"`
+}
+
+func newMessage(messageType string, pool data.NotifiedPool, contributions []data.Contribution) Message {
+	currency := data.CurrencyOrDefault(pool.BaseCurrency)
+	message := Message{
+		Type:          messageType,
+		MoneyPool:     pool.Name,
+		Title:         pool.Title,
+		Contributions: make([]MessageContribution, 0, len(contributions)),
+		Total:         formatCents(pool.Total(), currency),
+	}
+	if message.Title == "" {
+		message.Title = pool.Name
+	}
+	if pool.Notifications.Goal != nil {
+		message.Goal = formatCents(pool.Notifications.Goal.Cents(), currency)
+	}
+	for _, contribution := range contributions {
+		amount := contribution.Amount.String()
+		if contribution.RefundOf != "" {
+			amount = "-" + amount
+		}
+		messageContribution := MessageContribution{
+			Id:        contribution.Id,
+			Date:      contribution.Date,
+			Amount:    amount + " " + data.CurrencyOrDefault(contribution.Currency),
+			Anonymous: contribution.Anonymous,
+		}
+		if !contribution.Anonymous {
+			messageContribution.Name = contribution.Name
+		}
+		message.Contributions = append(message.Contributions, messageContribution)
+	}
+	return message
+}
+
+func (m Message) Subject() string {
+	switch m.Type {
+	case MessageGoal:
+		return fmt.Sprintf("%s reached its goal of %s", m.Title, m.Goal)
+	case MessageDigest:
+		return fmt.Sprintf("%d new contributions to %s on %s", len(m.Contributions), m.Title, m.Date)
+	default:
+		return fmt.Sprintf("New contribution to %s", m.Title)
+	}
+}
+
+func (m Message) Text() string {
+	var text strings.Builder
+	for _, contribution := range m.Contributions {
+		name := contribution.Name
+		if contribution.Anonymous {
+			name = "Anonymous"
+		}
+		fmt.Fprintf(&text, "%s: %s (%s)\n", name, contribution.Amount, contribution.Date)
+	}
+	fmt.Fprintf(&text, "\nTotal: %s", m.Total)
+	if m.Goal != "" {
+		fmt.Fprintf(&text, " of %s", m.Goal)
+	}
+	return text.String()
+}
+
+type telegramMessage struct {
+	ChatId string `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+type discordMessage struct {
+	Content string `json:"content"`
+}
+
+// post sends the payload as json, and fails unless the url answers with a success status.
+func (n *Notifier) post(target string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("could not encode notification: %v", err)
+	}
+	request, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid notification url")
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := n.Client.Do(request)
+	if err != nil {
+		// the url may contain a secret like a bot token, so it is left out of the error
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("could not send notification: %v", err)
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("notification was answered with status %d", response.StatusCode)
+	}
+	return nil
+}
+
+func formatCents(cents int, currency string) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d %s", sign, cents/100, cents%100, currency)
+}
+
+// truncate shortens the text to at most max runes.
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,161 @@
+// Package notify tells pool owners about new contributions, through the channels and on the triggers they chose for
+// each pool: mails, webhooks, and Telegram or Discord-compatible chat webhooks.
+package notify
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	"transaction/data"
+)
+
+// storedDateFormat is the date format of contributions written by the transaction lambda.
+const storedDateFormat = "02.01.06"
+
+// DefaultTelegramURL is the url of Telegram's bot api.
+const DefaultTelegramURL = "https://api.telegram.org"
+
+type Store interface {
+	GetNotifiedPool(moneyPool string) (*data.NotifiedPool, error)
+	GetNotifiedPools() ([]data.NotifiedPool, error)
+}
+
+// Mailer sends a plain text mail.
+type Mailer interface {
+	SendMail(to, subject, text string) error
+}
+
+type HTTPClient interface {
+	Do(request *http.Request) (*http.Response, error)
+}
+
+// DigestRequest asks for the daily digests, as sent by the scheduled event.
+type DigestRequest struct {
+	// Date is the day to send the digests for, e.g. 2022-03-01. If empty, it is the day before today.
+	Date string `json:"date"`
+}
+
+type Notifier struct {
+	Store Store
+	// Mailer sends the notifications of mail channels. Without it, they fail.
+	Mailer      Mailer
+	Client      HTTPClient
+	TelegramURL string
+}
+
+func New(store Store, mailer Mailer) *Notifier {
+	return &Notifier{
+		Store:       store,
+		Mailer:      mailer,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		TelegramURL: DefaultTelegramURL,
+	}
+}
+
+// Contribution notifies the channels of the pool about its stored transaction, if they are notified on payments, and
+// about the pool reaching its goal if the transaction made it reach it.
+func (n *Notifier) Contribution(moneyPool, transactionId string) error {
+	pool, err := n.Store.GetNotifiedPool(moneyPool)
+	if err != nil {
+		return err
+	}
+	if pool == nil || len(pool.Notifications.Channels) == 0 {
+		return nil
+	}
+	contribution := pool.Contribution(transactionId)
+	if contribution == nil {
+		return fmt.Errorf("transaction %s not found in moneypool %s", transactionId, moneyPool)
+	}
+
+	errs := &sendErrors{}
+	n.send(*pool, data.NotifyPayment, newMessage(MessagePayment, *pool, []data.Contribution{*contribution}), errs)
+	if pool.ReachesGoal(*contribution) {
+		n.send(*pool, data.NotifyGoal, newMessage(MessageGoal, *pool, []data.Contribution{*contribution}), errs)
+	}
+	return errs.err()
+}
+
+// Digest sends the contributions stored on the requested day to all channels notified daily. Pools without
+// contributions on that day get no digest.
+func (n *Notifier) Digest(request DigestRequest) error {
+	day := time.Now().AddDate(0, 0, -1)
+	if request.Date != "" {
+		var err error
+		if day, err = time.Parse("2006-01-02", request.Date); err != nil {
+			return fmt.Errorf("invalid digest date %q, expected e.g. 2022-03-01", request.Date)
+		}
+	}
+	pools, err := n.Store.GetNotifiedPools()
+	if err != nil {
+		return err
+	}
+
+	errs := &sendErrors{}
+	for _, pool := range pools {
+		var contributions []data.Contribution
+		for _, contribution := range pool.Contributions {
+			if !contribution.Voided && contribution.Date == day.Format(storedDateFormat) {
+				contributions = append(contributions, contribution)
+			}
+		}
+		if len(contributions) == 0 {
+			continue
+		}
+		message := newMessage(MessageDigest, pool, contributions)
+		message.Date = day.Format("2006-01-02")
+		n.send(pool, data.NotifyDaily, message, errs)
+	}
+	return errs.err()
+}
+
+// send sends the message to all of the pool's channels that are notified on the trigger. Failing channels don't keep
+// the message from being sent to the others.
+func (n *Notifier) send(pool data.NotifiedPool, trigger string, message Message, errs *sendErrors) {
+	for _, channel := range pool.Notifications.Channels {
+		if !channel.Notifies(trigger) {
+			continue
+		}
+		errs.sent++
+		if err := n.sendTo(channel, message); err != nil {
+			errs.add(fmt.Errorf("%s channel of moneypool %s: %v", channel.Type, pool.Name, err))
+		}
+	}
+}
+
+func (n *Notifier) sendTo(channel data.NotificationChannel, message Message) error {
+	switch channel.Type {
+	case data.ChannelMail:
+		if n.Mailer == nil {
+			return fmt.Errorf("sending mails is not configured")
+		}
+		return n.Mailer.SendMail(channel.Address, message.Subject(), message.Text())
+	case data.ChannelWebhook:
+		return n.post(channel.URL, message)
+	case data.ChannelTelegram:
+		return n.post(n.TelegramURL+"/bot"+channel.BotToken+"/sendMessage", telegramMessage{
+			ChatId: channel.ChatId,
+			Text:   message.Subject() + "\n\n" + message.Text(),
+		})
+	case data.ChannelDiscord:
+		return n.post(channel.URL, discordMessage{Content: truncate(message.Subject()+"\n\n"+message.Text(), maxDiscordLength)})
+	default:
+		return fmt.Errorf("unknown channel type %s", channel.Type)
+	}
+}
+
+// sendErrors collects the errors of sending a notification to several channels.
+type sendErrors struct {
+	sent   int
+	errors []error
+}
+
+func (e *sendErrors) add(err error) {
+	e.errors = append(e.errors, err)
+}
+
+func (e *sendErrors) err() error {
+	if len(e.errors) == 0 {
+		return nil
+	}
+	return fmt.Errorf("could not notify %d of %d channels, first error: %v", len(e.errors), e.sent, e.errors[0])
+}
@@ -0,0 +1,244 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"transaction/data"
+)
+
+func TestContribution(t *testing.T) {
+	server, requests := newTestServer(http.StatusOK)
+	defer server.Close()
+	mailer := &FakeMailer{}
+	pool := testPool(server.URL,
+		data.Contribution{Id: "1", Name: "Anna", Date: "01.03.22", Amount: data.Amount{Base: 30}},
+		data.Contribution{Id: "2", Name: "Paul", Date: "02.03.22", Amount: data.Amount{Base: 20, Fraction: 50}, Anonymous: true},
+	)
+	notifier := testNotifier(server.URL, &FakeStore{pools: []data.NotifiedPool{pool}}, mailer)
+
+	if err := notifier.Contribution("mom", "2"); err != nil {
+		t.Fatalf("Contribution returned error %v", err)
+	}
+	expectedMail := sentMail{
+		to:      "owner@example.com",
+		subject: "New contribution to Gift for mom",
+		text:    "Anonymous: 20.50 EUR (02.03.22)\n\nTotal: 50.50 EUR of 50.00 EUR",
+	}
+	if len(mailer.mails) != 2 || mailer.mails[0] != expectedMail {
+		t.Fatalf("expected payment mail %+v, but got %+v", expectedMail, mailer.mails)
+	}
+	if mailer.mails[1].subject != "Gift for mom reached its goal of 50.00 EUR" {
+		t.Fatalf("expected goal mail, but got %+v", mailer.mails[1])
+	}
+
+	expectedWebhook := Message{
+		Type:          MessagePayment,
+		MoneyPool:     "mom",
+		Title:         "Gift for mom",
+		Contributions: []MessageContribution{{Id: "2", Date: "02.03.22", Amount: "20.50 EUR", Anonymous: true}},
+		Total:         "50.50 EUR",
+		Goal:          "50.00 EUR",
+	}
+	var webhook Message
+	if err := json.Unmarshal([]byte((*requests)["/webhook"]), &webhook); err != nil || !reflect.DeepEqual(webhook, expectedWebhook) {
+		t.Fatalf("expected webhook %+v, but got %s", expectedWebhook, (*requests)["/webhook"])
+	}
+	expectedTelegram := `{"chat_id":"42","text":"Gift for mom reached its goal of 50.00 EUR\n\nAnonymous: 20.50 EUR (02.03.22)\n\nTotal: 50.50 EUR of 50.00 EUR"}`
+	if (*requests)["/botsecret/sendMessage"] != expectedTelegram {
+		t.Fatalf("expected telegram message %s, but got %s", expectedTelegram, (*requests)["/botsecret/sendMessage"])
+	}
+	if _, sent := (*requests)["/discord"]; sent {
+		t.Fatalf("sent to the discord channel, which is only notified daily")
+	}
+
+	// the goal was already reached, so only the payment is notified
+	mailer.mails = nil
+	pool.Contributions = append(pool.Contributions, data.Contribution{Id: "3", Name: "Eva", Date: "02.03.22", Amount: data.Amount{Base: 5}})
+	notifier.Store = &FakeStore{pools: []data.NotifiedPool{pool}}
+	if err := notifier.Contribution("mom", "3"); err != nil || len(mailer.mails) != 1 {
+		t.Fatalf("Contribution = %v and sent mails %+v, but expected only the payment mail", err, mailer.mails)
+	}
+}
+
+func TestContributionWithoutNotifications(t *testing.T) {
+	store := &FakeStore{pools: []data.NotifiedPool{{Name: "mom", Contributions: []data.Contribution{{Id: "1"}}}}}
+	notifier := testNotifier("", store, nil)
+	if err := notifier.Contribution("mom", "1"); err != nil {
+		t.Fatalf("Contribution returned error %v", err)
+	}
+	if err := notifier.Contribution("dad", "1"); err != nil {
+		t.Fatalf("Contribution to unknown pool returned error %v", err)
+	}
+}
+
+func TestContributionFailingChannels(t *testing.T) {
+	server, _ := newTestServer(http.StatusInternalServerError)
+	defer server.Close()
+	pool := testPool(server.URL, data.Contribution{Id: "1", Name: "Anna", Date: "01.03.22", Amount: data.Amount{Base: 5}})
+	notifier := testNotifier(server.URL, &FakeStore{pools: []data.NotifiedPool{pool}}, nil)
+
+	expected := errors.New("could not notify 2 of 2 channels, first error: mail channel of moneypool mom: sending mails is not configured")
+	if err := notifier.Contribution("mom", "1"); !compareErrors(err, expected) {
+		t.Fatalf("Contribution = %v, but expected %v", err, expected)
+	}
+	expected = errors.New("transaction 2 not found in moneypool mom")
+	if err := notifier.Contribution("mom", "2"); !compareErrors(err, expected) {
+		t.Fatalf("Contribution = %v, but expected %v", err, expected)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	server, requests := newTestServer(http.StatusOK)
+	defer server.Close()
+	mailer := &FakeMailer{}
+	pool := testPool(server.URL,
+		data.Contribution{Id: "1", Name: "Anna", Date: "01.03.22", Amount: data.Amount{Base: 30}},
+		data.Contribution{Id: "2", Name: "Paul", Date: "02.03.22", Amount: data.Amount{Base: 20}},
+		data.Contribution{Id: "3", Name: "Eva", Date: "02.03.22", Amount: data.Amount{Base: 5}, Voided: true},
+		data.Contribution{Id: "4", Name: "Anna", Date: "02.03.22", Amount: data.Amount{Base: 10}, RefundOf: "1"},
+	)
+	quiet := data.NotifiedPool{Name: "dad", Notifications: pool.Notifications}
+	notifier := testNotifier(server.URL, &FakeStore{pools: []data.NotifiedPool{pool, quiet}}, mailer)
+
+	if err := notifier.Digest(DigestRequest{Date: "2022-03-02"}); err != nil {
+		t.Fatalf("Digest returned error %v", err)
+	}
+	expectedMail := sentMail{
+		to:      "owner@example.com",
+		subject: "2 new contributions to Gift for mom on 2022-03-02",
+		text:    "Paul: 20.00 EUR (02.03.22)\nAnna: -10.00 EUR (02.03.22)\n\nTotal: 40.00 EUR of 50.00 EUR",
+	}
+	if len(mailer.mails) != 1 || mailer.mails[0] != expectedMail {
+		t.Fatalf("expected digest mail %+v, but got %+v", expectedMail, mailer.mails)
+	}
+	expectedDiscord := `{"content":"` + strings.ReplaceAll(expectedMail.subject+"\n\n"+expectedMail.text, "\n", `\n`) + `"}`
+	if (*requests)["/discord"] != expectedDiscord {
+		t.Fatalf("expected discord message %s, but got %s", expectedDiscord, (*requests)["/discord"])
+	}
+	if len(*requests) != 1 {
+		t.Fatalf("expected only the discord channel to get the digest, but got %v", *requests)
+	}
+
+	expected := errors.New(`invalid digest date "yesterday", expected e.g. 2022-03-01`)
+	if err := notifier.Digest(DigestRequest{Date: "yesterday"}); !compareErrors(err, expected) {
+		t.Fatalf("Digest = %v, but expected %v", err, expected)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if text := truncate("Grüße", 5); text != "Grüße" {
+		t.Fatalf("truncate(Grüße, 5) = %s", text)
+	}
+	if text := truncate("Grüße", 4); text != "Grü…" {
+		t.Fatalf("truncate(Grüße, 4) = %s", text)
+	}
+}
+
+func TestMailContent(t *testing.T) {
+	content := string(mailContent("pools@example.com", "owner@example.com", "Neuer Beitrag für Mama", "Anna: 5.00 EUR\n\nTotal: 5.00 EUR"))
+	expected := "From: pools@example.com\r\nTo: owner@example.com\r\nSubject: =?utf-8?q?Neuer_Beitrag_f=C3=BCr_Mama?=\r\n" +
+		"MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n" +
+		"Anna: 5.00 EUR\r\n\r\nTotal: 5.00 EUR\r\n"
+	if content != expected {
+		t.Fatalf("mailContent = %q, but expected %q", content, expected)
+	}
+	mailer := &SMTPMailer{Addr: "localhost:25", Sender: "pools@example.com"}
+	if err := mailer.SendMail("owner@example.com\r\nBcc: other@example.com", "subject", "text"); err == nil {
+		t.Fatalf("SendMail accepted an address with a header")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	mailer := &FileMailer{Dir: t.TempDir(), Sender: "pools@example.com"}
+	if err := mailer.SendMail("anna@example.com", "Danke", "Danke, Anna!"); err != nil {
+		t.Fatalf("SendMail returned error %v", err)
+	}
+	files, err := filepath.Glob(filepath.Join(mailer.Dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("SendMail wrote files %v, %v but expected one mail", files, err)
+	}
+	content, err := ioutil.ReadFile(files[0])
+	if err != nil || !strings.Contains(string(content), "To: anna@example.com\r\n") || !strings.HasSuffix(string(content), "Danke, Anna!\r\n") {
+		t.Fatalf("SendMail wrote %q, %v", content, err)
+	}
+}
+
+// testPool returns the pool mom, whose goal is 50 EUR. Its owner gets mails on payments and goals, the webhook gets
+// payments, the Telegram chat gets goals and the Discord chat gets digests.
+func testPool(serverURL string, contributions ...data.Contribution) data.NotifiedPool {
+	return data.NotifiedPool{
+		Name:  "mom",
+		Title: "Gift for mom",
+		Notifications: data.Notifications{
+			Goal: &data.Amount{Base: 50},
+			Channels: []data.NotificationChannel{
+				{Type: data.ChannelMail, Address: "owner@example.com", On: []string{data.NotifyPayment, data.NotifyGoal, data.NotifyDaily}},
+				{Type: data.ChannelWebhook, URL: serverURL + "/webhook", On: []string{data.NotifyPayment}},
+				{Type: data.ChannelTelegram, BotToken: "secret", ChatId: "42", On: []string{data.NotifyGoal}},
+				{Type: data.ChannelDiscord, URL: serverURL + "/discord", On: []string{data.NotifyDaily}},
+			},
+		},
+		Contributions: contributions,
+	}
+}
+
+func testNotifier(serverURL string, store Store, mailer Mailer) *Notifier {
+	notifier := New(store, mailer)
+	notifier.TelegramURL = serverURL
+	return notifier
+}
+
+// newTestServer answers all requests with the status, and keeps their bodies by path.
+func newTestServer(status int) (*httptest.Server, *map[string]string) {
+	requests := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests[r.URL.Path] = string(body)
+		w.WriteHeader(status)
+	}))
+	return server, &requests
+}
+
+type FakeStore struct {
+	pools []data.NotifiedPool
+}
+
+func (s *FakeStore) GetNotifiedPool(moneyPool string) (*data.NotifiedPool, error) {
+	for _, pool := range s.pools {
+		if pool.Name == moneyPool {
+			return &pool, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *FakeStore) GetNotifiedPools() ([]data.NotifiedPool, error) {
+	return s.pools, nil
+}
+
+type sentMail struct {
+	to, subject, text string
+}
+
+type FakeMailer struct {
+	mails []sentMail
+}
+
+func (m *FakeMailer) SendMail(to, subject, text string) error {
+	m.mails = append(m.mails, sentMail{to, subject, text})
+	return nil
+}
+
+func compareErrors(err1, err2 error) bool {
+	if err1 != nil && err2 != nil {
+		return err1.Error() == err2.Error()
+	}
+	return err1 == err2
+}
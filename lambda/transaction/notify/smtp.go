@@ -0,0 +1,49 @@
+package notify
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// SMTPMailer sends notification mails via an SMTP server, for deployments that don't send mails with SES.
+type SMTPMailer struct {
+	Addr     string // host and port of the server, e.g. smtp.example.com:587
+	Username string // if empty, mails are sent without authentication
+	Password string
+	Sender   string
+}
+
+func (m *SMTPMailer) SendMail(to, subject, text string) error {
+	if strings.ContainsAny(to, "\r\n") {
+		return fmt.Errorf("invalid mail address %q", to)
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return fmt.Errorf("invalid smtp server %s: %v", m.Addr, err)
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	if err := smtp.SendMail(m.Addr, auth, m.Sender, []string{to}, mailContent(m.Sender, to, subject, text)); err != nil {
+		return fmt.Errorf("could not send mail to %s: %v", to, err)
+	}
+	return nil
+}
+
+// mailContent writes the headers and the plain text body of a mail.
+func mailContent(from, to, subject, text string) []byte {
+	headers := []string{
+		"From: " + from,
+		"To: " + to,
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: 8bit",
+	}
+	body := strings.ReplaceAll(text, "\n", "\r\n")
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + body + "\r\n")
+}
@@ -7,24 +7,128 @@ import (
 	"fmt"
 	"github.com/DusanKasan/parsemail"
 	"github.com/ericchiang/css"
-	"github.com/leekchan/accounting"
 	"golang.org/x/net/html"
+	"net/mail"
 	"regexp"
-	"strconv"
 	"strings"
 	"transaction/data"
+	"transaction/money"
+)
+
+var (
+	// transactionCodeLabels precede the PayPal transaction code in the transaction details of a mail.
+	transactionCodeLabels = []string{"Transaktionscode", "Transaction ID"}
+	// originalCodeLabels precede the code of the refunded transaction in refund and reversal mails.
+	originalCodeLabels = []string{"Ursprünglicher Transaktionscode", "Original transaction ID"}
+	// feeLabels precede the fee PayPal deducted from payments for goods and services or from abroad.
+	feeLabels = []string{"Gebühr", "PayPal-Gebühr", "Fee", "PayPal fee"}
+	// netLabels precede the amount received after the fee.
+	netLabels = []string{"Nettobetrag", "Net amount"}
+	// senderAddressLabels precede the sender's mail address in the transaction details.
+	senderAddressLabels = []string{"E-Mail-Adresse des Absenders", "Sender's email"}
 )
 
 type TransactionMailParser struct {
 	NameAmountRegex string
+	// RefundRegex matches the name and amount in refund and reversal mails. It may contain alternatives for both
+	// kinds of mails, each with its own 'name' and 'amount' groups.
+	RefundRegex string
 }
 
-func NewTransactionMailParser(nameAmountRegex string) *TransactionMailParser {
-	return &TransactionMailParser{NameAmountRegex: nameAmountRegex}
+func NewTransactionMailParser(nameAmountRegex, refundRegex string) *TransactionMailParser {
+	return &TransactionMailParser{NameAmountRegex: nameAmountRegex, RefundRegex: refundRegex}
 }
 
 func (p *TransactionMailParser) GetTransactionInfo(email parsemail.Email) (*data.Transaction, error) {
+	rootNode, err := p.parseHtml(email)
+	if err != nil {
+		return nil, err
+	}
+
+	transInfo, err := p.getTransaction(rootNode)
+	if err != nil {
+		return nil, fmt.Errorf("Error while getting parser info %v", err)
+	}
+
+	note, err := p.getNote(rootNode)
+	if err != nil {
+		return nil, fmt.Errorf("Error while getting Note %v", err)
+	}
+
+	transInfo.Note = note
+	transInfo.PaypalId = p.getLabeledValue(rootNode, transactionCodeLabels)
+	p.addFee(rootNode, transInfo)
+	transInfo.SenderAddress = p.getSenderAddress(rootNode)
+	return transInfo, nil
+
+}
+
+// GetRefundInfo reads a refund or reversal mail. The code of the refunded transaction is empty if the mail doesn't
+// contain it.
+func (p *TransactionMailParser) GetRefundInfo(email parsemail.Email) (*data.Refund, error) {
+	if p.RefundRegex == "" {
+		return nil, errors.New("no refund pattern configured")
+	}
+	rootNode, err := p.parseHtml(email)
+	if err != nil {
+		return nil, err
+	}
+
+	name, amount, err := p.findNameAmount(rootNode, p.RefundRegex)
+	if err != nil {
+		return nil, fmt.Errorf("Error while getting refund info %v", err)
+	}
+	return &data.Refund{
+		Name:             name,
+		Base:             amount.Base,
+		Fraction:         amount.Fraction,
+		Decimals:         amount.Decimals,
+		Currency:         amount.currency,
+		PaypalId:         p.getLabeledValue(rootNode, transactionCodeLabels),
+		OriginalPaypalId: p.getLabeledValue(rootNode, originalCodeLabels),
+	}, nil
+}
+
+// addFee adds the fee and net amount, if the mail shows either of them. A fee that can't be read, or doesn't add up with
+// the net amount, is left out, since the transaction's amount is still right.
+func (p *TransactionMailParser) addFee(root *html.Node, transaction *data.Transaction) {
+	gross := transaction.Amount().Scaled()
+	fee, feeErr := p.getLabeledAmount(root, feeLabels, transaction.Currency)
+	net, netErr := p.getLabeledAmount(root, netLabels, transaction.Currency)
+	switch {
+	case feeErr == nil && netErr != nil:
+		net = gross - fee
+	case feeErr != nil && netErr == nil:
+		fee = gross - net
+	case feeErr != nil && netErr != nil:
+		return
+	}
+	if fee < 0 || net < 0 || fee+net != gross {
+		return
+	}
+	precision := money.Precision(transaction.Currency)
+	feeAmount, netAmount := data.ScaledAmount(fee, precision), data.ScaledAmount(net, precision)
+	transaction.Fee, transaction.Net = &feeAmount, &netAmount
+}
+
+// getLabeledAmount reads a labeled amount of the currency in units of its precision, see money.Amount.Scaled. Fees are
+// shown as negative amounts, so the sign is dropped.
+func (p *TransactionMailParser) getLabeledAmount(root *html.Node, labels []string, currency string) (int, error) {
+	text := p.getLabeledValue(root, labels)
+	if text == "" {
+		return 0, errors.New("no labeled amount found")
+	}
+	amount, err := money.ParseIn(text, currency)
+	if err != nil {
+		return 0, err
+	}
+	if scaled := amount.Scaled(); scaled < 0 {
+		return -scaled, nil
+	}
+	return amount.Scaled(), nil
+}
 
+func (p *TransactionMailParser) parseHtml(email parsemail.Email) (*html.Node, error) {
 	decodedHtml, err := b64.StdEncoding.DecodeString(email.HTMLBody)
 	if err != nil {
 		return nil, fmt.Errorf("Error while decoding base64 html %v", err)
@@ -34,20 +138,50 @@ func (p *TransactionMailParser) GetTransactionInfo(email parsemail.Email) (*data
 	if err != nil {
 		return nil, fmt.Errorf("Error while parsing html %v", err)
 	}
+	return rootNode, nil
+}
 
-	transInfo, err := p.getTransaction(rootNode)
+// getSenderAddress returns the sender's mail address shown in the transaction details. It returns an empty string if the
+// mail doesn't show the sender's address. Addresses elsewhere, e.g. in the note, are not the sender's.
+func (p *TransactionMailParser) getSenderAddress(root *html.Node) string {
+	address, err := mail.ParseAddress(p.getLabeledValue(root, senderAddressLabels))
 	if err != nil {
-		return nil, fmt.Errorf("Error while getting parser info %v", err)
+		return ""
 	}
+	return address.Address
+}
 
-	note, err := p.getNote(rootNode)
+// getLabeledValue returns the value of a transaction detail, shown as span with the label, followed by a span with the
+// value. It returns an empty string if the mail doesn't contain any of the labels.
+func (p *TransactionMailParser) getLabeledValue(root *html.Node, labels []string) string {
+	spanSelector, err := css.Parse("td > span")
 	if err != nil {
-		return nil, fmt.Errorf("Error while getting Note %v", err)
+		return ""
 	}
+	spans := spanSelector.Select(root)
+	for i, span := range spans {
+		if i+1 >= len(spans) || spans[i+1].Parent != span.Parent {
+			continue
+		}
+		label := strings.TrimSpace(textContent(span))
+		for _, expected := range labels {
+			if label == expected {
+				return strings.TrimSpace(textContent(spans[i+1]))
+			}
+		}
+	}
+	return ""
+}
 
-	transInfo.Note = note
-	return transInfo, nil
-
+func textContent(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	var text strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		text.WriteString(textContent(child))
+	}
+	return text.String()
 }
 
 func (p *TransactionMailParser) getNote(html *html.Node) (string, error) {
@@ -72,13 +206,35 @@ func (p *TransactionMailParser) getNote(html *html.Node) (string, error) {
 }
 
 func (p *TransactionMailParser) getTransaction(html *html.Node) (info *data.Transaction, err error) {
-	re := regexp.MustCompile(p.NameAmountRegex)
+	name, amount, err := p.findNameAmount(html, p.NameAmountRegex)
+	if err != nil {
+		return nil, err
+	}
+	return &data.Transaction{
+		Name:     name,
+		Base:     amount.Base,
+		Fraction: amount.Fraction,
+		Decimals: amount.Decimals,
+		Currency: amount.currency,
+	}, nil
+}
+
+// parsedAmount is an amount read from a mail, with the ISO code of its currency.
+type parsedAmount struct {
+	data.Amount
+	currency string
+}
+
+// findNameAmount returns the name and amount of the first text matching the pattern. If the pattern has alternatives
+// with groups of the same name, the groups of the matching alternative are used.
+func (p *TransactionMailParser) findNameAmount(html *html.Node, pattern string) (name string, amount parsedAmount, err error) {
+	re := regexp.MustCompile(pattern)
 	allTexts, err := p.getAllSpanTexts(html)
 	if len(allTexts) == 0 {
-		return nil, fmt.Errorf("no span text found")
+		return "", parsedAmount{}, fmt.Errorf("no span text found")
 	}
 	if err != nil {
-		return nil, err
+		return "", parsedAmount{}, err
 	}
 
 	for _, line := range allTexts {
@@ -92,67 +248,35 @@ func (p *TransactionMailParser) getTransaction(html *html.Node) (info *data.Tran
 
 		result := make(map[string]string)
 		for i, name := range re.SubexpNames() {
-			if i != 0 && name != "" {
+			if i != 0 && name != "" && matches[i] != "" {
 				result[name] = matches[i]
 			}
 		}
 
-		name := result["name"]
 		amountText := result["amount"]
 
-		base, fraction, err := p.parseAmountText(amountText)
+		amount, err := p.parseAmountText(amountText)
 		if err != nil {
 			fmt.Println(err)
 			continue
 		}
 
-		return &data.Transaction{
-			Name:     name,
-			Base:     base,
-			Fraction: fraction,
-		}, nil
+		return result["name"], amount, nil
 	}
-	return nil, errors.New("no text in html matched parser pattern")
+	return "", parsedAmount{}, errors.New("no text in html matched parser pattern")
 }
 
-func (p *TransactionMailParser) parseAmountText(amountText string) (base, fraction int, err error) {
-	numReg, err := regexp.Compile("[^0-9,\\.]+")
+// parseAmountText reads an amount like "1.234,56 € EUR" and returns it with the ISO code of its currency. Amounts of
+// currencies with three decimals keep them.
+func (p *TransactionMailParser) parseAmountText(amountText string) (parsedAmount, error) {
+	amount, err := money.Parse(amountText)
 	if err != nil {
-		return
-	}
-	numberText := numReg.ReplaceAllString(amountText, "")
-	if len(strings.TrimSpace(numberText)) == 0 {
-		return 0, 0, fmt.Errorf("no amount found in amount text %s", amountText)
-	}
-	if !strings.ContainsAny(numberText, "0123456789") {
-		return 0, 0, fmt.Errorf("no numbers found in number text %s", numberText)
+		return parsedAmount{}, err
 	}
-	curReg, err := regexp.Compile("[^a-zA-Z]+")
-	if err != nil {
-		return
-	}
-	currency := curReg.ReplaceAllString(amountText, "")
-
-	// the call to accounting.UnformatNumber panics if it cannot extract
-	// valid locale information based on the given currency string.
-	// we don't want panics, so we check the locale beforehand and return an error if necessary
-	if _, localeExists := accounting.LocaleInfo[currency]; !localeExists {
-		return 0, 0, fmt.Errorf("could not parse locale information from currency %s", currency)
-	}
-
-	amount := accounting.UnformatNumber(numberText, 2, currency)
-	amountItems := strings.Split(amount, ".")
-	baseString := strings.Replace(amountItems[0], ",", "", -1)
-	fractionString := amountItems[1]
-	base, err = strconv.Atoi(baseString)
-	if err != nil {
-		return 0, 0, fmt.Errorf("could not parse base string '%s' to int", baseString)
-	}
-	fraction, err = strconv.Atoi(fractionString)
-	if err != nil {
-		return 0, 0, fmt.Errorf("could not parse fraction string '%s' to int", fractionString)
+	if amount.Minor < 0 {
+		return parsedAmount{}, fmt.Errorf("negative amount in amount text %s", amountText)
 	}
-	return
+	return parsedAmount{Amount: data.ScaledAmount(amount.Scaled(), money.Precision(amount.Currency)), currency: amount.Currency}, nil
 }
 
 func (p *TransactionMailParser) getAllSpanTexts(node *html.Node) ([]string, error) {
@@ -7,12 +7,16 @@ import (
 	"github.com/DusanKasan/parsemail"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"testing"
 	"text/template"
 	"transaction/data"
 )
 
-const nameAmountRegex = "(?P<name>(.+)) hat Ihnen (?P<amount>(.+)) gesendet"
+const (
+	nameAmountRegex = "(?P<name>(.+)) hat Ihnen (?P<amount>(.+)) gesendet"
+	refundRegex     = "Sie haben (?P<amount>(.+)) an (?P<name>(.+)) zurückerstattet|Die Zahlung von (?P<name>(.+)) über (?P<amount>(.+)) wurde rückgängig gemacht"
+)
 
 var mailTemplate *template.Template
 
@@ -65,7 +69,7 @@ func TestGetTransactionInfoNames(t *testing.T) {
 		},
 	}
 	for _, test := range testTable {
-		parser := NewTransactionMailParser(nameAmountRegex)
+		parser := NewTransactionMailParser(nameAmountRegex, refundRegex)
 		output, err := parser.GetTransactionInfo(test.inputMail)
 		if !compareErrors(err, test.expectError) {
 			t.Fatalf("GetTransactionInfo(%s) returned error %v, but should return with error %v", test.name, err, test.expectError)
@@ -84,61 +88,67 @@ func TestGetTransactionInfoAmounts(t *testing.T) {
 		{
 			"valid_amount_1,99EUR",
 			getEmail(mailTemplate, "tests/amount/valid_amount_1,99EUR.html", true),
-			&data.Transaction{Base: 1, Fraction: 99},
+			&data.Transaction{Base: 1, Fraction: 99, Currency: "EUR"},
 			nil,
 		},
 		{
 			"valid_amount_00030,00EUR",
 			getEmail(mailTemplate, "tests/amount/valid_amount_00030,00EUR.html", true),
-			&data.Transaction{Base: 30, Fraction: 00},
+			&data.Transaction{Base: 30, Fraction: 00, Currency: "EUR"},
 			nil,
 		},
 		{
 			"valid_amount_1.234,56EUR",
 			getEmail(mailTemplate, "tests/amount/valid_amount_1.234,56EUR.html", true),
-			&data.Transaction{Base: 1234, Fraction: 56},
+			&data.Transaction{Base: 1234, Fraction: 56, Currency: "EUR"},
 			nil,
 		},
 		{
 			"valid_amount_1234,56EUR",
 			getEmail(mailTemplate, "tests/amount/valid_amount_1234,56EUR.html", true),
-			&data.Transaction{Base: 1234, Fraction: 56},
+			&data.Transaction{Base: 1234, Fraction: 56, Currency: "EUR"},
 			nil,
 		},
 		{
 			"valid_amount_20EUR",
 			getEmail(mailTemplate, "tests/amount/valid_amount_20EUR.html", true),
-			&data.Transaction{Base: 20, Fraction: 00},
+			&data.Transaction{Base: 20, Fraction: 00, Currency: "EUR"},
 			nil,
 		},
 		{
 			"valid_amount_whitespace",
 			getEmail(mailTemplate, "tests/amount/valid_amount_whitespace.html", true),
-			&data.Transaction{Base: 12, Fraction: 34},
+			&data.Transaction{Base: 12, Fraction: 34, Currency: "EUR"},
 			nil,
 		},
 		{
 			"valid_amount_2.99USD",
 			getEmail(mailTemplate, "tests/amount/valid_amount_2.99USD.html", true),
-			&data.Transaction{Base: 2, Fraction: 99},
+			&data.Transaction{Base: 2, Fraction: 99, Currency: "USD"},
 			nil,
 		},
 		{
 			"valid_amount_12,345.67USD",
 			getEmail(mailTemplate, "tests/amount/valid_amount_12,345.67USD.html", true),
-			&data.Transaction{Base: 12345, Fraction: 67},
+			&data.Transaction{Base: 12345, Fraction: 67, Currency: "USD"},
 			nil,
 		},
 		{
 			"valid_amount_1.00002USD",
 			getEmail(mailTemplate, "tests/amount/valid_amount_1.00002USD.html", true),
-			&data.Transaction{Base: 1, Fraction: 0},
+			&data.Transaction{Base: 1, Fraction: 0, Currency: "USD"},
+			nil,
+		},
+		{
+			"valid_amount_1.250KWD",
+			getEmail(mailTemplate, "tests/amount/valid_amount_1.250KWD.html", true),
+			&data.Transaction{Base: 1, Fraction: 250, Decimals: 3, Currency: "KWD"},
 			nil,
 		},
 	}
 
 	for _, test := range testTable {
-		parser := NewTransactionMailParser(nameAmountRegex)
+		parser := NewTransactionMailParser(nameAmountRegex, refundRegex)
 		output, err := parser.GetTransactionInfo(test.inputMail)
 		if !compareErrors(err, test.expectError) {
 			t.Fatalf("GetTransactionInfo(%s) returned error %v, but should return with error %v", test.name, err, test.expectError)
@@ -146,8 +156,8 @@ func TestGetTransactionInfoAmounts(t *testing.T) {
 		if test.expectedOut == nil {
 			return
 		}
-		if output.Base != test.expectedOut.Base || output.Fraction != test.expectedOut.Fraction {
-			t.Fatalf("GetTransactionInfo(%s) returned amount (%v,%v), but should return (%v,%v)", test.name, output.Base, output.Fraction, test.expectedOut.Base, test.expectedOut.Fraction)
+		if output.Amount() != test.expectedOut.Amount() || output.Currency != test.expectedOut.Currency {
+			t.Fatalf("GetTransactionInfo(%s) returned amount (%+v %v), but should return (%+v %v)", test.name, output.Amount(), output.Currency, test.expectedOut.Amount(), test.expectedOut.Currency)
 		}
 	}
 }
@@ -168,7 +178,7 @@ func TestGetTransactionInfoNotes(t *testing.T) {
 		},
 	}
 	for _, test := range testTable {
-		parser := NewTransactionMailParser(nameAmountRegex)
+		parser := NewTransactionMailParser(nameAmountRegex, refundRegex)
 		output, err := parser.GetTransactionInfo(test.inputMail)
 		if !compareErrors(err, test.expectError) {
 			t.Fatalf("GetTransactionInfo(%s) returned error %v, but should return with error %v", test.name, err, test.expectError)
@@ -234,7 +244,7 @@ func TestGetTransactionInfoInvalid(t *testing.T) {
 		},
 	}
 	for _, test := range testTable {
-		parser := NewTransactionMailParser(nameAmountRegex)
+		parser := NewTransactionMailParser(nameAmountRegex, refundRegex)
 		output, err := parser.GetTransactionInfo(test.inputMail)
 		if !compareErrors(err, test.expectError) || output != nil {
 			t.Fatalf("GetTransactionInfo(%s) returned %v, %v but should return %v, %v", test.name, output, err, nil, test.expectError)
@@ -242,6 +252,89 @@ func TestGetTransactionInfoInvalid(t *testing.T) {
 	}
 }
 
+func TestGetTransactionInfoPaypalId(t *testing.T) {
+	parser := NewTransactionMailParser(nameAmountRegex, refundRegex)
+	output, err := parser.GetTransactionInfo(getEmail(mailTemplate, "tests/name/valid_name_two_words.html", true))
+	if err != nil || output.PaypalId != "3K6613774G352493Y" {
+		t.Fatalf("GetTransactionInfo(paypal_id) returned %+v, %v but should return paypal id 3K6613774G352493Y", output, err)
+	}
+}
+
+func TestGetTransactionInfoSenderAddress(t *testing.T) {
+	testTable := map[string]string{
+		"tests/sender/sender_address.html":          "sender.person@example.com",
+		"tests/sender/sender_and_note_address.html": "sender.person@example.com",
+		"tests/sender/note_address.html":            "",
+		"tests/name/valid_name_two_words.html":      "",
+	}
+	for file, expected := range testTable {
+		parser := NewTransactionMailParser(nameAmountRegex, refundRegex)
+		output, err := parser.GetTransactionInfo(getEmail(mailTemplate, file, true))
+		if err != nil || output.SenderAddress != expected {
+			t.Fatalf("GetTransactionInfo(%s) returned %+v, %v but should return sender address %q", file, output, err, expected)
+		}
+	}
+}
+
+type feeTest struct {
+	file        string
+	expectedFee *data.Amount
+	expectedNet *data.Amount
+}
+
+func TestGetTransactionInfoFee(t *testing.T) {
+	testTable := []feeTest{
+		{"tests/fee/fee_net.html", &data.Amount{Base: 0, Fraction: 65}, &data.Amount{Base: 10, Fraction: 34}},
+		{"tests/fee/fee_only.html", &data.Amount{Base: 0, Fraction: 65}, &data.Amount{Base: 10, Fraction: 34}},
+		{"tests/fee/mismatch.html", nil, nil},
+		{"tests/name/valid_name_two_words.html", nil, nil},
+	}
+	for _, test := range testTable {
+		parser := NewTransactionMailParser(nameAmountRegex, refundRegex)
+		output, err := parser.GetTransactionInfo(getEmail(mailTemplate, test.file, true))
+		if err != nil || !reflect.DeepEqual(output.Fee, test.expectedFee) || !reflect.DeepEqual(output.Net, test.expectedNet) {
+			t.Fatalf("GetTransactionInfo(%s) returned %+v, %v but should return fee %v and net %v", test.file, output, err, test.expectedFee, test.expectedNet)
+		}
+	}
+}
+
+type RefundTest struct {
+	name        string
+	inputMail   parsemail.Email
+	expectedOut *data.Refund
+	expectError error
+}
+
+func TestGetRefundInfo(t *testing.T) {
+	testTable := []RefundTest{
+		{
+			"refund",
+			getEmail(mailTemplate, "tests/refund/refund.html", true),
+			&data.Refund{Name: "Sender Person", Base: 10, Fraction: 99, PaypalId: "8RS1234567890123T", OriginalPaypalId: "3K6613774G352493Y", Currency: "EUR"},
+			nil,
+		},
+		{
+			"reversal",
+			getEmail(mailTemplate, "tests/refund/reversal.html", true),
+			&data.Refund{Name: "Sender Person", Base: 10, Fraction: 99, PaypalId: "5XY1234567890123Z", Currency: "EUR"},
+			nil,
+		},
+		{
+			"payment_mail",
+			getEmail(mailTemplate, "tests/name/valid_name_two_words.html", true),
+			nil,
+			errors.New("Error while getting refund info no text in html matched parser pattern"),
+		},
+	}
+	for _, test := range testTable {
+		parser := NewTransactionMailParser(nameAmountRegex, refundRegex)
+		output, err := parser.GetRefundInfo(test.inputMail)
+		if !compareErrors(err, test.expectError) || !reflect.DeepEqual(output, test.expectedOut) {
+			t.Fatalf("GetRefundInfo(%s) returned %+v, %v but should return %+v, %v", test.name, output, err, test.expectedOut, test.expectError)
+		}
+	}
+}
+
 func compareErrors(err1, err2 error) bool {
 	if err1 != nil && err2 != nil {
 		return err1.Error() == err2.Error()
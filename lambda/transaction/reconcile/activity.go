@@ -0,0 +1,140 @@
+// Package reconcile compares PayPal's activity report with the stored transactions, to find payments that were missed
+// because their mail was lost or could not be parsed.
+package reconcile
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"transaction/data"
+	"transaction/money"
+)
+
+// ActivityRow is a completed payment received via PayPal, as listed in the activity report.
+type ActivityRow struct {
+	Line     int // record of the report, counting the header as record 1
+	PaypalId string
+	Date     time.Time
+	Name     string
+	Cents    int
+	Currency string
+	Note     string
+}
+
+// activityColumns maps the columns read from the report to their names in the English and German report.
+var activityColumns = map[string][]string{
+	"id":       {"transaction id", "transaktionscode"},
+	"date":     {"date", "datum"},
+	"name":     {"name"},
+	"gross":    {"gross", "brutto"},
+	"currency": {"currency", "währung"},
+	"note":     {"note", "hinweis"},
+	"status":   {"status"},
+}
+
+var requiredActivityColumns = []string{"date", "name", "gross"}
+
+// completedStatuses are the statuses of payments that were received. Pending or denied payments are skipped.
+var completedStatuses = map[string]bool{"": true, "completed": true, "abgeschlossen": true}
+
+// activityDateFormats are the date formats of the English, German and ISO reports.
+var activityDateFormats = []string{"01/02/2006", "02.01.2006", "2006-01-02"}
+
+// byteOrderMark starts the reports downloaded from PayPal.
+const byteOrderMark = "\uFEFF"
+
+// ParseActivity reads PayPal's activity report as downloaded as csv. It returns the received payments, and skips
+// outgoing payments, fees, refunds and payments that are not completed.
+func ParseActivity(reader io.Reader) (rows []ActivityRow, skipped int, err error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	records := csv.NewReader(strings.NewReader(strings.TrimPrefix(string(content), byteOrderMark)))
+	records.FieldsPerRecord = -1
+	records.TrimLeadingSpace = true
+	header, err := records.Read()
+	if err == io.EOF {
+		return nil, 0, fmt.Errorf("activity report is empty")
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid activity report header: %v", err)
+	}
+	columns := map[string]int{}
+	for index, name := range header {
+		name = strings.ToLower(strings.Trim(name, " \""))
+		for column, names := range activityColumns {
+			for _, alias := range names {
+				if name == alias {
+					columns[column] = index
+				}
+			}
+		}
+	}
+	for _, column := range requiredActivityColumns {
+		if _, exists := columns[column]; !exists {
+			return nil, 0, fmt.Errorf("activity report misses column %s", activityColumns[column][0])
+		}
+	}
+	value := func(record []string, column string) string {
+		if index, exists := columns[column]; exists && index < len(record) {
+			return strings.TrimSpace(record[index])
+		}
+		return ""
+	}
+
+	for line := 2; ; line++ {
+		record, err := records.Read()
+		if err == io.EOF {
+			return rows, skipped, nil
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid activity report: %v", err)
+		}
+		if len(strings.TrimSpace(strings.Join(record, ""))) == 0 {
+			continue
+		}
+		currency := strings.ToUpper(value(record, "currency"))
+		cents, err := parseGross(value(record, "gross"), data.CurrencyOrDefault(currency))
+		if err != nil {
+			return nil, 0, fmt.Errorf("line %d: %v", line, err)
+		}
+		if cents <= 0 || !completedStatuses[strings.ToLower(value(record, "status"))] {
+			skipped++
+			continue
+		}
+		date, err := parseActivityDate(value(record, "date"))
+		if err != nil {
+			return nil, 0, fmt.Errorf("line %d: %v", line, err)
+		}
+		rows = append(rows, ActivityRow{
+			Line:     line,
+			PaypalId: value(record, "id"),
+			Date:     date,
+			Name:     value(record, "name"),
+			Cents:    cents,
+			Currency: currency,
+			Note:     value(record, "note"),
+		})
+	}
+}
+
+// parseGross returns the amount of the currency in cents.
+func parseGross(text, currency string) (int, error) {
+	amount, err := money.ParseIn(text, currency)
+	if err != nil {
+		return 0, fmt.Errorf("invalid gross amount: %v", err)
+	}
+	return amount.Cents(), nil
+}
+
+func parseActivityDate(text string) (time.Time, error) {
+	for _, format := range activityDateFormats {
+		if date, err := time.Parse(format, text); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", text)
+}
@@ -0,0 +1,200 @@
+package reconcile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"transaction/data"
+	"transaction/importer"
+)
+
+// storedDateFormats are the formats of transaction dates, as written by the transaction lambda or by hand.
+var storedDateFormats = []string{"02.01.06", "02.01.2006", "2006-01-02"}
+
+// tenantSeparator joins a tenant's name and a pool's name to the key of the tenant's pool.
+const tenantSeparator = "."
+
+// dateTolerance allows stored dates to differ from PayPal's, since mails are dated when they are received, in UTC.
+const dateTolerance = 24 * time.Hour
+
+// Report is the result of comparing an activity report with the stored transactions.
+type Report struct {
+	Matched int
+	// MissingFromPools are payments received via PayPal that are not stored in any pool.
+	MissingFromPools []MissingRow
+	// MissingFromPayPal are stored contributions from payment mails within the report's period, that PayPal doesn't list.
+	MissingFromPayPal []PoolContribution
+	// AmountMismatches are payments whose stored amount or currency differs from PayPal's.
+	AmountMismatches []Mismatch
+}
+
+// MissingRow is a payment missing from the pools, with the pool its note names. Pool is empty if the note names no pool
+// or several pools, which Problem explains.
+type MissingRow struct {
+	ActivityRow
+	Pool    string
+	Problem string
+}
+
+type PoolContribution struct {
+	Pool string
+	data.Contribution
+}
+
+type Mismatch struct {
+	Row    ActivityRow
+	Stored PoolContribution
+}
+
+// Reconcile matches each payment of the activity report to a stored contribution of the given pools: by PayPal's
+// transaction code, or else by name, amount and date. Voided contributions are matched too, since they were received,
+// but refunds are not.
+func Reconcile(rows []ActivityRow, pools []data.StoredPool) Report {
+	var candidates []PoolContribution
+	for _, pool := range pools {
+		for _, contribution := range pool.Contributions {
+			if contribution.RefundOf == "" {
+				candidates = append(candidates, PoolContribution{Pool: pool.Name, Contribution: contribution})
+			}
+		}
+	}
+	used := make([]bool, len(candidates))
+	matches := make([]int, len(rows))
+	for i := range matches {
+		matches[i] = -1
+	}
+
+	for i, row := range rows {
+		if row.PaypalId == "" {
+			continue
+		}
+		for j, candidate := range candidates {
+			if !used[j] && candidate.PaypalId == row.PaypalId {
+				used[j], matches[i] = true, j
+				break
+			}
+		}
+	}
+	for i, row := range rows {
+		if matches[i] >= 0 {
+			continue
+		}
+		for j, candidate := range candidates {
+			if !used[j] && candidate.PaypalId == "" && sameContribution(row, candidate.Contribution) {
+				used[j], matches[i] = true, j
+				break
+			}
+		}
+	}
+
+	report := Report{}
+	for i, row := range rows {
+		if matches[i] < 0 {
+			pool, problem := poolOfNote(pools, row.Note)
+			report.MissingFromPools = append(report.MissingFromPools, MissingRow{ActivityRow: row, Pool: pool, Problem: problem})
+			continue
+		}
+		report.Matched++
+		stored := candidates[matches[i]]
+		if !sameAmount(row, stored.Contribution) {
+			report.AmountMismatches = append(report.AmountMismatches, Mismatch{Row: row, Stored: stored})
+		}
+	}
+
+	from, to, ok := period(rows)
+	for j, candidate := range candidates {
+		if used[j] || !ok || candidate.Voided || candidate.Source != "" {
+			continue
+		}
+		if date, parsed := storedDate(candidate.Date); parsed && !date.Before(from) && !date.After(to) {
+			report.MissingFromPayPal = append(report.MissingFromPayPal, candidate)
+		}
+	}
+	return report
+}
+
+// ImportRows returns the payments missing from the pools as rows to import, by pool. Payments whose pool is unknown are
+// left out.
+func (r Report) ImportRows() map[string][]importer.Row {
+	rows := map[string][]importer.Row{}
+	for _, missing := range r.MissingFromPools {
+		if missing.Pool == "" {
+			continue
+		}
+		rows[missing.Pool] = append(rows[missing.Pool], importer.Row{
+			Name:     missing.Name,
+			Amount:   fmt.Sprintf("%d.%02d", missing.Cents/100, missing.Cents%100),
+			Currency: missing.Currency,
+			Date:     missing.Date.Format("2006-01-02"),
+			Note:     missing.Note,
+			PaypalId: missing.PaypalId,
+		})
+	}
+	return rows
+}
+
+func sameContribution(row ActivityRow, contribution data.Contribution) bool {
+	date, parsed := storedDate(contribution.Date)
+	return parsed && strings.EqualFold(strings.TrimSpace(row.Name), strings.TrimSpace(contribution.Name)) &&
+		sameAmount(row, contribution) && absDuration(date.Sub(row.Date)) <= dateTolerance
+}
+
+func sameAmount(row ActivityRow, contribution data.Contribution) bool {
+	return contribution.Amount.Cents() == row.Cents &&
+		data.CurrencyOrDefault(row.Currency) == data.CurrencyOrDefault(contribution.Currency)
+}
+
+// poolOfNote finds the pool whose name the note starts with, like the pool of a payment mail is found. Names of tenants'
+// pools are compared without the tenant.
+func poolOfNote(pools []data.StoredPool, note string) (pool, problem string) {
+	var found []string
+	for _, storedPool := range pools {
+		name := storedPool.Name
+		if storedPool.Tenant != "" {
+			name = strings.TrimPrefix(name, storedPool.Tenant+tenantSeparator)
+		}
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(note)), strings.ToLower(name)) {
+			found = append(found, storedPool.Name)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", "note names no pool"
+	case 1:
+		return found[0], ""
+	default:
+		sort.Strings(found)
+		return "", fmt.Sprintf("note names several pools: %s", strings.Join(found, ", "))
+	}
+}
+
+// period returns the first and last day of the report, widened by the date tolerance.
+func period(rows []ActivityRow) (from, to time.Time, ok bool) {
+	for _, row := range rows {
+		if !ok || row.Date.Before(from) {
+			from = row.Date
+		}
+		if !ok || row.Date.After(to) {
+			to = row.Date
+		}
+		ok = true
+	}
+	return from.Add(-dateTolerance), to.Add(dateTolerance), ok
+}
+
+func storedDate(text string) (time.Time, bool) {
+	for _, format := range storedDateFormats {
+		if date, err := time.Parse(format, text); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func absDuration(duration time.Duration) time.Duration {
+	if duration < 0 {
+		return -duration
+	}
+	return duration
+}
@@ -0,0 +1,130 @@
+package reconcile
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"transaction/data"
+	"transaction/importer"
+)
+
+const englishReport = "\uFEFF\"Date\",\"Time\",\"TimeZone\",\"Name\",\"Type\",\"Status\",\"Currency\",\"Gross\",\"Fee\",\"Net\",\"Transaction ID\",\"Note\"\n" +
+	"\"03/01/2022\",\"10:00:00\",\"CET\",\"Anna Smith\",\"Mobile Payment\",\"Completed\",\"EUR\",\"1,012.50\",\"0.00\",\"1,012.50\",\"PP-1\",\"mom #anon\"\n" +
+	"\"03/01/2022\",\"11:00:00\",\"CET\",\"Bank\",\"General Withdrawal\",\"Completed\",\"EUR\",\"-20.00\",\"0.00\",\"-20.00\",\"PP-2\",\"\"\n" +
+	"\"03/02/2022\",\"12:00:00\",\"CET\",\"Paul\",\"Mobile Payment\",\"Pending\",\"EUR\",\"5.00\",\"0.00\",\"5.00\",\"PP-3\",\"mom\"\n"
+
+const germanReport = "\"Datum\",\"Uhrzeit\",\"Name\",\"Status\",\"Währung\",\"Brutto\",\"Transaktionscode\",\"Hinweis\"\n" +
+	"\"01.03.2022\",\"10:00:00\",\"Anna Smith\",\"Abgeschlossen\",\"EUR\",\"1.012,50\",\"PP-1\",\"mom #anon\"\n"
+
+func TestParseActivity(t *testing.T) {
+	expected := []ActivityRow{{
+		Line:     2,
+		PaypalId: "PP-1",
+		Date:     time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC),
+		Name:     "Anna Smith",
+		Cents:    101250,
+		Currency: "EUR",
+		Note:     "mom #anon",
+	}}
+	for name, report := range map[string]string{"english": englishReport, "german": germanReport} {
+		rows, _, err := ParseActivity(strings.NewReader(report))
+		if err != nil || !reflect.DeepEqual(rows, expected) {
+			t.Fatalf("ParseActivity(%s) = %+v, %v but expected %+v", name, rows, err, expected)
+		}
+	}
+	if _, skipped, _ := ParseActivity(strings.NewReader(englishReport)); skipped != 2 {
+		t.Fatalf("ParseActivity skipped %d rows, but expected the withdrawal and the pending payment", skipped)
+	}
+
+	_, _, err := ParseActivity(strings.NewReader("\"Date\",\"Name\"\n"))
+	expectedErr := errors.New("activity report misses column gross")
+	if err == nil || err.Error() != expectedErr.Error() {
+		t.Fatalf("ParseActivity(missing_column) = %v, but expected %v", err, expectedErr)
+	}
+}
+
+type parseGrossTest struct {
+	text     string
+	expected int
+}
+
+func TestParseGross(t *testing.T) {
+	testTable := []parseGrossTest{
+		{"12.50", 1250},
+		{"12,50", 1250},
+		{"1,234.56", 123456},
+		{"1.234,56", 123456},
+		{"-5,00", -500},
+		{"1234", 123400},
+		{"1 234,56", 123456},
+	}
+	for _, test := range testTable {
+		if cents, err := parseGross(test.text, "EUR"); err != nil || cents != test.expected {
+			t.Fatalf("parseGross(%s) = %d, %v but expected %d", test.text, cents, err, test.expected)
+		}
+	}
+	if _, err := parseGross("12.5.0", "EUR"); err == nil {
+		t.Fatalf("parseGross(12.5.0) returned no error")
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2022, 3, d, 0, 0, 0, 0, time.UTC) }
+	rows := []ActivityRow{
+		{Line: 2, PaypalId: "PP-1", Date: day(1), Name: "Anna", Cents: 1000, Note: "mom"},
+		{Line: 3, PaypalId: "PP-2", Date: day(2), Name: "Paul", Cents: 500, Note: "mom"},
+		{Line: 4, PaypalId: "PP-3", Date: day(3), Name: "Otto", Cents: 700, Note: "dad"},
+		{Line: 5, PaypalId: "PP-4", Date: day(4), Name: "Eve", Cents: 300, Note: "Mom and dad"},
+		{Line: 6, PaypalId: "PP-5", Date: day(4), Name: "Carl", Cents: 300, Currency: "EUR", Note: "mom #anon"},
+		{Line: 7, PaypalId: "PP-6", Date: day(4), Name: "Zoe", Cents: 200, Currency: "USD", Note: "mom"},
+	}
+	pools := []data.StoredPool{
+		{Name: "mom", Contributions: []data.Contribution{
+			// matched by id, but stored with a different amount
+			{Id: "1", Name: "Anna", Date: "01.03.22", Amount: data.Amount{Base: 12}, PaypalId: "PP-1"},
+			// matched by name, amount and date, a day later
+			{Id: "2", Name: "paul ", Date: "03.03.22", Amount: data.Amount{Base: 5}},
+			// refunds are never matched
+			{Id: "3", Name: "Carl", Date: "04.03.22", Amount: data.Amount{Base: 3}, RefundOf: "x"},
+			// matched by id, but stored in another currency
+			{Id: "8", Name: "Zoe", Date: "04.03.22", Amount: data.Amount{Base: 2}, PaypalId: "PP-6"},
+			// missing from PayPal
+			{Id: "4", Name: "Lost", Date: "02.03.22", Amount: data.Amount{Base: 1}},
+			// outside of the report's period, imported or voided contributions are not expected in the report
+			{Id: "5", Name: "Old", Date: "01.02.22", Amount: data.Amount{Base: 1}},
+			{Id: "6", Name: "Cash", Date: "02.03.22", Amount: data.Amount{Base: 1}, Source: importer.SourceManual},
+			{Id: "7", Name: "Twice", Date: "02.03.22", Amount: data.Amount{Base: 1}, Voided: true},
+		}},
+		{Name: "dad", Contributions: []data.Contribution{}},
+		{Name: "mom and dad", Contributions: []data.Contribution{}},
+	}
+
+	report := Reconcile(rows, pools)
+	if report.Matched != 3 {
+		t.Fatalf("Reconcile matched %d rows, but expected 3: %+v", report.Matched, report)
+	}
+	if len(report.AmountMismatches) != 2 || report.AmountMismatches[0].Stored.Id != "1" || report.AmountMismatches[1].Stored.Id != "8" {
+		t.Fatalf("Reconcile returned mismatches %+v, but expected PP-1 and PP-6", report.AmountMismatches)
+	}
+	expectedMissing := []MissingRow{
+		{ActivityRow: rows[2], Pool: "dad"},
+		{ActivityRow: rows[3], Problem: "note names several pools: mom, mom and dad"},
+		{ActivityRow: rows[4], Pool: "mom"},
+	}
+	if !reflect.DeepEqual(report.MissingFromPools, expectedMissing) {
+		t.Fatalf("Reconcile returned missing rows %+v, but expected %+v", report.MissingFromPools, expectedMissing)
+	}
+	if len(report.MissingFromPayPal) != 1 || report.MissingFromPayPal[0].Id != "4" || report.MissingFromPayPal[0].Pool != "mom" {
+		t.Fatalf("Reconcile returned contributions missing from PayPal %+v, but expected only 4", report.MissingFromPayPal)
+	}
+
+	expectedImport := map[string][]importer.Row{
+		"dad": {{Name: "Otto", Amount: "7.00", Date: "2022-03-03", Note: "dad", PaypalId: "PP-3"}},
+		"mom": {{Name: "Carl", Amount: "3.00", Currency: "EUR", Date: "2022-03-04", Note: "mom #anon", PaypalId: "PP-5"}},
+	}
+	if importRows := report.ImportRows(); !reflect.DeepEqual(importRows, expectedImport) {
+		t.Fatalf("ImportRows = %+v, but expected %+v", importRows, expectedImport)
+	}
+}
@@ -0,0 +1,146 @@
+// Package remind reminds the expected participants of open pools about their open balance by mail, if the pool's owner
+// enabled reminders. Reminders are sent by a weekly scheduled request.
+package remind
+
+import (
+	"fmt"
+	"strings"
+	"transaction/data"
+	"transaction/roster"
+)
+
+type Store interface {
+	GetNotifiedPool(moneyPool string) (*data.NotifiedPool, error)
+	GetNotifiedPools() ([]data.NotifiedPool, error)
+}
+
+// Mailer sends a plain text mail.
+type Mailer interface {
+	SendMail(to, subject, text string) error
+}
+
+// Request asks for the reminders of one pool, or of all pools if it names none.
+type Request struct {
+	MoneyPool string `json:"moneyPool"`
+}
+
+type Sender struct {
+	Store  Store
+	Mailer Mailer
+}
+
+func New(store Store, mailer Mailer) *Sender {
+	return &Sender{Store: store, Mailer: mailer}
+}
+
+// Send reminds the participants of the requested pools. Failing mails don't keep the other participants from being
+// reminded.
+func (s *Sender) Send(request Request) error {
+	var pools []data.NotifiedPool
+	if request.MoneyPool != "" {
+		pool, err := s.Store.GetNotifiedPool(request.MoneyPool)
+		if err != nil {
+			return err
+		}
+		if pool == nil {
+			return fmt.Errorf("moneypool %s not found", request.MoneyPool)
+		}
+		pools = append(pools, *pool)
+	} else {
+		var err error
+		if pools, err = s.Store.GetNotifiedPools(); err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	sent := 0
+	for _, pool := range pools {
+		for _, reminder := range Due(pool) {
+			sent++
+			if err := s.Mailer.SendMail(reminder.Participant.Email, reminder.Subject(), reminder.Text()); err != nil {
+				errs = append(errs, fmt.Errorf("moneypool %s: %v", pool.Name, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("could not send %d of %d reminders, first error: %v", len(errs), sent, errs[0])
+	}
+	return nil
+}
+
+// Reminder is the reminder of a participant with an open balance.
+type Reminder struct {
+	Pool        data.NotifiedPool
+	Participant data.Participant
+	Paid        int // cents in the pool's base currency
+}
+
+// Due returns the reminders of the pool's participants with a mail address and an open balance, if the pool is open and
+// enabled reminders. Pools with a suggested amount or without amount only remind participants who haven't paid at all.
+func Due(pool data.NotifiedPool) []Reminder {
+	expected := pool.Expected
+	if !pool.Open || expected == nil || !expected.Reminders {
+		return nil
+	}
+	var reminders []Reminder
+	for _, participant := range expected.Participants {
+		if participant.Email == "" {
+			continue
+		}
+		paid := roster.Paid(pool, participant)
+		fixed := expected.Amount != nil && expected.Mode == data.ModeFixed
+		if (fixed && paid >= expected.Amount.Cents()) || (!fixed && paid > 0) {
+			continue
+		}
+		reminders = append(reminders, Reminder{Pool: pool, Participant: participant, Paid: paid})
+	}
+	return reminders
+}
+
+func (r Reminder) Subject() string {
+	return "Reminder: " + strings.Join(strings.Fields(r.Pool.Title), " ")
+}
+
+func (r Reminder) Text() string {
+	currency := data.CurrencyOrDefault(r.Pool.BaseCurrency)
+	var text strings.Builder
+	fmt.Fprintf(&text, "Hi %s,\n\n", r.Participant.Name)
+	if r.Pool.Expected.Amount == nil {
+		fmt.Fprintf(&text, "you are on the list of %s, but we haven't received your payment yet.\n\n", r.Pool.Title)
+		text.WriteString(payInstructions(r.Pool))
+		return text.String()
+	}
+	expected := r.Pool.Expected.Amount.Cents()
+	if r.Pool.Expected.Mode == data.ModeFixed {
+		fmt.Fprintf(&text, "everyone is asked to pay %s for %s.", formatCents(expected, currency), r.Pool.Title)
+	} else {
+		fmt.Fprintf(&text, "the suggested contribution to %s is %s.", r.Pool.Title, formatCents(expected, currency))
+	}
+	if r.Paid > 0 {
+		fmt.Fprintf(&text, " We received %s from you so far, so %s are still open.", formatCents(r.Paid, currency), formatCents(expected-r.Paid, currency))
+	}
+	text.WriteString("\n\n")
+	text.WriteString(payInstructions(r.Pool))
+	return text.String()
+}
+
+func payInstructions(pool data.NotifiedPool) string {
+	via := "PayPal"
+	if pool.PayURL != "" {
+		via = pool.PayURL
+	}
+	return fmt.Sprintf("Please send it via %s and start your message with '%s'.\n", via, note(pool))
+}
+
+// note returns the name contributors start their message with, which is the pool's key without its tenant.
+func note(pool data.NotifiedPool) string {
+	if pool.Tenant == "" {
+		return pool.Name
+	}
+	return strings.TrimPrefix(pool.Name, pool.Tenant+".")
+}
+
+func formatCents(cents int, currency string) string {
+	return fmt.Sprintf("%d.%02d %s", cents/100, cents%100, currency)
+}
@@ -0,0 +1,110 @@
+package remind
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"transaction/data"
+)
+
+func TestSend(t *testing.T) {
+	pool := testPool(data.ModeFixed)
+	mailer := &FakeMailer{}
+	sender := New(&FakeStore{pools: []data.NotifiedPool{pool}}, mailer)
+
+	if err := sender.Send(Request{}); err != nil {
+		t.Fatalf("Send returned error %v", err)
+	}
+	expected := []sentMail{
+		{"ben@example.com", "Reminder: Concert tickets", "Hi Ben Meyer,\n\neveryone is asked to pay 25.00 EUR for Concert tickets. " +
+			"We received 15.00 EUR from you so far, so 10.00 EUR are still open.\n\n" +
+			"Please send it via https://paypal.me/anna and start your message with 'concert'.\n"},
+		{"carla@example.com", "Reminder: Concert tickets", "Hi Carla Vogel,\n\neveryone is asked to pay 25.00 EUR for Concert tickets.\n\n" +
+			"Please send it via https://paypal.me/anna and start your message with 'concert'.\n"},
+	}
+	if !reflect.DeepEqual(mailer.mails, expected) {
+		t.Fatalf("Send sent mails %q, but expected %q", mailer.mails, expected)
+	}
+}
+
+func TestDue(t *testing.T) {
+	suggested := testPool(data.ModeSuggested)
+	if reminders := Due(suggested); len(reminders) != 1 || reminders[0].Participant.Name != "Carla Vogel" {
+		t.Fatalf("Due(suggested) returned %+v", reminders)
+	}
+	closed := testPool(data.ModeFixed)
+	closed.Open = false
+	if reminders := Due(closed); len(reminders) != 0 {
+		t.Fatalf("Due(closed) returned %+v", reminders)
+	}
+	roster := testPool(data.ModeSuggested)
+	roster.Expected.Amount = nil
+	if reminders := Due(roster); len(reminders) != 1 || !strings.HasPrefix(reminders[0].Text(),
+		"Hi Carla Vogel,\n\nyou are on the list of Concert tickets, but we haven't received your payment yet.") {
+		t.Fatalf("Due(roster) returned %+v", reminders)
+	}
+	disabled := testPool(data.ModeFixed)
+	disabled.Expected.Reminders = false
+	if reminders := Due(disabled); len(reminders) != 0 {
+		t.Fatalf("Due(disabled) returned %+v", reminders)
+	}
+}
+
+// testPool has a participant who paid in full by a linked payment, one who underpaid by a payment matched by name, and
+// one who hasn't paid, besides one without mail address.
+func testPool(mode string) data.NotifiedPool {
+	return data.NotifiedPool{
+		Name:   "tenant.concert",
+		Tenant: "tenant",
+		Title:  "Concert tickets",
+		Open:   true,
+		PayURL: "https://paypal.me/anna",
+		Expected: &data.Expected{
+			Mode:      mode,
+			Amount:    &data.Amount{Base: 25},
+			Reminders: true,
+			Participants: []data.Participant{
+				{Id: "a", Name: "Anna Schmidt", Email: "anna@example.com"},
+				{Id: "b", Name: "Ben Meyer", Email: "ben@example.com"},
+				{Id: "c", Name: "Carla Vogel", Email: "carla@example.com"},
+				{Id: "d", Name: "Dora Klein"},
+			},
+		},
+		Contributions: []data.Contribution{
+			{Id: "1", Name: "A. Schmidt", Amount: data.Amount{Base: 25}, Participant: "a"},
+			{Id: "2", Name: "ben  meyer", Amount: data.Amount{Base: 20}},
+			{Id: "3", Name: "Ben Meyer", Amount: data.Amount{Base: 5}, RefundOf: "2"},
+			{Id: "4", Name: "Carla Vogel", Amount: data.Amount{Base: 25}, Voided: true},
+		},
+	}
+}
+
+type FakeStore struct {
+	pools []data.NotifiedPool
+}
+
+func (s *FakeStore) GetNotifiedPool(moneyPool string) (*data.NotifiedPool, error) {
+	for _, pool := range s.pools {
+		if pool.Name == moneyPool {
+			return &pool, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *FakeStore) GetNotifiedPools() ([]data.NotifiedPool, error) {
+	return s.pools, nil
+}
+
+type sentMail struct {
+	to, subject, text string
+}
+
+type FakeMailer struct {
+	mails []sentMail
+}
+
+func (m *FakeMailer) SendMail(to, subject, text string) error {
+	m.mails = append(m.mails, sentMail{to, subject, text})
+	return nil
+}
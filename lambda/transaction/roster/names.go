@@ -0,0 +1,110 @@
+package roster
+
+import (
+	"sort"
+	"strings"
+)
+
+// This file is the only implementation of name matching. The api lambda links payments that weren't linked when they
+// were stored, so it needs the same rules; it keeps a generated copy of this file, see api/moneypool/match.go.
+
+// Strengths of name matches, from the weakest to the strongest. A name is linked to the participant it matches strongest,
+// but only if no other participant matches as strong.
+const (
+	noMatch = iota
+	initialMatch
+	firstLastMatch
+	fullMatch
+)
+
+// Umlauts are written without diacritics in two ways: spelled out, as in "Mueller", or without their dots, as in
+// "Muller". Names are compared in both spellings, so "Müller" matches both, while letters that are already plain stay
+// unchanged and names like "Michael" and "Samuel" keep their "ae" and "ue".
+var (
+	spelledUmlauts = strings.NewReplacer("ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss")
+	plainUmlauts   = strings.NewReplacer("ä", "a", "ö", "o", "ü", "u", "ß", "ss")
+)
+
+// accents drops the diacritics of other letters, which are written without them in only one way.
+var accents = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "å", "a", "æ", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ø", "o",
+	"ú", "u", "ù", "u", "û", "u",
+	"ç", "c", "ñ", "n", "ý", "y", "ÿ", "y",
+)
+
+// nameStrength returns how strong the sender's name matches a participant's name, in the stronger of both spellings of
+// umlauts.
+func nameStrength(name, sender string) int {
+	strongest := noMatch
+	names, senders := nameSpellings(name), nameSpellings(sender)
+	for i := range names {
+		if strength := wordsStrength(names[i], senders[i]); strength > strongest {
+			strongest = strength
+		}
+	}
+	return strongest
+}
+
+// wordsStrength compares two names' words. Full matches have the same words in any order; first and last name matches
+// leave out middle names; initial matches abbreviate one of the first names, e.g. "A. Schmidt".
+func wordsStrength(name, sender []string) int {
+	if len(name) == 0 {
+		return noMatch
+	}
+	if sameWords(name, sender) {
+		return fullMatch
+	}
+	if len(name) < 2 || len(sender) < 2 {
+		return noMatch
+	}
+	nameFirst, nameLast := name[0], name[len(name)-1]
+	for _, order := range [][2]string{{sender[0], sender[len(sender)-1]}, {sender[len(sender)-1], sender[0]}} {
+		first, last := order[0], order[1]
+		if last != nameLast {
+			continue
+		}
+		if first == nameFirst {
+			return firstLastMatch
+		}
+		if (len(first) == 1 || len(nameFirst) == 1) && first[0] == nameFirst[0] {
+			return initialMatch
+		}
+	}
+	return noMatch
+}
+
+// nameSpellings splits a name into its normalized words, once with umlauts spelled out and once without their dots.
+// Names written as "Last, First" are reordered to "First Last".
+func nameSpellings(name string) [2][]string {
+	name = accents.Replace(strings.ToLower(name))
+	if parts := strings.SplitN(name, ",", 2); len(parts) == 2 {
+		name = parts[1] + " " + parts[0]
+	}
+	return [2][]string{nameWords(spelledUmlauts.Replace(name)), nameWords(plainUmlauts.Replace(name))}
+}
+
+// nameWords splits a normalized name into its words.
+func nameWords(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	})
+}
+
+func sameWords(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]string{}, a...)
+	sortedB := append([]string{}, b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
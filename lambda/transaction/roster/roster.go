@@ -0,0 +1,77 @@
+// Package roster links payments to a pool's expected participants by the sender's PayPal name. Names match despite
+// differences in case, spacing, punctuation, diacritics and word order, missing middle names and abbreviated first
+// names, and participants' aliases match like their names.
+package roster
+
+import (
+	"transaction/data"
+)
+
+// Paid returns the cents the participant paid in the pool's base currency. Contributions count for the participant they
+// were linked to, or else for the participant their sender's name matches. Voided contributions are left out and refunds
+// are subtracted.
+func Paid(pool data.NotifiedPool, participant data.Participant) int {
+	if pool.Expected == nil {
+		return 0
+	}
+	listed := map[string]bool{}
+	for _, other := range pool.Expected.Participants {
+		listed[other.Id] = true
+	}
+	currency := data.CurrencyOrDefault(pool.BaseCurrency)
+	cents := 0
+	for _, contribution := range pool.Contributions {
+		if contribution.Voided || data.CurrencyOrDefault(contribution.Currency) != currency {
+			continue
+		}
+		linked := contribution.Participant == participant.Id
+		if !linked && !listed[contribution.Participant] {
+			match := Match(pool.Expected.Participants, contribution.Name)
+			linked = match != nil && match.Id == participant.Id
+		}
+		if !linked {
+			continue
+		}
+		if contribution.RefundOf != "" {
+			cents -= contribution.Amount.Cents()
+		} else {
+			cents += contribution.Amount.Cents()
+		}
+	}
+	return cents
+}
+
+// Match returns the participant the sender's name belongs to, or nil if the name matches no participant, or several
+// participants equally well.
+func Match(participants []data.Participant, name string) *data.Participant {
+	if len(nameSpellings(name)[0]) == 0 {
+		return nil
+	}
+	var best *data.Participant
+	bestStrength, ambiguous := noMatch, false
+	for i := range participants {
+		strength := participantStrength(participants[i], name)
+		switch {
+		case strength == noMatch || strength < bestStrength:
+		case strength == bestStrength:
+			ambiguous = true
+		default:
+			best, bestStrength, ambiguous = &participants[i], strength, false
+		}
+	}
+	if ambiguous {
+		return nil
+	}
+	return best
+}
+
+// participantStrength returns how strong the sender's name matches the participant's name or one of their aliases.
+func participantStrength(participant data.Participant, sender string) int {
+	strongest := nameStrength(participant.Name, sender)
+	for _, alias := range participant.Aliases {
+		if aliasStrength := nameStrength(alias, sender); aliasStrength > strongest {
+			strongest = aliasStrength
+		}
+	}
+	return strongest
+}
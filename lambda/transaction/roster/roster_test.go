@@ -0,0 +1,84 @@
+package roster
+
+import (
+	"testing"
+	"transaction/data"
+)
+
+var participants = []data.Participant{
+	{Id: "anna", Name: "Anna Schmidt"},
+	{Id: "ben", Name: "Ben Müller", Aliases: []string{"Petra Müller"}},
+	{Id: "carla", Name: "Carla Maria Vogel"},
+	{Id: "lena", Name: "Lena Vogel"},
+	{Id: "leon", Name: "Leon Vogel"},
+	{Id: "michael", Name: "Michael Roth"},
+	{Id: "michal", Name: "Michal Roth"},
+	{Id: "samuel", Name: "Samuel Kraus"},
+}
+
+func TestMatch(t *testing.T) {
+	testTable := map[string]string{
+		"Anna Schmidt":      "anna",
+		"  anna   SCHMIDT ": "anna",
+		"Schmidt, Anna":     "anna",
+		"Schmidt Anna":      "anna",
+		"A. Schmidt":        "anna",
+		"Ben Mueller":       "ben",
+		"Ben Muller":        "ben",
+		"Petra Mueller":     "ben",
+		"Carla Vogel":       "carla",
+		"Carla M. Vogel":    "carla",
+		"L. Vogel":          "", // Lena and Leon
+		"Vogel":             "",
+		"Anna Meyer":        "",
+		"Michael Roth":      "michael",
+		"Michal Roth":       "michal",
+		"Samul Kraus":       "",
+		"Manuel Kraus":      "",
+		"":                  "",
+	}
+	for name, expected := range testTable {
+		participant := Match(participants, name)
+		id := ""
+		if participant != nil {
+			id = participant.Id
+		}
+		if id != expected {
+			t.Fatalf("Match(%q) returned %q, but expected %q", name, id, expected)
+		}
+	}
+}
+
+func TestMatchPrefersStrongerMatch(t *testing.T) {
+	participants := []data.Participant{{Id: "a", Name: "Anna Schmidt"}, {Id: "b", Name: "Alex Schmidt"}}
+	if participant := Match(participants, "Alex Schmidt"); participant == nil || participant.Id != "b" {
+		t.Fatalf("Match returned %+v, but expected participant b", participant)
+	}
+	if participant := Match(participants, "A. Schmidt"); participant != nil {
+		t.Fatalf("Match returned %+v for an ambiguous initial", participant)
+	}
+}
+
+func TestPaid(t *testing.T) {
+	pool := data.NotifiedPool{
+		Expected: &data.Expected{Participants: participants},
+		Contributions: []data.Contribution{
+			{Id: "1", Name: "Someone Else", Amount: data.Amount{Base: 10}, Participant: "anna"},
+			{Id: "2", Name: "Schmidt, Anna", Amount: data.Amount{Base: 5}},
+			{Id: "3", Name: "A. Schmidt", Amount: data.Amount{Base: 2}, RefundOf: "2"},
+			{Id: "4", Name: "Anna Schmidt", Amount: data.Amount{Base: 7}, Voided: true},
+			{Id: "5", Name: "Anna Schmidt", Amount: data.Amount{Base: 3}, Participant: "ben"},
+			{Id: "6", Name: "Anna Schmidt", Amount: data.Amount{Base: 4}, Currency: "USD"},
+		},
+	}
+	testTable := map[string]int{"anna": 1300, "ben": 300, "carla": 0}
+	for _, participant := range participants {
+		expected, tested := testTable[participant.Id]
+		if !tested {
+			continue
+		}
+		if paid := Paid(pool, participant); paid != expected {
+			t.Fatalf("Paid(%s) = %d, but expected %d", participant.Id, paid, expected)
+		}
+	}
+}
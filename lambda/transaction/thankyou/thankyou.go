@@ -0,0 +1,154 @@
+// Package thankyou thanks contributors by mail once their payment is credited, if the pool's owner enabled it. Mails are
+// built from the pool's text/templates, sent at most once per transaction, and rate-limited per recipient and per pool.
+package thankyou
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"transaction/data"
+)
+
+// Default templates of pools that enable thank-you mails without their own.
+const (
+	DefaultSubject  = "Thank you for contributing to {{.Title}}"
+	DefaultTemplate = "Hi {{.Name}},\n\nthank you for your contribution of {{.Amount}} to {{.Title}}! " +
+		"The pool now totals {{.Total}}.\n"
+)
+
+// Rate limits of thank-you mails. Contributors get at most MaxPerRecipient mails a day, and a pool sends at most
+// MaxPerPool mails an hour.
+const (
+	MaxPerRecipient = 3
+	MaxPerPool      = 60
+)
+
+var (
+	// ErrAlreadyThanked is returned for transactions that were thanked before, e.g. when a mail is processed twice.
+	ErrAlreadyThanked = errors.New("transaction was already thanked")
+	// ErrRateLimited is returned if the recipient or the pool reached their rate limit.
+	ErrRateLimited = errors.New("thank-you mails are rate-limited")
+)
+
+// Data is what the templates are executed with. Amounts are decimals followed by their currency, e.g. 12.50 EUR; the
+// total is in the pool's base currency.
+type Data struct {
+	Title  string
+	Name   string
+	Amount string
+	Total  string
+}
+
+type Store interface {
+	GetNotifiedPool(moneyPool string) (*data.NotifiedPool, error)
+}
+
+// Mailer sends a plain text mail.
+type Mailer interface {
+	SendMail(to, subject, text string) error
+}
+
+type Limiter interface {
+	// Claim records that the transaction is thanked. It returns false if the transaction was thanked before.
+	Claim(moneyPool, transactionId string) (bool, error)
+	// Allow counts a mail for the key in the current window. It returns false if the window's limit is reached.
+	Allow(key string, limit int, window time.Duration) (bool, error)
+}
+
+type Sender struct {
+	Store   Store
+	Mailer  Mailer
+	Limiter Limiter
+}
+
+func New(store Store, mailer Mailer, limiter Limiter) *Sender {
+	return &Sender{Store: store, Mailer: mailer, Limiter: limiter}
+}
+
+// Send thanks the contributor of the stored transaction at their address, if the pool enabled thank-you mails. The
+// transaction is claimed before the mail is sent, so a mail that fails to send is not sent again.
+func (s *Sender) Send(moneyPool, transactionId, address string) error {
+	pool, err := s.Store.GetNotifiedPool(moneyPool)
+	if err != nil {
+		return err
+	}
+	if pool == nil || pool.ThankYou == nil || !pool.ThankYou.Enabled {
+		return nil
+	}
+	contribution := pool.Contribution(transactionId)
+	if contribution == nil {
+		return fmt.Errorf("transaction %s not found in moneypool %s", transactionId, moneyPool)
+	}
+	subject, text, err := Render(*pool.ThankYou, Data{
+		Title:  pool.Title,
+		Name:   contribution.Name,
+		Amount: contribution.Amount.String() + " " + data.CurrencyOrDefault(contribution.Currency),
+		Total:  formatCents(pool.Total(), data.CurrencyOrDefault(pool.BaseCurrency)),
+	})
+	if err != nil {
+		return err
+	}
+
+	if claimed, err := s.Limiter.Claim(moneyPool, transactionId); err != nil || !claimed {
+		return firstError(err, ErrAlreadyThanked)
+	}
+	if allowed, err := s.Limiter.Allow("recipient#"+hashAddress(address), MaxPerRecipient, 24*time.Hour); err != nil || !allowed {
+		return firstError(err, ErrRateLimited)
+	}
+	if allowed, err := s.Limiter.Allow("pool#"+moneyPool, MaxPerPool, time.Hour); err != nil || !allowed {
+		return firstError(err, ErrRateLimited)
+	}
+	return s.Mailer.SendMail(address, subject, text)
+}
+
+// Render executes the subject and text templates of the settings, or the default templates if they are empty.
+func Render(settings data.ThankYou, values Data) (subject, text string, err error) {
+	if subject, err = execute("subject", settings.Subject, DefaultSubject, values); err != nil {
+		return "", "", err
+	}
+	if text, err = execute("text", settings.Template, DefaultTemplate, values); err != nil {
+		return "", "", err
+	}
+	// a subject spanning several lines would break the mail's headers
+	return strings.Join(strings.Fields(subject), " "), text, nil
+}
+
+func execute(name, text, defaultText string, values Data) (string, error) {
+	if text == "" {
+		text = defaultText
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid thank-you %s template: %v", name, err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, values); err != nil {
+		return "", fmt.Errorf("could not execute thank-you %s template: %v", name, err)
+	}
+	return out.String(), nil
+}
+
+// hashAddress keeps contributors' addresses out of the rate limit keys.
+func hashAddress(address string) string {
+	hash := sha256.Sum256([]byte(strings.ToLower(address)))
+	return hex.EncodeToString(hash[:])
+}
+
+func formatCents(cents int, currency string) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d %s", sign, cents/100, cents%100, currency)
+}
+
+func firstError(err, otherwise error) error {
+	if err != nil {
+		return err
+	}
+	return otherwise
+}
@@ -0,0 +1,167 @@
+package thankyou
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"transaction/data"
+	"transaction/notify"
+)
+
+func TestSend(t *testing.T) {
+	pool := testPool(&data.ThankYou{Enabled: true, Template: "Danke {{.Name}} für {{.Amount}}! Stand: {{.Total}}"})
+	mailer := &notify.FileMailer{Dir: t.TempDir(), Sender: "pools@example.com"}
+	sender := New(&FakeStore{pools: map[string]data.NotifiedPool{"mom": pool}}, mailer, NewFakeLimiter())
+
+	if err := sender.Send("mom", "2", "anna@example.com"); err != nil {
+		t.Fatalf("Send returned error %v", err)
+	}
+	mails := readMails(t, mailer.Dir)
+	if len(mails) != 1 || !strings.Contains(mails[0], "To: anna@example.com\r\n") ||
+		!strings.Contains(mails[0], "Subject: Thank you for contributing to Gift for mom\r\n") ||
+		!strings.HasSuffix(mails[0], "Danke Anna für 20.00 EUR! Stand: 50.00 EUR\r\n") {
+		t.Fatalf("Send wrote mails %q", mails)
+	}
+	if err := sender.Send("mom", "2", "anna@example.com"); err != ErrAlreadyThanked {
+		t.Fatalf("Send(again) returned error %v, but expected %v", err, ErrAlreadyThanked)
+	}
+	if mails := readMails(t, mailer.Dir); len(mails) != 1 {
+		t.Fatalf("Send(again) sent another mail")
+	}
+}
+
+func TestSendRateLimited(t *testing.T) {
+	var contributions []data.Contribution
+	for i := 0; i < MaxPerRecipient+1; i++ {
+		contributions = append(contributions, data.Contribution{Id: string(rune('a' + i)), Name: "Anna", Amount: data.Amount{Base: 1}})
+	}
+	pool := testPool(&data.ThankYou{Enabled: true})
+	pool.Contributions = contributions
+	mailer := &FakeMailer{}
+	sender := New(&FakeStore{pools: map[string]data.NotifiedPool{"mom": pool}}, mailer, NewFakeLimiter())
+
+	for i, contribution := range contributions {
+		err := sender.Send("mom", contribution.Id, "Anna@Example.com")
+		if i < MaxPerRecipient && err != nil {
+			t.Fatalf("Send(%d) returned error %v", i, err)
+		}
+		if i == MaxPerRecipient && err != ErrRateLimited {
+			t.Fatalf("Send(%d) returned error %v, but expected %v", i, err, ErrRateLimited)
+		}
+	}
+	if len(mailer.sent) != MaxPerRecipient {
+		t.Fatalf("sent %d mails, but expected %d", len(mailer.sent), MaxPerRecipient)
+	}
+}
+
+func TestSendDisabled(t *testing.T) {
+	testTable := map[string]*data.ThankYou{
+		"no_settings": nil,
+		"disabled":    {Enabled: false},
+	}
+	for name, settings := range testTable {
+		mailer := &FakeMailer{}
+		sender := New(&FakeStore{pools: map[string]data.NotifiedPool{"mom": testPool(settings)}}, mailer, NewFakeLimiter())
+		if err := sender.Send("mom", "2", "anna@example.com"); err != nil || len(mailer.sent) != 0 {
+			t.Fatalf("Send(%s) returned error %v and sent %v", name, err, mailer.sent)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	values := Data{Title: "Gift for mom", Name: "Anna", Amount: "20.00 EUR", Total: "50.00 EUR"}
+	subject, text, err := Render(data.ThankYou{Subject: "Danke\r\nBcc: x@example.com {{.Name}}"}, values)
+	if err != nil || subject != "Danke Bcc: x@example.com Anna" ||
+		text != "Hi Anna,\n\nthank you for your contribution of 20.00 EUR to Gift for mom! The pool now totals 50.00 EUR.\n" {
+		t.Fatalf("Render returned %q, %q, %v", subject, text, err)
+	}
+	testTable := map[string]error{
+		"{{.Name":      errors.New("invalid thank-you text template: template: text:1: unclosed action"),
+		"{{.Address}}": errors.New("could not execute thank-you text template: template: text:1:2: executing \"text\" at <.Address>: can't evaluate field Address in type thankyou.Data"),
+	}
+	for template, expected := range testTable {
+		if _, _, err := Render(data.ThankYou{Template: template}, values); err == nil || err.Error() != expected.Error() {
+			t.Fatalf("Render(%s) returned error %v, but expected %v", template, err, expected)
+		}
+	}
+}
+
+// testPool returns the pool mom with contributions of 30 EUR by Ben and 20 EUR by Anna.
+func testPool(settings *data.ThankYou) data.NotifiedPool {
+	return data.NotifiedPool{
+		Name:     "mom",
+		Title:    "Gift for mom",
+		ThankYou: settings,
+		Contributions: []data.Contribution{
+			{Id: "1", Name: "Ben", Amount: data.Amount{Base: 30}},
+			{Id: "2", Name: "Anna", Amount: data.Amount{Base: 20}, Currency: "EUR"},
+		},
+	}
+}
+
+func readMails(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		t.Fatalf("could not list mails: %v", err)
+	}
+	var mails []string
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("could not read mail: %v", err)
+		}
+		mails = append(mails, string(content))
+	}
+	return mails
+}
+
+type FakeStore struct {
+	pools map[string]data.NotifiedPool
+}
+
+func (s *FakeStore) GetNotifiedPool(moneyPool string) (*data.NotifiedPool, error) {
+	pool, exists := s.pools[moneyPool]
+	if !exists {
+		return nil, nil
+	}
+	return &pool, nil
+}
+
+type FakeMailer struct {
+	sent []string
+}
+
+func (m *FakeMailer) SendMail(to, subject, text string) error {
+	m.sent = append(m.sent, to+": "+subject)
+	return nil
+}
+
+// FakeLimiter counts in memory, with a single window for each key.
+type FakeLimiter struct {
+	claimed map[string]bool
+	counts  map[string]int
+}
+
+func NewFakeLimiter() *FakeLimiter {
+	return &FakeLimiter{claimed: map[string]bool{}, counts: map[string]int{}}
+}
+
+func (l *FakeLimiter) Claim(moneyPool, transactionId string) (bool, error) {
+	key := moneyPool + "#" + transactionId
+	if l.claimed[key] {
+		return false, nil
+	}
+	l.claimed[key] = true
+	return true, nil
+}
+
+func (l *FakeLimiter) Allow(key string, limit int, window time.Duration) (bool, error) {
+	if l.counts[key] >= limit {
+		return false, nil
+	}
+	l.counts[key]++
+	return true, nil
+}
@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"transaction/data"
+)
+
+// Transaction is the data of transaction.added events. Amounts are decimals in the transaction's currency. Names of
+// anonymous contributions are left out.
+type Transaction struct {
+	Id        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	Date      string `json:"date"`
+	Amount    string `json:"amount"`
+	Currency  string `json:"currency"`
+	Anonymous bool   `json:"anonymous,omitempty"`
+	RefundOf  string `json:"refundOf,omitempty"` // set for refunds, whose amount is subtracted
+}
+
+// GoalReached is the data of goal.reached events. Amounts are decimals in the pool's base currency.
+type GoalReached struct {
+	TransactionId string `json:"transactionId"`
+	Total         string `json:"total"`
+	Goal          string `json:"goal"`
+	Currency      string `json:"currency"`
+}
+
+// PoolClosed is the data of pool.closed events.
+type PoolClosed struct {
+	Title    string `json:"title"`
+	Total    string `json:"total"`
+	Currency string `json:"currency"`
+}
+
+// UnmatchedPayment is the data of payment.unmatched events. Pools lists the pools the note named, if it named several.
+type UnmatchedPayment struct {
+	Name     string   `json:"name,omitempty"`
+	Amount   string   `json:"amount"`
+	Currency string   `json:"currency"`
+	Note     string   `json:"note"`
+	PaypalId string   `json:"paypalId,omitempty"`
+	Pools    []string `json:"pools,omitempty"`
+}
+
+func newTransaction(contribution data.Contribution) Transaction {
+	transaction := Transaction{
+		Id:        contribution.Id,
+		Date:      contribution.Date,
+		Amount:    contribution.Amount.String(),
+		Currency:  data.CurrencyOrDefault(contribution.Currency),
+		Anonymous: contribution.Anonymous,
+		RefundOf:  contribution.RefundOf,
+	}
+	if !contribution.Anonymous {
+		transaction.Name = contribution.Name
+	}
+	return transaction
+}
+
+func newUnmatchedPayment(payment data.Transaction, pools []string) UnmatchedPayment {
+	unmatched := UnmatchedPayment{
+		Amount:   payment.Amount().String(),
+		Currency: data.CurrencyOrDefault(payment.Currency),
+		Note:     payment.Note,
+		PaypalId: payment.PaypalId,
+		Pools:    pools,
+	}
+	if !strings.Contains(strings.ToLower(payment.Note), data.AnonymousMarker) {
+		unmatched.Name = payment.Name
+	}
+	return unmatched
+}
+
+func formatCents(cents int) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// unwrapURLError leaves the url out of errors of http requests, since it may contain a secret.
+func unwrapURLError(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,287 @@
+// Package webhook delivers pool events to the webhooks that owners subscribed to via the api. Payloads are signed with
+// the subscription's secret. Failed deliveries are retried with exponential backoff, and every delivery is kept in the
+// delivery log.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"transaction/data"
+)
+
+// Headers of deliveries. The signature is "sha256=" followed by the hex encoded HMAC-SHA256 of the timestamp, a dot
+// and the body, keyed with the subscription's secret.
+const (
+	SignatureHeader = "X-Moneypool-Signature"
+	TimestampHeader = "X-Moneypool-Timestamp"
+	EventHeader     = "X-Moneypool-Event"
+	DeliveryHeader  = "X-Moneypool-Delivery"
+)
+
+// MaxAttempts limits the attempts of a delivery, which are retried after 1, 2, 4 ... minutes.
+const MaxAttempts = 8
+
+const firstRetryDelay = time.Minute
+
+// logRetention is how long deliveries are kept in the log.
+const logRetention = 30 * 24 * time.Hour
+
+// deliveryTimeFormat has a fixed width, so delivery ids sort by time.
+const deliveryTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+type Store interface {
+	GetNotifiedPool(moneyPool string) (*data.NotifiedPool, error)
+	GetNotifiedPools() ([]data.NotifiedPool, error)
+}
+
+type DeliveryLog interface {
+	Put(delivery data.WebhookDelivery) error
+	// DueDeliveries returns the pending deliveries whose next attempt is due.
+	DueDeliveries(now time.Time) ([]data.WebhookDelivery, error)
+}
+
+type HTTPClient interface {
+	Do(request *http.Request) (*http.Response, error)
+}
+
+// Request is an event sent by the api, or the scheduled request to retry failed deliveries.
+type Request struct {
+	Type      string `json:"type"`
+	MoneyPool string `json:"moneyPool"`
+	Retry     bool   `json:"retry"`
+}
+
+// Event is the payload of a delivery. Its id is the same for the deliveries of the event to all subscriptions.
+type Event struct {
+	Id        string      `json:"id"`
+	Type      string      `json:"type"`
+	MoneyPool string      `json:"moneyPool"`
+	CreatedAt string      `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+type Dispatcher struct {
+	Store  Store
+	Log    DeliveryLog
+	Client HTTPClient
+	// DefaultOwner receives the unmatched payments of the default tenant, whose pools belong to different owners. If
+	// empty, these payments are not delivered.
+	DefaultOwner string
+	now          func() time.Time
+}
+
+func New(store Store, log DeliveryLog) *Dispatcher {
+	return &Dispatcher{
+		Store:  store,
+		Log:    log,
+		Client: &http.Client{Timeout: 10 * time.Second},
+		now:    time.Now,
+	}
+}
+
+// Handle sends the event requested by the api, or retries the due deliveries.
+func (d *Dispatcher) Handle(request Request) error {
+	if request.Retry {
+		return d.Retry()
+	}
+	if request.Type != data.WebhookPoolClosed {
+		return fmt.Errorf("unknown webhook event %s", request.Type)
+	}
+	pool, err := d.Store.GetNotifiedPool(request.MoneyPool)
+	if err != nil {
+		return err
+	}
+	if pool == nil {
+		return fmt.Errorf("moneypool %s not found", request.MoneyPool)
+	}
+	return d.publish(*pool, data.WebhookPoolClosed, PoolClosed{Title: pool.Title, Total: formatCents(pool.Total()), Currency: data.CurrencyOrDefault(pool.BaseCurrency)})
+}
+
+// TransactionAdded sends the stored transaction, and the reached goal if the transaction made the pool reach it.
+func (d *Dispatcher) TransactionAdded(moneyPool, transactionId string) error {
+	pool, err := d.Store.GetNotifiedPool(moneyPool)
+	if err != nil {
+		return err
+	}
+	if pool == nil || len(pool.Webhooks) == 0 {
+		return nil
+	}
+	contribution := pool.Contribution(transactionId)
+	if contribution == nil {
+		return fmt.Errorf("transaction %s not found in moneypool %s", transactionId, moneyPool)
+	}
+	if err := d.publish(*pool, data.WebhookTransactionAdded, newTransaction(*contribution)); err != nil {
+		return err
+	}
+	if pool.ReachesGoal(*contribution) {
+		return d.publish(*pool, data.WebhookGoalReached, GoalReached{
+			TransactionId: transactionId,
+			Total:         formatCents(pool.Total()),
+			Goal:          formatCents(pool.Notifications.Goal.Cents()),
+			Currency:      data.CurrencyOrDefault(pool.BaseCurrency),
+		})
+	}
+	return nil
+}
+
+// PaymentUnmatched sends a payment whose note names no pool, or several pools, to the subscriptions of the pools that
+// the tenant's owner has in the tenant. Unmatched payments of the default tenant only go to the pools of the default
+// owner, if one is configured.
+func (d *Dispatcher) PaymentUnmatched(tenant data.Tenant, payment data.Transaction, pools []string) error {
+	owner := tenant.Owner
+	if tenant.Name == "" {
+		owner = d.DefaultOwner
+	}
+	if owner == "" {
+		return nil
+	}
+	allPools, err := d.Store.GetNotifiedPools()
+	if err != nil {
+		return err
+	}
+	unmatched := newUnmatchedPayment(payment, pools)
+	var errs []error
+	for _, pool := range allPools {
+		if pool.Tenant != tenant.Name || pool.Owner != owner {
+			continue
+		}
+		if err := d.publish(pool, data.WebhookPaymentUnmatched, unmatched); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("could not log deliveries of %d pools, first error: %v", len(errs), errs[0])
+	}
+	return nil
+}
+
+// publish delivers the event to all of the pool's subscriptions for its type. Deliveries that fail are retried later,
+// so only failing to log a delivery is an error.
+func (d *Dispatcher) publish(pool data.NotifiedPool, eventType string, eventData interface{}) error {
+	now := d.now().UTC()
+	payload, err := json.Marshal(Event{
+		Id:        uuid.New().String(),
+		Type:      eventType,
+		MoneyPool: pool.Name,
+		CreatedAt: now.Format(time.RFC3339),
+		Data:      eventData,
+	})
+	if err != nil {
+		return fmt.Errorf("could not encode %s event: %v", eventType, err)
+	}
+	for _, subscription := range pool.Webhooks {
+		if !subscription.Subscribes(eventType) {
+			continue
+		}
+		delivery := data.WebhookDelivery{
+			Pool:         pool.Name,
+			DeliveryId:   now.Format(deliveryTimeFormat) + "#" + uuid.New().String(),
+			Subscription: subscription.Id,
+			Event:        eventType,
+			Payload:      string(payload),
+			CreatedAt:    now.Format(time.RFC3339),
+			ExpiresAt:    now.Add(logRetention).Unix(),
+		}
+		d.attempt(&delivery, subscription)
+		if err := d.Log.Put(delivery); err != nil {
+			return fmt.Errorf("could not log delivery %s: %v", delivery.DeliveryId, err)
+		}
+	}
+	return nil
+}
+
+// Retry attempts all due deliveries again. Deliveries whose subscription was removed in the meantime fail.
+func (d *Dispatcher) Retry() error {
+	deliveries, err := d.Log.DueDeliveries(d.now())
+	if err != nil {
+		return err
+	}
+	pools := map[string]*data.NotifiedPool{}
+	for _, delivery := range deliveries {
+		pool, cached := pools[delivery.Pool]
+		if !cached {
+			if pool, err = d.Store.GetNotifiedPool(delivery.Pool); err != nil {
+				return err
+			}
+			pools[delivery.Pool] = pool
+		}
+		subscription := findSubscription(pool, delivery.Subscription)
+		if subscription == nil {
+			delivery.Status, delivery.NextAttempt, delivery.Error = data.DeliveryFailed, "", "subscription was removed"
+		} else {
+			d.attempt(&delivery, *subscription)
+		}
+		if err := d.Log.Put(delivery); err != nil {
+			return fmt.Errorf("could not log delivery %s: %v", delivery.DeliveryId, err)
+		}
+	}
+	return nil
+}
+
+// attempt sends the delivery's payload, and records the outcome in the delivery.
+func (d *Dispatcher) attempt(delivery *data.WebhookDelivery, subscription data.WebhookSubscription) {
+	now := d.now().UTC()
+	delivery.Attempts++
+	delivery.LastAttempt = now.Format(time.RFC3339)
+	delivery.ResponseStatus, delivery.Error = d.send(*delivery, subscription, now)
+	switch {
+	case delivery.Error == "":
+		delivery.Status, delivery.NextAttempt = data.DeliveryDelivered, ""
+	case delivery.Attempts >= MaxAttempts:
+		delivery.Status, delivery.NextAttempt = data.DeliveryFailed, ""
+	default:
+		delay := firstRetryDelay << (delivery.Attempts - 1)
+		delivery.Status, delivery.NextAttempt = data.DeliveryPending, now.Add(delay).Format(time.RFC3339)
+	}
+}
+
+// send posts the payload, and returns the response's status and why the delivery failed, if it did.
+func (d *Dispatcher) send(delivery data.WebhookDelivery, subscription data.WebhookSubscription, now time.Time) (int, string) {
+	request, err := http.NewRequest(http.MethodPost, subscription.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "invalid webhook url"
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventHeader, delivery.Event)
+	request.Header.Set(DeliveryHeader, delivery.DeliveryId)
+	request.Header.Set(TimestampHeader, timestamp)
+	request.Header.Set(SignatureHeader, Sign(subscription.Secret, timestamp, []byte(delivery.Payload)))
+	response, err := d.Client.Do(request)
+	if err != nil {
+		return 0, fmt.Sprintf("could not send request: %v", unwrapURLError(err))
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Sprintf("webhook answered with status %d", response.StatusCode)
+	}
+	return response.StatusCode, ""
+}
+
+// Sign returns the signature of a delivery's body, sent at the unix timestamp.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func findSubscription(pool *data.NotifiedPool, id string) *data.WebhookSubscription {
+	if pool == nil {
+		return nil
+	}
+	for _, subscription := range pool.Webhooks {
+		if subscription.Id == id {
+			return &subscription
+		}
+	}
+	return nil
+}
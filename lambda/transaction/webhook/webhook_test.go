@@ -0,0 +1,281 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"transaction/data"
+)
+
+var testNow = time.Date(2022, 3, 2, 10, 0, 0, 0, time.UTC)
+
+func TestSign(t *testing.T) {
+	// computed with: printf '1646215200.{"id":"1"}' | openssl dgst -sha256 -hmac secret
+	expected := "sha256=700d3e9129d0a2ca21097b1d815e8a819a8349f038bd538fd2345bd00b565e9a"
+	if signature := Sign("secret", "1646215200", []byte(`{"id":"1"}`)); signature != expected {
+		t.Fatalf("Sign returned %s, but expected %s", signature, expected)
+	}
+}
+
+func TestTransactionAdded(t *testing.T) {
+	server := newTestServer(http.StatusOK)
+	defer server.Close()
+	log := &FakeDeliveryLog{}
+	pool := testPool(server.URL,
+		data.Contribution{Id: "1", Name: "Anna", Date: "01.03.22", Amount: data.Amount{Base: 30}},
+		data.Contribution{Id: "2", Name: "Paul", Date: "02.03.22", Amount: data.Amount{Base: 20, Fraction: 50}, Anonymous: true},
+	)
+	dispatcher := testDispatcher(&FakeStore{pools: []data.NotifiedPool{pool}}, log)
+
+	if err := dispatcher.TransactionAdded("mom", "2"); err != nil {
+		t.Fatalf("TransactionAdded returned error %v", err)
+	}
+	if len(server.requests) != 3 || len(log.deliveries) != 3 {
+		t.Fatalf("expected transaction and goal deliveries to the spreadsheet and a transaction delivery to the dashboard, but got %d requests and deliveries %+v",
+			len(server.requests), log.deliveries)
+	}
+
+	request := server.requests[0]
+	delivery := log.deliveries[0]
+	if request.path != "/spreadsheet" || request.header.Get(EventHeader) != data.WebhookTransactionAdded ||
+		request.header.Get(DeliveryHeader) != delivery.DeliveryId || request.header.Get(TimestampHeader) != "1646215200" ||
+		request.header.Get(SignatureHeader) != Sign("spreadsheet-secret", "1646215200", []byte(request.body)) {
+		t.Fatalf("unexpected request %+v for delivery %+v", request, delivery)
+	}
+	var event struct {
+		Event
+		Data Transaction `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(request.body), &event); err != nil {
+		t.Fatalf("invalid payload %s: %v", request.body, err)
+	}
+	expectedTransaction := Transaction{Id: "2", Date: "02.03.22", Amount: "20.50", Currency: "EUR", Anonymous: true}
+	if event.Type != data.WebhookTransactionAdded || event.MoneyPool != "mom" || event.CreatedAt != "2022-03-02T10:00:00Z" ||
+		event.Id == "" || event.Data != expectedTransaction {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if delivery.Status != data.DeliveryDelivered || delivery.Attempts != 1 || delivery.ResponseStatus != 200 || delivery.Subscription != "spreadsheet" ||
+		delivery.Payload != request.body || delivery.ExpiresAt != testNow.Add(logRetention).Unix() {
+		t.Fatalf("unexpected delivery %+v", delivery)
+	}
+
+	goal := server.requests[2]
+	expectedGoal := `"data":{"transactionId":"2","total":"50.50","goal":"50.00","currency":"EUR"}`
+	if goal.header.Get(EventHeader) != data.WebhookGoalReached || !strings.Contains(goal.body, expectedGoal) {
+		t.Fatalf("expected goal payload with %s, but got %s", expectedGoal, goal.body)
+	}
+}
+
+func TestFailedDeliveries(t *testing.T) {
+	server := newTestServer(http.StatusServiceUnavailable)
+	defer server.Close()
+	log := &FakeDeliveryLog{}
+	pool := testPool(server.URL, data.Contribution{Id: "1", Name: "Anna", Date: "01.03.22", Amount: data.Amount{Base: 5}})
+	store := &FakeStore{pools: []data.NotifiedPool{pool}}
+	dispatcher := testDispatcher(store, log)
+
+	if err := dispatcher.TransactionAdded("mom", "1"); err != nil {
+		t.Fatalf("TransactionAdded returned error %v", err)
+	}
+	delivery := log.deliveries[0]
+	if delivery.Status != data.DeliveryPending || delivery.ResponseStatus != 503 || delivery.Error != "webhook answered with status 503" ||
+		delivery.NextAttempt != "2022-03-02T10:01:00Z" {
+		t.Fatalf("unexpected failed delivery %+v", delivery)
+	}
+
+	// retries wait twice as long after every attempt, and stop after the last attempt
+	expectedNextAttempts := []string{"2022-03-02T10:03:00Z", "2022-03-02T10:07:00Z", "2022-03-02T10:15:00Z", "2022-03-02T10:31:00Z",
+		"2022-03-02T11:03:00Z", "2022-03-02T12:07:00Z", ""}
+	for _, expected := range expectedNextAttempts {
+		dispatcher.now = func() time.Time { next, _ := time.Parse(time.RFC3339, log.deliveries[0].NextAttempt); return next }
+		if err := dispatcher.Retry(); err != nil {
+			t.Fatalf("Retry returned error %v", err)
+		}
+		if log.deliveries[0].NextAttempt != expected {
+			t.Fatalf("expected next attempt %s, but got delivery %+v", expected, log.deliveries[0])
+		}
+	}
+	if delivery := log.deliveries[0]; delivery.Status != data.DeliveryFailed || delivery.Attempts != MaxAttempts {
+		t.Fatalf("expected failed delivery after %d attempts, but got %+v", MaxAttempts, delivery)
+	}
+
+	// a delivery succeeds on retry, and the delivery to a removed subscription fails
+	log.deliveries[0].Status, log.deliveries[0].NextAttempt = data.DeliveryPending, "2022-03-02T13:00:00Z"
+	log.deliveries[1].NextAttempt = "2022-03-02T13:00:00Z"
+	store.pools[0].Webhooks = store.pools[0].Webhooks[:1]
+	server.status = http.StatusNoContent
+	dispatcher.now = func() time.Time { return time.Date(2022, 3, 2, 13, 0, 0, 0, time.UTC) }
+	if err := dispatcher.Retry(); err != nil {
+		t.Fatalf("Retry returned error %v", err)
+	}
+	if delivery := log.deliveries[0]; delivery.Status != data.DeliveryDelivered || delivery.NextAttempt != "" || delivery.ResponseStatus != 204 {
+		t.Fatalf("expected delivered delivery, but got %+v", delivery)
+	}
+	if delivery := log.deliveries[1]; delivery.Status != data.DeliveryFailed || delivery.Error != "subscription was removed" {
+		t.Fatalf("expected failed delivery to removed subscription, but got %+v", delivery)
+	}
+}
+
+func TestPaymentUnmatched(t *testing.T) {
+	server := newTestServer(http.StatusOK)
+	defer server.Close()
+	log := &FakeDeliveryLog{}
+	pool := testPool(server.URL)
+	strangers := testPool(server.URL)
+	strangers.Name, strangers.Owner = "dad", "other-sub"
+	tenants := testPool(server.URL)
+	tenants.Name, tenants.Tenant = "smiths.mom", "smiths"
+	dispatcher := testDispatcher(&FakeStore{pools: []data.NotifiedPool{pool, strangers, tenants}}, log)
+
+	payment := data.Transaction{Name: "Anna", Base: 12, Fraction: 5, Note: "for mama", Currency: "USD", PaypalId: "ABC"}
+	if err := dispatcher.PaymentUnmatched(data.Tenant{}, payment, nil); err != nil || len(server.requests) > 0 {
+		t.Fatalf("PaymentUnmatched without default owner = %v, %d deliveries, but expected no deliveries", err, len(server.requests))
+	}
+
+	dispatcher.DefaultOwner = "owner-sub"
+	if err := dispatcher.PaymentUnmatched(data.Tenant{}, payment, nil); err != nil {
+		t.Fatalf("PaymentUnmatched returned error %v", err)
+	}
+	if len(server.requests) != 1 || log.deliveries[0].Pool != "mom" || log.deliveries[0].Subscription != "dashboard" {
+		t.Fatalf("expected a delivery to the dashboard of the default owner's pool, but got %+v", log.deliveries)
+	}
+	expected := `"data":{"name":"Anna","amount":"12.05","currency":"USD","note":"for mama","paypalId":"ABC"}`
+	if !strings.Contains(server.requests[0].body, expected) {
+		t.Fatalf("expected payload with %s, but got %s", expected, server.requests[0].body)
+	}
+
+	if err := dispatcher.PaymentUnmatched(data.Tenant{Name: "smiths", Owner: "owner-sub"}, payment, nil); err != nil {
+		t.Fatalf("PaymentUnmatched returned error %v", err)
+	}
+	if len(server.requests) != 2 || log.deliveries[1].Pool != "smiths.mom" {
+		t.Fatalf("expected a delivery to the tenant's pool, but got %+v", log.deliveries)
+	}
+}
+
+func TestHandlePoolClosed(t *testing.T) {
+	server := newTestServer(http.StatusOK)
+	defer server.Close()
+	log := &FakeDeliveryLog{}
+	pool := testPool(server.URL, data.Contribution{Id: "1", Name: "Anna", Date: "01.03.22", Amount: data.Amount{Base: 5}})
+	dispatcher := testDispatcher(&FakeStore{pools: []data.NotifiedPool{pool}}, log)
+
+	if err := dispatcher.Handle(Request{Type: data.WebhookPoolClosed, MoneyPool: "mom"}); err != nil {
+		t.Fatalf("Handle returned error %v", err)
+	}
+	expected := `"data":{"title":"Gift for mom","total":"5.00","currency":"EUR"}`
+	if len(server.requests) != 1 || !strings.Contains(server.requests[0].body, expected) {
+		t.Fatalf("expected a delivery with %s, but got %+v", expected, server.requests)
+	}
+
+	testTable := map[Request]error{
+		{Type: data.WebhookTransactionAdded, MoneyPool: "mom"}: errors.New("unknown webhook event transaction.added"),
+		{Type: data.WebhookPoolClosed, MoneyPool: "dad"}:       errors.New("moneypool dad not found"),
+	}
+	for request, expected := range testTable {
+		if err := dispatcher.Handle(request); !compareErrors(err, expected) {
+			t.Fatalf("Handle(%+v) = %v, but expected %v", request, err, expected)
+		}
+	}
+}
+
+// testPool returns the pool mom with a goal of 50 EUR, whose spreadsheet webhook gets transactions and goals, and whose
+// dashboard webhook gets everything but goals.
+func testPool(serverURL string, contributions ...data.Contribution) data.NotifiedPool {
+	return data.NotifiedPool{
+		Name:          "mom",
+		Owner:         "owner-sub",
+		Title:         "Gift for mom",
+		Notifications: data.Notifications{Goal: &data.Amount{Base: 50}},
+		Webhooks: []data.WebhookSubscription{
+			{Id: "spreadsheet", URL: serverURL + "/spreadsheet", Secret: "spreadsheet-secret",
+				Events: []string{data.WebhookTransactionAdded, data.WebhookGoalReached}},
+			{Id: "dashboard", URL: serverURL + "/dashboard", Secret: "dashboard-secret",
+				Events: []string{data.WebhookTransactionAdded, data.WebhookPoolClosed, data.WebhookPaymentUnmatched}},
+		},
+		Contributions: contributions,
+	}
+}
+
+func testDispatcher(store Store, log DeliveryLog) *Dispatcher {
+	dispatcher := New(store, log)
+	dispatcher.now = func() time.Time { return testNow }
+	return dispatcher
+}
+
+type receivedRequest struct {
+	path   string
+	header http.Header
+	body   string
+}
+
+type TestServer struct {
+	*httptest.Server
+	status   int
+	requests []receivedRequest
+}
+
+// newTestServer answers all requests with the status, and keeps them.
+func newTestServer(status int) *TestServer {
+	server := &TestServer{status: status}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		server.requests = append(server.requests, receivedRequest{r.URL.Path, r.Header, string(body)})
+		w.WriteHeader(server.status)
+	}))
+	return server
+}
+
+type FakeStore struct {
+	pools []data.NotifiedPool
+}
+
+func (s *FakeStore) GetNotifiedPool(moneyPool string) (*data.NotifiedPool, error) {
+	for _, pool := range s.pools {
+		if pool.Name == moneyPool {
+			return &pool, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *FakeStore) GetNotifiedPools() ([]data.NotifiedPool, error) {
+	return s.pools, nil
+}
+
+// FakeDeliveryLog keeps the deliveries in the order they were first written.
+type FakeDeliveryLog struct {
+	deliveries []data.WebhookDelivery
+}
+
+func (l *FakeDeliveryLog) Put(delivery data.WebhookDelivery) error {
+	for i := range l.deliveries {
+		if l.deliveries[i].DeliveryId == delivery.DeliveryId {
+			l.deliveries[i] = delivery
+			return nil
+		}
+	}
+	l.deliveries = append(l.deliveries, delivery)
+	return nil
+}
+
+func (l *FakeDeliveryLog) DueDeliveries(now time.Time) ([]data.WebhookDelivery, error) {
+	var due []data.WebhookDelivery
+	for _, delivery := range l.deliveries {
+		if delivery.Status == data.DeliveryPending && delivery.NextAttempt <= now.UTC().Format(time.RFC3339) {
+			due = append(due, delivery)
+		}
+	}
+	return due, nil
+}
+
+func compareErrors(err1, err2 error) bool {
+	if err1 != nil && err2 != nil {
+		return err1.Error() == err2.Error()
+	}
+	return err1 == err2
+}